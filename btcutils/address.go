@@ -0,0 +1,159 @@
+// Provides checksum-validated decoding of Bitcoin addresses of every standard type, with errors saying what is wrong with an address.
+// See https://en.bitcoin.it/wiki/Invoice_address for the address formats.
+package btcutils
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Address types identified by DecodeAddress.
+const (
+	AddressTypeP2PKH   = "P2PKH"
+	AddressTypeP2SH    = "P2SH"
+	AddressTypeP2WPKH  = "P2WPKH"
+	AddressTypeP2WSH   = "P2WSH"
+	AddressTypeP2TR    = "P2TR"
+	AddressTypeWitness = "SegWit" //Witness versions 1 to 16 without a defined meaning yet, or version 1 programs not 32 bytes long
+)
+
+// Address is a decoded Bitcoin address.
+type Address struct {
+	Type           string
+	Network        *Network
+	Hash           []byte //Public key hash or script hash for P2PKH and P2SH, the witness program for SegWit types
+	WitnessVersion byte   //Witness version for SegWit types
+}
+
+// DecodeAddress decodes a Base58Check (P2PKH or P2SH) or Bech32/Bech32m (native SegWit) address, verifying its checksum and
+// identifying its type. Addresses of networks other than network are rejected with an error naming the network they are for.
+func DecodeAddress(address string, network *Network) (*Address, error) {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return nil, errors.New("Address cannot be empty.")
+	}
+	if hrp := segWitHRP(address); hrp != "" {
+		return decodeSegWitAddress(address, hrp, network)
+	}
+	return decodeBase58Address(address, network)
+}
+
+// segWitHRP returns the human-readable part of address if it is the Bech32 human-readable part of a known network, or an empty string otherwise.
+func segWitHRP(address string) string {
+	lowerAddress := strings.ToLower(address)
+	separator := strings.LastIndex(lowerAddress, "1")
+	if separator < 1 {
+		return ""
+	}
+	for _, network := range Networks {
+		if network.Bech32HRP == lowerAddress[:separator] {
+			return network.Bech32HRP
+		}
+	}
+	return ""
+}
+
+// decodeSegWitAddress decodes a native SegWit address with human-readable part hrp, checking it belongs to network.
+func decodeSegWitAddress(address string, hrp string, network *Network) (*Address, error) {
+	if hrp != network.Bech32HRP {
+		otherNames := otherNetworkNames(network, func(other *Network) bool { return other.Bech32HRP == hrp })
+		return nil, errors.New(fmt.Sprintf("Address '%s' is a SegWit address for %s, not %s.", address, otherNames, network.Name))
+	}
+	witnessVersion, witnessProgram, err := DecodeSegWitAddress(hrp, address)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Address '%s' is not a valid SegWit address. %v Check it for typos.", address, err))
+	}
+	decoded := &Address{Type: AddressTypeWitness, Network: network, Hash: witnessProgram, WitnessVersion: witnessVersion}
+	switch {
+	case witnessVersion == 0 && len(witnessProgram) == 20:
+		decoded.Type = AddressTypeP2WPKH
+	case witnessVersion == 0 && len(witnessProgram) == 32:
+		decoded.Type = AddressTypeP2WSH
+	case witnessVersion == 1 && len(witnessProgram) == 32:
+		decoded.Type = AddressTypeP2TR
+	}
+	return decoded, nil
+}
+
+// decodeBase58Address decodes a Base58Check P2PKH or P2SH address, checking it belongs to network.
+func decodeBase58Address(address string, network *Network) (*Address, error) {
+	decoded, err := DecodeBase58Check(address)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Address '%s' is not a valid address. %v Check it for typos.", address, err))
+	}
+	version := hex.EncodeToString(decoded[:1])
+	isWIF := func(n *Network) bool { return n.WIFPrefix == version }
+	if (len(decoded) == 33 || len(decoded) == 34) && (isWIF(network) || otherNetworkNames(network, isWIF) != "") {
+		return nil, errors.New(fmt.Sprintf("'%s' is a private key, not an address. Never share private keys.", address))
+	}
+	if len(decoded) != 21 {
+		return nil, errors.New(fmt.Sprintf("Address '%s' is not a valid address. It holds %d bytes, should hold a version byte and a 20 byte hash.", address, len(decoded)))
+	}
+	addressTypes := []struct {
+		addressType string
+		prefix      func(*Network) string
+	}{
+		{AddressTypeP2PKH, func(n *Network) string { return n.PubKeyHashPrefix }},
+		{AddressTypeP2SH, func(n *Network) string { return n.ScriptHashPrefix }},
+	}
+	for _, addressType := range addressTypes {
+		isType := func(n *Network) bool { return addressType.prefix(n) == version }
+		if isType(network) {
+			return &Address{Type: addressType.addressType, Network: network, Hash: decoded[1:]}, nil
+		}
+		if otherNames := otherNetworkNames(network, isType); otherNames != "" {
+			return nil, errors.New(fmt.Sprintf("Address '%s' is a %s address for %s, not %s.", address, addressType.addressType, otherNames, network.Name))
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("Address '%s' has unknown version byte 0x%s.", address, version))
+}
+
+// ScriptPubKey creates the scriptPubKey paying to the address.
+func (address *Address) ScriptPubKey() ([]byte, error) {
+	switch address.Type {
+	case AddressTypeP2PKH:
+		return NewP2PKHScriptPubKey(address.Hash)
+	case AddressTypeP2SH:
+		return NewP2SHScriptPubKey(address.Hash)
+	}
+	err := checkWitnessProgram(address.WitnessVersion, address.Hash)
+	if err != nil {
+		return nil, err
+	}
+	//SegWit scriptPubKey format:
+	//<OP_0 or OP_1 to OP_16 witness version> <witness program>
+	var scriptPubKey bytes.Buffer
+	if address.WitnessVersion == 0 {
+		scriptPubKey.WriteByte(byte(OP_0))
+	} else {
+		scriptPubKey.WriteByte(byte(OP_1 - 1 + int(address.WitnessVersion)))
+	}
+	scriptPubKey.WriteByte(byte(len(address.Hash))) //PUSH
+	scriptPubKey.Write(address.Hash)
+	return scriptPubKey.Bytes(), nil
+}
+
+// DecodeP2PKHAddress decodes a P2PKH address into its 20 byte public key hash, checking it belongs to network.
+func DecodeP2PKHAddress(address string, network *Network) ([]byte, error) {
+	return decodeAddressOfType(address, network, AddressTypeP2PKH)
+}
+
+// DecodeP2SHAddress decodes a P2SH address into its 20 byte redeem script hash, checking it belongs to network.
+func DecodeP2SHAddress(address string, network *Network) ([]byte, error) {
+	return decodeAddressOfType(address, network, AddressTypeP2SH)
+}
+
+// decodeAddressOfType decodes an address with DecodeAddress, checking it has type addressType, and returns its hash.
+func decodeAddressOfType(address string, network *Network, addressType string) ([]byte, error) {
+	decoded, err := DecodeAddress(address, network)
+	if err != nil {
+		return nil, err
+	}
+	if decoded.Type != addressType {
+		return nil, errors.New(fmt.Sprintf("Address '%s' is a %s address, but a %s address is needed here.", strings.TrimSpace(address), decoded.Type, addressType))
+	}
+	return decoded.Hash, nil
+}
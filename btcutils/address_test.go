@@ -0,0 +1,86 @@
+package btcutils
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+func TestDecodeAddress(t *testing.T) {
+	testAddresses := []struct {
+		address         string
+		network         *Network
+		addressType     string
+		scriptPubKeyHex string
+	}{
+		{"16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvM", MainNet, AddressTypeP2PKH, "76a914010966776006953d5567439e5e39f86a0d273bee88ac"},
+		{"31nVrspaydBz8aMpxH9WkS2DuhgqS1fCuG", MainNet, AddressTypeP2SH, "a914010966776006953d5567439e5e39f86a0d273bee87"},
+		{"mfcSEPR8EkJrpX91YkTJ9iscdAzppJrG9j", TestNet3, AddressTypeP2PKH, "76a914010966776006953d5567439e5e39f86a0d273bee88ac"},
+		{"2MsLhvckcb5hLLMzNdQmPNP1V83u1HVdeEb", RegTest, AddressTypeP2SH, "a914010966776006953d5567439e5e39f86a0d273bee87"},
+		//Examples from BIP173 and BIP350
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", MainNet, AddressTypeP2WPKH, "0014751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", SigNet, AddressTypeP2WSH, "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", MainNet, AddressTypeP2TR, "512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"},
+		{"bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs", MainNet, AddressTypeWitness, "5210751e76e8199196d454941c45d1b3a323"},
+		{"bcrt1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qzf4jry", RegTest, AddressTypeP2WSH, "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"},
+	}
+	for _, testAddress := range testAddresses {
+		address, err := DecodeAddress(testAddress.address, testAddress.network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if address.Type != testAddress.addressType {
+			testutils.CompareError(t, "Decoded address type different from expected type.", testAddress.addressType, address.Type)
+		}
+		if address.Network != testAddress.network {
+			testutils.CompareError(t, "Decoded address network different from expected network.", testAddress.network.Name, address.Network.Name)
+		}
+		scriptPubKey, err := address.ScriptPubKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(scriptPubKey) != testAddress.scriptPubKeyHex {
+			testutils.CompareError(t, "scriptPubKey of address different from expected scriptPubKey.", testAddress.scriptPubKeyHex, hex.EncodeToString(scriptPubKey))
+		}
+	}
+
+	invalidAddresses := []struct {
+		address string
+		network *Network
+	}{
+		{"", MainNet}, //empty address
+		{"16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvN", MainNet},                              //bad checksum
+		{"16UwLL9Risc3QfPqBUvKofHmBQ7wMtjv", MainNet},                               //truncated
+		{"mfcSEPR8EkJrpX91YkTJ9iscdAzppJrG9j", MainNet},                             //testnet address
+		{"31nVrspaydBz8aMpxH9WkS2DuhgqS1fCuG", SigNet},                              //mainnet address
+		{"5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ", MainNet},            //WIF private key
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", MainNet},                     //bad Bech32 checksum
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", RegTest}, //testnet SegWit address on regtest
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd", MainNet}, //Taproot address with Bech32 instead of Bech32m checksum
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh", MainNet},                     //version 0 address with Bech32m checksum
+		{"ltc1qw508d6qejxtdg4y5r3zarvary0c5xw7kgmn4n9", MainNet},                    //unknown human-readable part
+	}
+	for _, invalidAddress := range invalidAddresses {
+		if _, err := DecodeAddress(invalidAddress.address, invalidAddress.network); err == nil {
+			t.Error("DecodeAddress accepting invalid "+invalidAddress.network.Name+" address as valid:", invalidAddress.address)
+		}
+	}
+}
+
+func TestDecodeAddressOfType(t *testing.T) {
+	testHashHex := "010966776006953d5567439e5e39f86a0d273bee"
+	hash, err := DecodeP2PKHAddress("16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvM", MainNet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(hash) != testHashHex {
+		testutils.CompareError(t, "Hash decoded from P2PKH address different from expected hash.", testHashHex, hex.EncodeToString(hash))
+	}
+	if _, err := DecodeP2PKHAddress("31nVrspaydBz8aMpxH9WkS2DuhgqS1fCuG", MainNet); err == nil {
+		t.Error("DecodeP2PKHAddress accepting P2SH address.")
+	}
+	if _, err := DecodeP2SHAddress("BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", MainNet); err == nil {
+		t.Error("DecodeP2SHAddress accepting P2WPKH address.")
+	}
+}
@@ -0,0 +1,134 @@
+// Provides disassembly of scripts into ASM, the human-readable form of a script, and assembly of ASM back into scripts.
+// See https://en.bitcoin.it/wiki/Script for the opcode names used.
+package btcutils
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// opcodeNames gives the ASM name of each opcode. Opcodes 0x01-0x4b push data directly and have no name.
+var opcodeNames = [256]string{
+	OP_0: "OP_0", OP_PUSHDATA1: "OP_PUSHDATA1", OP_PUSHDATA2: "OP_PUSHDATA2", OP_PUSHDATA4: "OP_PUSHDATA4",
+	OP_1NEGATE: "OP_1NEGATE", OP_RESERVED: "OP_RESERVED",
+	OP_1: "OP_1", OP_2: "OP_2", OP_3: "OP_3", OP_4: "OP_4", OP_5: "OP_5", OP_6: "OP_6", OP_7: "OP_7", OP_8: "OP_8",
+	OP_9: "OP_9", OP_10: "OP_10", OP_11: "OP_11", OP_12: "OP_12", OP_13: "OP_13", OP_14: "OP_14", OP_15: "OP_15", OP_16: "OP_16",
+	OP_NOP: "OP_NOP", OP_VER: "OP_VER", OP_IF: "OP_IF", OP_NOTIF: "OP_NOTIF", OP_VERIF: "OP_VERIF", OP_VERNOTIF: "OP_VERNOTIF",
+	OP_ELSE: "OP_ELSE", OP_ENDIF: "OP_ENDIF", OP_VERIFY: "OP_VERIFY", OP_RETURN: "OP_RETURN",
+	OP_TOALTSTACK: "OP_TOALTSTACK", OP_FROMALTSTACK: "OP_FROMALTSTACK", OP_2DROP: "OP_2DROP", OP_2DUP: "OP_2DUP",
+	OP_3DUP: "OP_3DUP", OP_2OVER: "OP_2OVER", OP_2ROT: "OP_2ROT", OP_2SWAP: "OP_2SWAP", OP_IFDUP: "OP_IFDUP",
+	OP_DEPTH: "OP_DEPTH", OP_DROP: "OP_DROP", OP_DUP: "OP_DUP", OP_NIP: "OP_NIP", OP_OVER: "OP_OVER", OP_PICK: "OP_PICK",
+	OP_ROLL: "OP_ROLL", OP_ROT: "OP_ROT", OP_SWAP: "OP_SWAP", OP_TUCK: "OP_TUCK",
+	OP_CAT: "OP_CAT", OP_SUBSTR: "OP_SUBSTR", OP_LEFT: "OP_LEFT", OP_RIGHT: "OP_RIGHT", OP_SIZE: "OP_SIZE",
+	OP_INVERT: "OP_INVERT", OP_AND: "OP_AND", OP_OR: "OP_OR", OP_XOR: "OP_XOR", OP_EQUAL: "OP_EQUAL",
+	OP_EQUALVERIFY: "OP_EQUALVERIFY", OP_RESERVED1: "OP_RESERVED1", OP_RESERVED2: "OP_RESERVED2",
+	OP_1ADD: "OP_1ADD", OP_1SUB: "OP_1SUB", OP_2MUL: "OP_2MUL", OP_2DIV: "OP_2DIV", OP_NEGATE: "OP_NEGATE", OP_ABS: "OP_ABS",
+	OP_NOT: "OP_NOT", OP_0NOTEQUAL: "OP_0NOTEQUAL", OP_ADD: "OP_ADD", OP_SUB: "OP_SUB", OP_MUL: "OP_MUL", OP_DIV: "OP_DIV",
+	OP_MOD: "OP_MOD", OP_LSHIFT: "OP_LSHIFT", OP_RSHIFT: "OP_RSHIFT", OP_BOOLAND: "OP_BOOLAND", OP_BOOLOR: "OP_BOOLOR",
+	OP_NUMEQUAL: "OP_NUMEQUAL", OP_NUMEQUALVERIFY: "OP_NUMEQUALVERIFY", OP_NUMNOTEQUAL: "OP_NUMNOTEQUAL",
+	OP_LESSTHAN: "OP_LESSTHAN", OP_GREATERTHAN: "OP_GREATERTHAN", OP_LESSTHANOREQUAL: "OP_LESSTHANOREQUAL",
+	OP_GREATERTHANOREQUAL: "OP_GREATERTHANOREQUAL", OP_MIN: "OP_MIN", OP_MAX: "OP_MAX", OP_WITHIN: "OP_WITHIN",
+	OP_RIPEMD160: "OP_RIPEMD160", OP_SHA1: "OP_SHA1", OP_SHA256: "OP_SHA256", OP_HASH160: "OP_HASH160", OP_HASH256: "OP_HASH256",
+	OP_CODESEPARATOR: "OP_CODESEPARATOR", OP_CHECKSIG: "OP_CHECKSIG", OP_CHECKSIGVERIFY: "OP_CHECKSIGVERIFY",
+	OP_CHECKMULTISIG: "OP_CHECKMULTISIG", OP_CHECKMULTISIGVERIFY: "OP_CHECKMULTISIGVERIFY",
+	OP_NOP1: "OP_NOP1", OP_CHECKLOCKTIMEVERIFY: "OP_CHECKLOCKTIMEVERIFY", OP_CHECKSEQUENCEVERIFY: "OP_CHECKSEQUENCEVERIFY",
+	OP_NOP4: "OP_NOP4", OP_NOP5: "OP_NOP5", OP_NOP6: "OP_NOP6", OP_NOP7: "OP_NOP7", OP_NOP8: "OP_NOP8", OP_NOP9: "OP_NOP9",
+	OP_NOP10: "OP_NOP10", OP_CHECKSIGADD: "OP_CHECKSIGADD", OP_INVALIDOPCODE: "OP_INVALIDOPCODE",
+}
+
+// opcodeAliases are other names accepted for opcodes when assembling ASM.
+var opcodeAliases = map[string]byte{
+	"OP_FALSE": OP_FALSE,
+	"OP_TRUE":  OP_TRUE,
+	"OP_NOP2":  OP_NOP2,
+	"OP_NOP3":  OP_NOP3,
+}
+
+// OpcodeName returns the ASM name of opcode, eg. OP_CHECKMULTISIG. Unassigned opcodes are named OP_UNKNOWN followed by
+// their value, and direct push opcodes 0x01-0x4b are named OP_DATA_ followed by the number of bytes they push.
+func OpcodeName(opcode byte) string {
+	switch {
+	case opcodeNames[opcode] != "":
+		return opcodeNames[opcode]
+	case opcode < OP_PUSHDATA1:
+		return fmt.Sprintf("OP_DATA_%d", opcode)
+	default:
+		return fmt.Sprintf("OP_UNKNOWN%d", opcode)
+	}
+}
+
+// DisassembleScript converts script into ASM: opcode names and hex data pushes separated by spaces,
+// eg. "OP_2 <public key> <public key> <public key> OP_3 OP_CHECKMULTISIG".
+// Pushes not in their smallest encoding are written as raw script bytes prefixed with 0x, so AssembleScript gives back the same script.
+func DisassembleScript(script []byte) (string, error) {
+	ops, err := ParseScript(script)
+	if err != nil {
+		return "", err
+	}
+	tokens := make([]string, len(ops))
+	for i, op := range ops {
+		if op.Opcode == OP_0 || op.Opcode > OP_PUSHDATA4 {
+			tokens[i] = OpcodeName(op.Opcode)
+			continue
+		}
+		var opBuffer, minimalBuffer bytes.Buffer
+		writeScriptOp(&opBuffer, op)
+		WritePushData(&minimalBuffer, op.Data)
+		if bytes.Equal(opBuffer.Bytes(), minimalBuffer.Bytes()) {
+			tokens[i] = hex.EncodeToString(op.Data)
+		} else {
+			tokens[i] = "0x" + hex.EncodeToString(opBuffer.Bytes())
+		}
+	}
+	return strings.Join(tokens, " "), nil
+}
+
+// AssembleScript converts ASM back into a script. Each space separated token is an opcode name (OP_ prefix included,
+// in any case), hex data to push in its smallest push operation, or raw script bytes in hex prefixed with 0x.
+func AssembleScript(asm string) ([]byte, error) {
+	opcodeValues := make(map[string]byte)
+	for opcode, name := range opcodeNames {
+		if name != "" {
+			opcodeValues[name] = byte(opcode)
+		}
+	}
+	for name, opcode := range opcodeAliases {
+		opcodeValues[name] = opcode
+	}
+
+	var buffer bytes.Buffer
+	for _, token := range strings.Fields(asm) {
+		upperToken := strings.ToUpper(token)
+		if opcode, ok := opcodeValues[upperToken]; ok {
+			buffer.WriteByte(opcode)
+			continue
+		}
+		var opcode byte
+		if _, err := fmt.Sscanf(upperToken, "OP_UNKNOWN%d", &opcode); err == nil && OpcodeName(opcode) == upperToken {
+			buffer.WriteByte(opcode)
+			continue
+		}
+		if strings.HasPrefix(upperToken, "0X") {
+			raw, err := hex.DecodeString(token[2:])
+			if err != nil || len(raw) == 0 {
+				return nil, errors.New(fmt.Sprintf("ASM token %s is not valid raw script hex.", token))
+			}
+			buffer.Write(raw)
+			continue
+		}
+		data, err := hex.DecodeString(token)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("ASM token %s is neither an opcode name nor hex data.", token))
+		}
+		WritePushData(&buffer, data)
+	}
+	//Raw script bytes could leave a push operation short of its data
+	script := buffer.Bytes()
+	if _, err := ParseScript(script); err != nil {
+		return nil, err
+	}
+	return script, nil
+}
@@ -0,0 +1,81 @@
+package btcutils
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+var testScriptsASM = []struct {
+	script string
+	asm    string
+}{
+	//2-of-3 multisig redeem script
+	{"522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae",
+		"OP_2 03a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575 036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d 0311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef OP_3 OP_CHECKMULTISIG"},
+	//P2PKH, P2SH and P2WSH scriptPubKeys
+	{"76a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac", "OP_DUP OP_HASH160 569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab OP_EQUALVERIFY OP_CHECKSIG"},
+	{"a9141a8b0026343166625c7475f01e48b5ede8c0252e87", "OP_HASH160 1a8b0026343166625c7475f01e48b5ede8c0252e OP_EQUAL"},
+	{"002014fad15204f7a336442354d52d937820ac39eaab5ac54d55f3168a738b79d893", "OP_0 14fad15204f7a336442354d52d937820ac39eaab5ac54d55f3168a738b79d893"},
+	//Timelock, flow control and unassigned opcodes
+	{"6303a08601b17568ac", "OP_IF a08601 OP_CHECKLOCKTIMEVERIFY OP_DROP OP_ENDIF OP_CHECKSIG"},
+	{"4f00bbff", "OP_1NEGATE OP_0 OP_UNKNOWN187 OP_INVALIDOPCODE"},
+	//Pushes not in their smallest encoding are kept as raw bytes
+	{"4c02abcd4d0100ef", "0x4c02abcd 0x4d0100ef"},
+	{"", ""},
+}
+
+func TestDisassembleScript(t *testing.T) {
+	for _, testScript := range testScriptsASM {
+		script, _ := hex.DecodeString(testScript.script)
+		asm, err := DisassembleScript(script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if asm != testScript.asm {
+			testutils.CompareError(t, "Disassembled script different from expected ASM.", testScript.asm, asm)
+		}
+	}
+
+	//Push running past the end of the script
+	if _, err := DisassembleScript([]byte{0x4c, 0x05, 0x01}); err == nil {
+		t.Error("DisassembleScript accepting truncated script.")
+	}
+}
+
+func TestAssembleScript(t *testing.T) {
+	for _, testScript := range testScriptsASM {
+		script, err := AssembleScript(testScript.asm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(script) != testScript.script {
+			testutils.CompareError(t, "Assembled script different from expected script.", testScript.script, hex.EncodeToString(script))
+		}
+	}
+
+	//Aliases, lower case names and extra whitespace
+	script, err := AssembleScript("  op_true\tOP_NOP2 OP_FALSE\n OP_nop3 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(script) != "51b100b2" {
+		testutils.CompareError(t, "Assembled script different from expected script.", "51b100b2", hex.EncodeToString(script))
+	}
+
+	invalidASM := []string{
+		"OP_2 OP_CHECKMULTISIGG", //misspelt opcode
+		"OP_UNKNOWN172",          //assigned opcode as unknown
+		"OP_UNKNOWN256",          //not an opcode
+		"abc",                    //odd length hex
+		"0x",                     //empty raw bytes
+		"0x4c05ab",               //raw push past end of script
+		"OP_PUSHDATA1",           //push opcode without its length
+	}
+	for _, asm := range invalidASM {
+		if _, err := AssembleScript(asm); err == nil {
+			t.Error("AssembleScript accepting invalid ASM:", asm)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Provides Base58Check decoding that verifies the checksum, for addresses, WIF private keys and extended keys.
+// See https://en.bitcoin.it/wiki/Base58Check_encoding for full specification.
+package btcutils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// base58Alphabet is the 58 character alphabet used by Base58, indexed by value. It leaves out 0, O, I and l, which are easily confused.
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// DecodeBase58 decodes a Base58 string into bytes. Each leading '1' decodes to a leading zero byte.
+func DecodeBase58(encoded string) ([]byte, error) {
+	if encoded == "" {
+		return nil, errors.New("Base58 string cannot be empty.")
+	}
+	value := new(big.Int)
+	radix := big.NewInt(58)
+	for i := 0; i < len(encoded); i++ {
+		digit := strings.IndexByte(base58Alphabet, encoded[i])
+		if digit == -1 {
+			return nil, errors.New(fmt.Sprintf("Base58 string contains invalid character '%c'.", encoded[i]))
+		}
+		value.Mul(value, radix)
+		value.Add(value, big.NewInt(int64(digit)))
+	}
+	leadingZeros := 0
+	for leadingZeros < len(encoded) && encoded[leadingZeros] == base58Alphabet[0] {
+		leadingZeros++
+	}
+	return append(make([]byte, leadingZeros), value.Bytes()...), nil
+}
+
+// DecodeBase58Check decodes a Base58Check string, verifying its 4 byte checksum (the first 4 bytes of the double SHA256 of the rest).
+// Returns the version bytes followed by the payload, without the checksum.
+func DecodeBase58Check(encoded string) ([]byte, error) {
+	decoded, err := DecodeBase58(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) < 5 {
+		return nil, errors.New("Base58Check string is too short to hold a version byte and checksum.")
+	}
+	data := decoded[:len(decoded)-4]
+	checksum := decoded[len(decoded)-4:]
+	if !bytes.Equal(DoubleSha256(data)[:4], checksum) {
+		return nil, errors.New("Base58Check checksum is invalid.")
+	}
+	return data, nil
+}
@@ -0,0 +1,41 @@
+package btcutils
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+func TestDecodeBase58Check(t *testing.T) {
+	testCases := []struct {
+		encoded string
+		decoded string
+	}{
+		{"16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvM", "00010966776006953d5567439e5e39f86a0d273bee"},
+		{"1111111111111111111114oLvT2", "000000000000000000000000000000000000000000"},
+		{"5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ", "800c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d"},
+	}
+	for _, testCase := range testCases {
+		decoded, err := DecodeBase58Check(testCase.encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(decoded) != testCase.decoded {
+			testutils.CompareError(t, "Base58Check decoded bytes different from expected bytes.", testCase.decoded, hex.EncodeToString(decoded))
+		}
+	}
+
+	invalidStrings := []string{
+		"",                                   //empty string
+		"16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvN",  //bad checksum
+		"16UwLL9Risc3QfPqBUvKofHmBQ7wMtjv",   //truncated
+		"16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvM0", //invalid character
+		"1111",                               //too short for a checksum
+	}
+	for _, invalidString := range invalidStrings {
+		if _, err := DecodeBase58Check(invalidString); err == nil {
+			t.Error("DecodeBase58Check accepting invalid string as valid:", invalidString)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// Provides Bech32 and Bech32m encoding of native SegWit addresses.
+// See https://github.com/bitcoin/bips/blob/master/bip-0173.mediawiki and https://github.com/bitcoin/bips/blob/master/bip-0350.mediawiki for full specification.
+package btcutils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// bech32Charset is the 32 character alphabet used by Bech32, indexed by 5-bit value.
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32Generator holds the coefficients of the BCH code generator used for the Bech32 checksum.
+var bech32Generator = []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+// Checksum constants of Bech32, used for version 0 witness programs, and of Bech32m, used for version 1 (Taproot) and later.
+const (
+	bech32Constant  = 1
+	bech32mConstant = 0x2bc830a3
+)
+
+// bech32Polymod computes the Bech32 checksum polynomial over values.
+func bech32Polymod(values []byte) uint32 {
+	checksum := uint32(1)
+	for _, value := range values {
+		top := checksum >> 25
+		checksum = (checksum&0x1ffffff)<<5 ^ uint32(value)
+		for i, generator := range bech32Generator {
+			if (top>>uint(i))&1 == 1 {
+				checksum ^= generator
+			}
+		}
+	}
+	return checksum
+}
+
+// bech32HrpExpand expands the human-readable part for use in the checksum.
+func bech32HrpExpand(hrp string) []byte {
+	expanded := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
+
+// Bech32Encode encodes the 5-bit values in data with human-readable part hrp, appending a 6 character checksum.
+func Bech32Encode(hrp string, data []byte) (string, error) {
+	return bech32Encode(hrp, data, bech32Constant)
+}
+
+// Bech32mEncode encodes like Bech32Encode, but with the Bech32m checksum.
+func Bech32mEncode(hrp string, data []byte) (string, error) {
+	return bech32Encode(hrp, data, bech32mConstant)
+}
+
+// bech32Encode encodes the 5-bit values in data with human-readable part hrp and the checksum of the given constant.
+func bech32Encode(hrp string, data []byte, constant uint32) (string, error) {
+	if len(hrp) < 1 || len(hrp)+len(data)+7 > 90 {
+		return "", errors.New(fmt.Sprintf("Bech32 string would be %d characters long, must be at most 90.", len(hrp)+len(data)+7))
+	}
+	for _, value := range data {
+		if value > 31 {
+			return "", errors.New("Bech32 data values must be 5 bits.")
+		}
+	}
+	hrp = strings.ToLower(hrp)
+	values := append(bech32HrpExpand(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ constant
+	var encoded bytes.Buffer
+	encoded.WriteString(hrp)
+	encoded.WriteByte('1')
+	for _, value := range data {
+		encoded.WriteByte(bech32Charset[value])
+	}
+	for i := 0; i < 6; i++ {
+		encoded.WriteByte(bech32Charset[(polymod>>uint(5*(5-i)))&31])
+	}
+	return encoded.String(), nil
+}
+
+// Bech32Decode decodes a Bech32 string into its human-readable part and 5-bit data values, verifying the checksum.
+func Bech32Decode(bech string) (string, []byte, error) {
+	hrp, data, constant, err := bech32Decode(bech)
+	if err != nil {
+		return "", nil, err
+	}
+	if constant != bech32Constant {
+		return "", nil, errors.New("Bech32 string has a Bech32m checksum.")
+	}
+	return hrp, data, nil
+}
+
+// bech32Decode decodes a Bech32 or Bech32m string into its human-readable part and 5-bit data values, verifying the checksum.
+// Returns the checksum constant too, telling which of the two it is.
+func bech32Decode(bech string) (string, []byte, uint32, error) {
+	if len(bech) > 90 {
+		return "", nil, 0, errors.New(fmt.Sprintf("Bech32 string is %d characters long, must be at most 90.", len(bech)))
+	}
+	if strings.ToLower(bech) != bech && strings.ToUpper(bech) != bech {
+		return "", nil, 0, errors.New("Bech32 string must not mix upper and lower case.")
+	}
+	bech = strings.ToLower(bech)
+	separator := strings.LastIndex(bech, "1")
+	if separator < 1 || separator+7 > len(bech) {
+		return "", nil, 0, errors.New("Bech32 string is missing its human-readable part, separator or checksum.")
+	}
+	hrp := bech[:separator]
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", nil, 0, errors.New(fmt.Sprintf("Bech32 human-readable part contains invalid character 0x%x.", hrp[i]))
+		}
+	}
+	data := make([]byte, 0, len(bech)-separator-1)
+	for i := separator + 1; i < len(bech); i++ {
+		value := strings.IndexByte(bech32Charset, bech[i])
+		if value == -1 {
+			return "", nil, 0, errors.New(fmt.Sprintf("Bech32 data contains invalid character '%c'.", bech[i]))
+		}
+		data = append(data, byte(value))
+	}
+	constant := bech32Polymod(append(bech32HrpExpand(hrp), data...))
+	if constant != bech32Constant && constant != bech32mConstant {
+		return "", nil, 0, errors.New("Bech32 checksum is invalid.")
+	}
+	return hrp, data[:len(data)-6], constant, nil
+}
+
+// convertBits regroups data from fromBits-bit values into toBits-bit values.
+// If pad is false, leftover bits must be zero padding of less than fromBits bits.
+func convertBits(data []byte, fromBits uint, toBits uint, pad bool) ([]byte, error) {
+	accumulator := uint32(0)
+	bits := uint(0)
+	maxValue := uint32(1)<<toBits - 1
+	var converted []byte
+	for _, value := range data {
+		if uint32(value)>>fromBits != 0 {
+			return nil, errors.New(fmt.Sprintf("Value 0x%x does not fit in %d bits.", value, fromBits))
+		}
+		accumulator = accumulator<<fromBits | uint32(value)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			converted = append(converted, byte(accumulator>>bits&maxValue))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			converted = append(converted, byte(accumulator<<(toBits-bits)&maxValue))
+		}
+	} else if bits >= fromBits || accumulator<<(toBits-bits)&maxValue != 0 {
+		return nil, errors.New("Invalid padding when converting between bit groups.")
+	}
+	return converted, nil
+}
+
+// EncodeSegWitAddress creates a native SegWit address with human-readable part hrp ("bc" for mainnet)
+// from a witness version and witness program (eg. the SHA256 hash of a witness script for P2WSH).
+// Version 0 addresses use the Bech32 checksum, and version 1 (Taproot) to 16 the Bech32m checksum of BIP350.
+func EncodeSegWitAddress(hrp string, witnessVersion byte, witnessProgram []byte) (string, error) {
+	err := checkWitnessProgram(witnessVersion, witnessProgram)
+	if err != nil {
+		return "", err
+	}
+	data, err := convertBits(witnessProgram, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	if witnessVersion == 0 {
+		return Bech32Encode(hrp, append([]byte{witnessVersion}, data...))
+	}
+	return Bech32mEncode(hrp, append([]byte{witnessVersion}, data...))
+}
+
+// DecodeSegWitAddress decodes a native SegWit address, checking it has human-readable part hrp and the checksum its witness version uses.
+// Returns the witness version and witness program.
+func DecodeSegWitAddress(hrp string, address string) (byte, []byte, error) {
+	decodedHrp, data, constant, err := bech32Decode(address)
+	if err != nil {
+		return 0, nil, err
+	}
+	if decodedHrp != hrp {
+		return 0, nil, errors.New(fmt.Sprintf("SegWit address has human-readable part '%s', expected '%s'.", decodedHrp, hrp))
+	}
+	if len(data) < 1 || data[0] > 16 {
+		return 0, nil, errors.New("SegWit address has an invalid witness version. Should be 0 to 16.")
+	}
+	witnessVersion := data[0]
+	if witnessVersion == 0 && constant != bech32Constant {
+		return 0, nil, errors.New("SegWit version 0 address must have a Bech32 checksum, not Bech32m.")
+	}
+	if witnessVersion != 0 && constant != bech32mConstant {
+		return 0, nil, errors.New(fmt.Sprintf("SegWit version %d address must have a Bech32m checksum, not Bech32.", witnessVersion))
+	}
+	witnessProgram, err := convertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return 0, nil, err
+	}
+	err = checkWitnessProgram(witnessVersion, witnessProgram)
+	if err != nil {
+		return 0, nil, err
+	}
+	return witnessVersion, witnessProgram, nil
+}
+
+// checkWitnessProgram checks a witness version is 0 to 16 and its witness program is 2 to 40 bytes long,
+// or exactly 20 or 32 bytes long for version 0.
+func checkWitnessProgram(witnessVersion byte, witnessProgram []byte) error {
+	if witnessVersion > 16 {
+		return errors.New(fmt.Sprintf("Witness version %d is invalid. Should be 0 to 16.", witnessVersion))
+	}
+	if witnessVersion == 0 && len(witnessProgram) != 20 && len(witnessProgram) != 32 {
+		return errors.New(fmt.Sprintf("Version 0 witness program is %d bytes long, must be 20 or 32 bytes.", len(witnessProgram)))
+	}
+	if len(witnessProgram) < 2 || len(witnessProgram) > 40 {
+		return errors.New(fmt.Sprintf("Witness program is %d bytes long, must be 2 to 40 bytes.", len(witnessProgram)))
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package btcutils
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+func TestEncodeSegWitAddress(t *testing.T) {
+	//Examples from BIP173
+	testCases := []struct {
+		hrp            string
+		witnessProgram string
+		address        string
+	}{
+		{"bc", "751e76e8199196d454941c45d1b3a323f1433bd6", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"},
+		{"tb", "1863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262", "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7"},
+	}
+	for _, testCase := range testCases {
+		witnessProgram, _ := hex.DecodeString(testCase.witnessProgram)
+		address, err := EncodeSegWitAddress(testCase.hrp, 0, witnessProgram)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if address != testCase.address {
+			testutils.CompareError(t, "SegWit address different from expected address.", testCase.address, address)
+		}
+		witnessVersion, decodedProgram, err := DecodeSegWitAddress(testCase.hrp, address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if witnessVersion != 0 || hex.EncodeToString(decodedProgram) != testCase.witnessProgram {
+			testutils.CompareError(t, "Decoded SegWit witness program different from expected program.", testCase.witnessProgram, hex.EncodeToString(decodedProgram))
+		}
+	}
+	//Upper case addresses are valid too
+	if _, _, err := DecodeSegWitAddress("bc", "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4"); err != nil {
+		t.Error(err)
+	}
+	//Version 1 and later use Bech32m, examples from BIP350
+	bech32mTestCases := []struct {
+		witnessVersion byte
+		witnessProgram string
+		address        string
+	}{
+		{1, "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0"},
+		{2, "751e76e8199196d454941c45d1b3a323", "bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs"},
+		{16, "751e", "bc1sw50qgdz25j"},
+	}
+	for _, testCase := range bech32mTestCases {
+		witnessProgram, _ := hex.DecodeString(testCase.witnessProgram)
+		address, err := EncodeSegWitAddress("bc", testCase.witnessVersion, witnessProgram)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if address != testCase.address {
+			testutils.CompareError(t, "SegWit address different from expected address.", testCase.address, address)
+		}
+		witnessVersion, decodedProgram, err := DecodeSegWitAddress("bc", address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if witnessVersion != testCase.witnessVersion || hex.EncodeToString(decodedProgram) != testCase.witnessProgram {
+			testutils.CompareError(t, "Decoded SegWit witness program different from expected program.", testCase.witnessProgram, hex.EncodeToString(decodedProgram))
+		}
+	}
+	if _, err := EncodeSegWitAddress("bc", 0, make([]byte, 21)); err == nil {
+		t.Error("EncodeSegWitAddress accepting version 0 witness program of invalid length.")
+	}
+}
+
+func TestDecodeSegWitAddressInvalid(t *testing.T) {
+	invalidAddresses := []string{
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5",                     //invalid checksum
+		"BC1QW508D6QEJXTDG4Y5R3ZARVARY0c5xw7kv8f3t4",                     //mixed case
+		"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", //wrong human-readable part
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3tb",                     //invalid character
+		"bc1rw5uspcuh", //unsupported witness version
+		"bc1gmk9yu",    //empty data
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd", //version 1 with Bech32 instead of Bech32m checksum
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh",                     //version 0 with Bech32m checksum
+	}
+	for _, address := range invalidAddresses {
+		if _, _, err := DecodeSegWitAddress("bc", address); err == nil {
+			t.Error("DecodeSegWitAddress accepting invalid address as valid:", address)
+		}
+	}
+}
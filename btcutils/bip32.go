@@ -0,0 +1,336 @@
+// Provides BIP32 hierarchical deterministic (HD) extended keys.
+// See https://github.com/bitcoin/bips/blob/master/bip-0032.mediawiki for full specification.
+package btcutils
+
+import (
+	"github.com/prettymuchbryce/hellobitcoin/base58check"
+
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// HardenedKeyStart is the first hardened child index. Hardened children can only be derived from extended private keys.
+const HardenedKeyStart uint32 = 0x80000000
+
+// extendedKeyLength is the length of a serialized extended key, without version bytes and checksum.
+const extendedKeyLength = 74
+
+// secp256k1Prime is the prime P of the secp256k1 curve field, used for public child key derivation.
+var secp256k1Prime, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+
+// ExtendedKey is a BIP32 extended private or public key: a key plus the chain code needed to derive its children.
+type ExtendedKey struct {
+	Depth             byte   //0 for the master key, 1 for its children and so on
+	ParentFingerprint []byte //First 4 bytes of Hash160 of the parent public key, zero for the master key
+	ChildNumber       uint32 //Index of this key within its parent, HardenedKeyStart and above if hardened
+	ChainCode         []byte //32 byte chain code
+	Key               []byte //32 byte private key, or 33 byte compressed public key
+	IsPrivate         bool
+	Network           *Network //Network whose version bytes the key is serialized with
+}
+
+// NewMasterKey creates the master extended private key on network from a seed of 16 to 64 bytes.
+func NewMasterKey(seed []byte, network *Network) (*ExtendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, errors.New(fmt.Sprintf("Seed must be between 16 and 64 bytes long. Provided seed is %d bytes long.", len(seed)))
+	}
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	if !isValidPrivateKey(sum[:32]) {
+		return nil, errors.New("Seed gives an invalid master key. Use another seed.")
+	}
+	return &ExtendedKey{
+		ParentFingerprint: []byte{0, 0, 0, 0},
+		ChainCode:         sum[32:],
+		Key:               sum[:32],
+		IsPrivate:         true,
+		Network:           network,
+	}, nil
+}
+
+// PublicKey returns the 33 byte compressed public key of the extended key.
+func (key *ExtendedKey) PublicKey() ([]byte, error) {
+	if !key.IsPrivate {
+		return key.Key, nil
+	}
+	return NewPublicKey(key.Key, true)
+}
+
+// Fingerprint returns the first 4 bytes of Hash160 of the public key, which identifies the key to its children.
+func (key *ExtendedKey) Fingerprint() ([]byte, error) {
+	publicKey, err := key.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	publicKeyHash, err := Hash160(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return publicKeyHash[:4], nil
+}
+
+// Neuter returns the extended public key of an extended private key, which can derive all non-hardened child public keys.
+func (key *ExtendedKey) Neuter() (*ExtendedKey, error) {
+	publicKey, err := key.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return &ExtendedKey{
+		Depth:             key.Depth,
+		ParentFingerprint: key.ParentFingerprint,
+		ChildNumber:       key.ChildNumber,
+		ChainCode:         key.ChainCode,
+		Key:               publicKey,
+		IsPrivate:         false,
+		Network:           key.Network,
+	}, nil
+}
+
+// Child derives the child extended key at index. Indexes from HardenedKeyStart are hardened children,
+// which need an extended private key. Private keys give private children, public keys give public children.
+func (key *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	if key.Depth == 255 {
+		return nil, errors.New("Extended key is at maximum depth 255 and cannot have children.")
+	}
+	publicKey, err := key.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	//Hardened children hash the parent private key, non-hardened children hash the parent public key
+	var data bytes.Buffer
+	if index >= HardenedKeyStart {
+		if !key.IsPrivate {
+			return nil, errors.New("Cannot derive a hardened child from an extended public key.")
+		}
+		data.WriteByte(0x00)
+		data.Write(key.Key)
+	} else {
+		data.Write(publicKey)
+	}
+	binary.Write(&data, binary.BigEndian, index)
+	mac := hmac.New(sha512.New, key.ChainCode)
+	mac.Write(data.Bytes())
+	sum := mac.Sum(nil)
+	tweak, chainCode := sum[:32], sum[32:]
+	if !isValidPrivateKey(tweak) {
+		return nil, errors.New(fmt.Sprintf("Child key #%d is invalid. Use the next index.", index))
+	}
+
+	var childKey []byte
+	if key.IsPrivate {
+		//Child private key is tweak + parent private key (mod N)
+		childKeyInt := new(big.Int).SetBytes(tweak)
+		childKeyInt.Add(childKeyInt, new(big.Int).SetBytes(key.Key))
+		childKeyInt.Mod(childKeyInt, secp256k1Order)
+		if childKeyInt.Sign() == 0 {
+			return nil, errors.New(fmt.Sprintf("Child key #%d is invalid. Use the next index.", index))
+		}
+		childKey = padTo32Bytes(childKeyInt.Bytes())
+	} else {
+		//Child public key is point(tweak) + parent public key
+		tweakPublicKey, err := NewPublicKey(tweak, true)
+		if err != nil {
+			return nil, err
+		}
+		childKey, err = addPublicKeys(tweakPublicKey, key.Key)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Child key #%d is invalid. Use the next index.", index))
+		}
+	}
+	fingerprint, err := key.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+	return &ExtendedKey{
+		Depth:             key.Depth + 1,
+		ParentFingerprint: fingerprint,
+		ChildNumber:       index,
+		ChainCode:         chainCode,
+		Key:               childKey,
+		IsPrivate:         key.IsPrivate,
+		Network:           key.Network,
+	}, nil
+}
+
+// DerivePath derives the descendant extended key at a path relative to key, eg. "m/0'/1/2'" or "0/5".
+// Hardened indexes are marked with ' or h.
+func (key *ExtendedKey) DerivePath(path string) (*ExtendedKey, error) {
+	indexes, err := ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range indexes {
+		key, err = key.Child(index)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// ParseDerivationPath converts a derivation path such as "m/45'/0/1" into child indexes.
+// A leading "m" is optional and hardened indexes are marked with ' or h.
+func ParseDerivationPath(path string) ([]uint32, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "m"), "/")
+	if path == "" {
+		return nil, nil
+	}
+	parts := strings.Split(path, "/")
+	indexes := make([]uint32, len(parts))
+	for i, part := range parts {
+		hardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h")
+		if hardened {
+			part = part[:len(part)-1]
+		}
+		index, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || uint32(index) >= HardenedKeyStart {
+			return nil, errors.New(fmt.Sprintf("Invalid derivation path index '%s'. Must be a number below %d, with ' or h appended if hardened.", parts[i], HardenedKeyStart))
+		}
+		indexes[i] = uint32(index)
+		if hardened {
+			indexes[i] += HardenedKeyStart
+		}
+	}
+	return indexes, nil
+}
+
+// String serializes the extended key, as base58 with prefix 'xprv' for private keys or 'xpub' for public keys on mainnet,
+// and 'tprv' or 'tpub' on the test networks.
+func (key *ExtendedKey) String() string {
+	version := key.Network.XpubVersion
+	var serialized bytes.Buffer
+	serialized.WriteByte(key.Depth)
+	serialized.Write(key.ParentFingerprint)
+	binary.Write(&serialized, binary.BigEndian, key.ChildNumber)
+	serialized.Write(key.ChainCode)
+	if key.IsPrivate {
+		version = key.Network.XprvVersion
+		serialized.WriteByte(0x00)
+	}
+	serialized.Write(key.Key)
+	return base58check.Encode(version, serialized.Bytes())
+}
+
+// ParseExtendedKey parses a serialized extended private key ('xprv' on mainnet) or extended public key ('xpub' on mainnet),
+// checking it belongs to network.
+func ParseExtendedKey(encodedKey string, network *Network) (*ExtendedKey, error) {
+	encodedKey = strings.TrimSpace(encodedKey)
+	decoded, err := DecodeBase58Check(encodedKey)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Extended key is not valid. %v Check it for typos.", err))
+	}
+	if len(decoded) != extendedKeyLength+4 {
+		return nil, errors.New("Extended key has an invalid length.")
+	}
+	version := hex.EncodeToString(decoded[:4])
+	serialized := decoded[4:]
+	var isPrivate bool
+	switch version {
+	case network.XprvVersion:
+		isPrivate = true
+	case network.XpubVersion:
+		isPrivate = false
+	default:
+		otherNames := otherNetworkNames(network, func(other *Network) bool {
+			return version == other.XprvVersion || version == other.XpubVersion
+		})
+		if otherNames != "" {
+			return nil, errors.New(fmt.Sprintf("Extended key is for %s, not %s.", otherNames, network.Name))
+		}
+		return nil, errors.New(fmt.Sprintf("Extended key has unknown version bytes 0x%s.", version))
+	}
+	key := &ExtendedKey{
+		Depth:             serialized[0],
+		ParentFingerprint: serialized[1:5],
+		ChildNumber:       binary.BigEndian.Uint32(serialized[5:9]),
+		ChainCode:         serialized[9:41],
+		IsPrivate:         isPrivate,
+		Network:           network,
+	}
+	if key.IsPrivate {
+		if serialized[41] != 0x00 || !isValidPrivateKey(serialized[42:]) {
+			return nil, errors.New("Extended private key contains an invalid private key.")
+		}
+		key.Key = serialized[42:]
+	} else {
+		if _, _, err := decompressPublicKey(serialized[41:]); err != nil {
+			return nil, err
+		}
+		key.Key = serialized[41:]
+	}
+	if key.Depth == 0 && (!bytes.Equal(key.ParentFingerprint, []byte{0, 0, 0, 0}) || key.ChildNumber != 0) {
+		return nil, errors.New("Extended key has depth 0 but a parent fingerprint or child number.")
+	}
+	return key, nil
+}
+
+// isValidPrivateKey returns true if the 32 byte big-endian integer privateKey is between 1 and N-1.
+func isValidPrivateKey(privateKey []byte) bool {
+	privateKeyInt := new(big.Int).SetBytes(privateKey)
+	return privateKeyInt.Sign() > 0 && privateKeyInt.Cmp(secp256k1Order) < 0
+}
+
+// decompressPublicKey returns the curve point coordinates of a 33 byte compressed public key.
+func decompressPublicKey(publicKey []byte) (*big.Int, *big.Int, error) {
+	if len(publicKey) != 33 || (publicKey[0] != 0x02 && publicKey[0] != 0x03) {
+		return nil, nil, errors.New("Public key is not a 33 byte compressed public key.")
+	}
+	//Solve y^2 = x^3 + 7 (mod P), picking the root whose parity matches the prefix byte
+	x := new(big.Int).SetBytes(publicKey[1:])
+	if x.Cmp(secp256k1Prime) >= 0 {
+		return nil, nil, errors.New("Public key x coordinate is not on the secp256k1 curve.")
+	}
+	ySquared := new(big.Int).Exp(x, big.NewInt(3), secp256k1Prime)
+	ySquared.Add(ySquared, big.NewInt(7))
+	ySquared.Mod(ySquared, secp256k1Prime)
+	y := new(big.Int).ModSqrt(ySquared, secp256k1Prime)
+	if y == nil {
+		return nil, nil, errors.New("Public key x coordinate is not on the secp256k1 curve.")
+	}
+	if y.Bit(0) != uint(publicKey[0]&1) {
+		y.Sub(secp256k1Prime, y)
+	}
+	return x, y, nil
+}
+
+// addPublicKeys adds two compressed public keys as secp256k1 curve points, returning the compressed sum.
+func addPublicKeys(a []byte, b []byte) ([]byte, error) {
+	ax, ay, err := decompressPublicKey(a)
+	if err != nil {
+		return nil, err
+	}
+	bx, by, err := decompressPublicKey(b)
+	if err != nil {
+		return nil, err
+	}
+	//Slope of the line through both points, or of the tangent if they are the same point
+	var slope *big.Int
+	if ax.Cmp(bx) == 0 {
+		if ay.Cmp(by) != 0 {
+			return nil, errors.New("Public keys sum to the point at infinity.")
+		}
+		numerator := new(big.Int).Mul(big.NewInt(3), new(big.Int).Mul(ax, ax))
+		denominator := new(big.Int).ModInverse(new(big.Int).Lsh(ay, 1), secp256k1Prime)
+		slope = numerator.Mul(numerator, denominator)
+	} else {
+		numerator := new(big.Int).Sub(by, ay)
+		denominator := new(big.Int).ModInverse(new(big.Int).Mod(new(big.Int).Sub(bx, ax), secp256k1Prime), secp256k1Prime)
+		slope = numerator.Mul(numerator, denominator)
+	}
+	slope.Mod(slope, secp256k1Prime)
+	x := new(big.Int).Mul(slope, slope)
+	x.Sub(x, ax).Sub(x, bx).Mod(x, secp256k1Prime)
+	y := new(big.Int).Sub(ax, x)
+	y.Mul(y, slope).Sub(y, ay).Mod(y, secp256k1Prime)
+	return append([]byte{byte(0x02 + y.Bit(0))}, padTo32Bytes(x.Bytes())...), nil
+}
@@ -0,0 +1,184 @@
+package btcutils
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+func TestExtendedKeyDerivePath(t *testing.T) {
+	//BIP32 test vectors 1 and 2
+	testVectors := []struct {
+		seedHex      string
+		path         string
+		xprv         string
+		xpub         string
+		publicKeyHex string
+	}{
+		{
+			"000102030405060708090a0b0c0d0e0f",
+			"m",
+			"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi",
+			"xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8",
+			"0339a36013301597daef41fbe593a02cc513d0b55527ec2df1050e2e8ff49c85c2",
+		},
+		{
+			"000102030405060708090a0b0c0d0e0f",
+			"m/0'/1/2'/2/1000000000",
+			"xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76",
+			"xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy",
+			"022a471424da5e657499d1ff51cb43c47481a03b1e77f951fe64cec9f5a48f7011",
+		},
+		{
+			"fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c999693908d8a8784817e7b7875726f6c696663605d5a5754514e4b484542",
+			"m/0/2147483647'/1/2147483646'/2",
+			"xprvA2nrNbFZABcdryreWet9Ea4LvTJcGsqrMzxHx98MMrotbir7yrKCEXw7nadnHM8Dq38EGfSh6dqA9QWTyefMLEcBYJUuekgW4BYPJcr9E7j",
+			"xpub6FnCn6nSzZAw5Tw7cgR9bi15UV96gLZhjDstkXXxvCLsUXBGXPdSnLFbdpq8p9HmGsApME5hQTZ3emM2rnY5agb9rXpVGyy3bdW6EEgAtqt",
+			"024d902e1a2fc7a8755ab5b694c575fce742c48d9ff192e63df5193e4c7afe1f9c",
+		},
+	}
+
+	for _, testVector := range testVectors {
+		seed, _ := hex.DecodeString(testVector.seedHex)
+		masterKey, err := NewMasterKey(seed, MainNet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := masterKey.DerivePath(testVector.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.String() != testVector.xprv {
+			testutils.CompareError(t, "Derived extended private key different from expected key at "+testVector.path+".", testVector.xprv, key.String())
+		}
+		publicKey, err := key.Neuter()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if publicKey.String() != testVector.xpub {
+			testutils.CompareError(t, "Derived extended public key different from expected key at "+testVector.path+".", testVector.xpub, publicKey.String())
+		}
+		publicKeyHex := hex.EncodeToString(publicKey.Key)
+		if publicKeyHex != testVector.publicKeyHex {
+			testutils.CompareError(t, "Derived public key different from expected key at "+testVector.path+".", testVector.publicKeyHex, publicKeyHex)
+		}
+	}
+}
+
+func TestExtendedKeyPublicDerivation(t *testing.T) {
+	//Master extended public key of BIP32 test vector 2, derived without any private key
+	testMasterXpub := "xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB"
+	testPath := "m/0/1/2"
+	testXpub := "xpub6DNS386KAmtZRsvqEwuB5RhL158MiobNR1vKNyAFpz2fnLEV2ajrYMqwq6zg6a8jGLZAt1gh4pNsJvEziFrkpzktwaEdqt7yCzcHZ1EqqKL"
+
+	masterKey, err := ParseExtendedKey(testMasterXpub, MainNet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if masterKey.IsPrivate {
+		t.Error("Extended public key parsed as private.")
+	}
+	key, err := masterKey.DerivePath(testPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.String() != testXpub {
+		testutils.CompareError(t, "Publicly derived extended public key different from expected key.", testXpub, key.String())
+	}
+	if _, err := masterKey.Child(HardenedKeyStart); err == nil {
+		t.Error("Hardened child derived from extended public key.")
+	}
+}
+
+func TestParseExtendedKey(t *testing.T) {
+	testExtendedKeys := []struct {
+		extendedKey string
+		network     *Network
+	}{
+		{"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi", MainNet},
+		{"xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy", MainNet},
+		{"tprv8ec4egZgPaC5Zvq1gQicwuowzSy4v8o3dAE391VSQMBvwXhcBUzGdFrvGANKyGzZcDZEsbY56NfVk1Aotq3vkkArvqnE63bmLgCxSz8BRup", TestNet3},
+		{"tpubDBJ6o6bvXwskTProa4PDMKU4ZUV15TyxCTppRXXjpczKn1xNosorokUnSJXimrWxB67E22XJFRLu1rPpsvn4ZwKMbNshVv7ecfhZcuq4j2w", RegTest},
+	}
+	for _, test := range testExtendedKeys {
+		testExtendedKey := test.extendedKey
+		key, err := ParseExtendedKey(testExtendedKey, test.network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.String() != testExtendedKey {
+			testutils.CompareError(t, "Reserialized extended key different from parsed key.", testExtendedKey, key.String())
+		}
+	}
+
+	invalidExtendedKeys := []string{
+		"", //empty key
+		"tpubD6NzVbkrYhZ4XgiXtGrdW5XDAPFCL9h7we1vwNCpn8tGbBcgfVYjXyhWo4E1xkh56hjod1RhGjxbaTLV3X4FyWuejifB9jusQ46QzG87VKp", //testnet key
+		"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHj", //bad checksum
+		"xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet",  //truncated key
+	}
+	for _, extendedKey := range invalidExtendedKeys {
+		if _, err := ParseExtendedKey(extendedKey, MainNet); err == nil {
+			t.Error("ParseExtendedKey accepting invalid extended key as valid:", extendedKey)
+		}
+	}
+}
+
+func TestExtendedKeyNetwork(t *testing.T) {
+	//BIP32 test vector 1 key at m/0/1, serialized for testnet
+	testSeedHex := "000102030405060708090a0b0c0d0e0f"
+	testPath := "m/0/1"
+	testTprv := "tprv8ec4egZgPaC5Zvq1gQicwuowzSy4v8o3dAE391VSQMBvwXhcBUzGdFrvGANKyGzZcDZEsbY56NfVk1Aotq3vkkArvqnE63bmLgCxSz8BRup"
+	testTpub := "tpubDBJ6o6bvXwskTProa4PDMKU4ZUV15TyxCTppRXXjpczKn1xNosorokUnSJXimrWxB67E22XJFRLu1rPpsvn4ZwKMbNshVv7ecfhZcuq4j2w"
+
+	seed, _ := hex.DecodeString(testSeedHex)
+	masterKey, err := NewMasterKey(seed, TestNet3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := masterKey.DerivePath(testPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.String() != testTprv {
+		testutils.CompareError(t, "Derived testnet extended private key different from expected key.", testTprv, key.String())
+	}
+	publicKey, err := key.Neuter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if publicKey.String() != testTpub {
+		testutils.CompareError(t, "Derived testnet extended public key different from expected key.", testTpub, publicKey.String())
+	}
+	//Testnet keys are rejected on mainnet and vice versa
+	if _, err := ParseExtendedKey(testTpub, MainNet); err == nil {
+		t.Error("ParseExtendedKey accepting testnet extended key as mainnet key.")
+	}
+	if _, err := ParseExtendedKey("xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8", SigNet); err == nil {
+		t.Error("ParseExtendedKey accepting mainnet extended key as signet key.")
+	}
+}
+
+func TestParseDerivationPath(t *testing.T) {
+	indexes, err := ParseDerivationPath("m/45'/0/7h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testIndexes := []uint32{HardenedKeyStart + 45, 0, HardenedKeyStart + 7}
+	if len(indexes) != len(testIndexes) {
+		t.Fatalf("Parsed %d path indexes, expected %d.", len(indexes), len(testIndexes))
+	}
+	for i := range testIndexes {
+		if indexes[i] != testIndexes[i] {
+			t.Errorf("Path index #%d parsed as %d, expected %d.", i, indexes[i], testIndexes[i])
+		}
+	}
+
+	invalidPaths := []string{"m/a", "m/2147483648", "m//1", "m/-1"}
+	for _, path := range invalidPaths {
+		if _, err := ParseDerivationPath(path); err == nil {
+			t.Error("ParseDerivationPath accepting invalid path as valid:", path)
+		}
+	}
+}
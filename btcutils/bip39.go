@@ -0,0 +1,95 @@
+// Provides BIP39 mnemonic sentences for backing up and restoring HD wallet seeds.
+// See https://github.com/bitcoin/bips/blob/master/bip-0039.mediawiki for the specification.
+package btcutils
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"code.google.com/p/go.crypto/pbkdf2"
+)
+
+// bip39SeedIterations is the number of PBKDF2-HMAC-SHA512 rounds used to stretch a mnemonic into a seed.
+const bip39SeedIterations = 2048
+
+// NewMnemonic encodes entropy as a BIP39 mnemonic sentence using the English wordlist.
+// Entropy must be 16 to 32 bytes long, in multiples of 4 bytes, giving 12 to 24 words.
+func NewMnemonic(entropy []byte) (string, error) {
+	if len(entropy) < 16 || len(entropy) > 32 || len(entropy)%4 != 0 {
+		return "", errors.New(fmt.Sprintf("Mnemonic entropy is %d bytes long. Should be 16, 20, 24, 28 or 32 bytes long.", len(entropy)))
+	}
+	//Append checksum: the first ENT/32 bits of SHA256(entropy)
+	checksumBits := uint(len(entropy) / 4)
+	hash := sha256.Sum256(entropy)
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, checksumBits)
+	data.Or(data, big.NewInt(int64(hash[0]>>(8-checksumBits))))
+
+	//Split into 11 bit word indexes, from the last word to the first
+	wordCount := (len(entropy)*8 + int(checksumBits)) / 11
+	words := make([]string, wordCount)
+	mask := big.NewInt(2047)
+	for i := wordCount - 1; i >= 0; i-- {
+		index := new(big.Int).And(data, mask).Int64()
+		words[i] = bip39EnglishWordlist[index]
+		data.Rsh(data, 11)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicToEntropy decodes a BIP39 mnemonic sentence back into its entropy, checking every word is in the English
+// wordlist and the checksum matches, so mistyped or missing words are caught.
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(strings.ToLower(mnemonic))
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, errors.New(fmt.Sprintf("Mnemonic has %d words. Should have 12, 15, 18, 21 or 24 words.", len(words)))
+	}
+	wordIndexes := make(map[string]int64, len(bip39EnglishWordlist))
+	for i, word := range bip39EnglishWordlist {
+		wordIndexes[word] = int64(i)
+	}
+	data := new(big.Int)
+	for _, word := range words {
+		index, ok := wordIndexes[word]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("Mnemonic word '%s' is not in the BIP39 English wordlist.", word))
+		}
+		data.Lsh(data, 11)
+		data.Or(data, big.NewInt(index))
+	}
+
+	checksumBits := uint(len(words) / 3)
+	checksum := new(big.Int).And(data, big.NewInt(int64(1<<checksumBits-1))).Int64()
+	data.Rsh(data, checksumBits)
+	entropy := make([]byte, int(checksumBits)*4)
+	dataBytes := data.Bytes()
+	copy(entropy[len(entropy)-len(dataBytes):], dataBytes)
+
+	hash := sha256.Sum256(entropy)
+	if int64(hash[0]>>(8-checksumBits)) != checksum {
+		return nil, errors.New("Mnemonic checksum is invalid. Check the words and their order.")
+	}
+	return entropy, nil
+}
+
+// NewSeedFromMnemonic derives the 64 byte BIP32 seed for a mnemonic sentence and optional passphrase.
+// The mnemonic is validated first. Any passphrase gives a valid but different seed, so it must be backed up too.
+// Passphrases are not NFKD normalized as BIP39 requires, so only ASCII passphrases, which normalization leaves unchanged, are accepted.
+func NewSeedFromMnemonic(mnemonic string, passphrase string) ([]byte, error) {
+	_, err := MnemonicToEntropy(mnemonic)
+	if err != nil {
+		return nil, err
+	}
+	//Without NFKD normalization, a non-ASCII passphrase could give a different seed than other BIP39 wallets do
+	for _, r := range passphrase {
+		if r > 127 {
+			return nil, errors.New("Mnemonic passphrase must only contain ASCII characters.")
+		}
+	}
+	normalizedMnemonic := strings.Join(strings.Fields(strings.ToLower(mnemonic)), " ")
+	return pbkdf2.Key([]byte(normalizedMnemonic), []byte("mnemonic"+passphrase), bip39SeedIterations, 64, sha512.New), nil
+}
@@ -0,0 +1,123 @@
+package btcutils
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+// BIP39 test vectors, all with passphrase "TREZOR"
+var testMnemonicVectors = []struct {
+	entropyHex string
+	mnemonic   string
+	seedHex    string
+}{
+	{
+		"00000000000000000000000000000000",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+	},
+	{
+		"80808080808080808080808080808080",
+		"letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+		"d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8",
+	},
+	{
+		"ffffffffffffffffffffffffffffffffffffffffffffffff",
+		"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo when",
+		"0cd6e5d827bb62eb8fc1e262254223817fd068a74b5b449cc2f667c3f1f985a76379b43348d952e2265b4cd129090758b3e3c2c49103b5051aac2eaeb890a528",
+	},
+	{
+		"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		"legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth title",
+		"bc09fca1804f7e69da93c2f2028eb238c227f2e9dda30cd63699232578480a4021b146ad717fbb7e451ce9eb835f43620bf5c514db0f8add49f5d121449d3e87",
+	},
+	{
+		"b63a9c59a6e641f288ebc103017f1da9f8290b3da6bdef7b",
+		"renew stay biology evidence goat welcome casual join adapt armor shuffle fault little machine walk stumble urge swap",
+		"9248d83e06f4cd98debf5b6f010542760df925ce46cf38a1bdb4e4de7d21f5c39366941c69e1bdbf2966e0f6e6dbece898a0e2f0a4c2b3e640953dfe8b7bbdc5",
+	},
+	{
+		"3e141609b97933b66a060dcddc71fad1d91677db872031e85f4c015c5e7e8982",
+		"dignity pass list indicate nasty swamp pool script soccer toe leaf photo multiply desk host tomato cradle drill spread actor shine dismiss champion exotic",
+		"ff7f3184df8696d8bef94b6c03114dbee0ef89ff938712301d27ed8336ca89ef9635da20af07d4175f2bf5f3de130f39c9d9e8dd0472489c19b1a020a940da67",
+	},
+}
+
+func TestNewMnemonic(t *testing.T) {
+	for _, testVector := range testMnemonicVectors {
+		entropy, _ := hex.DecodeString(testVector.entropyHex)
+		mnemonic, err := NewMnemonic(entropy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mnemonic != testVector.mnemonic {
+			testutils.CompareError(t, "Generated mnemonic different from expected mnemonic.", testVector.mnemonic, mnemonic)
+		}
+	}
+	//Invalid entropy lengths
+	for _, length := range []int{0, 12, 18, 36} {
+		_, err := NewMnemonic(make([]byte, length))
+		if err == nil {
+			t.Errorf("Expected error for %d byte mnemonic entropy.", length)
+		}
+	}
+}
+
+func TestMnemonicToEntropy(t *testing.T) {
+	for _, testVector := range testMnemonicVectors {
+		entropy, err := MnemonicToEntropy(testVector.mnemonic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(entropy) != testVector.entropyHex {
+			testutils.CompareError(t, "Decoded mnemonic entropy different from expected entropy.", testVector.entropyHex, hex.EncodeToString(entropy))
+		}
+	}
+	//Extra whitespace and capitals are accepted
+	entropy, err := MnemonicToEntropy("  Abandon abandon abandon abandon abandon abandon\tabandon abandon abandon abandon abandon ABOUT\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(entropy) != testMnemonicVectors[0].entropyHex {
+		testutils.CompareError(t, "Decoded mnemonic entropy different from expected entropy.", testMnemonicVectors[0].entropyHex, hex.EncodeToString(entropy))
+	}
+
+	testInvalidMnemonics := []string{
+		//Bad checksum
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+		//Word not in wordlist
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon aboot",
+		//Wrong number of words
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		"",
+	}
+	for _, testMnemonic := range testInvalidMnemonics {
+		_, err := MnemonicToEntropy(testMnemonic)
+		if err == nil {
+			t.Errorf("Expected error for invalid mnemonic '%s'.", testMnemonic)
+		}
+	}
+}
+
+func TestNewSeedFromMnemonic(t *testing.T) {
+	for _, testVector := range testMnemonicVectors {
+		seed, err := NewSeedFromMnemonic(testVector.mnemonic, "TREZOR")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(seed) != testVector.seedHex {
+			testutils.CompareError(t, "Generated mnemonic seed different from expected seed.", testVector.seedHex, hex.EncodeToString(seed))
+		}
+	}
+	_, err := NewSeedFromMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", "TREZOR")
+	if err == nil {
+		t.Error("Expected error for mnemonic with bad checksum.")
+	}
+	//Passphrases are not NFKD normalized, so a non-ASCII one could give a different seed than other wallets
+	_, err = NewSeedFromMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "caf\u00e9")
+	if err == nil {
+		t.Error("Expected error for non-ASCII passphrase.")
+	}
+}
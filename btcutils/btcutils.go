@@ -6,49 +6,18 @@ import (
 	"bytes"
 	"crypto/rand"
 	"crypto/sha256"
-	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
-	"math"
-	mathrand "math/rand"
-	"time"
+	"sort"
+	"strings"
 
 	"code.google.com/p/go.crypto/ripemd160"
+	"github.com/prettymuchbryce/hellobitcoin/base58check"
 	secp256k1 "github.com/toxeus/go-secp256k1"
 )
 
-// setFixedNonce is used for testing and debugging. It is by default false, but if set to true, then newNonce()
-// will always return a zero-valued [32]byte{}. Allows repeatable ECDSA signatures for testing
-// **Should never be turned on in production. Limit to use in tests only.**
-var SetFixedNonce bool
-
-//Fixed nonce value for repeatable testing.
-//We declare var and not const because Go slices are mutable and cannot be const, but we use fixedNonce like a constant.
-var FIXED_NONCE = [...]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31}
-
-func randInt(min int, max int) uint8 {
-	//This function is *not* cryptographically secure.
-	//Used for nonce which does not need to be cryptographically secure, just changing with each signature
-	mathrand.Seed(time.Now().UTC().UnixNano())
-	return uint8(min + mathrand.Intn(max-min))
-}
-
-func newNonce() [32]byte {
-	//This function is *not* cryptographically secure.
-	//Used for nonce which does not need to be cryptographically secure, just changing with each signature
-	var bytes [32]byte
-	if !SetFixedNonce {
-		for i := 0; i < 32; i++ {
-			bytes[i] = byte(randInt(0, math.MaxUint8))
-		}
-	} else {
-		bytes = FIXED_NONCE
-	}
-	return bytes
-}
-
 // NewRandomBytes generates pseudorandom bytes of length size.
 // Cryptographically secure to the limits of crypto/rand package.
 func NewRandomBytes(size int) ([]byte, error) {
@@ -71,17 +40,61 @@ func NewPrivateKey() []byte {
 	return bytes
 }
 
-// NewPublicKey generates the public key from the private key.
+// NewWIF encodes a private key in Wallet Import Format (WIF) by base58 encoding with the network's prefix, 0x80 for mainnet.
+// Keys whose public key is used compressed get the 0x01 suffix, making mainnet WIF start with 'K' or 'L' instead of '5'.
+func NewWIF(privateKey []byte, compressed bool, network *Network) string {
+	if compressed {
+		return base58check.Encode(network.WIFPrefix, append(append([]byte{}, privateKey...), 0x01))
+	}
+	return base58check.Encode(network.WIFPrefix, privateKey)
+}
+
+// ParseWIF decodes a private key in Wallet Import Format (WIF), verifying its checksum and checking it belongs to network.
+// Returns the 32 byte private key and whether its public key is used compressed.
+func ParseWIF(wif string, network *Network) ([]byte, bool, error) {
+	wif = strings.TrimSpace(wif)
+	if wif == "" {
+		return nil, false, errors.New("Private key cannot be empty.")
+	}
+	decoded, err := DecodeBase58Check(wif)
+	if err != nil {
+		return nil, false, errors.New(fmt.Sprintf("Private key is not valid in Wallet Import Format. %v Check it for typos.", err))
+	}
+	var privateKey []byte
+	var compressed bool
+	switch {
+	case len(decoded) == 33:
+		privateKey, compressed = decoded[1:], false
+	case len(decoded) == 34 && decoded[33] == 0x01:
+		privateKey, compressed = decoded[1:33], true
+	case len(decoded) == 21:
+		return nil, false, errors.New(fmt.Sprintf("'%s' is an address, not a private key.", wif))
+	default:
+		return nil, false, errors.New(fmt.Sprintf("Private key in Wallet Import Format should decode to 32 bytes, or 33 bytes ending in 0x01 if compressed. Provided key decodes to %d bytes.", len(decoded)-1))
+	}
+	version := hex.EncodeToString(decoded[:1])
+	if version != network.WIFPrefix {
+		otherNames := otherNetworkNames(network, func(other *Network) bool { return other.WIFPrefix == version })
+		if otherNames != "" {
+			return nil, false, errors.New(fmt.Sprintf("Private key is for %s, not %s.", otherNames, network.Name))
+		}
+		return nil, false, errors.New(fmt.Sprintf("Private key has unknown version byte 0x%s. Should be 0x%s for %s.", version, network.WIFPrefix, network.Name))
+	}
+	return privateKey, compressed, nil
+}
+
+// NewPublicKey generates the public key from the private key, either as a 33 byte compressed key
+// or as a 65 byte uncompressed key.
 // Unfortunately golang ecdsa package does not include a
 // secp256k1 curve as this is fairly specific to Bitcoin.
 // Using toxeus/go-secp256k1 which wraps the official bitcoin/c-secp256k1 with cgo.
-func NewPublicKey(privateKey []byte) ([]byte, error) {
+func NewPublicKey(privateKey []byte, compressed bool) ([]byte, error) {
 	var privateKey32 [32]byte
 	for i := 0; i < 32; i++ {
 		privateKey32[i] = privateKey[i]
 	}
 	secp256k1.Start()
-	publicKey, success := secp256k1.Pubkey_create(privateKey32, false)
+	publicKey, success := secp256k1.Pubkey_create(privateKey32, compressed)
 	if !success {
 		return nil, errors.New("Failed to create public key from provided private key.")
 	}
@@ -106,6 +119,13 @@ func Hash160(data []byte) ([]byte, error) {
 	return hash, nil
 }
 
+// DoubleSha256 hashes the given data twice with SHA256, as used for transaction IDs, signature hashes and checksums.
+func DoubleSha256(data []byte) []byte {
+	hash := sha256.Sum256(data)
+	hash = sha256.Sum256(hash[:])
+	return hash[:]
+}
+
 // NewMOfNRedeemScript creates a M-of-N Multisig redeem script given m, n and n public keys
 func NewMOfNRedeemScript(m int, n int, publicKeys [][]byte) ([]byte, error) {
 	//Check we have valid numbers for M and N
@@ -141,16 +161,136 @@ func NewMOfNRedeemScript(m int, n int, publicKeys [][]byte) ([]byte, error) {
 	return redeemScript.Bytes(), nil
 }
 
+// ParseMOfNRedeemScript recovers m, n and the n public keys from a M-of-N multisig redeem script
+// in the format created by NewMOfNRedeemScript, returning an error for any other script. Unlike NewMOfNRedeemScript,
+// n may be up to 16, so scripts from other wallets can still be read.
+func ParseMOfNRedeemScript(redeemScript []byte) (int, int, [][]byte, error) {
+	ops, err := ParseScript(redeemScript)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	//Smallest multisig script is <OP_m> <pubkey> <OP_n> OP_CHECKMULTISIG
+	if len(ops) < 4 || ops[len(ops)-1].Opcode != OP_CHECKMULTISIG {
+		return 0, 0, nil, errors.New("Redeem script is not a M-of-N multisig script. Expected <OP_m> <pubkeys>... <OP_n> OP_CHECKMULTISIG.")
+	}
+	mOp, nOp := ops[0].Opcode, ops[len(ops)-2].Opcode
+	if mOp < OP_1 || mOp > OP_16 || nOp < OP_1 || nOp > OP_16 {
+		return 0, 0, nil, errors.New("Redeem script M and N must be pushed with OP_1 through OP_16.")
+	}
+	m := int(mOp) - (OP_1 - 1)
+	n := int(nOp) - (OP_1 - 1)
+	publicKeys := make([][]byte, 0, n)
+	for _, op := range ops[1 : len(ops)-2] {
+		if op.Data == nil {
+			return 0, 0, nil, errors.New("Redeem script contains a non-push operation where a public key was expected.")
+		}
+		if int(op.Opcode) != len(op.Data) {
+			return 0, 0, nil, errors.New("Redeem script public keys must be pushed directly, without OP_PUSHDATA.")
+		}
+		err = CheckPublicKeyIsValid(op.Data)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		publicKeys = append(publicKeys, op.Data)
+	}
+	if len(publicKeys) != n {
+		return 0, 0, nil, errors.New(fmt.Sprintf("Redeem script declares %d public keys but contains %d.", n, len(publicKeys)))
+	}
+	if m > n {
+		return 0, 0, nil, errors.New(fmt.Sprintf("Redeem script requires %d signatures from only %d public keys.", m, n))
+	}
+	return m, n, publicKeys, nil
+}
+
+// SortPublicKeys returns a copy of publicKeys sorted lexicographically by their serialized bytes, as per BIP67.
+// See https://github.com/bitcoin/bips/blob/master/bip-0067.mediawiki
+func SortPublicKeys(publicKeys [][]byte) [][]byte {
+	sortedPublicKeys := make([][]byte, len(publicKeys))
+	copy(sortedPublicKeys, publicKeys)
+	sort.Slice(sortedPublicKeys, func(i, j int) bool {
+		return bytes.Compare(sortedPublicKeys[i], sortedPublicKeys[j]) < 0
+	})
+	return sortedPublicKeys
+}
+
+// NewSortedMOfNRedeemScript creates a M-of-N Multisig redeem script as NewMOfNRedeemScript does, but with the public keys
+// in BIP67 order, so the script depends only on the set of public keys and not the order they are given in.
+// BIP67 requires compressed public keys.
+func NewSortedMOfNRedeemScript(m int, n int, publicKeys [][]byte) ([]byte, error) {
+	for _, publicKey := range publicKeys {
+		if len(publicKey) != 33 {
+			return nil, errors.New("BIP67 sorted multisig requires 33 byte compressed public keys.")
+		}
+	}
+	return NewMOfNRedeemScript(m, n, SortPublicKeys(publicKeys))
+}
+
+// NewP2SHMultisigScriptSig creates the scriptSig spending a P2SH multisig output given the signatures
+// (each with hash type byte appended, in the same order as their public keys in the redeem script) and the redeemScript.
+func NewP2SHMultisigScriptSig(signatures [][]byte, redeemScript []byte) []byte {
+	//P2SH multisig scriptSig format:
+	//OP_0 <A sig> <B sig>... <redeemScript>
+	var scriptSig bytes.Buffer
+	scriptSig.WriteByte(byte(OP_0)) //OP_0 for Multisig off-by-one error
+	for _, signature := range signatures {
+		WritePushData(&scriptSig, signature)
+	}
+	WritePushData(&scriptSig, redeemScript)
+	return scriptSig.Bytes()
+}
+
+// NewP2SHScriptSig creates the scriptSig spending a P2SH output given the items its redeemScript takes from the stack, bottom
+// first (eg. signatures followed by OP_IF branch selectors), and the redeemScript. Each item is pushed with the smallest push
+// operation: OP_0 if empty and OP_1 through OP_16 for single byte numbers, as standardness rules require.
+func NewP2SHScriptSig(items [][]byte, redeemScript []byte) []byte {
+	var scriptSig bytes.Buffer
+	for _, item := range items {
+		if len(item) == 1 && item[0] >= 1 && item[0] <= 16 {
+			WriteScriptNumber(&scriptSig, int64(item[0]))
+			continue
+		}
+		WritePushData(&scriptSig, item)
+	}
+	WritePushData(&scriptSig, redeemScript)
+	return scriptSig.Bytes()
+}
+
+// NewP2WSHWitness creates the witness spending a P2WSH output given the items its witnessScript takes from the stack, bottom first,
+// and the witnessScript.
+func NewP2WSHWitness(items [][]byte, witnessScript []byte) [][]byte {
+	witness := make([][]byte, 0, len(items)+1)
+	witness = append(witness, items...)
+	return append(witness, witnessScript)
+}
+
+// NewP2SHP2WSHScriptSig creates the scriptSig spending a P2WSH output nested in P2SH given the witnessScript.
+// The scriptSig only pushes the P2SH redeem script, which is the P2WSH witness program; signatures go in the witness.
+func NewP2SHP2WSHScriptSig(witnessScript []byte) ([]byte, error) {
+	//P2SH-P2WSH scriptSig format:
+	//<OP_0 <SHA256(witnessScript)>>
+	witnessScriptHash := sha256.Sum256(witnessScript)
+	redeemScript, err := NewP2WSHScriptPubKey(witnessScriptHash[:])
+	if err != nil {
+		return nil, err
+	}
+	var scriptSig bytes.Buffer
+	WritePushData(&scriptSig, redeemScript)
+	return scriptSig.Bytes(), nil
+}
+
 // CheckPublicKeyIsValid runs a couple of checks to make sure a public key looks valid.
+// Both 33 byte compressed keys and 65 byte uncompressed keys are valid.
 // Returns an error with a helpful message or nil if key is valid.
 func CheckPublicKeyIsValid(publicKey []byte) error {
 	errMessage := ""
 	if publicKey == nil {
 		errMessage += "Public key cannot be empty.\n"
-	} else if len(publicKey) != 65 {
-		errMessage += fmt.Sprintf("Public key should be 65 bytes long. Provided public key is %d bytes long.", len(publicKey))
-	} else if publicKey[0] != byte(4) {
-		errMessage += fmt.Sprintf("Public key first byte should be 0x04. Provided public key first byte is 0x%v.", hex.EncodeToString([]byte{publicKey[0]}))
+	} else if len(publicKey) != 33 && len(publicKey) != 65 {
+		errMessage += fmt.Sprintf("Public key should be 33 bytes long (compressed) or 65 bytes long (uncompressed). Provided public key is %d bytes long.", len(publicKey))
+	} else if len(publicKey) == 33 && publicKey[0] != byte(2) && publicKey[0] != byte(3) {
+		errMessage += fmt.Sprintf("Compressed public key first byte should be 0x02 or 0x03. Provided public key first byte is 0x%v.", hex.EncodeToString([]byte{publicKey[0]}))
+	} else if len(publicKey) == 65 && publicKey[0] != byte(4) {
+		errMessage += fmt.Sprintf("Uncompressed public key first byte should be 0x04. Provided public key first byte is 0x%v.", hex.EncodeToString([]byte{publicKey[0]}))
 	}
 	if errMessage != "" {
 		errMessage += "Invalid public key:\n"
@@ -175,6 +315,20 @@ func NewP2SHScriptPubKey(redeemScriptHash []byte) ([]byte, error) {
 	return scriptPubKey.Bytes(), nil
 }
 
+// NewP2WSHScriptPubKey creates a scriptPubKey for a native SegWit P2WSH transaction given the witnessScript SHA256 hash
+func NewP2WSHScriptPubKey(witnessScriptHash []byte) ([]byte, error) {
+	if len(witnessScriptHash) != 32 {
+		return nil, errors.New("witnessScriptHash must be a 32 byte SHA256 hash.")
+	}
+	//P2WSH scriptPubKey format:
+	//<OP_0> <SHA256(witnessScript)>
+	var scriptPubKey bytes.Buffer
+	scriptPubKey.WriteByte(byte(OP_0))                   //Witness version 0
+	scriptPubKey.WriteByte(byte(len(witnessScriptHash))) //PUSH
+	scriptPubKey.Write(witnessScriptHash)
+	return scriptPubKey.Bytes(), nil
+}
+
 // NewP2PKHScriptPubKey creates a scriptPubKey for a P2PKH transaction given the destination public key hash
 func NewP2PKHScriptPubKey(publicKeyHash []byte) ([]byte, error) {
 	if publicKeyHash == nil {
@@ -192,82 +346,30 @@ func NewP2PKHScriptPubKey(publicKeyHash []byte) ([]byte, error) {
 	return scriptPubKey.Bytes(), nil
 }
 
-// NewRawTransaction creates a Bitcoin transaction given inputs, output satoshi amount, scriptSig and scriptPubKey
+// NewRawTransaction creates a Bitcoin transaction given inputs, output satoshi amount, scriptSig and scriptPubKey.
+// It is a convenience wrapper around Transaction for the common case of one input at output index 0 and one output.
 func NewRawTransaction(inputTxHash string, satoshis int, scriptSig []byte, scriptPubKey []byte) ([]byte, error) {
-	//Version field
-	version, err := hex.DecodeString("01000000")
-	if err != nil {
-		return nil, err
-	}
-	//# of inputs (always 1 in our case)
-	inputs, err := hex.DecodeString("01")
-	if err != nil {
-		return nil, err
-	}
-	//Input transaction hash
-	inputTxBytes, err := hex.DecodeString(inputTxHash)
+	txIn, err := NewTxIn(inputTxHash, 0, scriptSig)
 	if err != nil {
 		return nil, err
 	}
-	//Convert input transaction hash to little-endian form
-	inputTxBytesReversed := make([]byte, len(inputTxBytes))
-	for i := 0; i < len(inputTxBytes); i++ {
-		inputTxBytesReversed[i] = inputTxBytes[len(inputTxBytes)-i-1]
-	}
-	//Ouput index of input transaction
-	outputIndex, err := hex.DecodeString("00000000")
-	if err != nil {
-		return nil, err
-	}
-	//scriptSig length. To allow scriptSig > 255 bytes, we use variable length integer syntax from protocol spec
-	var scriptSigLengthBytes []byte
-	if len(scriptSig) < 253 {
-		scriptSigLengthBytes = []byte{byte(len(scriptSig))}
-	} else {
-		scriptSigLengthBytes = make([]byte, 3)
-		binary.LittleEndian.PutUint16(scriptSigLengthBytes, uint16(len(scriptSig)))
-		copy(scriptSigLengthBytes[1:3], scriptSigLengthBytes[0:2])
-		scriptSigLengthBytes[0] = 253 //Signifies that next two bytes are 2-byte representation of scriptSig length
-
-	}
-	//sequence_no. Normally 0xFFFFFFFF. Always in this case.
-	sequence, err := hex.DecodeString("ffffffff")
-	if err != nil {
-		return nil, err
-	}
-	//Numbers of outputs for the transaction being created. Always one in this example.
-	numOutputs, err := hex.DecodeString("01")
-	if err != nil {
-		return nil, err
-	}
-	//Satoshis to send.
-	satoshiBytes := make([]byte, 8)
-	binary.LittleEndian.PutUint64(satoshiBytes, uint64(satoshis))
-	//Lock time field
-	lockTimeField, err := hex.DecodeString("00000000")
-	if err != nil {
-		return nil, err
-	}
-
-	var buffer bytes.Buffer
-	buffer.Write(version)
-	buffer.Write(inputs)
-	buffer.Write(inputTxBytesReversed)
-	buffer.Write(outputIndex)
-	buffer.Write(scriptSigLengthBytes)
-	buffer.Write(scriptSig)
-	buffer.Write(sequence)
-	buffer.Write(numOutputs)
-	buffer.Write(satoshiBytes)
-	buffer.WriteByte(byte(len(scriptPubKey)))
-	buffer.Write(scriptPubKey)
-	buffer.Write(lockTimeField)
-
-	return buffer.Bytes(), nil
+	tx := NewTransaction()
+	tx.AddInput(txIn)
+	tx.AddOutput(NewTxOut(int64(satoshis), scriptPubKey))
+	return tx.Serialize(), nil
 }
 
 // NewSignature generates a ECDSA signature given the raw transaction and privateKey to sign with
 func NewSignature(rawTransaction []byte, privateKey []byte) ([]byte, error) {
+	//Hash the raw transaction twice with SHA256 before the signing
+	rawTransactionHashed := DoubleSha256(rawTransaction)
+	return NewSignatureForHash(rawTransactionHashed, privateKey)
+}
+
+// NewSignatureForHash generates a ECDSA signature given an already computed 32 byte signature hash
+// (eg. from SignatureHash) and privateKey to sign with.
+// Signatures are deterministic (RFC6979) and low-S (BIP62), so the same hash and key always give the same signature.
+func NewSignatureForHash(hash []byte, privateKey []byte) ([]byte, error) {
 	//Start secp256k1
 	secp256k1.Start()
 	var privateKey32 [32]byte
@@ -279,24 +381,21 @@ func NewSignature(rawTransaction []byte, privateKey []byte) ([]byte, error) {
 	if !success {
 		return nil, errors.New("Failed to create public key from provided private key.")
 	}
-	//Hash the raw transaction twice with SHA256 before the signing
-	shaHash := sha256.New()
-	shaHash.Write(rawTransaction)
-	var hash []byte = shaHash.Sum(nil)
-	shaHash2 := sha256.New()
-	shaHash2.Write(hash)
-	rawTransactionHashed := shaHash2.Sum(nil)
-	//Sign the raw transaction
-	signedTransaction, success := secp256k1.Sign(rawTransactionHashed, privateKey32, newNonce())
+	//Sign the hash with a deterministic RFC6979 nonce, then make sure S is low as required for standardness
+	signature, success := secp256k1.Sign(hash, privateKey32, newNonceRFC6979(privateKey32[:], hash))
 	if !success {
 		return nil, errors.New("Failed to sign transaction")
 	}
+	signature, err := normalizeLowS(signature)
+	if err != nil {
+		return nil, err
+	}
 	//Verify that it worked.
-	verified := secp256k1.Verify(rawTransactionHashed, signedTransaction, publicKey)
+	verified := secp256k1.Verify(hash, signature, publicKey)
 	if !verified {
 		return nil, errors.New("Failed to verify signed transaction")
 	}
 	//Stop secp256k1 and return signature
 	secp256k1.Stop()
-	return signedTransaction, nil
+	return signature, nil
 }
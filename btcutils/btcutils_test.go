@@ -4,6 +4,7 @@ import (
 	"github.com/prettymuchbryce/hellobitcoin/base58check"
 	"github.com/soroushjp/go-bitcoin-multisig/testutils"
 
+	"crypto/sha256"
 	"encoding/hex"
 	"reflect"
 	"testing"
@@ -13,13 +14,95 @@ func TestNewPublicKey(t *testing.T) {
 	testPrivateKey := []byte{38, 245, 100, 77, 9, 147, 145, 209, 208, 136, 215, 161, 171, 75, 199, 219, 26, 95, 66, 101, 110, 38, 86, 249, 86, 179, 195, 10, 70, 153, 203, 150}
 	testPublicKey := []byte{4, 231, 41, 35, 190, 64, 15, 200, 89, 150, 144, 81, 215, 14, 125, 179, 216, 217, 70, 21, 254, 204, 182, 219, 188, 192, 54, 241, 31, 248, 110, 145, 167, 117, 225, 204, 37, 169, 182, 242, 22, 12, 200, 26, 250, 241, 215, 142, 206, 63, 30, 119, 105, 242, 234, 41, 44, 103, 152, 1, 87, 24, 207, 190, 156}
 
-	publicKey, err := NewPublicKey(testPrivateKey)
+	publicKey, err := NewPublicKey(testPrivateKey, false)
 	if err != nil {
 		t.Error(err)
 	}
 	if !reflect.DeepEqual(testPublicKey, publicKey) {
 		testutils.CompareError(t, "Public key different from expected key.", testPublicKey, publicKey)
 	}
+
+	testCompressedPublicKeyHex := "02e72923be400fc859969051d70e7db3d8d94615feccb6dbbcc036f11ff86e91a7"
+	compressedPublicKey, err := NewPublicKey(testPrivateKey, true)
+	if err != nil {
+		t.Error(err)
+	}
+	compressedPublicKeyHex := hex.EncodeToString(compressedPublicKey)
+	if compressedPublicKeyHex != testCompressedPublicKeyHex {
+		testutils.CompareError(t, "Compressed public key different from expected key.", testCompressedPublicKeyHex, compressedPublicKeyHex)
+	}
+}
+
+func TestNewWIF(t *testing.T) {
+	testPrivateKeyHex := "0c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d"
+	testWIF := "5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ"
+	testCompressedWIF := "KwdMAjGmerYanjeui5SHS7JkmpZvVipYvB2LJGU1ZxJwYvP98617"
+	testTestnetWIF := "91gGn1HgSap6CbU12F6z3pJri26xzp7Ay1VW6NHCoEayNXwRpu2"
+	testTestnetCompressedWIF := "cMzLdeGd5vEqxB8B6VFQoRopQ3sLAAvEzDAoQgvX54xwofSWj1fx"
+
+	privateKey, _ := hex.DecodeString(testPrivateKeyHex)
+	wif := NewWIF(privateKey, false, MainNet)
+	if wif != testWIF {
+		testutils.CompareError(t, "WIF private key different from expected key.", testWIF, wif)
+	}
+	compressedWIF := NewWIF(privateKey, true, MainNet)
+	if compressedWIF != testCompressedWIF {
+		testutils.CompareError(t, "Compressed WIF private key different from expected key.", testCompressedWIF, compressedWIF)
+	}
+	testnetWIF := NewWIF(privateKey, false, TestNet3)
+	if testnetWIF != testTestnetWIF {
+		testutils.CompareError(t, "Testnet WIF private key different from expected key.", testTestnetWIF, testnetWIF)
+	}
+	testnetCompressedWIF := NewWIF(privateKey, true, RegTest)
+	if testnetCompressedWIF != testTestnetCompressedWIF {
+		testutils.CompareError(t, "Regtest compressed WIF private key different from expected key.", testTestnetCompressedWIF, testnetCompressedWIF)
+	}
+}
+
+func TestParseWIF(t *testing.T) {
+	testPrivateKeyHex := "0c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d"
+	testWIFs := []struct {
+		wif        string
+		network    *Network
+		compressed bool
+	}{
+		{"5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ", MainNet, false},
+		{"KwdMAjGmerYanjeui5SHS7JkmpZvVipYvB2LJGU1ZxJwYvP98617", MainNet, true},
+		{"91gGn1HgSap6CbU12F6z3pJri26xzp7Ay1VW6NHCoEayNXwRpu2", TestNet3, false},
+		{"cMzLdeGd5vEqxB8B6VFQoRopQ3sLAAvEzDAoQgvX54xwofSWj1fx", SigNet, true},
+	}
+
+	for _, testWIF := range testWIFs {
+		wif, testCompressed := testWIF.wif, testWIF.compressed
+		privateKey, compressed, err := ParseWIF(wif, testWIF.network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		privateKeyHex := hex.EncodeToString(privateKey)
+		if privateKeyHex != testPrivateKeyHex {
+			testutils.CompareError(t, "Private key parsed from WIF different from expected key.", testPrivateKeyHex, privateKeyHex)
+		}
+		if compressed != testCompressed {
+			t.Errorf("WIF %s parsed with compressed %v, expected %v.", wif, compressed, testCompressed)
+		}
+	}
+
+	invalidWIFs := []string{
+		"", //empty WIF
+		base58check.Encode("80", make([]byte, 31)),            //wrong length key
+		base58check.Encode("80", append(make([]byte, 32), 2)), //wrong compression suffix
+		"91gGn1HgSap6CbU12F6z3pJri26xzp7Ay1VW6NHCoEayNXwRpu2", //testnet WIF
+		"5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTK", //bad checksum
+		"16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvM",                   //P2PKH address
+	}
+	for _, wif := range invalidWIFs {
+		if _, _, err := ParseWIF(wif, MainNet); err == nil {
+			t.Error("ParseWIF accepting invalid mainnet WIF as valid:", wif)
+		}
+	}
+	if _, _, err := ParseWIF("KwdMAjGmerYanjeui5SHS7JkmpZvVipYvB2LJGU1ZxJwYvP98617", RegTest); err == nil {
+		t.Error("ParseWIF accepting mainnet WIF as valid regtest WIF.")
+	}
 }
 
 func TestHash160(t *testing.T) {
@@ -59,11 +142,110 @@ func TestNewMOfNRedeemScript(t *testing.T) {
 	}
 }
 
+func TestSortPublicKeys(t *testing.T) {
+	//BIP67 test vector 3: keys differing only in the last byte and the prefix byte
+	testPublicKeyStrings := []string{
+		"030000000000000000000000000000000000004141414141414141414141414141",
+		"020000000000000000000000000000000000004141414141414141414141414141",
+		"020000000000000000000000000000000000004141414141414141414141414140",
+		"030000000000000000000000000000000000004141414141414141414141414140",
+	}
+	testSortedPublicKeyStrings := []string{
+		"020000000000000000000000000000000000004141414141414141414141414140",
+		"020000000000000000000000000000000000004141414141414141414141414141",
+		"030000000000000000000000000000000000004141414141414141414141414140",
+		"030000000000000000000000000000000000004141414141414141414141414141",
+	}
+
+	publicKeys := make([][]byte, len(testPublicKeyStrings))
+	for i, publicKeyString := range testPublicKeyStrings {
+		publicKeys[i], _ = hex.DecodeString(publicKeyString)
+	}
+	sortedPublicKeys := SortPublicKeys(publicKeys)
+	for i, testSortedPublicKeyString := range testSortedPublicKeyStrings {
+		if hex.EncodeToString(sortedPublicKeys[i]) != testSortedPublicKeyString {
+			testutils.CompareError(t, "Sorted public key different from expected key.", testSortedPublicKeyString, hex.EncodeToString(sortedPublicKeys[i]))
+		}
+	}
+	//Input order must be left unchanged
+	if hex.EncodeToString(publicKeys[0]) != testPublicKeyStrings[0] {
+		t.Error("SortPublicKeys modified the order of its input.")
+	}
+}
+
+func TestNewSortedMOfNRedeemScript(t *testing.T) {
+	//BIP67 test vector 1
+	testPublicKeyStrings := []string{
+		"02ff12471208c14bd580709cb2358d98975247d8765f92bc25eab3b2763ed605f8",
+		"02fe6f0a5a297eb38c391581c4413e084773ea23954d93f7753db7dc0adc188b2f",
+	}
+	testM := 2
+	testN := 2
+	testRedeemScriptHex := "522102fe6f0a5a297eb38c391581c4413e084773ea23954d93f7753db7dc0adc188b2f2102ff12471208c14bd580709cb2358d98975247d8765f92bc25eab3b2763ed605f852ae"
+
+	publicKeys := make([][]byte, len(testPublicKeyStrings))
+	for i, publicKeyString := range testPublicKeyStrings {
+		publicKeys[i], _ = hex.DecodeString(publicKeyString)
+	}
+	redeemScript, err := NewSortedMOfNRedeemScript(testM, testN, publicKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redeemScriptHex := hex.EncodeToString(redeemScript)
+	if redeemScriptHex != testRedeemScriptHex {
+		testutils.CompareError(t, "Sorted M-of-N redeem script different from expected script.", testRedeemScriptHex, redeemScriptHex)
+	}
+
+	//Uncompressed public keys are not allowed by BIP67
+	uncompressedPublicKey, _ := hex.DecodeString("0446f1c8de232a065da428bf76e44b41f59a46620dec0aedfc9b5ab651e91f2051d610fddc78b8eba38a634bfe9a74bb015a88c52b9b844c74997035e08a695ce9")
+	_, err = NewSortedMOfNRedeemScript(1, 2, [][]byte{publicKeys[0], uncompressedPublicKey})
+	if err == nil {
+		t.Error("NewSortedMOfNRedeemScript accepting uncompressed public key.")
+	}
+}
+
+func TestParseMOfNRedeemScript(t *testing.T) {
+	testRedeemScriptHex := "52410446f1c8de232a065da428bf76e44b41f59a46620dec0aedfc9b5ab651e91f2051d610fddc78b8eba38a634bfe9a74bb015a88c52b9b844c74997035e08a695ce94104704e19d4fc234a42d707d41053c87011f990b564949532d72cab009e136bd60d7d0602f925fce79da77c0dfef4a49c6f44bd0540faef548e37557d74b36da1244104b75a8cb10fd3f1785addbafdb41b409ecd6ffd50d5ad71d8a3cdc5503bcb35d3d13cdf23f6d0eb6ab88446276e2ba5b92d8786da7e5c0fb63aafb62f87443d2853ae"
+	testM := 2
+	testN := 3
+	testSecondPublicKeyHex := "04704e19d4fc234a42d707d41053c87011f990b564949532d72cab009e136bd60d7d0602f925fce79da77c0dfef4a49c6f44bd0540faef548e37557d74b36da124"
+
+	redeemScript, _ := hex.DecodeString(testRedeemScriptHex)
+	m, n, publicKeys, err := ParseMOfNRedeemScript(redeemScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != testM || n != testN || len(publicKeys) != testN {
+		t.Errorf("Parsed %d-of-%d redeem script with %d public keys, expected %d-of-%d.", m, n, len(publicKeys), testM, testN)
+	}
+	if hex.EncodeToString(publicKeys[1]) != testSecondPublicKeyHex {
+		testutils.CompareError(t, "Parsed public key different from expected key.", testSecondPublicKeyHex, hex.EncodeToString(publicKeys[1]))
+	}
+
+	invalidRedeemScriptHexs := []string{
+		"", //empty script
+		"76a914199db810a3c8ae5e55c0432d2b72e55b0634f79088ac",                             //P2PKH script
+		"5221020000000000000000000000000000000000000000000000000000000000000000000051ae", //m greater than n
+		"5121020000000000000000000000000000000000000000000000000000000000000000000052ae", //n does not match number of keys
+		"5121020000000000000000000000000000000000000000000000000000000000000000",         //truncated public key push
+		"512104000000000000000000000000000000000000000000000000000000000000000051ae",     //invalid public key prefix
+		"514c2102000000000000000000000000000000000000000000000000000000000000000051ae",   //public key pushed with OP_PUSHDATA1
+		"5121020000000000000000000000000000000000000000000000000000000000000000ac51ae",   //non-push operation in place of a public key
+	}
+	for _, redeemScriptHex := range invalidRedeemScriptHexs {
+		redeemScript, _ := hex.DecodeString(redeemScriptHex)
+		if _, _, _, err := ParseMOfNRedeemScript(redeemScript); err == nil {
+			t.Error("ParseMOfNRedeemScript accepting invalid redeem script as valid:", redeemScriptHex)
+		}
+	}
+}
+
 func TestCheckPublicKeyIsValid(t *testing.T) {
 	invalidPublicKeyStrings := []string{
 		"", //empty key
 		"0446f1c8de232a065da428bf76e44b41f59a46620dec0aedfc9b5ab651e91f2051d610fddc78b8eba38a634bfe9a74bb015a88c52b9b844c74997035e08a695c",   //wrong length key
 		"0346f1c8de232a065da428bf76e44b41f59a46620dec0aedfc9b5ab651e91f2051d610fddc78b8eba38a634bfe9a74bb015a88c52b9b844c74997035e08a695ce9", //wrong prefix key
+		"0446f1c8de232a065da428bf76e44b41f59a46620dec0aedfc9b5ab651e91f2051",                                                                 //wrong prefix compressed key
 	}
 	for _, publicKeyString := range invalidPublicKeyStrings {
 		publicKey, _ := hex.DecodeString(publicKeyString)
@@ -71,6 +253,17 @@ func TestCheckPublicKeyIsValid(t *testing.T) {
 			t.Error("CheckPublicKeyIsValid accepting invalids public keys as valid.")
 		}
 	}
+
+	validPublicKeyStrings := []string{
+		"0446f1c8de232a065da428bf76e44b41f59a46620dec0aedfc9b5ab651e91f2051d610fddc78b8eba38a634bfe9a74bb015a88c52b9b844c74997035e08a695ce9", //uncompressed key
+		"03a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575",                                                                 //compressed key
+	}
+	for _, publicKeyString := range validPublicKeyStrings {
+		publicKey, _ := hex.DecodeString(publicKeyString)
+		if err := CheckPublicKeyIsValid(publicKey); err != nil {
+			t.Error("CheckPublicKeyIsValid rejecting valid public key:", err)
+		}
+	}
 }
 
 func TestNewP2SHScriptPubKey(t *testing.T) {
@@ -88,6 +281,41 @@ func TestNewP2SHScriptPubKey(t *testing.T) {
 	}
 }
 
+func TestNewP2WSHScriptPubKey(t *testing.T) {
+	testWitnessScriptHashString := "1863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"
+	testScriptPubKeyHex := "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"
+
+	witnessScriptHash, _ := hex.DecodeString(testWitnessScriptHashString)
+	scriptPubKey, err := NewP2WSHScriptPubKey(witnessScriptHash)
+	if err != nil {
+		t.Error(err)
+	}
+	scriptPubKeyHex := hex.EncodeToString(scriptPubKey)
+	if scriptPubKeyHex != testScriptPubKeyHex {
+		testutils.CompareError(t, "P2WSH scriptPubKey different from expected script.", testScriptPubKeyHex, scriptPubKeyHex)
+	}
+	if _, err := NewP2WSHScriptPubKey(witnessScriptHash[:20]); err == nil {
+		t.Error("NewP2WSHScriptPubKey accepting witness script hash that is not 32 bytes.")
+	}
+}
+
+func TestNewP2SHP2WSHScriptSig(t *testing.T) {
+	testWitnessScriptHex := "5121020000000000000000000000000000000000000000000000000000000000000000000051ae"
+	testScriptSigHex := "220020"
+
+	witnessScript, _ := hex.DecodeString(testWitnessScriptHex)
+	scriptSig, err := NewP2SHP2WSHScriptSig(witnessScript)
+	if err != nil {
+		t.Error(err)
+	}
+	witnessScriptHash := sha256.Sum256(witnessScript)
+	testScriptSigHex += hex.EncodeToString(witnessScriptHash[:])
+	scriptSigHex := hex.EncodeToString(scriptSig)
+	if scriptSigHex != testScriptSigHex {
+		testutils.CompareError(t, "P2SH-P2WSH scriptSig different from expected script.", testScriptSigHex, scriptSigHex)
+	}
+}
+
 func TestNewP2PKHScriptPubKey(t *testing.T) {
 	testPublicAddressString := "13LSqJeZBpqLHzmLkJ5mvRHiM11waShFUP"
 	testPublicKeyHash := base58check.Decode(testPublicAddressString)
@@ -122,9 +350,9 @@ func TestNewRawTransaction(t *testing.T) {
 func TestNewSignature(t *testing.T) {
 	testRawTx := []byte{1, 0, 0, 0, 1, 172, 198, 251, 158, 194, 195, 136, 77, 58, 18, 168, 158, 112, 120, 200, 56, 83, 217, 183, 145, 34, 129, 206, 251, 20, 186, 192, 10, 39, 55, 211, 58, 0, 0, 0, 0, 25, 118, 169, 20, 146, 3, 228, 122, 22, 247, 153, 222, 208, 53, 50, 227, 228, 82, 96, 111, 220, 82, 0, 126, 136, 172, 255, 255, 255, 255, 1, 64, 0, 1, 0, 0, 0, 0, 0, 23, 169, 20, 26, 139, 0, 38, 52, 49, 102, 98, 92, 116, 117, 240, 30, 72, 181, 237, 232, 192, 37, 46, 135, 0, 0, 0, 0}
 	testPrivateKey := []byte{20, 175, 46, 68, 8, 91, 132, 129, 57, 230, 158, 54, 186, 115, 191, 245, 121, 11, 108, 224, 125, 96, 99, 40, 11, 156, 199, 158, 55, 199, 110, 229}
-	testSignature := []byte{48, 68, 2, 32, 109, 108, 170, 194, 72, 175, 150, 246, 175, 167, 249, 4, 245, 80, 37, 58, 15, 62, 243, 245, 170, 47, 230, 131, 138, 149, 178, 22, 105, 20, 104, 226, 2, 32, 121, 239, 192, 104, 145, 56, 231, 141, 41, 172, 104, 123, 214, 135, 215, 255, 145, 125, 106, 219, 104, 4, 242, 63, 219, 107, 193, 152, 184, 110, 20, 41}
+	testSignature := []byte{48, 69, 2, 33, 0, 178, 94, 76, 190, 47, 250, 176, 232, 225, 177, 102, 156, 123, 205, 101, 110, 13, 80, 77, 140, 60, 204, 32, 41, 56, 199, 205, 21, 5, 182, 219, 59, 2, 32, 13, 40, 107, 255, 198, 63, 144, 23, 75, 231, 227, 220, 125, 89, 77, 162, 201, 201, 51, 111, 100, 111, 123, 252, 166, 15, 5, 213, 15, 20, 181, 189}
 
-	SetFixedNonce = true //Use testing-only setting to fix nonce at zero bytes, so we can get repeatability in testing
+	//Signatures use deterministic RFC6979 nonces, so they are repeatable without any testing-only settings
 	signature, err := NewSignature(testRawTx, testPrivateKey)
 	if err != nil {
 		t.Error(err)
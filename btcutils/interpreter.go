@@ -0,0 +1,749 @@
+// Provides a Bitcoin Script interpreter, to verify a signed transaction input satisfies the output it spends before it is broadcast.
+// Covers legacy scripts, P2SH (BIP16) and SegWit version 0 (BIP141) spends, with the standardness rules nodes apply when relaying.
+// See https://en.bitcoin.it/wiki/Script for full specification.
+package btcutils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	secp256k1 "github.com/toxeus/go-secp256k1"
+)
+
+// ScriptFlags selects the rules a script is verified under.
+type ScriptFlags uint32
+
+// Script verification flags. Consensus rules are enforced by every node; the others are standardness rules,
+// which nodes enforce before relaying or mining a transaction.
+const (
+	ScriptVerifyP2SH                ScriptFlags = 1 << iota //Evaluate P2SH redeem scripts (BIP16)
+	ScriptVerifyStrictEncoding                              //Signatures must be strict DER (BIP66) with a defined hash type, public keys compressed or uncompressed
+	ScriptVerifyLowS                                        //Signatures must have S at most N/2 (BIP62)
+	ScriptVerifyNullDummy                                   //The extra item OP_CHECKMULTISIG pops must be empty (BIP147)
+	ScriptVerifySigPushOnly                                 //scriptSigs may only push data
+	ScriptVerifyMinimalData                                 //Data must be pushed with the smallest push operation
+	ScriptVerifyCleanStack                                  //Exactly one item must be left on the stack
+	ScriptVerifyWitness                                     //Evaluate SegWit witness programs (BIP141)
+	ScriptVerifyNullFail                                    //Failed signature checks must have empty signatures (BIP146)
+	ScriptVerifyWitnessPubKeyType                           //Public keys in SegWit scripts must be compressed
+	ScriptVerifyCheckLockTimeVerify                         //Evaluate OP_CHECKLOCKTIMEVERIFY, formerly OP_NOP2 (BIP65)
+	ScriptVerifyCheckSequenceVerify                         //Evaluate OP_CHECKSEQUENCEVERIFY, formerly OP_NOP3 (BIP112)
+)
+
+// Sets of script verification flags.
+const (
+	ConsensusScriptFlags = ScriptVerifyP2SH | ScriptVerifyWitness | ScriptVerifyCheckLockTimeVerify | ScriptVerifyCheckSequenceVerify
+	StandardScriptFlags  = ConsensusScriptFlags | ScriptVerifyStrictEncoding | ScriptVerifyLowS | ScriptVerifyNullDummy | ScriptVerifySigPushOnly |
+		ScriptVerifyMinimalData | ScriptVerifyCleanStack | ScriptVerifyNullFail | ScriptVerifyWitnessPubKeyType
+)
+
+// Script size limits.
+const (
+	maxScriptSize            = 10000 //Bytes in a script
+	maxScriptElementSize     = 520   //Bytes in a single stack item
+	maxScriptOps             = 201   //Non-push operations executed by a script
+	maxStackSize             = 1000  //Items on the stack
+	maxPublicKeysPerMultisig = 20
+)
+
+// Signature versions, which decide how the signature hash is computed.
+const (
+	sigVersionBase      = 0 //Legacy and P2SH scripts
+	sigVersionWitnessV0 = 1 //SegWit version 0 scripts (BIP143)
+)
+
+// scriptEngine executes the scripts of input inputIndex of tx, which spends an output worth amount Satoshis.
+type scriptEngine struct {
+	tx         *Transaction
+	inputIndex int
+	amount     int64
+	flags      ScriptFlags
+}
+
+// VerifyTransaction verifies every input of tx satisfies the output it spends under flags. prevOutputs holds the
+// output spent by each input, in input order; amounts are only needed for SegWit inputs.
+func VerifyTransaction(tx *Transaction, prevOutputs []*TxOut, flags ScriptFlags) error {
+	if len(prevOutputs) != len(tx.Inputs) {
+		return errors.New(fmt.Sprintf("Transaction has %d inputs, but %d previous outputs were given.", len(tx.Inputs), len(prevOutputs)))
+	}
+	for i, prevOutput := range prevOutputs {
+		err := VerifyInput(tx, i, prevOutput.ScriptPubKey, prevOutput.Value, flags)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Input #%d is invalid. %v", i, err))
+		}
+	}
+	return nil
+}
+
+// VerifyInput executes the scriptSig and witness of input inputIndex of tx against prevScriptPubKey, the scriptPubKey of the
+// output it spends, worth amount Satoshis. Returns nil if the input is valid under flags, or an error saying why it is not.
+func VerifyInput(tx *Transaction, inputIndex int, prevScriptPubKey []byte, amount int64, flags ScriptFlags) error {
+	if inputIndex < 0 || inputIndex >= len(tx.Inputs) {
+		return errors.New(fmt.Sprintf("Input index %d out of range for transaction with %d inputs.", inputIndex, len(tx.Inputs)))
+	}
+	engine := &scriptEngine{tx: tx, inputIndex: inputIndex, amount: amount, flags: flags}
+	txIn := tx.Inputs[inputIndex]
+	if flags&ScriptVerifySigPushOnly != 0 && !isPushOnly(txIn.ScriptSig) {
+		return errors.New("scriptSig must only push data.")
+	}
+	stack, err := engine.execute(txIn.ScriptSig, nil, sigVersionBase)
+	if err != nil {
+		return errors.New(fmt.Sprintf("scriptSig failed: %v", err))
+	}
+	scriptSigStack := copyStack(stack)
+	stack, err = engine.execute(prevScriptPubKey, stack, sigVersionBase)
+	if err != nil {
+		return errors.New(fmt.Sprintf("scriptPubKey failed: %v", err))
+	}
+	if !stackResult(stack) {
+		return errors.New("Script evaluated to false. Check the signatures and the script being spent.")
+	}
+	hadWitness := false
+	//Native SegWit: the scriptSig must be empty, and the witness satisfies the witness program
+	if witnessVersion, witnessProgram, ok := parseWitnessProgram(prevScriptPubKey); ok && flags&ScriptVerifyWitness != 0 {
+		hadWitness = true
+		if len(txIn.ScriptSig) != 0 {
+			return errors.New("scriptSig must be empty when spending a native SegWit output.")
+		}
+		err = engine.verifyWitnessProgram(txIn.Witness, witnessVersion, witnessProgram)
+		if err != nil {
+			return err
+		}
+		stack = stack[:1]
+	}
+	//P2SH: the last item pushed by the scriptSig is the redeem script, which runs on the rest of the scriptSig's stack
+	if flags&ScriptVerifyP2SH != 0 && isP2SHScript(prevScriptPubKey) {
+		if !isPushOnly(txIn.ScriptSig) {
+			return errors.New("scriptSig must only push data when spending a P2SH output.")
+		}
+		stack = scriptSigStack
+		if len(stack) == 0 {
+			return errors.New("scriptSig must push the redeem script when spending a P2SH output.")
+		}
+		redeemScript := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		stack, err = engine.execute(redeemScript, stack, sigVersionBase)
+		if err != nil {
+			return errors.New(fmt.Sprintf("P2SH redeem script failed: %v", err))
+		}
+		if !stackResult(stack) {
+			return errors.New("P2SH redeem script evaluated to false. Check the signatures and the redeem script.")
+		}
+		//P2SH-P2WSH and P2SH-P2WPKH: the scriptSig pushes only the witness program, and the witness satisfies it
+		if witnessVersion, witnessProgram, ok := parseWitnessProgram(redeemScript); ok && flags&ScriptVerifyWitness != 0 {
+			hadWitness = true
+			var expectedScriptSig bytes.Buffer
+			WritePushData(&expectedScriptSig, redeemScript)
+			if !bytes.Equal(txIn.ScriptSig, expectedScriptSig.Bytes()) {
+				return errors.New("scriptSig must only push the witness program when spending a nested SegWit output.")
+			}
+			err = engine.verifyWitnessProgram(txIn.Witness, witnessVersion, witnessProgram)
+			if err != nil {
+				return err
+			}
+			stack = stack[:1]
+		}
+	}
+	if flags&ScriptVerifyCleanStack != 0 && len(stack) != 1 {
+		return errors.New(fmt.Sprintf("Script left %d items on the stack, should leave exactly one.", len(stack)))
+	}
+	if flags&ScriptVerifyWitness != 0 && !hadWitness && len(txIn.Witness) != 0 {
+		return errors.New("Input has a witness but does not spend a SegWit output.")
+	}
+	return nil
+}
+
+// verifyWitnessProgram verifies witness satisfies a SegWit witness program of witnessVersion.
+func (engine *scriptEngine) verifyWitnessProgram(witness [][]byte, witnessVersion byte, witnessProgram []byte) error {
+	if witnessVersion != 0 {
+		//Witness versions above 0 are left for future soft forks, so anything satisfies them under version 0 rules
+		return nil
+	}
+	var script []byte
+	var stack [][]byte
+	switch len(witnessProgram) {
+	case 20:
+		//P2WPKH: the witness is a signature and public key, checked as for P2PKH
+		if len(witness) != 2 {
+			return errors.New(fmt.Sprintf("P2WPKH witness has %d items, should hold a signature and public key.", len(witness)))
+		}
+		script, _ = NewP2PKHScriptPubKey(witnessProgram)
+		stack = copyStack(witness)
+	case 32:
+		//P2WSH: the last witness item is the witness script, which runs on the other items
+		if len(witness) == 0 {
+			return errors.New("P2WSH witness is empty, should end with the witness script.")
+		}
+		script = witness[len(witness)-1]
+		witnessScriptHash := sha256.Sum256(script)
+		if !bytes.Equal(witnessScriptHash[:], witnessProgram) {
+			return errors.New("P2WSH witness script does not match the witness program being spent.")
+		}
+		stack = copyStack(witness[:len(witness)-1])
+	default:
+		return errors.New(fmt.Sprintf("Version 0 witness program is %d bytes long, should be 20 or 32.", len(witnessProgram)))
+	}
+	for _, item := range stack {
+		if len(item) > maxScriptElementSize {
+			return errors.New(fmt.Sprintf("Witness item of %d bytes is above the %d byte limit.", len(item), maxScriptElementSize))
+		}
+	}
+	stack, err := engine.execute(script, stack, sigVersionWitnessV0)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Witness script failed: %v", err))
+	}
+	//SegWit scripts must always leave exactly one true item on the stack
+	if len(stack) != 1 {
+		return errors.New(fmt.Sprintf("Witness script left %d items on the stack, should leave exactly one.", len(stack)))
+	}
+	if !stackResult(stack) {
+		return errors.New("Witness script evaluated to false. Check the signatures and the witness script.")
+	}
+	return nil
+}
+
+// execute runs script on stack, returning the resulting stack.
+func (engine *scriptEngine) execute(script []byte, stack [][]byte, sigVersion int) ([][]byte, error) {
+	if len(script) > maxScriptSize {
+		return nil, errors.New(fmt.Sprintf("Script of %d bytes is above the %d byte limit.", len(script), maxScriptSize))
+	}
+	ops, err := ParseScript(script)
+	if err != nil {
+		return nil, err
+	}
+	var conditions []bool //Whether each enclosing OP_IF branch is being executed
+	opCount := 0
+	//Signature checks sign the script from just after the last OP_CODESEPARATOR executed
+	scriptCode := script
+	position := 0
+	for _, op := range ops {
+		var opBuffer bytes.Buffer
+		writeScriptOp(&opBuffer, op)
+		position += opBuffer.Len()
+		executing := true
+		for _, condition := range conditions {
+			executing = executing && condition
+		}
+		if len(op.Data) > maxScriptElementSize {
+			return nil, errors.New(fmt.Sprintf("Push of %d bytes is above the %d byte limit.", len(op.Data), maxScriptElementSize))
+		}
+		if op.Opcode > OP_16 {
+			opCount++
+			if opCount > maxScriptOps {
+				return nil, errors.New(fmt.Sprintf("Script runs more than %d operations.", maxScriptOps))
+			}
+		}
+		//Disabled operations, OP_VERIF and OP_VERNOTIF fail the script even in a branch that is not executed
+		if isDisabledOpcode(op.Opcode) || op.Opcode == OP_VERIF || op.Opcode == OP_VERNOTIF {
+			return nil, errors.New(fmt.Sprintf("%s is not allowed anywhere in a script.", OpcodeName(op.Opcode)))
+		}
+		switch {
+		case op.Opcode <= OP_PUSHDATA4:
+			if !executing {
+				continue
+			}
+			if engine.flags&ScriptVerifyMinimalData != 0 && !isMinimalPush(op) {
+				return nil, errors.New(fmt.Sprintf("Push of %d bytes does not use the smallest push operation.", len(op.Data)))
+			}
+			stack = append(stack, op.Data)
+		case op.Opcode == OP_IF || op.Opcode == OP_NOTIF:
+			condition := false
+			if executing {
+				if len(stack) < 1 {
+					return nil, errors.New("OP_IF with an empty stack.")
+				}
+				condition = castToBool(stack[len(stack)-1]) == (op.Opcode == OP_IF)
+				stack = stack[:len(stack)-1]
+			}
+			conditions = append(conditions, condition)
+		case op.Opcode == OP_ELSE:
+			if len(conditions) == 0 {
+				return nil, errors.New("OP_ELSE without OP_IF.")
+			}
+			conditions[len(conditions)-1] = !conditions[len(conditions)-1]
+		case op.Opcode == OP_ENDIF:
+			if len(conditions) == 0 {
+				return nil, errors.New("OP_ENDIF without OP_IF.")
+			}
+			conditions = conditions[:len(conditions)-1]
+		case !executing:
+			continue
+		case op.Opcode == OP_CODESEPARATOR:
+			scriptCode = script[position:]
+		default:
+			stack, err = engine.executeOp(op.Opcode, stack, scriptCode, sigVersion, &opCount)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(stack) > maxStackSize {
+			return nil, errors.New(fmt.Sprintf("Stack holds more than %d items.", maxStackSize))
+		}
+	}
+	if len(conditions) != 0 {
+		return nil, errors.New("OP_IF without OP_ENDIF.")
+	}
+	return stack, nil
+}
+
+// executeOp runs a single non-push, non-flow control operation on stack, returning the resulting stack.
+// script is the part of the script being run signed by signature checks, and opCount the number of operations run so far.
+func (engine *scriptEngine) executeOp(opcode byte, stack [][]byte, script []byte, sigVersion int, opCount *int) ([][]byte, error) {
+	switch {
+	case opcode == OP_1NEGATE:
+		return append(stack, []byte{0x81}), nil
+	case opcode >= OP_1 && opcode <= OP_16:
+		return append(stack, []byte{opcode - OP_1 + 1}), nil
+	case opcode == OP_CHECKLOCKTIMEVERIFY && engine.flags&ScriptVerifyCheckLockTimeVerify != 0:
+		return stack, engine.checkLockTime(stack)
+	case opcode == OP_CHECKSEQUENCEVERIFY && engine.flags&ScriptVerifyCheckSequenceVerify != 0:
+		return stack, engine.checkSequence(stack)
+	case opcode == OP_NOP || (opcode >= OP_NOP1 && opcode <= OP_NOP10):
+		return stack, nil
+	case opcode == OP_RETURN:
+		return nil, errors.New("OP_RETURN makes the output unspendable.")
+	}
+	//Every other supported operation takes at least one item from the stack
+	if len(stack) < 1 {
+		return nil, errors.New(fmt.Sprintf("%s with an empty stack.", OpcodeName(opcode)))
+	}
+	top := stack[len(stack)-1]
+	switch opcode {
+	case OP_VERIFY:
+		if !castToBool(top) {
+			return nil, errors.New("OP_VERIFY failed.")
+		}
+		return stack[:len(stack)-1], nil
+	case OP_DROP:
+		return stack[:len(stack)-1], nil
+	case OP_DUP:
+		return append(stack, top), nil
+	case OP_SHA256:
+		hash := sha256.Sum256(top)
+		return append(stack[:len(stack)-1], hash[:]), nil
+	case OP_HASH160:
+		hash, err := Hash160(top)
+		if err != nil {
+			return nil, err
+		}
+		return append(stack[:len(stack)-1], hash), nil
+	case OP_HASH256:
+		return append(stack[:len(stack)-1], DoubleSha256(top)), nil
+	case OP_EQUAL, OP_EQUALVERIFY:
+		if len(stack) < 2 {
+			return nil, errors.New(fmt.Sprintf("%s needs two stack items.", OpcodeName(opcode)))
+		}
+		equal := bytes.Equal(stack[len(stack)-2], top)
+		stack = stack[:len(stack)-2]
+		if opcode == OP_EQUALVERIFY {
+			if !equal {
+				return nil, errors.New("OP_EQUALVERIFY failed: items are not equal.")
+			}
+			return stack, nil
+		}
+		return append(stack, boolBytes(equal)), nil
+	case OP_CHECKSIG, OP_CHECKSIGVERIFY:
+		if len(stack) < 2 {
+			return nil, errors.New(fmt.Sprintf("%s needs a signature and public key.", OpcodeName(opcode)))
+		}
+		signature, publicKey := stack[len(stack)-2], top
+		stack = stack[:len(stack)-2]
+		valid, err := engine.checkSignature(signature, publicKey, removeSignature(script, signature, sigVersion), sigVersion)
+		if err != nil {
+			return nil, err
+		}
+		if !valid && len(signature) != 0 && engine.flags&ScriptVerifyNullFail != 0 {
+			return nil, errors.New("Signature is not valid for the public key and transaction.")
+		}
+		if opcode == OP_CHECKSIGVERIFY {
+			if !valid {
+				return nil, errors.New("OP_CHECKSIGVERIFY failed: signature is not valid.")
+			}
+			return stack, nil
+		}
+		return append(stack, boolBytes(valid)), nil
+	case OP_CHECKMULTISIG, OP_CHECKMULTISIGVERIFY:
+		return engine.checkMultisig(opcode, stack, script, sigVersion, opCount)
+	}
+	return nil, errors.New(fmt.Sprintf("%s is not supported.", OpcodeName(opcode)))
+}
+
+// checkMultisig runs OP_CHECKMULTISIG or OP_CHECKMULTISIGVERIFY on stack, which holds, from the top: N, N public keys, M,
+// M signatures and one extra item (popped due to an off-by-one bug in the original implementation).
+// Signatures must be in the same order as the public keys they belong to. Each of the N public keys counts towards opCount.
+func (engine *scriptEngine) checkMultisig(opcode byte, stack [][]byte, script []byte, sigVersion int, opCount *int) ([][]byte, error) {
+	requireMinimal := engine.flags&ScriptVerifyMinimalData != 0
+	pop := func() []byte {
+		item := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return item
+	}
+	n, err := parseScriptNumber(pop(), requireMinimal, 4)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n > maxPublicKeysPerMultisig {
+		return nil, errors.New(fmt.Sprintf("%s public key count %d is not from 0 to %d.", OpcodeName(opcode), n, maxPublicKeysPerMultisig))
+	}
+	*opCount += int(n)
+	if *opCount > maxScriptOps {
+		return nil, errors.New(fmt.Sprintf("Script runs more than %d operations.", maxScriptOps))
+	}
+	if len(stack) < int(n)+1 {
+		return nil, errors.New(fmt.Sprintf("%s needs %d public keys and a signature count.", OpcodeName(opcode), n))
+	}
+	publicKeys := make([][]byte, n)
+	for i := int(n) - 1; i >= 0; i-- {
+		publicKeys[i] = pop()
+	}
+	m, err := parseScriptNumber(pop(), requireMinimal, 4)
+	if err != nil {
+		return nil, err
+	}
+	if m < 0 || m > n {
+		return nil, errors.New(fmt.Sprintf("%s signature count %d is not from 0 to the public key count %d.", OpcodeName(opcode), m, n))
+	}
+	if len(stack) < int(m)+1 {
+		return nil, errors.New(fmt.Sprintf("%s needs %d signatures and an extra item.", OpcodeName(opcode), m))
+	}
+	signatures := make([][]byte, m)
+	for i := int(m) - 1; i >= 0; i-- {
+		signatures[i] = pop()
+	}
+	dummy := pop()
+	if engine.flags&ScriptVerifyNullDummy != 0 && len(dummy) != 0 {
+		return nil, errors.New(fmt.Sprintf("%s extra item must be empty.", OpcodeName(opcode)))
+	}
+	for _, signature := range signatures {
+		script = removeSignature(script, signature, sigVersion)
+	}
+	//Match each signature against the remaining public keys, from the last of each backwards as Bitcoin Core does, so the same
+	//keys have their encoding checked. Fails once fewer keys than signatures are left.
+	valid := true
+	signatureIndex := len(signatures) - 1
+	for keyIndex := len(publicKeys) - 1; valid && signatureIndex >= 0; keyIndex-- {
+		matches, err := engine.checkSignature(signatures[signatureIndex], publicKeys[keyIndex], script, sigVersion)
+		if err != nil {
+			return nil, err
+		}
+		if matches {
+			signatureIndex--
+		}
+		if signatureIndex+1 > keyIndex {
+			valid = false
+		}
+	}
+	if !valid && engine.flags&ScriptVerifyNullFail != 0 {
+		for _, signature := range signatures {
+			if len(signature) != 0 {
+				return nil, errors.New(fmt.Sprintf("%s failed: signatures are not valid for the public keys and transaction, or are not in public key order.", OpcodeName(opcode)))
+			}
+		}
+	}
+	if opcode == OP_CHECKMULTISIGVERIFY {
+		if !valid {
+			return nil, errors.New("OP_CHECKMULTISIGVERIFY failed: signatures are not valid.")
+		}
+		return stack, nil
+	}
+	return append(stack, boolBytes(valid)), nil
+}
+
+// checkLockTime runs OP_CHECKLOCKTIMEVERIFY, which leaves stack unchanged. The locktime on top of stack must be the same kind
+// (block height or Unix timestamp, see LockTimeThreshold) as the transaction's locktime and no later than it, and the input being
+// verified must not have a final sequence number, which would disable the transaction's locktime.
+func (engine *scriptEngine) checkLockTime(stack [][]byte) error {
+	if len(stack) < 1 {
+		return errors.New("OP_CHECKLOCKTIMEVERIFY with an empty stack.")
+	}
+	//Locktimes above 2^31-1 need 5 byte script numbers
+	lockTime, err := parseScriptNumber(stack[len(stack)-1], engine.flags&ScriptVerifyMinimalData != 0, 5)
+	if err != nil {
+		return err
+	}
+	if lockTime < 0 {
+		return errors.New(fmt.Sprintf("OP_CHECKLOCKTIMEVERIFY locktime %d is negative.", lockTime))
+	}
+	txLockTime := int64(engine.tx.LockTime)
+	if (lockTime < LockTimeThreshold) != (txLockTime < LockTimeThreshold) {
+		return errors.New(fmt.Sprintf("OP_CHECKLOCKTIMEVERIFY locktime %d and transaction locktime %d are not both block heights or both timestamps.", lockTime, txLockTime))
+	}
+	if lockTime > txLockTime {
+		return errors.New(fmt.Sprintf("Transaction locktime %d is before the OP_CHECKLOCKTIMEVERIFY locktime %d.", txLockTime, lockTime))
+	}
+	if engine.tx.Inputs[engine.inputIndex].Sequence == MaxTxInSequenceNum {
+		return errors.New("OP_CHECKLOCKTIMEVERIFY input has the final sequence number, which disables the transaction locktime.")
+	}
+	return nil
+}
+
+// checkSequence runs OP_CHECKSEQUENCEVERIFY, which leaves stack unchanged. Unless the relative locktime on top of stack has
+// its disable flag set, the transaction must be version 2 or later and the input being verified must have a relative locktime
+// (see SequenceLockTimeMask) of the same kind (blocks or 512 second units) and no shorter.
+func (engine *scriptEngine) checkSequence(stack [][]byte) error {
+	if len(stack) < 1 {
+		return errors.New("OP_CHECKSEQUENCEVERIFY with an empty stack.")
+	}
+	//Sequence numbers above 2^31-1 need 5 byte script numbers
+	sequence, err := parseScriptNumber(stack[len(stack)-1], engine.flags&ScriptVerifyMinimalData != 0, 5)
+	if err != nil {
+		return err
+	}
+	if sequence < 0 {
+		return errors.New(fmt.Sprintf("OP_CHECKSEQUENCEVERIFY relative locktime %d is negative.", sequence))
+	}
+	//With the disable flag set, OP_CHECKSEQUENCEVERIFY is left as a no-op for future soft forks
+	if sequence&SequenceLockTimeDisabled != 0 {
+		return nil
+	}
+	if engine.tx.Version < 2 {
+		return errors.New(fmt.Sprintf("OP_CHECKSEQUENCEVERIFY in a version %d transaction, which has no relative locktimes. Version 2 is needed.", engine.tx.Version))
+	}
+	txSequence := int64(engine.tx.Inputs[engine.inputIndex].Sequence)
+	if txSequence&SequenceLockTimeDisabled != 0 {
+		return errors.New(fmt.Sprintf("OP_CHECKSEQUENCEVERIFY input sequence number 0x%08x has the relative locktime disable flag set.", txSequence))
+	}
+	//Only the kind and value of the relative locktimes are compared
+	sequence &= SequenceLockTimeIsSeconds | SequenceLockTimeMask
+	txSequence &= SequenceLockTimeIsSeconds | SequenceLockTimeMask
+	if (sequence < SequenceLockTimeIsSeconds) != (txSequence < SequenceLockTimeIsSeconds) {
+		return errors.New(fmt.Sprintf("OP_CHECKSEQUENCEVERIFY relative locktime 0x%08x and input sequence number 0x%08x are not both in blocks or both in time.", sequence, txSequence))
+	}
+	if sequence > txSequence {
+		return errors.New(fmt.Sprintf("Input sequence number 0x%08x has a shorter relative locktime than the OP_CHECKSEQUENCEVERIFY relative locktime 0x%08x.", txSequence, sequence))
+	}
+	return nil
+}
+
+// checkSignature checks signature, followed by its hash type byte, is a valid signature by publicKey of the transaction
+// input with script as the script being satisfied. Returns an error, rather than false, for encodings the flags do not allow.
+func (engine *scriptEngine) checkSignature(signature []byte, publicKey []byte, script []byte, sigVersion int) (bool, error) {
+	//Encodings are checked as Bitcoin Core does: the signature's unless it is empty, then the public key's whatever the signature
+	if len(signature) != 0 && engine.flags&ScriptVerifyStrictEncoding != 0 {
+		if !isStrictDERSignature(signature) {
+			return false, errors.New("Signature is not strict DER encoded.")
+		}
+		if !isDefinedHashType(signature[len(signature)-1]) {
+			return false, errors.New(fmt.Sprintf("Signature hash type 0x%02x is not defined.", signature[len(signature)-1]))
+		}
+	}
+	if len(signature) != 0 && engine.flags&ScriptVerifyLowS != 0 {
+		_, s, err := parseDERSignature(signature[:len(signature)-1])
+		if err != nil {
+			return false, err
+		}
+		if s.Cmp(secp256k1HalfOrder) > 0 {
+			return false, errors.New("Signature S value is above N/2 (high-S).")
+		}
+	}
+	if engine.flags&ScriptVerifyStrictEncoding != 0 {
+		if err := CheckPublicKeyIsValid(publicKey); err != nil {
+			return false, err
+		}
+	}
+	if engine.flags&ScriptVerifyWitnessPubKeyType != 0 && sigVersion == sigVersionWitnessV0 && (len(publicKey) != 33 || (publicKey[0] != 0x02 && publicKey[0] != 0x03)) {
+		return false, errors.New("Public keys in SegWit scripts must be compressed.")
+	}
+	if len(signature) == 0 {
+		return false, nil
+	}
+	derSignature := signature[:len(signature)-1]
+	hashType := uint32(signature[len(signature)-1])
+	var hash []byte
+	var err error
+	if sigVersion == sigVersionWitnessV0 {
+		hash, err = WitnessSignatureHash(engine.tx, engine.inputIndex, script, engine.amount, hashType)
+	} else {
+		hash, err = SignatureHash(engine.tx, engine.inputIndex, script, hashType)
+	}
+	if err != nil {
+		return false, err
+	}
+	return verifySignature(hash, derSignature, publicKey), nil
+}
+
+// verifySignature checks a DER encoded signature of hash by publicKey.
+func verifySignature(hash []byte, signature []byte, publicKey []byte) bool {
+	secp256k1.Start()
+	defer secp256k1.Stop()
+	return secp256k1.Verify(hash, signature, publicKey)
+}
+
+// removeSignature returns script without any push of signature, since a legacy signature cannot sign itself.
+// SegWit signature hashes do not include the script's signatures, so SegWit scripts are returned unchanged.
+func removeSignature(script []byte, signature []byte, sigVersion int) []byte {
+	if sigVersion != sigVersionBase || len(signature) == 0 {
+		return script
+	}
+	ops, err := ParseScript(script)
+	if err != nil {
+		return script
+	}
+	var signaturePush bytes.Buffer
+	WritePushData(&signaturePush, signature)
+	var remaining bytes.Buffer
+	for _, op := range ops {
+		var opBytes bytes.Buffer
+		writeScriptOp(&opBytes, op)
+		if !bytes.Equal(opBytes.Bytes(), signaturePush.Bytes()) {
+			remaining.Write(opBytes.Bytes())
+		}
+	}
+	return remaining.Bytes()
+}
+
+// parseWitnessProgram splits a SegWit scriptPubKey (or P2SH redeem script) into its witness version and witness program.
+// ok is false if script is not a witness program: a version opcode followed by a single 2 to 40 byte push.
+func parseWitnessProgram(script []byte) (witnessVersion byte, witnessProgram []byte, ok bool) {
+	if len(script) < 4 || len(script) > 42 || int(script[1]) != len(script)-2 {
+		return 0, nil, false
+	}
+	switch {
+	case script[0] == OP_0:
+		return 0, script[2:], true
+	case script[0] >= OP_1 && script[0] <= OP_16:
+		return script[0] - OP_1 + 1, script[2:], true
+	}
+	return 0, nil, false
+}
+
+// isP2SHScript returns true if script is a P2SH scriptPubKey: OP_HASH160 <20 byte hash> OP_EQUAL.
+func isP2SHScript(script []byte) bool {
+	return len(script) == 23 && script[0] == OP_HASH160 && script[1] == 20 && script[22] == OP_EQUAL
+}
+
+// isDisabledOpcode returns true if opcode was disabled in Bitcoin's early releases, so any script containing it is invalid.
+func isDisabledOpcode(opcode byte) bool {
+	switch opcode {
+	case OP_CAT, OP_SUBSTR, OP_LEFT, OP_RIGHT, OP_INVERT, OP_AND, OP_OR, OP_XOR, OP_2MUL, OP_2DIV, OP_MUL, OP_DIV, OP_MOD, OP_LSHIFT, OP_RSHIFT:
+		return true
+	}
+	return false
+}
+
+// isPushOnly returns true if script only pushes data (including OP_1NEGATE and OP_1 to OP_16).
+func isPushOnly(script []byte) bool {
+	ops, err := ParseScript(script)
+	if err != nil {
+		return false
+	}
+	for _, op := range ops {
+		if op.Opcode > OP_16 {
+			return false
+		}
+	}
+	return true
+}
+
+// isMinimalPush returns true if a push operation uses the smallest push for its data: OP_0, OP_1NEGATE or OP_1 to OP_16
+// for empty and single small number pushes, then direct pushes, then OP_PUSHDATA1, OP_PUSHDATA2 and OP_PUSHDATA4.
+func isMinimalPush(op ScriptOp) bool {
+	switch {
+	case len(op.Data) == 0:
+		return op.Opcode == OP_0
+	case len(op.Data) == 1 && op.Data[0] >= 1 && op.Data[0] <= 16:
+		return false //Should be OP_1 to OP_16
+	case len(op.Data) == 1 && op.Data[0] == 0x81:
+		return false //Should be OP_1NEGATE
+	case len(op.Data) < OP_PUSHDATA1:
+		return int(op.Opcode) == len(op.Data)
+	case len(op.Data) <= 0xff:
+		return op.Opcode == OP_PUSHDATA1
+	case len(op.Data) <= 0xffff:
+		return op.Opcode == OP_PUSHDATA2
+	}
+	return true
+}
+
+// isStrictDERSignature returns true if signature, followed by its hash type byte, is strictly DER encoded as per BIP66.
+func isStrictDERSignature(signature []byte) bool {
+	//DER signature format:
+	//0x30 <length> 0x02 <R length> <R> 0x02 <S length> <S> <hash type>
+	if len(signature) < 9 || len(signature) > 73 {
+		return false
+	}
+	if signature[0] != 0x30 || int(signature[1]) != len(signature)-3 {
+		return false
+	}
+	rLength := int(signature[3])
+	if 5+rLength >= len(signature) {
+		return false
+	}
+	sLength := int(signature[5+rLength])
+	if rLength+sLength+7 != len(signature) {
+		return false
+	}
+	//R and S must be positive integers with no unnecessary leading zero bytes
+	if signature[2] != 0x02 || rLength == 0 || signature[4]&0x80 != 0 {
+		return false
+	}
+	if rLength > 1 && signature[4] == 0x00 && signature[5]&0x80 == 0 {
+		return false
+	}
+	if signature[4+rLength] != 0x02 || sLength == 0 || signature[6+rLength]&0x80 != 0 {
+		return false
+	}
+	if sLength > 1 && signature[6+rLength] == 0x00 && signature[7+rLength]&0x80 == 0 {
+		return false
+	}
+	return true
+}
+
+// isDefinedHashType returns true if hashType is SIGHASH_ALL, SIGHASH_NONE or SIGHASH_SINGLE, optionally with SIGHASH_ANYONECANPAY.
+func isDefinedHashType(hashType byte) bool {
+	baseType := hashType &^ SIGHASH_ANYONECANPAY
+	return baseType >= SIGHASH_ALL && baseType <= SIGHASH_SINGLE
+}
+
+// parseScriptNumber decodes a stack item as a script number: little-endian, up to maxSize bytes (4 for arithmetic), with the top bit
+// of the last byte as the sign. If requireMinimal is set, numbers must not have unnecessary trailing zero bytes.
+func parseScriptNumber(item []byte, requireMinimal bool, maxSize int) (int64, error) {
+	if len(item) > maxSize {
+		return 0, errors.New(fmt.Sprintf("Script number of %d bytes is longer than %d bytes.", len(item), maxSize))
+	}
+	if len(item) == 0 {
+		return 0, nil
+	}
+	last := item[len(item)-1]
+	if requireMinimal && last&0x7f == 0 && (len(item) == 1 || item[len(item)-2]&0x80 == 0) {
+		return 0, errors.New("Script number is not minimally encoded.")
+	}
+	var number int64
+	for i, b := range item {
+		number |= int64(b) << uint(8*i)
+	}
+	if last&0x80 != 0 {
+		return -(number &^ (int64(0x80) << uint(8*(len(item)-1)))), nil
+	}
+	return number, nil
+}
+
+// castToBool returns the truth value of a stack item: false for any encoding of zero, including negative zero.
+func castToBool(item []byte) bool {
+	for i, b := range item {
+		if b != 0 {
+			return !(i == len(item)-1 && b == 0x80)
+		}
+	}
+	return false
+}
+
+// boolBytes returns the stack item for a boolean result: 1 for true, empty for false.
+func boolBytes(value bool) []byte {
+	if value {
+		return []byte{1}
+	}
+	return []byte{}
+}
+
+// stackResult returns true if stack is not empty and its top item is true.
+func stackResult(stack [][]byte) bool {
+	return len(stack) > 0 && castToBool(stack[len(stack)-1])
+}
+
+// copyStack returns a copy of stack, so later changes to it do not affect the original.
+func copyStack(stack [][]byte) [][]byte {
+	return append([][]byte(nil), stack...)
+}
@@ -0,0 +1,477 @@
+package btcutils
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// Signed transactions generated by go-bitcoin-multisig fund and spend, and the scriptPubKey and amount of the output each spends.
+// All were cross-checked as valid against btcsuite's txscript.
+var testVerifyInputs = []struct {
+	description  string
+	transaction  string
+	scriptPubKey string
+	amount       int64
+}{
+	{"P2PKH", "0100000001acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000008b483045022100fb244ac83b257f4233920077819dfa5203a11cd330c58a37c984699bc8048e9102200caca5b3772022a5cb5ce8e31f644da4e27e2c4f121cfd9b5291e3bccf7017d701410431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddfffffffff01400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8700000000", "76a9149203e47a16f799ded03532e3e452606fdc52007e88ac", 0},
+	{"2-of-3 P2SH multisig", "01000000013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b00200000000fd5e0100483045022100af4831eb0cee1b9642ff8691acb53c2fd8e28bbd6c0389af69c132b342405c8502200627e67cbb0bee502421be4d2b8e370d24eb17ff7c3d77d604a5c07ee5934a74014830450221009bfee48a5ae99a8fc0f78c631b0b2beb6d96337fc9d1e95c333a08bdcecb3647022040a30b3528b136821077d0bb45c324d7eedcd0ac61af7019ba1286e9277b7c54014cc9524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353aeffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000", "a9141a8b0026343166625c7475f01e48b5ede8c0252e87", 0},
+	{"2-of-3 P2WSH multisig", "010000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000ffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac0400473044022051e94657dd7654c881aa16d6f0e8b16801e5471ba46da7cc3df54b625f884270022041078fff8d287ad21d5a98018d471795658c67c2af548d0d4de6f6911beaf99101473044022033e50672858b02187fc4361ea0f4f23efeb1c0ea080722eae57dcded87bd9cea02207eb6fb5965fca629bc75efbffaa6dde804a0ec31870ddc3ef974cbff3aaca7740169522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae00000000", "002014fad15204f7a336442354d52d937820ac39eaab5ac54d55f3168a738b79d893", 65600},
+}
+
+func TestVerifyInput(t *testing.T) {
+	for _, testInput := range testVerifyInputs {
+		tx, scriptPubKey := parseTestVerifyInput(t, testInput.transaction, testInput.scriptPubKey)
+		for _, flags := range []ScriptFlags{StandardScriptFlags, ConsensusScriptFlags} {
+			if err := VerifyInput(tx, 0, scriptPubKey, testInput.amount, flags); err != nil {
+				t.Errorf("VerifyInput rejecting valid %s input with flags 0x%x: %v", testInput.description, flags, err)
+			}
+		}
+	}
+}
+
+func TestVerifyInputInvalid(t *testing.T) {
+	p2shInput, p2wshInput := testVerifyInputs[1], testVerifyInputs[2]
+	testCases := []struct {
+		description  string
+		testInput    int
+		modify       func(tx *Transaction)
+		scriptPubKey string
+		amount       int64
+	}{
+		{"output amount changed after signing", 2, func(tx *Transaction) { tx.Outputs[0].Value-- }, p2wshInput.scriptPubKey, p2wshInput.amount},
+		{"wrong input amount", 2, func(tx *Transaction) {}, p2wshInput.scriptPubKey, p2wshInput.amount + 1},
+		{"signatures out of public key order", 2, func(tx *Transaction) {
+			tx.Inputs[0].Witness[1], tx.Inputs[0].Witness[2] = tx.Inputs[0].Witness[2], tx.Inputs[0].Witness[1]
+		}, p2wshInput.scriptPubKey, p2wshInput.amount},
+		{"missing signature", 2, func(tx *Transaction) {
+			tx.Inputs[0].Witness = append(tx.Inputs[0].Witness[:2], tx.Inputs[0].Witness[3])
+		}, p2wshInput.scriptPubKey, p2wshInput.amount},
+		{"witness script of another address", 2, func(tx *Transaction) {}, "0020e381a304851e57acf999f9a134b5bb91d340fd33289e54c3a080ab5f3ebf556d", p2wshInput.amount},
+		{"scriptSig with witness spend", 2, func(tx *Transaction) { tx.Inputs[0].ScriptSig = []byte{OP_1} }, p2wshInput.scriptPubKey, p2wshInput.amount},
+		{"locktime changed after signing", 1, func(tx *Transaction) { tx.LockTime = 1 }, p2shInput.scriptPubKey, 0},
+		{"redeem script of another address", 1, func(tx *Transaction) {}, "a9144ac90c972d92febe3786185fd29fecaf97fd32cc87", 0},
+		{"non push scriptSig", 1, func(tx *Transaction) {
+			tx.Inputs[0].ScriptSig = append([]byte{OP_1, OP_DROP}, tx.Inputs[0].ScriptSig...)
+		}, p2shInput.scriptPubKey, 0},
+		{"unexpected witness", 1, func(tx *Transaction) { tx.Inputs[0].Witness = [][]byte{{}} }, p2shInput.scriptPubKey, 0},
+	}
+	for _, testCase := range testCases {
+		tx, scriptPubKey := parseTestVerifyInput(t, testVerifyInputs[testCase.testInput].transaction, testCase.scriptPubKey)
+		testCase.modify(tx)
+		if err := VerifyInput(tx, 0, scriptPubKey, testCase.amount, StandardScriptFlags); err == nil {
+			t.Error("VerifyInput accepting invalid input:", testCase.description)
+		}
+	}
+}
+
+func TestVerifyInputStandardness(t *testing.T) {
+	//Each of these is valid by consensus rules, but not standard
+	p2wshInput := testVerifyInputs[2]
+	testCases := []struct {
+		description  string
+		transaction  string
+		modify       func(tx *Transaction)
+		scriptPubKey string
+	}{
+		{"OP_CHECKMULTISIG extra item not empty", p2wshInput.transaction, func(tx *Transaction) { tx.Inputs[0].Witness[0] = []byte{1} }, p2wshInput.scriptPubKey},
+		{"high-S signature", p2wshInput.transaction, func(tx *Transaction) {
+			signature := tx.Inputs[0].Witness[1]
+			highS, _ := normalizeHighS(signature[:len(signature)-1])
+			tx.Inputs[0].Witness[1] = append(highS, signature[len(signature)-1])
+		}, p2wshInput.scriptPubKey},
+		//2-of-3 P2SH-P2WSH multisig with uncompressed public keys
+		{"uncompressed public keys in SegWit script", "010000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000023220020e381a304851e57acf999f9a134b5bb91d340fd33289e54c3a080ab5f3ebf556dffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac0400483045022100cf0f422b00367b062740dcff95af09f41f015dccb6694ecb69dd0934fd4a3e0302204c3f3bc108ee873929a94d92dd94775e0aa8ce334b79e2e1e4394593c5e0bdcd01483045022100dd7caa994f6ac7eb91673ff74c31c340d6150c245031bf93196c8be5e8644a210220700165544e788844c28bba733c97db614030d2d76e3db947b02a8533968d3bc501c9524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae00000000", func(tx *Transaction) {}, "a9144ac90c972d92febe3786185fd29fecaf97fd32cc87"},
+	}
+	for _, testCase := range testCases {
+		tx, scriptPubKey := parseTestVerifyInput(t, testCase.transaction, testCase.scriptPubKey)
+		testCase.modify(tx)
+		if err := VerifyInput(tx, 0, scriptPubKey, p2wshInput.amount, ConsensusScriptFlags); err != nil {
+			t.Errorf("VerifyInput rejecting input valid by consensus rules, %s: %v", testCase.description, err)
+		}
+		if err := VerifyInput(tx, 0, scriptPubKey, p2wshInput.amount, StandardScriptFlags); err == nil {
+			t.Error("VerifyInput accepting non-standard input:", testCase.description)
+		}
+	}
+}
+
+func TestVerifyInputCodeSeparator(t *testing.T) {
+	//<public key> OP_CODESEPARATOR OP_CHECKSIG, spent directly and as a P2WSH witness script. Signatures sign only the OP_CHECKSIG
+	//after the OP_CODESEPARATOR, so signatures of the whole script are not valid.
+	privateKey, _, err := ParseWIF("L1qH5hF9PBBTEx8fyeSDiFp2ti7eSCsj3itBFTNV7a2w2ZkJWBXK", MainNet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := NewPublicKey(privateKey, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scriptBuffer bytes.Buffer
+	WritePushData(&scriptBuffer, publicKey)
+	scriptBuffer.Write([]byte{OP_CODESEPARATOR, OP_CHECKSIG})
+	script := scriptBuffer.Bytes()
+	witnessScriptHash := sha256.Sum256(script)
+	p2wshScriptPubKey := append([]byte{OP_0, 32}, witnessScriptHash[:]...)
+	testAmount := int64(65600)
+
+	for _, witness := range []bool{false, true} {
+		for _, subscript := range [][]byte{{OP_CHECKSIG}, script} {
+			tx := NewTransaction()
+			txIn, err := NewTxIn("02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d", 0, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tx.AddInput(txIn)
+			tx.AddOutput(NewTxOut(55600, p2wshScriptPubKey))
+			var hash []byte
+			if witness {
+				hash, err = WitnessSignatureHash(tx, 0, subscript, testAmount, SIGHASH_ALL)
+			} else {
+				hash, err = SignatureHash(tx, 0, subscript, SIGHASH_ALL)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			signature, err := NewSignatureForHash(hash, privateKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signature = append(signature, SIGHASH_ALL)
+			scriptPubKey := script
+			if witness {
+				txIn.Witness = [][]byte{signature, script}
+				scriptPubKey = p2wshScriptPubKey
+			} else {
+				var scriptSig bytes.Buffer
+				WritePushData(&scriptSig, signature)
+				txIn.ScriptSig = scriptSig.Bytes()
+			}
+			err = VerifyInput(tx, 0, scriptPubKey, testAmount, StandardScriptFlags)
+			if len(subscript) == 1 && err != nil {
+				t.Errorf("VerifyInput rejecting signature of the script after OP_CODESEPARATOR (witness %v): %v", witness, err)
+			}
+			if len(subscript) != 1 && err == nil {
+				t.Errorf("VerifyInput accepting signature of the script before OP_CODESEPARATOR (witness %v).", witness)
+			}
+		}
+	}
+}
+
+func TestVerifyInputCheckLockTimeVerify(t *testing.T) {
+	//<locktime> OP_CHECKLOCKTIMEVERIFY OP_DROP <public key> OP_CHECKSIG P2WSH witness script, as in the recovery branch of a
+	//recovery redeem script, spent by transactions signed with different locktimes and sequence numbers
+	privateKey, _, err := ParseWIF("L1qH5hF9PBBTEx8fyeSDiFp2ti7eSCsj3itBFTNV7a2w2ZkJWBXK", MainNet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := NewPublicKey(privateKey, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAmount := int64(65600)
+	testCases := []struct {
+		description    string
+		scriptLockTime int64
+		txLockTime     uint32
+		sequence       uint32
+		valid          bool
+	}{
+		{"locktime reached", 700000, 700000, MaxTxInSequenceNum - 1, true},
+		{"locktime passed", 700000, 700001, MaxTxInSequenceNum - 1, true},
+		{"timestamp passed", 1700000000, 1700000001, 0, true},
+		{"locktime not reached", 700000, 699999, MaxTxInSequenceNum - 1, false},
+		{"final sequence number", 700000, 700000, MaxTxInSequenceNum, false},
+		{"timestamp for block height", 700000, 1700000000, MaxTxInSequenceNum - 1, false},
+		{"block height for timestamp", 1700000000, 700000, MaxTxInSequenceNum - 1, false},
+		{"negative locktime", -1, 700000, MaxTxInSequenceNum - 1, false},
+	}
+	for _, testCase := range testCases {
+		var scriptBuffer bytes.Buffer
+		WriteScriptNumber(&scriptBuffer, testCase.scriptLockTime)
+		scriptBuffer.Write([]byte{OP_CHECKLOCKTIMEVERIFY, OP_DROP})
+		WritePushData(&scriptBuffer, publicKey)
+		scriptBuffer.WriteByte(OP_CHECKSIG)
+		witnessScript := scriptBuffer.Bytes()
+		witnessScriptHash := sha256.Sum256(witnessScript)
+		scriptPubKey := append([]byte{OP_0, 32}, witnessScriptHash[:]...)
+
+		tx := NewTransaction()
+		txIn, err := NewTxIn("02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d", 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txIn.Sequence = testCase.sequence
+		tx.AddInput(txIn)
+		tx.AddOutput(NewTxOut(55600, scriptPubKey))
+		tx.LockTime = testCase.txLockTime
+		hash, err := WitnessSignatureHash(tx, 0, witnessScript, testAmount, SIGHASH_ALL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature, err := NewSignatureForHash(hash, privateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txIn.Witness = [][]byte{append(signature, SIGHASH_ALL), witnessScript}
+
+		err = VerifyInput(tx, 0, scriptPubKey, testAmount, StandardScriptFlags)
+		if testCase.valid && err != nil {
+			t.Errorf("VerifyInput rejecting valid OP_CHECKLOCKTIMEVERIFY spend, %s: %v", testCase.description, err)
+		}
+		if !testCase.valid && err == nil {
+			t.Error("VerifyInput accepting invalid OP_CHECKLOCKTIMEVERIFY spend:", testCase.description)
+		}
+		//Without BIP65, OP_CHECKLOCKTIMEVERIFY is OP_NOP2 and any locktime is accepted
+		if err := VerifyInput(tx, 0, scriptPubKey, testAmount, ConsensusScriptFlags&^ScriptVerifyCheckLockTimeVerify); err != nil {
+			t.Errorf("VerifyInput rejecting OP_NOP2 spend without BIP65, %s: %v", testCase.description, err)
+		}
+	}
+}
+
+func TestVerifyInputCheckSequenceVerify(t *testing.T) {
+	//<sequence> OP_CHECKSEQUENCEVERIFY OP_DROP <public key> OP_CHECKSIG P2WSH witness script, as in the delayed branch of a
+	//delayed redeem script, spent by transactions signed with different versions and input sequence numbers
+	privateKey, _, err := ParseWIF("L1qH5hF9PBBTEx8fyeSDiFp2ti7eSCsj3itBFTNV7a2w2ZkJWBXK", MainNet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := NewPublicKey(privateKey, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAmount := int64(65600)
+	testCases := []struct {
+		description    string
+		scriptSequence int64
+		txVersion      int32
+		sequence       uint32
+		valid          bool
+	}{
+		{"delay reached", 4320, 2, 4320, true},
+		{"delay passed", 4320, 2, 4321, true},
+		{"delay in time passed", SequenceLockTimeIsSeconds | 169, 2, SequenceLockTimeIsSeconds | 170, true},
+		{"only relative locktime bits compared", 4320, 2, 1<<25 | 4320, true},
+		{"relative locktime disabled in script", SequenceLockTimeDisabled | 4320, 1, MaxTxInSequenceNum, true},
+		{"delay not reached", 4320, 2, 4319, false},
+		{"version 1 transaction", 4320, 1, 4320, false},
+		{"relative locktime disabled in input", 4320, 2, SequenceLockTimeDisabled | 4320, false},
+		{"time for blocks", 4320, 2, SequenceLockTimeIsSeconds | 4320, false},
+		{"blocks for time", SequenceLockTimeIsSeconds | 169, 2, 169, false},
+		{"negative relative locktime", -1, 2, 4320, false},
+	}
+	for _, testCase := range testCases {
+		var scriptBuffer bytes.Buffer
+		WriteScriptNumber(&scriptBuffer, testCase.scriptSequence)
+		scriptBuffer.Write([]byte{OP_CHECKSEQUENCEVERIFY, OP_DROP})
+		WritePushData(&scriptBuffer, publicKey)
+		scriptBuffer.WriteByte(OP_CHECKSIG)
+		witnessScript := scriptBuffer.Bytes()
+		witnessScriptHash := sha256.Sum256(witnessScript)
+		scriptPubKey := append([]byte{OP_0, 32}, witnessScriptHash[:]...)
+
+		tx := NewTransaction()
+		tx.Version = testCase.txVersion
+		txIn, err := NewTxIn("02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d", 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txIn.Sequence = testCase.sequence
+		tx.AddInput(txIn)
+		tx.AddOutput(NewTxOut(55600, scriptPubKey))
+		hash, err := WitnessSignatureHash(tx, 0, witnessScript, testAmount, SIGHASH_ALL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature, err := NewSignatureForHash(hash, privateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txIn.Witness = [][]byte{append(signature, SIGHASH_ALL), witnessScript}
+
+		err = VerifyInput(tx, 0, scriptPubKey, testAmount, StandardScriptFlags)
+		if testCase.valid && err != nil {
+			t.Errorf("VerifyInput rejecting valid OP_CHECKSEQUENCEVERIFY spend, %s: %v", testCase.description, err)
+		}
+		if !testCase.valid && err == nil {
+			t.Error("VerifyInput accepting invalid OP_CHECKSEQUENCEVERIFY spend:", testCase.description)
+		}
+		//Without BIP112, OP_CHECKSEQUENCEVERIFY is OP_NOP3 and any sequence number is accepted
+		if err := VerifyInput(tx, 0, scriptPubKey, testAmount, ConsensusScriptFlags&^ScriptVerifyCheckSequenceVerify); err != nil {
+			t.Errorf("VerifyInput rejecting OP_NOP3 spend without BIP112, %s: %v", testCase.description, err)
+		}
+	}
+}
+
+func TestVerifyTransaction(t *testing.T) {
+	p2wshInput := testVerifyInputs[2]
+	tx, scriptPubKey := parseTestVerifyInput(t, p2wshInput.transaction, p2wshInput.scriptPubKey)
+	if err := VerifyTransaction(tx, []*TxOut{NewTxOut(p2wshInput.amount, scriptPubKey)}, StandardScriptFlags); err != nil {
+		t.Error(err)
+	}
+	if err := VerifyTransaction(tx, nil, StandardScriptFlags); err == nil {
+		t.Error("VerifyTransaction accepting missing previous outputs.")
+	}
+}
+
+func TestVerifyInputScriptTests(t *testing.T) {
+	//Vectors from Bitcoin Core's script_tests.json using only the operations VerifyInput supports, with Bitcoin Core's
+	//comments as descriptions. Scripts are in the ASM of AssembleScript, and the witness in hex.
+	testCases := []struct {
+		description  string
+		scriptSig    string
+		scriptPubKey string
+		witness      []string
+		amount       int64
+		flags        ScriptFlags
+		valid        bool
+	}{
+		{"nOpCount is incremented by the number of keys evaluated in addition to the usual one op per op. In this case we have zero keys, so we can execute 201 CHECKMULTISIGS", "", "OP_0 " + strings.Repeat("OP_0 OP_0 OP_CHECKMULTISIG ", 201), nil, 0, ScriptVerifyP2SH | ScriptVerifyStrictEncoding, true},
+		{"Even though there are no signatures being checked nOpCount is incremented by the number of keys.", "", strings.Repeat("OP_NOP ", 12) + strings.Repeat("OP_0 OP_0 61 62 63 64 65 66 67 68 69 6a 6b 6c 6d 6e 6f 70 71 72 73 74 14 OP_CHECKMULTISIG ", 9), nil, 0, ScriptVerifyP2SH | ScriptVerifyStrictEncoding, true},
+		{"202 CHECKMULTISIGS, fails due to 201 op limit", "", "OP_0 " + strings.Repeat("OP_0 OP_0 OP_CHECKMULTISIG ", 202), nil, 0, ScriptVerifyP2SH | ScriptVerifyStrictEncoding, false},
+		{"Fails due to 201 script operation limit", "", strings.Repeat("OP_NOP ", 13) + strings.Repeat("OP_0 OP_0 61 62 63 64 65 66 67 68 69 6a 6b 6c 6d 6e 6f 70 71 72 73 74 14 OP_CHECKMULTISIG ", 9), nil, 0, ScriptVerifyP2SH | ScriptVerifyStrictEncoding, false},
+		{"VERIF illegal everywhere", "OP_0", "OP_IF OP_VERIF OP_ELSE OP_1 OP_ENDIF", nil, 0, ScriptVerifyP2SH | ScriptVerifyStrictEncoding, false},
+		{"CAT disabled", "61 62", "OP_CAT", nil, 0, ScriptVerifyP2SH | ScriptVerifyStrictEncoding, false},
+		{"CHECKMULTISIG must error when there are no stack items", "", "OP_CHECKMULTISIG OP_NOT", nil, 0, ScriptVerifyStrictEncoding, false},
+		{"CHECKMULTISIG must error when the specified number of pubkeys is negative", "", "OP_1NEGATE OP_CHECKMULTISIG OP_NOT", nil, 0, ScriptVerifyStrictEncoding, false},
+		{"CHECKMULTISIG must error when there are not enough pubkeys on the stack", "", "OP_1 OP_CHECKMULTISIG OP_NOT", nil, 0, ScriptVerifyStrictEncoding, false},
+		{"CHECKMULTISIG must error when the specified number of signatures is negative", "", "OP_1NEGATE OP_0 OP_CHECKMULTISIG OP_NOT", nil, 0, ScriptVerifyStrictEncoding, false},
+		{"CHECKMULTISIG must error when there are not enough signatures on the stack", "", "OP_1 706b31 OP_1 OP_CHECKMULTISIG OP_NOT", nil, 0, ScriptVerifyStrictEncoding, false},
+		{"CHECKMULTISIG must push false to stack when signature is invalid when NOT in strict enc mode", "", "64756d6d79 73696731 OP_1 706b31 OP_1 OP_CHECKMULTISIG OP_IF OP_1 OP_ENDIF", nil, 0, 0, false},
+		{"nPubKeys > 20", "OP_0 OP_0 OP_1 OP_2 OP_3 OP_4 OP_5 OP_6 OP_7 OP_8 OP_9 OP_10 OP_11 OP_12 OP_13 OP_14 OP_15 OP_16 11 12 13 14 15", "15 OP_CHECKMULTISIG OP_1", nil, 0, ScriptVerifyP2SH | ScriptVerifyStrictEncoding, false},
+		{"nSigs > nPubKeys", "OP_0 736967 OP_1 OP_0", "OP_CHECKMULTISIG OP_1", nil, 0, ScriptVerifyP2SH | ScriptVerifyStrictEncoding, false},
+		{"2-of-2 CHECKMULTISIG NOT with the first pubkey invalid, and both signatures validly encoded.", "OP_0 3044022044dc17b0887c161bb67ba9635bf758735bdde503e4b0a0987f587f14a4e1143d022009a215772d49a85dae40d8ca03955af26ad3978a0ff965faa12915e9586249a501 3044022044dc17b0887c161bb67ba9635bf758735bdde503e4b0a0987f587f14a4e1143d022009a215772d49a85dae40d8ca03955af26ad3978a0ff965faa12915e9586249a501", "OP_2 02865c40293a680cb9c020e7b1e106d8c1916d3cef99aa431a56d253e69256dac0 OP_0 OP_2 OP_CHECKMULTISIG OP_NOT", nil, 0, ScriptVerifyStrictEncoding, false},
+		{"2-of-2 CHECKMULTISIG NOT with both pubkeys valid, but first signature invalid.", "OP_0 3044022044dc17b0887c161bb67ba9635bf758735bdde503e4b0a0987f587f14a4e1143d022009a215772d49a85dae40d8ca03955af26ad3978a0ff965faa12915e9586249a501 OP_1", "OP_2 02865c40293a680cb9c020e7b1e106d8c1916d3cef99aa431a56d253e69256dac0 02865c40293a680cb9c020e7b1e106d8c1916d3cef99aa431a56d253e69256dac0 OP_2 OP_CHECKMULTISIG OP_NOT", nil, 0, ScriptVerifyStrictEncoding, false},
+		{"2-of-3 with one valid and one invalid signature due to parse error, nSigs > validSigs", "OP_0 304402205451ce65ad844dbb978b8bdedf5082e33b43cae8279c30f2c74d9e9ee49a94f802203fe95a7ccf74da7a232ee523ef4a53cb4d14bdd16289680cdb97a63819b8f42f01 304402205451ce65ad844dbb978b8bdedf5082e33b43cae8279c30f2c74d9e9ee49a94f802203fe95a7ccf74da7a232ee523ef4a53cb4d14bdd16289680cdb97a63819b8f42f", "OP_2 02a673638cb9587cb68ea08dbef685c6f2d2a751a8b3c6f2a7e9a4999e6e4bfaf5 02a673638cb9587cb68ea08dbef685c6f2d2a751a8b3c6f2a7e9a4999e6e4bfaf5 02a673638cb9587cb68ea08dbef685c6f2d2a751a8b3c6f2a7e9a4999e6e4bfaf5 OP_3 OP_CHECKMULTISIG", nil, 0, ScriptVerifyP2SH | ScriptVerifyStrictEncoding, false},
+		{"3-of-3", "OP_0 3044022051254b9fb476a52d85530792b578f86fea70ec1ffb4393e661bcccb23d8d63d3022076505f94a403c86097841944e044c70c2045ce90e36de51f7e9d3828db98a07501 304402200a358f750934b3feb822f1966bfcd8bbec9eeaa3a8ca941e11ee5960e181fa01022050bf6b5a8e7750f70354ae041cb68a7bade67ec6c3ab19eb359638974410626e01 304402200955d031fff71d8653221e85e36c3c85533d2312fc3045314b19650b7ae2f81002202a6bb8505e36201909d0921f01abff390ae6b7ff97bbf959f98aedeb0a56730901", "OP_3 0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798 038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f51508 03363d90d447b00c9c99ceac05b6262ee053441c7e55552ffe526bad8f83ff4640 OP_3 OP_CHECKMULTISIG", nil, 0, 0, true},
+		{"3-of-3, 2 sigs", "OP_0 3044022051254b9fb476a52d85530792b578f86fea70ec1ffb4393e661bcccb23d8d63d3022076505f94a403c86097841944e044c70c2045ce90e36de51f7e9d3828db98a07501 304402200a358f750934b3feb822f1966bfcd8bbec9eeaa3a8ca941e11ee5960e181fa01022050bf6b5a8e7750f70354ae041cb68a7bade67ec6c3ab19eb359638974410626e01 OP_0", "OP_3 0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798 038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f51508 03363d90d447b00c9c99ceac05b6262ee053441c7e55552ffe526bad8f83ff4640 OP_3 OP_CHECKMULTISIG", nil, 0, 0, false},
+		{"P2SH(2-of-3)", "OP_0 304402205b7d2c2f177ae76cfbbf14d589c113b0b35db753d305d5562dd0b61cbf366cfb02202e56f93c4f08a27f986cd424ffc48a462c3202c4902104d4d0ff98ed28f4bf8001 30440220563e5b3b1fc11662a84bc5ea2a32cc3819703254060ba30d639a1aaf2d5068ad0220601c1f47ddc76d93284dd9ed68f7c9974c4a0ea7cbe8a247d6bc3878567a5fca01 52210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f8179821038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f515082103363d90d447b00c9c99ceac05b6262ee053441c7e55552ffe526bad8f83ff464053ae", "OP_HASH160 c9e4a896d149702d0d1695434feddd52e24ad78d OP_EQUAL", nil, 0, ScriptVerifyP2SH, true},
+		{"P2SH(2-of-3), 1 sig", "OP_0 304402205b7d2c2f177ae76cfbbf14d589c113b0b35db753d305d5562dd0b61cbf366cfb02202e56f93c4f08a27f986cd424ffc48a462c3202c4902104d4d0ff98ed28f4bf8001 OP_0 52210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f8179821038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f515082103363d90d447b00c9c99ceac05b6262ee053441c7e55552ffe526bad8f83ff464053ae", "OP_HASH160 c9e4a896d149702d0d1695434feddd52e24ad78d OP_EQUAL", nil, 0, ScriptVerifyP2SH, false},
+		{"1-of-2 with the second 1 hybrid pubkey", "OP_0 304402202e79441ad1baf5a07fb86bae3753184f6717d9692680947ea8b6e8b777c69af1022079a262e13d868bb5a0964fefe3ba26942e1b0669af1afb55ef3344bc9d4fc4c401", "OP_1 0679be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8 038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f51508 OP_2 OP_CHECKMULTISIG", nil, 0, ScriptVerifyStrictEncoding, true},
+		{"1-of-2 with the first 1 hybrid pubkey", "OP_0 3044022079c7824d6c868e0e1a273484e28c2654a27d043c8a27f49f52cb72efed0759090220452bbbf7089574fa082095a4fc1b3a16bafcf97a3a34d745fafc922cce66b27201", "OP_1 038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f51508 0679be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8 OP_2 OP_CHECKMULTISIG", nil, 0, ScriptVerifyStrictEncoding, false},
+		{"3-of-3 with nonzero dummy but no NULLDUMMY", "OP_1 3044022051254b9fb476a52d85530792b578f86fea70ec1ffb4393e661bcccb23d8d63d3022076505f94a403c86097841944e044c70c2045ce90e36de51f7e9d3828db98a07501 304402200a358f750934b3feb822f1966bfcd8bbec9eeaa3a8ca941e11ee5960e181fa01022050bf6b5a8e7750f70354ae041cb68a7bade67ec6c3ab19eb359638974410626e01 304402200955d031fff71d8653221e85e36c3c85533d2312fc3045314b19650b7ae2f81002202a6bb8505e36201909d0921f01abff390ae6b7ff97bbf959f98aedeb0a56730901", "OP_3 0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798 038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f51508 03363d90d447b00c9c99ceac05b6262ee053441c7e55552ffe526bad8f83ff4640 OP_3 OP_CHECKMULTISIG", nil, 0, 0, true},
+		{"3-of-3 with nonzero dummy", "OP_1 3044022051254b9fb476a52d85530792b578f86fea70ec1ffb4393e661bcccb23d8d63d3022076505f94a403c86097841944e044c70c2045ce90e36de51f7e9d3828db98a07501 304402200a358f750934b3feb822f1966bfcd8bbec9eeaa3a8ca941e11ee5960e181fa01022050bf6b5a8e7750f70354ae041cb68a7bade67ec6c3ab19eb359638974410626e01 304402200955d031fff71d8653221e85e36c3c85533d2312fc3045314b19650b7ae2f81002202a6bb8505e36201909d0921f01abff390ae6b7ff97bbf959f98aedeb0a56730901", "OP_3 0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798 038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f51508 03363d90d447b00c9c99ceac05b6262ee053441c7e55552ffe526bad8f83ff4640 OP_3 OP_CHECKMULTISIG", nil, 0, ScriptVerifyNullDummy, false},
+		{"3-of-3 NOT with invalid sig with nonzero dummy", "OP_1 304402201bb2edab700a5d020236df174fefed78087697143731f659bea59642c759c16d022061f42cdbae5bcd3e8790f20bf76687443436e94a634321c16a72aa54cbc7c2ea01 304402204bb4a64f2a6e5c7fb2f07fef85ee56fde5e6da234c6a984262307a20e99842d702206f8303aaba5e625d223897e2ffd3f88ef1bcffef55f38dc3768e5f2e94c923f901 3044022040c2809b71fffb155ec8b82fe7a27f666bd97f941207be4e14ade85a1249dd4d02204d56c85ec525dd18e29a0533d5ddf61b6b1bb32980c2f63edf951aebf7a27bfe01", "OP_3 0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798 038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f51508 03363d90d447b00c9c99ceac05b6262ee053441c7e55552ffe526bad8f83ff4640 OP_3 OP_CHECKMULTISIG OP_NOT", nil, 0, ScriptVerifyNullDummy, false},
+		{"2-of-2 with two identical keys and sigs pushed using OP_DUP but no SIGPUSHONLY", "OP_0 304402200abeb4bd07f84222f474aed558cfbdfc0b4e96cde3c2935ba7098b1ff0bd74c302204a04c1ca67b2a20abee210cf9a21023edccbbf8024b988812634233115c6b73901 OP_DUP", "OP_2 038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f51508 038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f51508 OP_2 OP_CHECKMULTISIG", nil, 0, 0, true},
+		{"2-of-2 with two identical keys and sigs pushed using OP_DUP", "OP_0 304402200abeb4bd07f84222f474aed558cfbdfc0b4e96cde3c2935ba7098b1ff0bd74c302204a04c1ca67b2a20abee210cf9a21023edccbbf8024b988812634233115c6b73901 OP_DUP", "OP_2 038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f51508 038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f51508 OP_2 OP_CHECKMULTISIG", nil, 0, ScriptVerifySigPushOnly, false},
+		{"P2WSH CHECKMULTISIG with compressed keys", "", "OP_0 06c24420938f0fa3c1cb2707d867154220dca365cdbfa0dd2a83854730221460", []string{"", "304402207eb8a59b5c65fc3f6aeef77066556ed5c541948a53a3ba7f7c375b8eed76ee7502201e036a7a9a98ff919ff94dc905d67a1ec006f79ef7cff0708485c8bb79dce38e01", "5121038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f51508210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f8179852ae"}, 1, ScriptVerifyP2SH | ScriptVerifyWitness | ScriptVerifyWitnessPubKeyType, true},
+		{"P2SH(P2WSH) CHECKMULTISIG with compressed keys", "002006c24420938f0fa3c1cb2707d867154220dca365cdbfa0dd2a83854730221460", "OP_HASH160 26282aad7c29369d15fed062a778b6100d31a340 OP_EQUAL", []string{"", "3044022033706aed33b8155d5486df3b9bca8cdd3bd4bdb5436dce46d72cdaba51d22b4002203626e94fe53a178af46624f17315c6931f20a30b103f5e044e1eda0c3fe185c601", "5121038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f51508210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f8179852ae"}, 1, ScriptVerifyP2SH | ScriptVerifyWitness | ScriptVerifyWitnessPubKeyType, true},
+		{"P2WSH CHECKMULTISIG with first key uncompressed and signing with the first key", "", "OP_0 08a6665ebfd43b02323423e764e185d98d1587f903b81507dbb69bfc41005efa", []string{"", "304402202d092ededd1f060609dbf8cb76950634ff42b3e62cf4adb69ab92397b07d742302204ff886f8d0817491a96d1daccdcc820f6feb122ee6230143303100db37dfa79f01", "5121038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f51508410479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b852ae"}, 1, ScriptVerifyP2SH | ScriptVerifyWitness, true},
+		{"P2SH(P2WSH) CHECKMULTISIG first key uncompressed and signing with the first key", "002008a6665ebfd43b02323423e764e185d98d1587f903b81507dbb69bfc41005efa", "OP_HASH160 6f5ecd4b83b77f3c438f5214eff96454934fc5d1 OP_EQUAL", []string{"", "304402202dd7e91243f2235481ffb626c3b7baf2c859ae3a5a77fb750ef97b99a8125dc002204960de3d3c3ab9496e218ec57e5240e0e10a6f9546316fe240c216d45116d29301", "5121038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f51508410479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b852ae"}, 1, ScriptVerifyP2SH | ScriptVerifyWitness, true},
+		{"P2WSH CHECKMULTISIG with first key uncompressed and signing with the first key", "", "OP_0 08a6665ebfd43b02323423e764e185d98d1587f903b81507dbb69bfc41005efa", []string{"", "304402202d092ededd1f060609dbf8cb76950634ff42b3e62cf4adb69ab92397b07d742302204ff886f8d0817491a96d1daccdcc820f6feb122ee6230143303100db37dfa79f01", "5121038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f51508410479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b852ae"}, 1, ScriptVerifyP2SH | ScriptVerifyWitness | ScriptVerifyWitnessPubKeyType, false},
+		{"P2SH(P2WSH) CHECKMULTISIG with first key uncompressed and signing with the first key", "002008a6665ebfd43b02323423e764e185d98d1587f903b81507dbb69bfc41005efa", "OP_HASH160 6f5ecd4b83b77f3c438f5214eff96454934fc5d1 OP_EQUAL", []string{"", "304402202dd7e91243f2235481ffb626c3b7baf2c859ae3a5a77fb750ef97b99a8125dc002204960de3d3c3ab9496e218ec57e5240e0e10a6f9546316fe240c216d45116d29301", "5121038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f51508410479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b852ae"}, 1, ScriptVerifyP2SH | ScriptVerifyWitness | ScriptVerifyWitnessPubKeyType, false},
+		{"P2WSH CHECKMULTISIG with first key uncompressed and signing with the second key", "", "OP_0 08a6665ebfd43b02323423e764e185d98d1587f903b81507dbb69bfc41005efa", []string{"", "304402201e9e6f7deef5b2f21d8223c5189b7d5e82d237c10e97165dd08f547c4e5ce6ed02206796372eb1cc6acb52e13ee2d7f45807780bf96b132cb6697f69434be74b1af901", "5121038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f51508410479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b852ae"}, 1, ScriptVerifyP2SH | ScriptVerifyWitness, true},
+		{"P2WSH CHECKMULTISIG with first key uncompressed and signing with the second key", "", "OP_0 08a6665ebfd43b02323423e764e185d98d1587f903b81507dbb69bfc41005efa", []string{"", "304402201e9e6f7deef5b2f21d8223c5189b7d5e82d237c10e97165dd08f547c4e5ce6ed02206796372eb1cc6acb52e13ee2d7f45807780bf96b132cb6697f69434be74b1af901", "5121038282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f51508410479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b852ae"}, 1, ScriptVerifyP2SH | ScriptVerifyWitness | ScriptVerifyWitnessPubKeyType, false},
+		{"P2WSH CHECKMULTISIG with second key uncompressed and signing with the first key", "", "OP_0 230828ed48871f0f362ce9432aa52f620f442cc8d9ce7a8b5e798365595a38bb", []string{"", "3044022046f5367a261fd8f8d7de6eb390491344f8ec2501638fb9a1095a0599a21d3f4c02205c1b3b51d20091c5f1020841bbca87b44ebe25405c64e4acf758f2eae8665f8401", "5141048282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f5150811f8a8098557dfe45e8256e830b60ace62d613ac2f7b17bed31b6eaff6e26caf210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f8179852ae"}, 1, ScriptVerifyP2SH | ScriptVerifyWitness, true},
+		{"P2WSH CHECKMULTISIG with second key uncompressed and signing with the first key should pass as the uncompressed key is not used", "", "OP_0 230828ed48871f0f362ce9432aa52f620f442cc8d9ce7a8b5e798365595a38bb", []string{"", "3044022046f5367a261fd8f8d7de6eb390491344f8ec2501638fb9a1095a0599a21d3f4c02205c1b3b51d20091c5f1020841bbca87b44ebe25405c64e4acf758f2eae8665f8401", "5141048282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f5150811f8a8098557dfe45e8256e830b60ace62d613ac2f7b17bed31b6eaff6e26caf210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f8179852ae"}, 1, ScriptVerifyP2SH | ScriptVerifyWitness | ScriptVerifyWitnessPubKeyType, true},
+		{"P2SH(P2WSH) CHECKMULTISIG with second key uncompressed and signing with the first key should pass as the uncompressed key is not used", "0020230828ed48871f0f362ce9432aa52f620f442cc8d9ce7a8b5e798365595a38bb", "OP_HASH160 3478e7019ce61a68148f87549579b704cbe4c393 OP_EQUAL", []string{"", "3044022053e210e4fb1881e6092fd75c3efc5163105599e246ded661c0ee2b5682cc2d6c02203a26b7ada8682a095b84c6d1b881637000b47d761fc837c4cee33555296d63f101", "5141048282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f5150811f8a8098557dfe45e8256e830b60ace62d613ac2f7b17bed31b6eaff6e26caf210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f8179852ae"}, 1, ScriptVerifyP2SH | ScriptVerifyWitness | ScriptVerifyWitnessPubKeyType, true},
+		{"P2WSH CHECKMULTISIG with second key uncompressed and signing with the second key", "", "OP_0 230828ed48871f0f362ce9432aa52f620f442cc8d9ce7a8b5e798365595a38bb", []string{"", "304402206c6d9f5daf85b54af2a93ec38b15ab27f205dbf5c735365ff12451e43613d1f40220736a44be63423ed5ebf53491618b7cc3d8a5093861908da853739c73717938b701", "5141048282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f5150811f8a8098557dfe45e8256e830b60ace62d613ac2f7b17bed31b6eaff6e26caf210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f8179852ae"}, 1, ScriptVerifyP2SH | ScriptVerifyWitness, true},
+		{"P2WSH CHECKMULTISIG with second key uncompressed and signing with the second key", "", "OP_0 230828ed48871f0f362ce9432aa52f620f442cc8d9ce7a8b5e798365595a38bb", []string{"", "304402206c6d9f5daf85b54af2a93ec38b15ab27f205dbf5c735365ff12451e43613d1f40220736a44be63423ed5ebf53491618b7cc3d8a5093861908da853739c73717938b701", "5141048282263212c609d9ea2a6e3e172de238d8c39cabd5ac1ca10646e23fd5f5150811f8a8098557dfe45e8256e830b60ace62d613ac2f7b17bed31b6eaff6e26caf210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f8179852ae"}, 1, ScriptVerifyP2SH | ScriptVerifyWitness | ScriptVerifyWitnessPubKeyType, false},
+	}
+	for _, testCase := range testCases {
+		scriptSig, err := AssembleScript(testCase.scriptSig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scriptPubKey, err := AssembleScript(testCase.scriptPubKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var witness [][]byte
+		for _, itemHex := range testCase.witness {
+			item, _ := hex.DecodeString(itemHex)
+			witness = append(witness, item)
+		}
+		tx := newTestSpendingTransaction(scriptSig, witness, scriptPubKey, testCase.amount)
+		err = VerifyInput(tx, 0, scriptPubKey, testCase.amount, testCase.flags)
+		if testCase.valid && err != nil {
+			t.Errorf("VerifyInput rejecting valid input with flags 0x%x, %s: %v", testCase.flags, testCase.description, err)
+		}
+		if !testCase.valid && err == nil {
+			t.Errorf("VerifyInput accepting invalid input with flags 0x%x, %s", testCase.flags, testCase.description)
+		}
+	}
+}
+
+func TestParseScriptNumber(t *testing.T) {
+	testCases := []struct {
+		item   string
+		number int64
+	}{
+		{"", 0},
+		{"01", 1},
+		{"10", 16},
+		{"81", -1},
+		{"ff00", 255},
+		{"ff80", -255},
+		{"ffffff7f", 2147483647},
+		{"ffffffff00", 4294967295}, //5 bytes, as for OP_CHECKLOCKTIMEVERIFY locktimes
+	}
+	for _, testCase := range testCases {
+		item, _ := hex.DecodeString(testCase.item)
+		number, err := parseScriptNumber(item, true, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if number != testCase.number {
+			testutils.CompareError(t, "Script number different from expected number.", testCase.number, number)
+		}
+		//Encoding the number gives back the same minimal item
+		encodedHex := hex.EncodeToString(scriptNumberBytes(number))
+		if encodedHex != testCase.item {
+			testutils.CompareError(t, "Encoded script number different from expected item.", testCase.item, encodedHex)
+		}
+	}
+	invalidItems := []string{
+		"00",         //zero with trailing zero byte
+		"0100",       //one with trailing zero byte
+		"0000000001", //longer than 4 bytes
+	}
+	for _, invalidItem := range invalidItems {
+		item, _ := hex.DecodeString(invalidItem)
+		if _, err := parseScriptNumber(item, true, 4); err == nil {
+			t.Error("parseScriptNumber accepting invalid script number:", invalidItem)
+		}
+	}
+}
+
+// parseTestVerifyInput decodes a test transaction and scriptPubKey from hex.
+func parseTestVerifyInput(t *testing.T, transactionHex string, scriptPubKeyHex string) (*Transaction, []byte) {
+	rawTransaction, _ := hex.DecodeString(transactionHex)
+	tx, err := ParseTransaction(rawTransaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scriptPubKey, _ := hex.DecodeString(scriptPubKeyHex)
+	return tx, scriptPubKey
+}
+
+// newTestSpendingTransaction returns a transaction spending with scriptSig and witness the only output of a transaction
+// paying amount to scriptPubKey, as Bitcoin Core's script tests build them.
+func newTestSpendingTransaction(scriptSig []byte, witness [][]byte, scriptPubKey []byte, amount int64) *Transaction {
+	creditingTx := NewTransaction()
+	creditingTx.AddInput(&TxIn{PreviousTxHash: make([]byte, 32), PreviousOutputIndex: 0xffffffff, ScriptSig: []byte{OP_0, OP_0}, Sequence: MaxTxInSequenceNum})
+	creditingTx.AddOutput(NewTxOut(amount, scriptPubKey))
+	tx := NewTransaction()
+	txIn, _ := NewTxIn(creditingTx.TxHash(), 0, scriptSig)
+	txIn.Witness = witness
+	tx.AddInput(txIn)
+	tx.AddOutput(NewTxOut(amount, nil))
+	return tx
+}
+
+// normalizeHighS is the opposite of normalizeLowS, replacing S with N-S if S is below N/2.
+func normalizeHighS(signature []byte) ([]byte, error) {
+	r, s, err := parseDERSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+	if s.Cmp(secp256k1HalfOrder) > 0 {
+		return signature, nil
+	}
+	return encodeDERSignature(r, new(big.Int).Sub(secp256k1Order, s)), nil
+}
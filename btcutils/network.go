@@ -0,0 +1,119 @@
+// Provides the parameters that make addresses, WIF private keys and extended keys specific to one Bitcoin network.
+// See https://github.com/bitcoin/bitcoin/blob/master/src/kernel/chainparams.cpp for the values used by Bitcoin Core.
+package btcutils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/prettymuchbryce/hellobitcoin/base58check"
+)
+
+// Network holds the version bytes and prefixes of one Bitcoin network.
+// Version bytes are in hex, as taken by base58check.Encode.
+type Network struct {
+	Name             string
+	PubKeyHashPrefix string //Version byte of P2PKH addresses
+	ScriptHashPrefix string //Version byte of P2SH addresses
+	WIFPrefix        string //Version byte of WIF private keys
+	Bech32HRP        string //Human-readable part of native SegWit addresses
+	XprvVersion      string //Version bytes of BIP32 extended private keys
+	XpubVersion      string //Version bytes of BIP32 extended public keys
+	CoinType         uint32 //BIP44 coin type in HD derivation paths, 0 for mainnet and 1 for every test network
+}
+
+// MainNet is the main Bitcoin network. Addresses start with '1', '3' or 'bc1', and extended keys with 'xprv' or 'xpub'.
+var MainNet = &Network{
+	Name:             "mainnet",
+	PubKeyHashPrefix: "00",
+	ScriptHashPrefix: "05",
+	WIFPrefix:        "80",
+	Bech32HRP:        "bc",
+	XprvVersion:      "0488ade4",
+	XpubVersion:      "0488b21e",
+	CoinType:         0,
+}
+
+// TestNet3 is the public test network. Addresses start with 'm', 'n', '2' or 'tb1', and extended keys with 'tprv' or 'tpub'.
+var TestNet3 = &Network{
+	Name:             "testnet3",
+	PubKeyHashPrefix: "6f",
+	ScriptHashPrefix: "c4",
+	WIFPrefix:        "ef",
+	Bech32HRP:        "tb",
+	XprvVersion:      "04358394",
+	XpubVersion:      "043587cf",
+	CoinType:         1,
+}
+
+// SigNet is the signed test network (BIP325), which shares testnet3 prefixes.
+var SigNet = &Network{
+	Name:             "signet",
+	PubKeyHashPrefix: "6f",
+	ScriptHashPrefix: "c4",
+	WIFPrefix:        "ef",
+	Bech32HRP:        "tb",
+	XprvVersion:      "04358394",
+	XpubVersion:      "043587cf",
+	CoinType:         1,
+}
+
+// RegTest is the local regression test network. It shares testnet3 prefixes except for SegWit addresses, which start with 'bcrt1'.
+var RegTest = &Network{
+	Name:             "regtest",
+	PubKeyHashPrefix: "6f",
+	ScriptHashPrefix: "c4",
+	WIFPrefix:        "ef",
+	Bech32HRP:        "bcrt",
+	XprvVersion:      "04358394",
+	XpubVersion:      "043587cf",
+	CoinType:         1,
+}
+
+// Networks lists every supported network.
+var Networks = []*Network{MainNet, TestNet3, SigNet, RegTest}
+
+// ParseNetwork returns the network with the given name: mainnet, testnet3 (or testnet), signet or regtest.
+func ParseNetwork(name string) (*Network, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "testnet" {
+		name = TestNet3.Name
+	}
+	for _, network := range Networks {
+		if network.Name == name {
+			return network, nil
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("Unknown network '%s'. Should be mainnet, testnet3, signet or regtest.", name))
+}
+
+// otherNetworkNames lists the names of networks other than network for which matches returns true, eg. "testnet3, signet or regtest".
+// Returns an empty string if there are none, so callers can tell a key or address of the wrong network from an invalid one.
+func otherNetworkNames(network *Network, matches func(*Network) bool) string {
+	var names []string
+	for _, other := range Networks {
+		if other != network && matches(other) {
+			names = append(names, other.Name)
+		}
+	}
+	if len(names) <= 1 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// NewP2PKHAddress creates the P2PKH address on network for a public key hash.
+func NewP2PKHAddress(publicKeyHash []byte, network *Network) string {
+	return base58check.Encode(network.PubKeyHashPrefix, publicKeyHash)
+}
+
+// NewP2SHAddress creates the P2SH address on network for a redeem script hash.
+func NewP2SHAddress(redeemScriptHash []byte, network *Network) string {
+	return base58check.Encode(network.ScriptHashPrefix, redeemScriptHash)
+}
+
+// NewP2WSHAddress creates the native SegWit P2WSH address on network for a witness script hash (SHA256 of the witness script).
+func NewP2WSHAddress(witnessScriptHash []byte, network *Network) (string, error) {
+	return EncodeSegWitAddress(network.Bech32HRP, 0, witnessScriptHash)
+}
@@ -0,0 +1,100 @@
+package btcutils
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+func TestParseNetwork(t *testing.T) {
+	testNetworks := map[string]*Network{
+		"mainnet":   MainNet,
+		"testnet3":  TestNet3,
+		"testnet":   TestNet3,
+		" Signet ":  SigNet,
+		"regtest":   RegTest,
+		"REGTEST\n": RegTest,
+	}
+	for name, testNetwork := range testNetworks {
+		network, err := ParseNetwork(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if network != testNetwork {
+			t.Errorf("Network '%s' parsed as %s, expected %s.", name, network.Name, testNetwork.Name)
+		}
+	}
+	if _, err := ParseNetwork("litecoin"); err == nil {
+		t.Error("ParseNetwork accepting unknown network.")
+	}
+}
+
+func TestNetworkAddresses(t *testing.T) {
+	testHashHex := "010966776006953d5567439e5e39f86a0d273bee"
+	testWitnessScriptHashHex := "1863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"
+	testAddresses := []struct {
+		network      *Network
+		p2pkhAddress string
+		p2shAddress  string
+		p2wshAddress string
+	}{
+		{MainNet, "16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvM", "31nVrspaydBz8aMpxH9WkS2DuhgqS1fCuG", "bc1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qccfmv3"},
+		{TestNet3, "mfcSEPR8EkJrpX91YkTJ9iscdAzppJrG9j", "2MsLhvckcb5hLLMzNdQmPNP1V83u1HVdeEb", "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7"},
+		{SigNet, "mfcSEPR8EkJrpX91YkTJ9iscdAzppJrG9j", "2MsLhvckcb5hLLMzNdQmPNP1V83u1HVdeEb", "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7"},
+		{RegTest, "mfcSEPR8EkJrpX91YkTJ9iscdAzppJrG9j", "2MsLhvckcb5hLLMzNdQmPNP1V83u1HVdeEb", "bcrt1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qzf4jry"},
+	}
+
+	hash, _ := hex.DecodeString(testHashHex)
+	witnessScriptHash, _ := hex.DecodeString(testWitnessScriptHashHex)
+	for _, testAddress := range testAddresses {
+		network := testAddress.network
+		if address := NewP2PKHAddress(hash, network); address != testAddress.p2pkhAddress {
+			testutils.CompareError(t, "P2PKH address on "+network.Name+" different from expected address.", testAddress.p2pkhAddress, address)
+		}
+		if address := NewP2SHAddress(hash, network); address != testAddress.p2shAddress {
+			testutils.CompareError(t, "P2SH address on "+network.Name+" different from expected address.", testAddress.p2shAddress, address)
+		}
+		address, err := NewP2WSHAddress(witnessScriptHash, network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if address != testAddress.p2wshAddress {
+			testutils.CompareError(t, "P2WSH address on "+network.Name+" different from expected address.", testAddress.p2wshAddress, address)
+		}
+
+		decodedHash, err := DecodeP2PKHAddress(testAddress.p2pkhAddress, network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(decodedHash) != testHashHex {
+			testutils.CompareError(t, "Hash decoded from P2PKH address different from expected hash.", testHashHex, hex.EncodeToString(decodedHash))
+		}
+		decodedHash, err = DecodeP2SHAddress(testAddress.p2shAddress, network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(decodedHash) != testHashHex {
+			testutils.CompareError(t, "Hash decoded from P2SH address different from expected hash.", testHashHex, hex.EncodeToString(decodedHash))
+		}
+	}
+
+	//Addresses of the wrong network or type are rejected
+	invalidAddresses := []struct {
+		address string
+		network *Network
+		decode  func(string, *Network) ([]byte, error)
+	}{
+		{"mfcSEPR8EkJrpX91YkTJ9iscdAzppJrG9j", MainNet, DecodeP2PKHAddress},
+		{"16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvM", RegTest, DecodeP2PKHAddress},
+		{"2MsLhvckcb5hLLMzNdQmPNP1V83u1HVdeEb", MainNet, DecodeP2SHAddress},
+		{"31nVrspaydBz8aMpxH9WkS2DuhgqS1fCuG", MainNet, DecodeP2PKHAddress},
+		{"16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvM", MainNet, DecodeP2SHAddress},
+		{"", MainNet, DecodeP2PKHAddress},
+	}
+	for _, invalidAddress := range invalidAddresses {
+		if _, err := invalidAddress.decode(invalidAddress.address, invalidAddress.network); err == nil {
+			t.Error("Accepting invalid "+invalidAddress.network.Name+" address as valid:", invalidAddress.address)
+		}
+	}
+}
@@ -0,0 +1,550 @@
+// Provides Partially Signed Bitcoin Transactions (PSBT) so cosigners can each add their signature separately.
+// See https://github.com/bitcoin/bips/blob/master/bip-0174.mediawiki for full specification.
+package btcutils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// psbtMagic is the "psbt" magic followed by the 0xff separator that starts every serialized PSBT.
+var psbtMagic = []byte{0x70, 0x73, 0x62, 0x74, 0xff}
+
+// PSBT key types, per map.
+const (
+	psbtGlobalUnsignedTx = 0x00
+
+	psbtInNonWitnessUtxo     = 0x00
+	psbtInWitnessUtxo        = 0x01
+	psbtInPartialSig         = 0x02
+	psbtInSighashType        = 0x03
+	psbtInRedeemScript       = 0x04
+	psbtInWitnessScript      = 0x05
+	psbtInBip32Derivation    = 0x06
+	psbtInFinalScriptSig     = 0x07
+	psbtInFinalScriptWitness = 0x08
+
+	psbtOutRedeemScript    = 0x00
+	psbtOutWitnessScript   = 0x01
+	psbtOutBip32Derivation = 0x02
+)
+
+// PsbtKeyValue is a raw key-value pair of a type this package does not interpret, preserved as is.
+type PsbtKeyValue struct {
+	Key   []byte
+	Value []byte
+}
+
+// PsbtPartialSig is one cosigner's signature (with hash type byte appended) for an input.
+type PsbtPartialSig struct {
+	PublicKey []byte
+	Signature []byte
+}
+
+// PsbtBip32Derivation records the master key fingerprint and derivation path of a public key.
+type PsbtBip32Derivation struct {
+	PublicKey   []byte
+	Fingerprint uint32
+	Path        []uint32
+}
+
+// PsbtInput holds the per-input data needed to sign and finalize one input of the unsigned transaction.
+type PsbtInput struct {
+	NonWitnessUtxo     *Transaction
+	WitnessUtxo        *TxOut
+	PartialSigs        []*PsbtPartialSig
+	SighashType        uint32 //0 if not specified
+	RedeemScript       []byte
+	WitnessScript      []byte
+	Bip32Derivation    []*PsbtBip32Derivation
+	FinalScriptSig     []byte
+	FinalScriptWitness [][]byte
+	Unknown            []PsbtKeyValue
+}
+
+// PsbtOutput holds the per-output data describing an output of the unsigned transaction.
+type PsbtOutput struct {
+	RedeemScript    []byte
+	WitnessScript   []byte
+	Bip32Derivation []*PsbtBip32Derivation
+	Unknown         []PsbtKeyValue
+}
+
+// Psbt is a Partially Signed Bitcoin Transaction: an unsigned transaction plus the data each signer needs.
+type Psbt struct {
+	UnsignedTx *Transaction
+	Unknown    []PsbtKeyValue
+	Inputs     []*PsbtInput
+	Outputs    []*PsbtOutput
+}
+
+// NewPsbt creates a PSBT for the unsigned transaction tx. All scriptSigs and witnesses of tx must be empty.
+func NewPsbt(tx *Transaction) (*Psbt, error) {
+	for i, txIn := range tx.Inputs {
+		if len(txIn.ScriptSig) != 0 || len(txIn.Witness) != 0 {
+			return nil, errors.New(fmt.Sprintf("PSBT transaction input #%d must be unsigned, with empty scriptSig and witness.", i))
+		}
+	}
+	psbt := &Psbt{
+		UnsignedTx: tx.Copy(),
+		Inputs:     make([]*PsbtInput, len(tx.Inputs)),
+		Outputs:    make([]*PsbtOutput, len(tx.Outputs)),
+	}
+	for i := range psbt.Inputs {
+		psbt.Inputs[i] = &PsbtInput{}
+	}
+	for i := range psbt.Outputs {
+		psbt.Outputs[i] = &PsbtOutput{}
+	}
+	return psbt, nil
+}
+
+// ParsePsbtBase64 decodes a PSBT from its base64 encoding.
+func ParsePsbtBase64(encoded string) (*Psbt, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePsbt(data)
+}
+
+// Base64 returns the base64 encoding of the serialized PSBT.
+func (psbt *Psbt) Base64() string {
+	return base64.StdEncoding.EncodeToString(psbt.Serialize())
+}
+
+// Serialize returns the PSBT in its binary format.
+func (psbt *Psbt) Serialize() []byte {
+	var buffer bytes.Buffer
+	buffer.Write(psbtMagic)
+	//Global map
+	writePsbtKeyValue(&buffer, []byte{psbtGlobalUnsignedTx}, psbt.UnsignedTx.SerializeNoWitness())
+	writePsbtUnknown(&buffer, psbt.Unknown)
+	buffer.WriteByte(0x00)
+	//Input maps
+	for _, input := range psbt.Inputs {
+		if input.NonWitnessUtxo != nil {
+			writePsbtKeyValue(&buffer, []byte{psbtInNonWitnessUtxo}, input.NonWitnessUtxo.Serialize())
+		}
+		if input.WitnessUtxo != nil {
+			var txOut bytes.Buffer
+			writeUint64(&txOut, uint64(input.WitnessUtxo.Value))
+			WriteVarBytes(&txOut, input.WitnessUtxo.ScriptPubKey)
+			writePsbtKeyValue(&buffer, []byte{psbtInWitnessUtxo}, txOut.Bytes())
+		}
+		for _, partialSig := range input.PartialSigs {
+			writePsbtKeyValue(&buffer, append([]byte{psbtInPartialSig}, partialSig.PublicKey...), partialSig.Signature)
+		}
+		if input.SighashType != 0 {
+			var sighashType bytes.Buffer
+			writeUint32(&sighashType, input.SighashType)
+			writePsbtKeyValue(&buffer, []byte{psbtInSighashType}, sighashType.Bytes())
+		}
+		if input.RedeemScript != nil {
+			writePsbtKeyValue(&buffer, []byte{psbtInRedeemScript}, input.RedeemScript)
+		}
+		if input.WitnessScript != nil {
+			writePsbtKeyValue(&buffer, []byte{psbtInWitnessScript}, input.WitnessScript)
+		}
+		writePsbtBip32Derivation(&buffer, psbtInBip32Derivation, input.Bip32Derivation)
+		if input.FinalScriptSig != nil {
+			writePsbtKeyValue(&buffer, []byte{psbtInFinalScriptSig}, input.FinalScriptSig)
+		}
+		if input.FinalScriptWitness != nil {
+			var witness bytes.Buffer
+			WriteVarInt(&witness, uint64(len(input.FinalScriptWitness)))
+			for _, item := range input.FinalScriptWitness {
+				WriteVarBytes(&witness, item)
+			}
+			writePsbtKeyValue(&buffer, []byte{psbtInFinalScriptWitness}, witness.Bytes())
+		}
+		writePsbtUnknown(&buffer, input.Unknown)
+		buffer.WriteByte(0x00)
+	}
+	//Output maps
+	for _, output := range psbt.Outputs {
+		if output.RedeemScript != nil {
+			writePsbtKeyValue(&buffer, []byte{psbtOutRedeemScript}, output.RedeemScript)
+		}
+		if output.WitnessScript != nil {
+			writePsbtKeyValue(&buffer, []byte{psbtOutWitnessScript}, output.WitnessScript)
+		}
+		writePsbtBip32Derivation(&buffer, psbtOutBip32Derivation, output.Bip32Derivation)
+		writePsbtUnknown(&buffer, output.Unknown)
+		buffer.WriteByte(0x00)
+	}
+	return buffer.Bytes()
+}
+
+// ParsePsbt decodes a PSBT from its binary format.
+func ParsePsbt(data []byte) (*Psbt, error) {
+	if !bytes.HasPrefix(data, psbtMagic) {
+		return nil, errors.New("Data is not a PSBT. PSBTs must start with magic bytes 0x70736274ff.")
+	}
+	reader := bytes.NewReader(data[len(psbtMagic):])
+	psbt := &Psbt{}
+	//Global map
+	keyValues, err := readPsbtMap(reader)
+	if err != nil {
+		return nil, err
+	}
+	for _, keyValue := range keyValues {
+		switch {
+		case keyValue.Key[0] == psbtGlobalUnsignedTx && len(keyValue.Key) == 1:
+			psbt.UnsignedTx, err = ParseTransaction(keyValue.Value)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			psbt.Unknown = append(psbt.Unknown, keyValue)
+		}
+	}
+	if psbt.UnsignedTx == nil {
+		return nil, errors.New("PSBT is missing the unsigned transaction.")
+	}
+	for i, txIn := range psbt.UnsignedTx.Inputs {
+		if len(txIn.ScriptSig) != 0 || len(txIn.Witness) != 0 {
+			return nil, errors.New(fmt.Sprintf("PSBT unsigned transaction input #%d has a non-empty scriptSig or witness.", i))
+		}
+	}
+	//Input maps
+	for i := range psbt.UnsignedTx.Inputs {
+		keyValues, err := readPsbtMap(reader)
+		if err != nil {
+			return nil, err
+		}
+		input, err := parsePsbtInput(keyValues)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("PSBT input #%d: %v", i, err))
+		}
+		psbt.Inputs = append(psbt.Inputs, input)
+	}
+	//Output maps
+	for i := range psbt.UnsignedTx.Outputs {
+		keyValues, err := readPsbtMap(reader)
+		if err != nil {
+			return nil, err
+		}
+		output, err := parsePsbtOutput(keyValues)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("PSBT output #%d: %v", i, err))
+		}
+		psbt.Outputs = append(psbt.Outputs, output)
+	}
+	if reader.Len() != 0 {
+		return nil, errors.New(fmt.Sprintf("PSBT has %d unexpected trailing bytes.", reader.Len()))
+	}
+	return psbt, nil
+}
+
+func parsePsbtInput(keyValues []PsbtKeyValue) (*PsbtInput, error) {
+	input := &PsbtInput{}
+	for _, keyValue := range keyValues {
+		keyType, keyData := keyValue.Key[0], keyValue.Key[1:]
+		if keyType != psbtInPartialSig && keyType != psbtInBip32Derivation && len(keyData) != 0 {
+			input.Unknown = append(input.Unknown, keyValue)
+			continue
+		}
+		switch keyType {
+		case psbtInNonWitnessUtxo:
+			tx, err := ParseTransaction(keyValue.Value)
+			if err != nil {
+				return nil, err
+			}
+			input.NonWitnessUtxo = tx
+		case psbtInWitnessUtxo:
+			reader := bytes.NewReader(keyValue.Value)
+			value, err := readUint64(reader)
+			if err != nil {
+				return nil, err
+			}
+			scriptPubKey, err := ReadVarBytes(reader)
+			if err != nil {
+				return nil, err
+			}
+			input.WitnessUtxo = NewTxOut(int64(value), scriptPubKey)
+		case psbtInPartialSig:
+			if err := CheckPublicKeyIsValid(keyData); err != nil {
+				return nil, err
+			}
+			input.PartialSigs = append(input.PartialSigs, &PsbtPartialSig{PublicKey: keyData, Signature: keyValue.Value})
+		case psbtInSighashType:
+			if len(keyValue.Value) != 4 {
+				return nil, errors.New("Sighash type must be 4 bytes long.")
+			}
+			input.SighashType = binary.LittleEndian.Uint32(keyValue.Value)
+		case psbtInRedeemScript:
+			input.RedeemScript = keyValue.Value
+		case psbtInWitnessScript:
+			input.WitnessScript = keyValue.Value
+		case psbtInBip32Derivation:
+			derivation, err := parsePsbtBip32Derivation(keyData, keyValue.Value)
+			if err != nil {
+				return nil, err
+			}
+			input.Bip32Derivation = append(input.Bip32Derivation, derivation)
+		case psbtInFinalScriptSig:
+			input.FinalScriptSig = keyValue.Value
+		case psbtInFinalScriptWitness:
+			reader := bytes.NewReader(keyValue.Value)
+			itemCount, err := ReadVarInt(reader)
+			if err != nil {
+				return nil, err
+			}
+			input.FinalScriptWitness = [][]byte{}
+			for j := uint64(0); j < itemCount; j++ {
+				item, err := ReadVarBytes(reader)
+				if err != nil {
+					return nil, err
+				}
+				input.FinalScriptWitness = append(input.FinalScriptWitness, item)
+			}
+		default:
+			input.Unknown = append(input.Unknown, keyValue)
+		}
+	}
+	return input, nil
+}
+
+func parsePsbtOutput(keyValues []PsbtKeyValue) (*PsbtOutput, error) {
+	output := &PsbtOutput{}
+	for _, keyValue := range keyValues {
+		keyType, keyData := keyValue.Key[0], keyValue.Key[1:]
+		switch {
+		case keyType == psbtOutRedeemScript && len(keyData) == 0:
+			output.RedeemScript = keyValue.Value
+		case keyType == psbtOutWitnessScript && len(keyData) == 0:
+			output.WitnessScript = keyValue.Value
+		case keyType == psbtOutBip32Derivation:
+			derivation, err := parsePsbtBip32Derivation(keyData, keyValue.Value)
+			if err != nil {
+				return nil, err
+			}
+			output.Bip32Derivation = append(output.Bip32Derivation, derivation)
+		default:
+			output.Unknown = append(output.Unknown, keyValue)
+		}
+	}
+	return output, nil
+}
+
+// AddPartialSig adds a signature (with hash type byte appended) by publicKey to input inputIndex,
+// replacing any earlier signature by the same key.
+func (psbt *Psbt) AddPartialSig(inputIndex int, publicKey []byte, signature []byte) error {
+	if inputIndex < 0 || inputIndex >= len(psbt.Inputs) {
+		return errors.New(fmt.Sprintf("Input index %d out of range for PSBT with %d inputs.", inputIndex, len(psbt.Inputs)))
+	}
+	input := psbt.Inputs[inputIndex]
+	if input.FinalScriptSig != nil || input.FinalScriptWitness != nil {
+		return errors.New(fmt.Sprintf("PSBT input #%d is already finalized.", inputIndex))
+	}
+	for _, partialSig := range input.PartialSigs {
+		if bytes.Equal(partialSig.PublicKey, publicKey) {
+			partialSig.Signature = signature
+			return nil
+		}
+	}
+	input.PartialSigs = append(input.PartialSigs, &PsbtPartialSig{PublicKey: publicKey, Signature: signature})
+	return nil
+}
+
+// Combine merges the data of other, a PSBT for the same unsigned transaction (eg. signed by another cosigner), into psbt.
+func (psbt *Psbt) Combine(other *Psbt) error {
+	if !bytes.Equal(psbt.UnsignedTx.Serialize(), other.UnsignedTx.Serialize()) {
+		return errors.New("Cannot combine PSBTs for different unsigned transactions.")
+	}
+	psbt.Unknown = combinePsbtUnknown(psbt.Unknown, other.Unknown)
+	for i, input := range psbt.Inputs {
+		otherInput := other.Inputs[i]
+		if input.NonWitnessUtxo == nil {
+			input.NonWitnessUtxo = otherInput.NonWitnessUtxo
+		}
+		if input.WitnessUtxo == nil {
+			input.WitnessUtxo = otherInput.WitnessUtxo
+		}
+		//Partial signatures are no longer needed once an input is finalized
+		if input.FinalScriptSig == nil && input.FinalScriptWitness == nil {
+			for _, partialSig := range otherInput.PartialSigs {
+				if err := psbt.AddPartialSig(i, partialSig.PublicKey, partialSig.Signature); err != nil {
+					return err
+				}
+			}
+		}
+		if input.SighashType == 0 {
+			input.SighashType = otherInput.SighashType
+		}
+		if input.RedeemScript == nil {
+			input.RedeemScript = otherInput.RedeemScript
+		}
+		if input.WitnessScript == nil {
+			input.WitnessScript = otherInput.WitnessScript
+		}
+		input.Bip32Derivation = combinePsbtBip32Derivation(input.Bip32Derivation, otherInput.Bip32Derivation)
+		if input.FinalScriptSig == nil {
+			input.FinalScriptSig = otherInput.FinalScriptSig
+		}
+		if input.FinalScriptWitness == nil {
+			input.FinalScriptWitness = otherInput.FinalScriptWitness
+		}
+		input.Unknown = combinePsbtUnknown(input.Unknown, otherInput.Unknown)
+	}
+	for i, output := range psbt.Outputs {
+		otherOutput := other.Outputs[i]
+		if output.RedeemScript == nil {
+			output.RedeemScript = otherOutput.RedeemScript
+		}
+		if output.WitnessScript == nil {
+			output.WitnessScript = otherOutput.WitnessScript
+		}
+		output.Bip32Derivation = combinePsbtBip32Derivation(output.Bip32Derivation, otherOutput.Bip32Derivation)
+		output.Unknown = combinePsbtUnknown(output.Unknown, otherOutput.Unknown)
+	}
+	return nil
+}
+
+// FinalizeMultisig builds the final scriptSig for every P2SH multisig input from its partial signatures,
+// placing them in redeem script public key order. Fields only needed for signing are then removed, as per BIP174.
+func (psbt *Psbt) FinalizeMultisig() error {
+	for i, input := range psbt.Inputs {
+		if input.FinalScriptSig != nil || input.FinalScriptWitness != nil {
+			continue
+		}
+		if input.RedeemScript == nil {
+			return errors.New(fmt.Sprintf("PSBT input #%d has no redeem script to finalize with.", i))
+		}
+		m, _, publicKeys, err := ParseMOfNRedeemScript(input.RedeemScript)
+		if err != nil {
+			return err
+		}
+		signatures := make([][]byte, 0, m)
+		for _, publicKey := range publicKeys {
+			for _, partialSig := range input.PartialSigs {
+				if len(signatures) < m && bytes.Equal(partialSig.PublicKey, publicKey) {
+					signatures = append(signatures, partialSig.Signature)
+				}
+			}
+		}
+		if len(signatures) < m {
+			return errors.New(fmt.Sprintf("PSBT input #%d has %d of the %d signatures required.", i, len(signatures), m))
+		}
+		input.FinalScriptSig = NewP2SHMultisigScriptSig(signatures, input.RedeemScript)
+		input.PartialSigs = nil
+		input.SighashType = 0
+		input.RedeemScript = nil
+		input.WitnessScript = nil
+		input.Bip32Derivation = nil
+	}
+	return nil
+}
+
+// Extract returns the final, fully signed transaction once every input is finalized.
+func (psbt *Psbt) Extract() (*Transaction, error) {
+	tx := psbt.UnsignedTx.Copy()
+	for i, input := range psbt.Inputs {
+		if input.FinalScriptSig == nil && input.FinalScriptWitness == nil {
+			return nil, errors.New(fmt.Sprintf("PSBT input #%d is not finalized.", i))
+		}
+		tx.Inputs[i].ScriptSig = input.FinalScriptSig
+		tx.Inputs[i].Witness = input.FinalScriptWitness
+	}
+	return tx, nil
+}
+
+func readPsbtMap(reader *bytes.Reader) ([]PsbtKeyValue, error) {
+	var keyValues []PsbtKeyValue
+	seenKeys := make(map[string]bool)
+	for {
+		key, err := ReadVarBytes(reader)
+		if err != nil {
+			return nil, errors.New("PSBT is truncated.")
+		}
+		//A zero length key is the separator ending the map
+		if len(key) == 0 {
+			return keyValues, nil
+		}
+		if seenKeys[string(key)] {
+			return nil, errors.New(fmt.Sprintf("PSBT contains duplicate key 0x%x.", key))
+		}
+		seenKeys[string(key)] = true
+		value, err := ReadVarBytes(reader)
+		if err != nil {
+			return nil, errors.New("PSBT is truncated.")
+		}
+		keyValues = append(keyValues, PsbtKeyValue{Key: key, Value: value})
+	}
+}
+
+func writePsbtKeyValue(buffer *bytes.Buffer, key []byte, value []byte) {
+	WriteVarBytes(buffer, key)
+	WriteVarBytes(buffer, value)
+}
+
+func writePsbtUnknown(buffer *bytes.Buffer, keyValues []PsbtKeyValue) {
+	for _, keyValue := range keyValues {
+		writePsbtKeyValue(buffer, keyValue.Key, keyValue.Value)
+	}
+}
+
+func writePsbtBip32Derivation(buffer *bytes.Buffer, keyType byte, derivations []*PsbtBip32Derivation) {
+	for _, derivation := range derivations {
+		var value bytes.Buffer
+		//Fingerprint is the first 4 bytes of the master key's Hash160, kept in that byte order
+		fingerprint := make([]byte, 4)
+		binary.BigEndian.PutUint32(fingerprint, derivation.Fingerprint)
+		value.Write(fingerprint)
+		for _, index := range derivation.Path {
+			writeUint32(&value, index)
+		}
+		writePsbtKeyValue(buffer, append([]byte{keyType}, derivation.PublicKey...), value.Bytes())
+	}
+}
+
+func parsePsbtBip32Derivation(publicKey []byte, value []byte) (*PsbtBip32Derivation, error) {
+	if err := CheckPublicKeyIsValid(publicKey); err != nil {
+		return nil, err
+	}
+	if len(value) < 4 || len(value)%4 != 0 {
+		return nil, errors.New("BIP32 derivation must be a 4 byte fingerprint followed by 4 byte path indexes.")
+	}
+	derivation := &PsbtBip32Derivation{
+		PublicKey:   publicKey,
+		Fingerprint: binary.BigEndian.Uint32(value[:4]),
+	}
+	for i := 4; i < len(value); i += 4 {
+		derivation.Path = append(derivation.Path, binary.LittleEndian.Uint32(value[i:i+4]))
+	}
+	return derivation, nil
+}
+
+func combinePsbtUnknown(keyValues []PsbtKeyValue, otherKeyValues []PsbtKeyValue) []PsbtKeyValue {
+	for _, otherKeyValue := range otherKeyValues {
+		found := false
+		for _, keyValue := range keyValues {
+			if bytes.Equal(keyValue.Key, otherKeyValue.Key) {
+				found = true
+			}
+		}
+		if !found {
+			keyValues = append(keyValues, otherKeyValue)
+		}
+	}
+	return keyValues
+}
+
+func combinePsbtBip32Derivation(derivations []*PsbtBip32Derivation, otherDerivations []*PsbtBip32Derivation) []*PsbtBip32Derivation {
+	for _, otherDerivation := range otherDerivations {
+		found := false
+		for _, derivation := range derivations {
+			if bytes.Equal(derivation.PublicKey, otherDerivation.PublicKey) {
+				found = true
+			}
+		}
+		if !found {
+			derivations = append(derivations, otherDerivation)
+		}
+	}
+	return derivations
+}
@@ -0,0 +1,143 @@
+package btcutils
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// 2-of-3 P2SH multisig spend used by the PSBT tests, matching the README spend example
+var (
+	testPsbtUnsignedTxHex = "01000000013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000ffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000"
+	testPsbtRedeemScript  = "524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae"
+	testPsbtSignatures    = []string{
+		"3045022100af4831eb0cee1b9642ff8691acb53c2fd8e28bbd6c0389af69c132b342405c8502200627e67cbb0bee502421be4d2b8e370d24eb17ff7c3d77d604a5c07ee5934a7401",
+		"30450221009bfee48a5ae99a8fc0f78c631b0b2beb6d96337fc9d1e95c333a08bdcecb3647022040a30b3528b136821077d0bb45c324d7eedcd0ac61af7019ba1286e9277b7c5401",
+	}
+	testPsbtFinalTxHex = "01000000013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b00200000000fd5e0100483045022100af4831eb0cee1b9642ff8691acb53c2fd8e28bbd6c0389af69c132b342405c8502200627e67cbb0bee502421be4d2b8e370d24eb17ff7c3d77d604a5c07ee5934a74014830450221009bfee48a5ae99a8fc0f78c631b0b2beb6d96337fc9d1e95c333a08bdcecb3647022040a30b3528b136821077d0bb45c324d7eedcd0ac61af7019ba1286e9277b7c54014cc9524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353aeffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000"
+)
+
+// newTestPsbt returns a PSBT for the test spend with the redeem script set and the given cosigners' signatures added.
+func newTestPsbt(t *testing.T, signers ...int) *Psbt {
+	rawTx, _ := hex.DecodeString(testPsbtUnsignedTxHex)
+	tx, err := ParseTransaction(rawTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	psbt, err := NewPsbt(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redeemScript, _ := hex.DecodeString(testPsbtRedeemScript)
+	_, _, publicKeys, err := ParseMOfNRedeemScript(redeemScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	psbt.Inputs[0].RedeemScript = redeemScript
+	for _, signer := range signers {
+		signature, _ := hex.DecodeString(testPsbtSignatures[signer])
+		if err := psbt.AddPartialSig(0, publicKeys[signer], signature); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return psbt
+}
+
+func TestParsePsbt(t *testing.T) {
+	//Produced independently by btcsuite's psbt package: one partial signature, SIGHASH_ALL and the redeem script
+	testPsbtBase64 := "cHNidP8BAFUBAAAAAT3NfYeQTJy39LefNrWgP5bi5ykoTAmFYjjVNT4RgrACAAAAAAD/////ATDZAAAAAAAAGXapFFaQdro5/E/2oikdnqkZbYwI+ceriKwAAAAAAEICBKiC1BTkeAOc1bUqkv+xPdXmvUUVSXQ53/1pGg8Sr5V1+jSbVpTtMVWxNvCeY5daFwDJ9NTfhJMj2sBs871kWM1IMEUCIQCvSDHrDO4blkL/hpGstTwv2OKLvWwDia9pwTKzQkBchQIgBifmfLsL7lAkIb5NK443DSTrF/98PXfWBKXAfuWTSnQBAQMEAQAAAAEEyVJBBKiC1BTkeAOc1bUqkv+xPdXmvUUVSXQ53/1pGg8Sr5V1+jSbVpTtMVWxNvCeY5daFwDJ9NTfhJMj2sBs871kWM1BBGzjHbm91UPnL+MDmh8cBH2rhwN8NqZp/5DijaGEj2QN5owv6RPTY6URVKDGLXreobgi0FA1B3QYJnsaE3l5AYdBBBH/02xwd2U40Hn7rhF9w47/r7MzBK+DzkiUWJdHruHvmS9jKAVn9S9bqHBni0q0/2yOpgC9IXhwqLTx8J86joNTrgAA"
+
+	psbt, err := ParsePsbtBase64(testPsbtBase64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(psbt.UnsignedTx.Serialize()) != testPsbtUnsignedTxHex {
+		testutils.CompareError(t, "PSBT unsigned transaction different from expected transaction.", testPsbtUnsignedTxHex, hex.EncodeToString(psbt.UnsignedTx.Serialize()))
+	}
+	input := psbt.Inputs[0]
+	if len(input.PartialSigs) != 1 || hex.EncodeToString(input.PartialSigs[0].Signature) != testPsbtSignatures[0] {
+		t.Error("PSBT partial signature not parsed as expected.")
+	}
+	if input.SighashType != SIGHASH_ALL {
+		t.Errorf("PSBT sighash type parsed as %d, expected %d.", input.SighashType, SIGHASH_ALL)
+	}
+	if hex.EncodeToString(input.RedeemScript) != testPsbtRedeemScript {
+		testutils.CompareError(t, "PSBT redeem script different from expected script.", testPsbtRedeemScript, hex.EncodeToString(input.RedeemScript))
+	}
+	//Round trip back to the same encoding
+	if psbt.Base64() != testPsbtBase64 {
+		testutils.CompareError(t, "Reserialized PSBT different from parsed PSBT.", testPsbtBase64, psbt.Base64())
+	}
+}
+
+func TestParsePsbtInvalid(t *testing.T) {
+	invalidPsbtHexs := []string{
+		"",                 //empty PSBT
+		"0100000001",       //no magic bytes
+		"70736274ff00",     //no unsigned transaction
+		"70736274ff0100",   //truncated global map
+		"70736274ff01000a", //truncated unsigned transaction value
+		//unsigned transaction with a non-empty scriptSig
+		"70736274ff01003d0100000001da69765bad9cc46a70480a153b8e229c41f38eecb57699693d5c4444e036e0c20000000001ffffffffff0100000000000000000000000000000000",
+		//duplicate unsigned transaction key
+		"70736274ff01003c0100000001da69765bad9cc46a70480a153b8e229c41f38eecb57699693d5c4444e036e0c20000000000ffffffff01000000000000000000000000000001003c0100000001da69765bad9cc46a70480a153b8e229c41f38eecb57699693d5c4444e036e0c20000000000ffffffff010000000000000000000000000000000000",
+		//missing input and output maps
+		"70736274ff01003c0100000001da69765bad9cc46a70480a153b8e229c41f38eecb57699693d5c4444e036e0c20000000000ffffffff01000000000000000000000000000000",
+	}
+	for _, psbtHex := range invalidPsbtHexs {
+		rawPsbt, _ := hex.DecodeString(psbtHex)
+		if _, err := ParsePsbt(rawPsbt); err == nil {
+			t.Error("ParsePsbt accepting invalid PSBT as valid:", psbtHex)
+		}
+	}
+}
+
+func TestPsbtCombineFinalizeExtract(t *testing.T) {
+	//Each cosigner signs their own copy, in reverse redeem script order
+	psbt := newTestPsbt(t, 1)
+	if err := psbt.Combine(newTestPsbt(t, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if len(psbt.Inputs[0].PartialSigs) != 2 {
+		t.Fatalf("Combined PSBT has %d partial signatures, expected 2.", len(psbt.Inputs[0].PartialSigs))
+	}
+	if _, err := psbt.Extract(); err == nil {
+		t.Error("Extract accepting PSBT that is not finalized.")
+	}
+	if err := psbt.FinalizeMultisig(); err != nil {
+		t.Fatal(err)
+	}
+	if psbt.Inputs[0].PartialSigs != nil || psbt.Inputs[0].RedeemScript != nil {
+		t.Error("Finalized PSBT input still has partial signatures or redeem script.")
+	}
+	tx, err := psbt.Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalTxHex := hex.EncodeToString(tx.Serialize())
+	if finalTxHex != testPsbtFinalTxHex {
+		testutils.CompareError(t, "Extracted PSBT transaction different from expected transaction.", testPsbtFinalTxHex, finalTxHex)
+	}
+	//The unsigned transaction itself must stay unsigned
+	if !bytes.Equal(psbt.UnsignedTx.Serialize(), newTestPsbt(t).UnsignedTx.Serialize()) {
+		t.Error("Extract modified the PSBT unsigned transaction.")
+	}
+}
+
+func TestPsbtCombineInvalid(t *testing.T) {
+	psbt := newTestPsbt(t, 0)
+	other := newTestPsbt(t, 1)
+	other.UnsignedTx.LockTime = 1
+	if err := psbt.Combine(other); err == nil {
+		t.Error("Combine accepting PSBT for a different unsigned transaction.")
+	}
+}
+
+func TestPsbtFinalizeMissingSignatures(t *testing.T) {
+	psbt := newTestPsbt(t, 1)
+	if err := psbt.FinalizeMultisig(); err == nil {
+		t.Error("FinalizeMultisig accepting 2-of-3 multisig input with only 1 signature.")
+	}
+}
@@ -1,7 +1,14 @@
-// Provides Bitcoin Script enum to improve code readability.
+// Provides Bitcoin Script enum to improve code readability, plus helpers to parse and build scripts.
 // See https://en.bitcoin.it/wiki/Script for full specification.
 package btcutils
 
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
 // OP_1 through OP_16
 const (
 	OP_1 = 81 + iota
@@ -22,15 +29,254 @@ const (
 	OP_16 //96
 )
 
-// OP codes other than OP_1 through OP_16, used in P2SH Multisig transanctions.
+// Push value OP codes other than OP_1 through OP_16.
+const (
+	OP_0         = 0
+	OP_FALSE     = OP_0
+	OP_PUSHDATA1 = 76
+	OP_PUSHDATA2 = 77
+	OP_PUSHDATA4 = 78
+	OP_1NEGATE   = 79
+	OP_RESERVED  = 80
+	OP_TRUE      = OP_1
+)
+
+// Flow control OP codes.
+const (
+	OP_NOP      = 97
+	OP_VER      = 98
+	OP_IF       = 99
+	OP_NOTIF    = 100
+	OP_VERIF    = 101
+	OP_VERNOTIF = 102
+	OP_ELSE     = 103
+	OP_ENDIF    = 104
+	OP_VERIFY   = 105
+	OP_RETURN   = 106
+)
+
+// Stack OP codes.
+const (
+	OP_TOALTSTACK   = 107
+	OP_FROMALTSTACK = 108
+	OP_2DROP        = 109
+	OP_2DUP         = 110
+	OP_3DUP         = 111
+	OP_2OVER        = 112
+	OP_2ROT         = 113
+	OP_2SWAP        = 114
+	OP_IFDUP        = 115
+	OP_DEPTH        = 116
+	OP_DROP         = 117
+	OP_DUP          = 118
+	OP_NIP          = 119
+	OP_OVER         = 120
+	OP_PICK         = 121
+	OP_ROLL         = 122
+	OP_ROT          = 123
+	OP_SWAP         = 124
+	OP_TUCK         = 125
+)
+
+// Splice and bitwise logic OP codes. All but OP_SIZE, OP_EQUAL and OP_EQUALVERIFY are disabled.
+const (
+	OP_CAT         = 126
+	OP_SUBSTR      = 127
+	OP_LEFT        = 128
+	OP_RIGHT       = 129
+	OP_SIZE        = 130
+	OP_INVERT      = 131
+	OP_AND         = 132
+	OP_OR          = 133
+	OP_XOR         = 134
+	OP_EQUAL       = 135
+	OP_EQUALVERIFY = 136
+	OP_RESERVED1   = 137
+	OP_RESERVED2   = 138
+)
+
+// Arithmetic OP codes. OP_2MUL, OP_2DIV, OP_MUL, OP_DIV, OP_MOD, OP_LSHIFT and OP_RSHIFT are disabled.
+const (
+	OP_1ADD               = 139
+	OP_1SUB               = 140
+	OP_2MUL               = 141
+	OP_2DIV               = 142
+	OP_NEGATE             = 143
+	OP_ABS                = 144
+	OP_NOT                = 145
+	OP_0NOTEQUAL          = 146
+	OP_ADD                = 147
+	OP_SUB                = 148
+	OP_MUL                = 149
+	OP_DIV                = 150
+	OP_MOD                = 151
+	OP_LSHIFT             = 152
+	OP_RSHIFT             = 153
+	OP_BOOLAND            = 154
+	OP_BOOLOR             = 155
+	OP_NUMEQUAL           = 156
+	OP_NUMEQUALVERIFY     = 157
+	OP_NUMNOTEQUAL        = 158
+	OP_LESSTHAN           = 159
+	OP_GREATERTHAN        = 160
+	OP_LESSTHANOREQUAL    = 161
+	OP_GREATERTHANOREQUAL = 162
+	OP_MIN                = 163
+	OP_MAX                = 164
+	OP_WITHIN             = 165
+)
+
+// Crypto OP codes, used in P2PKH and P2SH Multisig transactions.
+const (
+	OP_RIPEMD160           = 166
+	OP_SHA1                = 167
+	OP_SHA256              = 168
+	OP_HASH160             = 169
+	OP_HASH256             = 170
+	OP_CODESEPARATOR       = 171
+	OP_CHECKSIG            = 172
+	OP_CHECKSIGVERIFY      = 173
+	OP_CHECKMULTISIG       = 174
+	OP_CHECKMULTISIGVERIFY = 175
+)
+
+// Expansion OP codes. OP_NOP1 to OP_NOP10 do nothing, and are reserved for soft fork upgrades, of which OP_NOP2 and OP_NOP3
+// have become OP_CHECKLOCKTIMEVERIFY (BIP65) and OP_CHECKSEQUENCEVERIFY (BIP112).
 const (
-	OP_0             = 0
-	OP_PUSHDATA1     = 76
-	OP_PUSHDATA2     = 77
-	OP_DUP           = 118
-	OP_EQUAL         = 135
-	OP_EQUALVERIFY   = 136
-	OP_HASH160       = 169
-	OP_CHECKSIG      = 172
-	OP_CHECKMULTISIG = 174
+	OP_NOP1                = 176
+	OP_NOP2                = 177
+	OP_CHECKLOCKTIMEVERIFY = OP_NOP2
+	OP_NOP3                = 178
+	OP_CHECKSEQUENCEVERIFY = OP_NOP3
+	OP_NOP4                = 179
+	OP_NOP5                = 180
+	OP_NOP6                = 181
+	OP_NOP7                = 182
+	OP_NOP8                = 183
+	OP_NOP9                = 184
+	OP_NOP10               = 185
+	OP_CHECKSIGADD         = 186 //Tapscript only (BIP342)
+	OP_INVALIDOPCODE       = 255
 )
+
+// ScriptOp is a single parsed script operation: the opcode and, for push operations, the data pushed.
+type ScriptOp struct {
+	Opcode byte
+	Data   []byte
+}
+
+// ParseScript splits a script into its operations, returning an error if a push runs past the end of the script.
+func ParseScript(script []byte) ([]ScriptOp, error) {
+	var ops []ScriptOp
+	for i := 0; i < len(script); {
+		opcode := script[i]
+		i++
+		var dataLength int
+		switch {
+		case opcode > OP_0 && opcode < OP_PUSHDATA1:
+			dataLength = int(opcode)
+		case opcode == OP_PUSHDATA1:
+			if i+1 > len(script) {
+				return nil, errors.New("Script truncated in OP_PUSHDATA1 length.")
+			}
+			dataLength = int(script[i])
+			i++
+		case opcode == OP_PUSHDATA2:
+			if i+2 > len(script) {
+				return nil, errors.New("Script truncated in OP_PUSHDATA2 length.")
+			}
+			dataLength = int(binary.LittleEndian.Uint16(script[i : i+2]))
+			i += 2
+		case opcode == OP_PUSHDATA4:
+			if i+4 > len(script) {
+				return nil, errors.New("Script truncated in OP_PUSHDATA4 length.")
+			}
+			dataLength = int(binary.LittleEndian.Uint32(script[i : i+4]))
+			i += 4
+		}
+		if dataLength < 0 || i+dataLength > len(script) {
+			return nil, errors.New(fmt.Sprintf("Script push of %d bytes runs past end of script.", dataLength))
+		}
+		op := ScriptOp{Opcode: opcode}
+		if opcode <= OP_PUSHDATA4 {
+			op.Data = script[i : i+dataLength]
+		}
+		i += dataLength
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// WritePushData writes the smallest push operation for data to buffer, followed by data itself.
+func WritePushData(buffer *bytes.Buffer, data []byte) {
+	switch {
+	case len(data) < OP_PUSHDATA1:
+		buffer.WriteByte(byte(len(data))) //Opcodes 0x01-0x4b push that many bytes directly
+	case len(data) <= 0xff:
+		buffer.WriteByte(OP_PUSHDATA1) //OP_PUSHDATA1 specifies next *one byte* will be length to be pushed to stack
+		buffer.WriteByte(byte(len(data)))
+	case len(data) <= 0xffff:
+		buffer.WriteByte(OP_PUSHDATA2) //OP_PUSHDATA2 specifies next *two bytes* will be length to be pushed to stack
+		lengthBytes := make([]byte, 2)
+		binary.LittleEndian.PutUint16(lengthBytes, uint16(len(data)))
+		buffer.Write(lengthBytes)
+	default:
+		buffer.WriteByte(OP_PUSHDATA4) //OP_PUSHDATA4 specifies next *four bytes* will be length to be pushed to stack
+		writeUint32(buffer, uint32(len(data)))
+	}
+	buffer.Write(data)
+}
+
+// WriteScriptNumber writes the smallest push of the script number n to buffer: OP_0, OP_1NEGATE or OP_1 to OP_16 where
+// they can be used, otherwise n in the minimal encoding of scriptNumberBytes.
+func WriteScriptNumber(buffer *bytes.Buffer, n int64) {
+	switch {
+	case n == 0:
+		buffer.WriteByte(OP_0)
+	case n == -1 || (n >= 1 && n <= 16):
+		buffer.WriteByte(byte(OP_1 - 1 + n)) //OP_1NEGATE is just below OP_1, with OP_RESERVED in between
+	default:
+		WritePushData(buffer, scriptNumberBytes(n))
+	}
+}
+
+// scriptNumberBytes encodes n as a script number: its magnitude in as few little-endian bytes as possible, with the sign
+// in the top bit of the last byte. An extra byte is added when the magnitude already uses that bit.
+func scriptNumberBytes(n int64) []byte {
+	negative := n < 0
+	magnitude := uint64(n)
+	if negative {
+		magnitude = uint64(-n)
+	}
+	var encoded []byte
+	for ; magnitude > 0; magnitude >>= 8 {
+		encoded = append(encoded, byte(magnitude))
+	}
+	switch {
+	case len(encoded) == 0:
+		return encoded
+	case encoded[len(encoded)-1]&0x80 != 0 && negative:
+		encoded = append(encoded, 0x80)
+	case encoded[len(encoded)-1]&0x80 != 0:
+		encoded = append(encoded, 0x00)
+	case negative:
+		encoded[len(encoded)-1] |= 0x80
+	}
+	return encoded
+}
+
+// writeScriptOp writes a parsed script operation to buffer exactly as it was in the script it was parsed from.
+func writeScriptOp(buffer *bytes.Buffer, op ScriptOp) {
+	buffer.WriteByte(op.Opcode)
+	switch op.Opcode {
+	case OP_PUSHDATA1:
+		buffer.WriteByte(byte(len(op.Data)))
+	case OP_PUSHDATA2:
+		lengthBytes := make([]byte, 2)
+		binary.LittleEndian.PutUint16(lengthBytes, uint16(len(op.Data)))
+		buffer.Write(lengthBytes)
+	case OP_PUSHDATA4:
+		writeUint32(buffer, uint32(len(op.Data)))
+	}
+	buffer.Write(op.Data)
+}
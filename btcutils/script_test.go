@@ -0,0 +1,91 @@
+package btcutils
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestWritePushData(t *testing.T) {
+	testCases := []struct {
+		dataLength     int
+		expectedPrefix string
+	}{
+		{1, "01"},
+		{75, "4b"},
+		{76, "4c4c"},
+		{255, "4cff"},
+		{256, "4d0001"},
+		{0xffff, "4dffff"},
+		{0x10000, "4e00000100"},
+	}
+	for _, testCase := range testCases {
+		var buffer bytes.Buffer
+		WritePushData(&buffer, make([]byte, testCase.dataLength))
+		prefixHex := hex.EncodeToString(buffer.Bytes()[:buffer.Len()-testCase.dataLength])
+		if prefixHex != testCase.expectedPrefix {
+			testutils.CompareError(t, "Push operation different from expected minimal push.", testCase.expectedPrefix, prefixHex)
+		}
+	}
+}
+
+func TestWriteScriptNumber(t *testing.T) {
+	testCases := []struct {
+		number      int64
+		expectedHex string
+	}{
+		{0, "00"},  //OP_0
+		{-1, "4f"}, //OP_1NEGATE
+		{1, "51"},  //OP_1
+		{16, "60"}, //OP_16
+		{17, "0111"},
+		{-5, "0185"},
+		{128, "028000"}, //Extra byte for the sign bit
+		{700000, "0360ae0a"},
+		{4294967295, "05ffffffff00"},
+	}
+	for _, testCase := range testCases {
+		var buffer bytes.Buffer
+		WriteScriptNumber(&buffer, testCase.number)
+		numberHex := hex.EncodeToString(buffer.Bytes())
+		if numberHex != testCase.expectedHex {
+			testutils.CompareError(t, "Script number push different from expected push.", testCase.expectedHex, numberHex)
+		}
+	}
+}
+
+func TestParseScript(t *testing.T) {
+	//OP_0 <3 byte push> <OP_PUSHDATA1 76 byte push> OP_CHECKMULTISIG
+	testScriptHex := "0003aabbcc4c4c" + hex.EncodeToString(make([]byte, 76)) + "ae"
+	testOpcodes := []byte{OP_0, 3, OP_PUSHDATA1, OP_CHECKMULTISIG}
+	testDataLengths := []int{0, 3, 76, 0}
+
+	script, _ := hex.DecodeString(testScriptHex)
+	ops, err := ParseScript(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != len(testOpcodes) {
+		t.Fatalf("Parsed %d script operations, expected %d.", len(ops), len(testOpcodes))
+	}
+	for i, op := range ops {
+		if op.Opcode != testOpcodes[i] || len(op.Data) != testDataLengths[i] {
+			t.Errorf("Script operation #%d parsed as opcode %d pushing %d bytes, expected opcode %d pushing %d bytes.", i, op.Opcode, len(op.Data), testOpcodes[i], testDataLengths[i])
+		}
+	}
+
+	invalidScriptHexs := []string{
+		"03aabb",     //push longer than script
+		"4c",         //truncated OP_PUSHDATA1 length
+		"4d01",       //truncated OP_PUSHDATA2 length
+		"4e01000000", //OP_PUSHDATA4 push longer than script
+	}
+	for _, scriptHex := range invalidScriptHexs {
+		script, _ := hex.DecodeString(scriptHex)
+		if _, err := ParseScript(script); err == nil {
+			t.Error("ParseScript accepting invalid script as valid:", scriptHex)
+		}
+	}
+}
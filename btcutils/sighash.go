@@ -0,0 +1,183 @@
+// Provides signature hash (sighash) computation for signing transaction inputs.
+// See https://en.bitcoin.it/wiki/OP_CHECKSIG for full specification of legacy signature hashes,
+// and https://github.com/bitcoin/bips/blob/master/bip-0143.mediawiki for SegWit version 0 signature hashes.
+package btcutils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Signature hash types, appended to each signature to specify which parts of the transaction it commits to.
+// SIGHASH_ALL commits to every input and output, SIGHASH_NONE to no outputs, letting anyone choose where the funds go,
+// and SIGHASH_SINGLE only to the output with the same index as the input signed.
+// SIGHASH_ANYONECANPAY may be combined with any of them to commit only to the input signed, letting others add inputs.
+const (
+	SIGHASH_ALL          = 0x01
+	SIGHASH_NONE         = 0x02
+	SIGHASH_SINGLE       = 0x03
+	SIGHASH_ANYONECANPAY = 0x80
+)
+
+// sigHashTypeNames gives the name of each base signature hash type, without SIGHASH_ANYONECANPAY.
+var sigHashTypeNames = map[uint32]string{
+	SIGHASH_ALL:    "ALL",
+	SIGHASH_NONE:   "NONE",
+	SIGHASH_SINGLE: "SINGLE",
+}
+
+// ParseSigHashType parses a signature hash type name: ALL, NONE or SINGLE, optionally followed by |ANYONECANPAY,
+// eg. ALL|ANYONECANPAY. Names are case insensitive and may start with SIGHASH_.
+func ParseSigHashType(name string) (uint32, error) {
+	parts := strings.Split(strings.ToUpper(strings.TrimSpace(name)), "|")
+	if len(parts) > 2 || (len(parts) == 2 && strings.TrimPrefix(strings.TrimSpace(parts[1]), "SIGHASH_") != "ANYONECANPAY") {
+		return 0, errors.New(fmt.Sprintf("Signature hash type %s is not ALL, NONE or SINGLE, optionally followed by |ANYONECANPAY.", name))
+	}
+	baseName := strings.TrimPrefix(strings.TrimSpace(parts[0]), "SIGHASH_")
+	for hashType, typeName := range sigHashTypeNames {
+		if typeName == baseName {
+			if len(parts) == 2 {
+				hashType |= SIGHASH_ANYONECANPAY
+			}
+			return hashType, nil
+		}
+	}
+	return 0, errors.New(fmt.Sprintf("Signature hash type %s is not ALL, NONE or SINGLE, optionally followed by |ANYONECANPAY.", name))
+}
+
+// SigHashTypeName returns the name of hashType as accepted by ParseSigHashType, eg. ALL|ANYONECANPAY.
+func SigHashTypeName(hashType uint32) string {
+	name, ok := sigHashTypeNames[hashType&^SIGHASH_ANYONECANPAY]
+	if !ok {
+		return fmt.Sprintf("0x%02x", hashType)
+	}
+	if hashType&SIGHASH_ANYONECANPAY != 0 {
+		name += "|ANYONECANPAY"
+	}
+	return name
+}
+
+// sigHashSingleBug is the legacy signature hash of an input signed with SIGHASH_SINGLE that has no output with the same index.
+// The original implementation returned 1 as an error code, and signed it as if it were the hash, so it is now a consensus rule.
+var sigHashSingleBug = append([]byte{0x01}, make([]byte, 31)...)
+
+// SignatureHash computes the legacy (pre-segwit) signature hash for input inputIndex of tx.
+// subscript is the script being satisfied: the previous output's scriptPubKey, or the redeemScript for P2SH, from just after
+// the last OP_CODESEPARATOR executed. The transaction is serialized with every other input's scriptSig blanked and subscript,
+// less any OP_CODESEPARATORs, in place of the signed input's scriptSig. hashType is appended in little-endian form, and the
+// result is hashed twice with SHA256.
+// With SIGHASH_NONE and SIGHASH_SINGLE, outputs not signed for are removed or blanked and other inputs' sequence numbers
+// are zeroed, and with SIGHASH_ANYONECANPAY only the signed input is kept.
+// SIGHASH_SINGLE without an output with the same index as the input gives the hash 1 (see sigHashSingleBug); signatures of it
+// can be reused to spend any output of the same key, so avoid signing it.
+func SignatureHash(tx *Transaction, inputIndex int, subscript []byte, hashType uint32) ([]byte, error) {
+	if inputIndex < 0 || inputIndex >= len(tx.Inputs) {
+		return nil, errors.New(fmt.Sprintf("Input index %d out of range for transaction with %d inputs.", inputIndex, len(tx.Inputs)))
+	}
+	baseType := hashType & 0x1f
+	if baseType == SIGHASH_SINGLE && inputIndex >= len(tx.Outputs) {
+		return append([]byte{}, sigHashSingleBug...), nil
+	}
+	subscript = removeCodeSeparators(subscript)
+	txCopy := tx.Copy()
+	for i, txIn := range txCopy.Inputs {
+		txIn.Witness = nil
+		if i == inputIndex {
+			txIn.ScriptSig = subscript
+		} else {
+			txIn.ScriptSig = nil
+			//Other inputs can be updated (eg. replaced) without invalidating a signature not committing to every output
+			if baseType == SIGHASH_NONE || baseType == SIGHASH_SINGLE {
+				txIn.Sequence = 0
+			}
+		}
+	}
+	switch baseType {
+	case SIGHASH_NONE:
+		txCopy.Outputs = nil
+	case SIGHASH_SINGLE:
+		//Outputs before the signed one are kept as blank placeholders, with value -1 and an empty scriptPubKey
+		txCopy.Outputs = txCopy.Outputs[:inputIndex+1]
+		for i := 0; i < inputIndex; i++ {
+			txCopy.Outputs[i] = NewTxOut(-1, nil)
+		}
+	}
+	if hashType&SIGHASH_ANYONECANPAY != 0 {
+		txCopy.Inputs = txCopy.Inputs[inputIndex : inputIndex+1]
+	}
+	var buffer bytes.Buffer
+	buffer.Write(txCopy.SerializeNoWitness())
+	writeUint32(&buffer, hashType)
+	return DoubleSha256(buffer.Bytes()), nil
+}
+
+// removeCodeSeparators returns script without its OP_CODESEPARATOR operations, which are never signed in legacy signature hashes.
+// Scripts that do not parse are returned unchanged.
+func removeCodeSeparators(script []byte) []byte {
+	ops, err := ParseScript(script)
+	if err != nil {
+		return script
+	}
+	var remaining bytes.Buffer
+	for _, op := range ops {
+		if op.Opcode != OP_CODESEPARATOR {
+			writeScriptOp(&remaining, op)
+		}
+	}
+	return remaining.Bytes()
+}
+
+// WitnessSignatureHash computes the BIP143 signature hash for SegWit version 0 input inputIndex of tx.
+// scriptCode is the script being satisfied (the witnessScript for P2WSH) from just after the last OP_CODESEPARATOR executed,
+// signed as is, and amount is the value in satoshis of the output being spent, which BIP143 commits to so signers can't be
+// misled about the fee.
+func WitnessSignatureHash(tx *Transaction, inputIndex int, scriptCode []byte, amount int64, hashType uint32) ([]byte, error) {
+	if inputIndex < 0 || inputIndex >= len(tx.Inputs) {
+		return nil, errors.New(fmt.Sprintf("Input index %d out of range for transaction with %d inputs.", inputIndex, len(tx.Inputs)))
+	}
+	//Hashes of all outpoints, all sequence numbers and all outputs, shared between the inputs of a transaction.
+	//Each is left as 32 zero bytes when hashType does not commit to it.
+	baseType := hashType & 0x1f
+	anyoneCanPay := hashType&SIGHASH_ANYONECANPAY != 0
+	hashPrevouts, hashSequence, hashOutputs := make([]byte, 32), make([]byte, 32), make([]byte, 32)
+	var prevouts, sequences, outputs bytes.Buffer
+	for _, txIn := range tx.Inputs {
+		prevouts.Write(txIn.PreviousTxHash)
+		writeUint32(&prevouts, txIn.PreviousOutputIndex)
+		writeUint32(&sequences, txIn.Sequence)
+	}
+	if !anyoneCanPay {
+		hashPrevouts = DoubleSha256(prevouts.Bytes())
+	}
+	if !anyoneCanPay && baseType != SIGHASH_NONE && baseType != SIGHASH_SINGLE {
+		hashSequence = DoubleSha256(sequences.Bytes())
+	}
+	switch {
+	case baseType != SIGHASH_NONE && baseType != SIGHASH_SINGLE:
+		for _, txOut := range tx.Outputs {
+			writeUint64(&outputs, uint64(txOut.Value))
+			WriteVarBytes(&outputs, txOut.ScriptPubKey)
+		}
+		hashOutputs = DoubleSha256(outputs.Bytes())
+	case baseType == SIGHASH_SINGLE && inputIndex < len(tx.Outputs):
+		writeUint64(&outputs, uint64(tx.Outputs[inputIndex].Value))
+		WriteVarBytes(&outputs, tx.Outputs[inputIndex].ScriptPubKey)
+		hashOutputs = DoubleSha256(outputs.Bytes())
+	}
+	txIn := tx.Inputs[inputIndex]
+	var buffer bytes.Buffer
+	writeUint32(&buffer, uint32(tx.Version))
+	buffer.Write(hashPrevouts)
+	buffer.Write(hashSequence)
+	buffer.Write(txIn.PreviousTxHash)
+	writeUint32(&buffer, txIn.PreviousOutputIndex)
+	WriteVarBytes(&buffer, scriptCode)
+	writeUint64(&buffer, uint64(amount))
+	writeUint32(&buffer, txIn.Sequence)
+	buffer.Write(hashOutputs)
+	writeUint32(&buffer, tx.LockTime)
+	writeUint32(&buffer, hashType)
+	return DoubleSha256(buffer.Bytes()), nil
+}
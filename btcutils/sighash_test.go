@@ -0,0 +1,244 @@
+package btcutils
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+func TestSignatureHash(t *testing.T) {
+	//Signing the second input of a two input transaction. Expected hash cross-checked against btcsuite's txscript.
+	testRawTxHex := "0100000002acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a0000000000ffffffff3dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020300000000ffffffff02400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8730d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000"
+	testSubscriptHex := "76a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac"
+	testInputIndex := 1
+	testHashes := map[uint32]string{
+		SIGHASH_ALL:                           "76257f0096e1ecb234020d2b186bbe9331f0cfb443ea0e0b1a1ac125e1c5dda9",
+		SIGHASH_NONE:                          "1fc04c168612022b399d3b8a8643f870d53bbecbaf2941f32f707bdf2067a765",
+		SIGHASH_SINGLE:                        "a8772f92863857e074f6f17e302f6d168f7663698bb0cc3e254bdd3655c0d9b3",
+		SIGHASH_ALL | SIGHASH_ANYONECANPAY:    "1fa244e3ce1629beac16457452061b482e01efd81f3fc834f063d55fa3cf4a35",
+		SIGHASH_NONE | SIGHASH_ANYONECANPAY:   "31ab3e80e470728b6a69016daddc53576ddc64f7520ac1629a0738f2696a94f0",
+		SIGHASH_SINGLE | SIGHASH_ANYONECANPAY: "c7feca496c44fa49b3fafd7ed37831ab2ac0d98fa7f047d78c40a273c2cc662d",
+	}
+
+	rawTx, _ := hex.DecodeString(testRawTxHex)
+	tx, err := ParseTransaction(rawTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscript, _ := hex.DecodeString(testSubscriptHex)
+	for hashType, testHashHex := range testHashes {
+		hash, err := SignatureHash(tx, testInputIndex, subscript, hashType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashHex := hex.EncodeToString(hash)
+		if hashHex != testHashHex {
+			testutils.CompareError(t, "Signature hash different from expected hash for "+SigHashTypeName(hashType)+".", testHashHex, hashHex)
+		}
+	}
+	//The transaction being signed must not be modified
+	if hex.EncodeToString(tx.Serialize()) != testRawTxHex {
+		t.Error("SignatureHash modified the transaction being signed.")
+	}
+	if _, err := SignatureHash(tx, 2, subscript, SIGHASH_ALL); err == nil {
+		t.Error("SignatureHash accepting out of range input index.")
+	}
+	//SIGHASH_SINGLE without an output with the same index as the input signed gives the hash 1
+	tx.Outputs = tx.Outputs[:1]
+	testSingleBugHashHex := "0100000000000000000000000000000000000000000000000000000000000000"
+	for _, hashType := range []uint32{SIGHASH_SINGLE, SIGHASH_SINGLE | SIGHASH_ANYONECANPAY} {
+		hash, err := SignatureHash(tx, testInputIndex, subscript, hashType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashHex := hex.EncodeToString(hash)
+		if hashHex != testSingleBugHashHex {
+			testutils.CompareError(t, "SIGHASH_SINGLE signature hash without a matching output different from expected hash.", testSingleBugHashHex, hashHex)
+		}
+	}
+}
+
+// Legacy signature hash test vectors from Bitcoin Core's src/test/data/sighash.json, with random transactions, scripts
+// (including OP_CODESEPARATORs, 0xab) and 32 bit hash types. Expected hashes are in byte-reversed (RPC) order.
+var testSignatureHashVectors = []struct {
+	rawTxHex        string
+	subscriptHex    string
+	inputIndex      int
+	hashType        int32
+	reversedHashHex string
+}{
+	{
+		"8edcf5a1014b604e53f0d12fe143cf4284f86dc79a634a9f17d7e9f8725f7beb95e8ffcd2403000000046aabac52ffffffff01c402b5040000000005ab6a63525100000000",
+		"6351525251acabab6a", 0, 1520147826,
+		"2765bbdcd3ebb8b1a316c04656b28d637f80bffbe9b040661481d3dc83eea6d6",
+	},
+	{
+		"4763ed4401c3e6ab204bed280528e84d5288f9cac5fb8a2e7bd699c7b98d4df4ac0c40e55303000000066a6aacab5165ffffffff015b57f80400000000046a63535100000000",
+		"ac51abab53", 0, -592611747,
+		"849033a2321b5755e56ef4527ae6f51e30e3bca50149d5707368479723d744f8",
+	},
+	{
+		"24f24cd90132b2162f938f1c22d3ca5e7daa83515883f31a61a5177aebf99d7db6bdfc398c010000000163ffffffff01d5562d0100000000016300000000",
+		"5265ac5165ac5252ab", 0, 1055129103,
+		"5eeb03e03806cd7bfd44bbba69c30f84c2c5120df9e68cd8facc605fcfbc9693",
+	},
+	{
+		"6f62138301436f33a00b84a26a0457ccbfc0f82403288b9cbae39986b34357cb2ff9b889b302000000045253655335a7ff6701bac9960400000000086552ab656352635200000000",
+		"6aac51", 0, 1444414211,
+		"502a2435fd02898d2ff3ab08a3c19078414b32ec9b73d64a944834efc9dae10c",
+	},
+	{
+		"5c45d09801bb4d8e7679d857b86b97697472d514f8b76d862460e7421e8617b15a2df217c6010000000863acacab6565006affffffff01156dbc03000000000952ac63516551ac6aac00000000",
+		"6aabac", 0, 1310125891,
+		"270445ab77258ced2e5e22a6d0d8c36ac7c30fff9beefa4b3e981867b03fa0ad",
+	},
+	{
+		"d3b7421e011f4de0f1cea9ba7458bf3486bee722519efab711a963fa8c100970cf7488b7bb0200000003525352dcd61b300148be5d05000000000000000000",
+		"535251536aac536a", 0, -1960128125,
+		"29aa6d2d752d3310eba20442770ad345b7f6a35f96161ede5f07b33e92053e2a",
+	},
+	{
+		"009046a1023f266d0113556d604931374d7932b4d6a7952d08fbd9c9b87cbd83f4f4c178b4030000000452ac526346e73b438c4516c60edd5488023131f07acb5f9ea1540b3e84de92f4e3c432289781ea4900000000046500655357dfd6da02baef910100000000026a007d101703000000000800516500abacac5100000000",
+		"6aab6553ac", 0, -802456605,
+		"f8757fbb4448ca34e0cd41b997685b37238d331e70316659a9cc9087d116169d",
+	},
+	{
+		"b240517501334021240427adb0b413433641555424f6d24647211e3e6bfbb22a8045cbda2f000000000071bac8630112717802000000000000000000",
+		"6a5165abac52656551", 0, 1790414254,
+		"2c8be597620d95abd88f9c1cf4967c1ae3ca2309f3afec8928058c9598660e9e",
+	},
+	{
+		"c33028b301d5093e1e8397270d75a0b009b2a6509a01861061ab022ca122a6ba935b8513320200000000ffffffff013bcf5a0500000000015200000000",
+		"", 0, -513413204,
+		"6b1459536f51482f5dbf42d7e561896557461e1e3b6bf67871e2b51faae2832c",
+	},
+	{
+		"25ee54ef0187387564bb86e0af96baec54289ca8d15e81a507a2ed6668dc92683111dfb7a50100000004005263634cecf17d0429aa4d000000000007636a6aabab5263daa75601000000000251ab4df70a01000000000151980a890400000000065253ac6a006377fd24e3",
+		"65ab", 0, 797877378,
+		"069f38fd5d47abff46f04ee3ae27db03275e9aa4737fa0d2f5394779f9654845",
+	},
+	//Inputs other than the first, whose sequence numbers SIGHASH_NONE and SIGHASH_SINGLE leave out of the other inputs
+	{
+		"cf7bdc250249e22cbe23baf6b648328d31773ea0e771b3b76a48b4748d7fbd390e88a004d30000000003ac536a4ab8cce0e097136c90b2037f231b7fde2063017facd40ed4e5896da7ad00e9c71dd70ae600000000096a0063516352525365ffffffff01b71e3e00000000000300536a00000000",
+		"", 1, 546970113,
+		"6a815ba155270af102322c882f26d22da11c5330a751f520807936b320b9af5d",
+	},
+	{
+		"d5c1b16f0248c60a3ddccf7ebd1b3f260360bbdf2230577d1c236891a1993725e262e1b6cb000000000363636affffffff0a32362cfe68d25b243a015fc9aa172ea9c6b087c9e231474bb01824fd6bd8bc0300000005ab52ab516affffffff0420d9a70200000000045152656a45765d0000000000055252536a5277bad100000000000252ab3f3f3803000000000463acac5200000000",
+		"52636a52ab65", 1, 1305123906,
+		"978dc178ecd03d403b048213d904653979d11c51730381c96c4208e3ea24243a",
+	},
+	{
+		"ed3bb93802ddbd08cb030ef60a2247f715a0226de390c9c1a81d52e83f8674879065b5f87d0300000003ab6552ffffffff04d2c5e60a21fb6da8de20bf206db43b720e2a24ce26779bca25584c3f765d1e0200000008ab656a6aacab00ab6e946ded025a811d04000000000951abac6352ac00ab5143cfa3030000000005635200636a00000000",
+		"5352ac650065535300", 1, -668727133,
+		"e9995065e1fddef72a796eef5274de62012249660dc9d233a4f24e02a2979c87",
+	},
+	{
+		"1a28c4f702c8efaad96d879b38ec65c5283b5c084b819ad7db1c086e85e32446c7818dc7a90300000008656351536a525165fa78cef86c982f1aac9c5eb8b707aee8366f74574c8f42ef240599c955ef4401cf578be30200000002ab518893292204c430eb0100000000016503138a0300000000040053abac60e0eb010000000005525200ab63567c2d030000000004abab52006cf81e85",
+		"ab51525152", 1, 2118315905,
+		"4e4c9a781f626b59b1d3ad8f2c488eb6dee8bb19b9bc138bf0dc33e7799210d4",
+	},
+	{
+		"53bd749603798ed78798ef0f1861b498fc61dcee2ee0f2b37cddb115b118e73bc6a5a47a0201000000096a63656a6aab6a000007ff674a0d74f8b4be9d2e8e654840e99d533263adbdd0cf083fa1d5dd38e44d2d163d900100000007abab5251ac6a51c8b6b63f744a9b9273ccfdd47ceb05d3be6400c1ed0f7283d32b34a7f4f0889cccf06be30000000009516a52636551ab516a9ac1fe63030c677e05000000000027bc610000000000086565636a635100526e2dc60200000000015300000000",
+		"6552536a515351ab", 1, -1617066878,
+		"fe516df92299e995b8e6489be824c6839543071ec5e9286060b2600935bf1f20",
+	},
+	//SIGHASH_SINGLE past the last output, signing the second input of a transaction from sighash.json with one output, gives the hash 1
+	{
+		"73107cbd025c22ebc8c3e0a47b2a760739216a528de8d4dab5d45cbeb3051cebae73b01ca10200000007ab6353656a636affffffffe26816dffc670841e6a6c8c61c586da401df1261a330a6c6b3dd9f9a0789bc9e000000000800ac6552ac6aac51ffffffff0174a8f0010000000004ac52515100000000",
+		"5163ac63635151ac", 1, 3,
+		"0000000000000000000000000000000000000000000000000000000000000001",
+	},
+	{
+		"73107cbd025c22ebc8c3e0a47b2a760739216a528de8d4dab5d45cbeb3051cebae73b01ca10200000007ab6353656a636affffffffe26816dffc670841e6a6c8c61c586da401df1261a330a6c6b3dd9f9a0789bc9e000000000800ac6552ac6aac51ffffffff0174a8f0010000000004ac52515100000000",
+		"5163ac63635151ac", 1, -1660944253,
+		"0000000000000000000000000000000000000000000000000000000000000001",
+	},
+}
+
+func TestSignatureHashVectors(t *testing.T) {
+	for _, testVector := range testSignatureHashVectors {
+		rawTx, _ := hex.DecodeString(testVector.rawTxHex)
+		tx, err := ParseTransaction(rawTx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		subscript, _ := hex.DecodeString(testVector.subscriptHex)
+		hash, err := SignatureHash(tx, testVector.inputIndex, subscript, uint32(testVector.hashType))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reversedHashHex := hex.EncodeToString(ReverseBytes(hash))
+		if reversedHashHex != testVector.reversedHashHex {
+			testutils.CompareError(t, "Signature hash different from Bitcoin Core test vector.", testVector.reversedHashHex, reversedHashHex)
+		}
+	}
+}
+
+func TestWitnessSignatureHash(t *testing.T) {
+	//Native P2WPKH example from BIP143, signing the second input
+	testRawTxHex := "0100000002fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f0000000000eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac11000000"
+	testScriptCodeHex := "76a9141d0f172a0ecb48aee1be1f2687d2963ae33f71a188ac"
+	testInputIndex := 1
+	testAmount := int64(600000000)
+	//Hashes other than SIGHASH_ALL cross-checked against btcsuite's txscript
+	testHashes := map[uint32]string{
+		SIGHASH_ALL:                           "c37af31116d1b27caf68aae9e3ac82f1477929014d5b917657d0eb49478cb670",
+		SIGHASH_NONE:                          "6ff11a9b87fb510a3a31af006bd3811b632f8a39d88a2bfda49cee203dcc356e",
+		SIGHASH_SINGLE:                        "f4fe57286dd2ca8ac0e3dfccd54c352fcdcacbed80f194e264b75d7a7c74e4ce",
+		SIGHASH_ALL | SIGHASH_ANYONECANPAY:    "fc5b6bbc855883bcfdaefb77071740ccde4929f15e6a13286584e779b2529d91",
+		SIGHASH_NONE | SIGHASH_ANYONECANPAY:   "4abb5ef58a968f8e1ab88a9fb72f2ce74b3022e65d334ac7b8aeda747515dc15",
+		SIGHASH_SINGLE | SIGHASH_ANYONECANPAY: "79ff9ff708f79ce8f7a4f90d62028533a99d7340b7fb3d819dfd9a599a78e39c",
+	}
+
+	rawTx, _ := hex.DecodeString(testRawTxHex)
+	tx, err := ParseTransaction(rawTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scriptCode, _ := hex.DecodeString(testScriptCodeHex)
+	for hashType, testHashHex := range testHashes {
+		hash, err := WitnessSignatureHash(tx, testInputIndex, scriptCode, testAmount, hashType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashHex := hex.EncodeToString(hash)
+		if hashHex != testHashHex {
+			testutils.CompareError(t, "Witness signature hash different from expected hash for "+SigHashTypeName(hashType)+".", testHashHex, hashHex)
+		}
+	}
+	if _, err := WitnessSignatureHash(tx, 2, scriptCode, testAmount, SIGHASH_ALL); err == nil {
+		t.Error("WitnessSignatureHash accepting out of range input index.")
+	}
+}
+
+func TestParseSigHashType(t *testing.T) {
+	testNames := map[string]uint32{
+		"ALL":                                 SIGHASH_ALL,
+		"none":                                SIGHASH_NONE,
+		"SIGHASH_SINGLE":                      SIGHASH_SINGLE,
+		"ALL|ANYONECANPAY":                    SIGHASH_ALL | SIGHASH_ANYONECANPAY,
+		"none | anyonecanpay":                 SIGHASH_NONE | SIGHASH_ANYONECANPAY,
+		"SIGHASH_SINGLE|SIGHASH_ANYONECANPAY": SIGHASH_SINGLE | SIGHASH_ANYONECANPAY,
+	}
+	for name, testHashType := range testNames {
+		hashType, err := ParseSigHashType(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hashType != testHashType {
+			testutils.CompareError(t, "Signature hash type parsed from "+name+" different from expected type.", testHashType, hashType)
+		}
+		//Names given by SigHashTypeName parse back to the same type
+		hashType, err = ParseSigHashType(SigHashTypeName(testHashType))
+		if err != nil || hashType != testHashType {
+			t.Errorf("SigHashTypeName(%#x) does not parse back to the same signature hash type.", testHashType)
+		}
+	}
+	for _, name := range []string{"", "ANYONECANPAY", "ALL|NONE", "ALL|ANYONECANPAY|ANYONECANPAY", "DEFAULT"} {
+		if _, err := ParseSigHashType(name); err == nil {
+			t.Errorf("ParseSigHashType accepting invalid signature hash type %q.", name)
+		}
+	}
+}
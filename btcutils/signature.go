@@ -0,0 +1,123 @@
+// Provides deterministic ECDSA nonces and low-S normalization for signatures.
+// See https://tools.ietf.org/html/rfc6979 for deterministic nonces, and
+// https://github.com/bitcoin/bips/blob/master/bip-0146.mediawiki for the low-S rule.
+package btcutils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// secp256k1Order is the order N of the secp256k1 curve group.
+var secp256k1Order, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+
+// secp256k1HalfOrder is N/2. Signatures with S above it are high-S and non-standard.
+var secp256k1HalfOrder = new(big.Int).Rsh(secp256k1Order, 1)
+
+// newNonceRFC6979 derives the ECDSA nonce for signing hash with privateKey as per RFC6979 with HMAC-SHA256.
+// The nonce is unique to each private key and hash, so signatures are reproducible without ever reusing a nonce.
+func newNonceRFC6979(privateKey []byte, hash []byte) [32]byte {
+	//bits2octets(hash): reduce the hash modulo N, then pad to 32 bytes
+	hashInt := new(big.Int).SetBytes(hash)
+	hashInt.Mod(hashInt, secp256k1Order)
+	seed := append(padTo32Bytes(privateKey), padTo32Bytes(hashInt.Bytes())...)
+
+	v := bytes.Repeat([]byte{0x01}, 32)
+	k := make([]byte, 32)
+	k = hmacSha256(k, v, []byte{0x00}, seed)
+	v = hmacSha256(k, v)
+	k = hmacSha256(k, v, []byte{0x01}, seed)
+	v = hmacSha256(k, v)
+	for {
+		v = hmacSha256(k, v)
+		candidate := new(big.Int).SetBytes(v)
+		if candidate.Sign() > 0 && candidate.Cmp(secp256k1Order) < 0 {
+			var nonce [32]byte
+			copy(nonce[:], v)
+			return nonce
+		}
+		//Candidate out of range, which happens with negligible probability. Try the next one.
+		k = hmacSha256(k, v, []byte{0x00})
+		v = hmacSha256(k, v)
+	}
+}
+
+// hmacSha256 computes HMAC-SHA256 with key over the concatenation of data.
+func hmacSha256(key []byte, data ...[]byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+// padTo32Bytes left pads data with zero bytes to 32 bytes long.
+func padTo32Bytes(data []byte) []byte {
+	padded := make([]byte, 32)
+	copy(padded[32-len(data):], data)
+	return padded
+}
+
+// normalizeLowS takes a DER encoded signature and replaces S with N-S if S is above N/2.
+// Both forms are valid, but only low-S signatures are standard (BIP62) and, inside SegWit scripts, BIP146 requires them.
+func normalizeLowS(signature []byte) ([]byte, error) {
+	r, s, err := parseDERSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+	if s.Cmp(secp256k1HalfOrder) <= 0 {
+		return signature, nil
+	}
+	return encodeDERSignature(r, new(big.Int).Sub(secp256k1Order, s)), nil
+}
+
+// parseDERSignature splits a DER encoded signature into its R and S values.
+func parseDERSignature(signature []byte) (*big.Int, *big.Int, error) {
+	//DER signature format:
+	//0x30 <length> 0x02 <R length> <R> 0x02 <S length> <S>
+	if len(signature) < 8 || signature[0] != 0x30 || int(signature[1]) != len(signature)-2 {
+		return nil, nil, errors.New("Signature is not a DER encoded sequence.")
+	}
+	if signature[2] != 0x02 {
+		return nil, nil, errors.New("Signature R value is not a DER encoded integer.")
+	}
+	rLength := int(signature[3])
+	if 4+rLength+2 > len(signature) || signature[4+rLength] != 0x02 {
+		return nil, nil, errors.New("Signature S value is not a DER encoded integer.")
+	}
+	sLength := int(signature[5+rLength])
+	if 6+rLength+sLength != len(signature) {
+		return nil, nil, errors.New("Signature length does not match its R and S lengths.")
+	}
+	r := new(big.Int).SetBytes(signature[4 : 4+rLength])
+	s := new(big.Int).SetBytes(signature[6+rLength:])
+	return r, s, nil
+}
+
+// encodeDERSignature builds a DER encoded signature from its R and S values.
+func encodeDERSignature(r *big.Int, s *big.Int) []byte {
+	rBytes, sBytes := derInteger(r), derInteger(s)
+	var signature bytes.Buffer
+	signature.WriteByte(0x30)
+	signature.WriteByte(byte(4 + len(rBytes) + len(sBytes)))
+	signature.WriteByte(0x02)
+	signature.WriteByte(byte(len(rBytes)))
+	signature.Write(rBytes)
+	signature.WriteByte(0x02)
+	signature.WriteByte(byte(len(sBytes)))
+	signature.Write(sBytes)
+	return signature.Bytes()
+}
+
+// derInteger returns the minimal big-endian encoding of a positive integer for DER,
+// with a leading zero byte if the top bit is set so it is not read as negative.
+func derInteger(value *big.Int) []byte {
+	encoded := value.Bytes()
+	if len(encoded) == 0 || encoded[0]&0x80 != 0 {
+		encoded = append([]byte{0x00}, encoded...)
+	}
+	return encoded
+}
@@ -0,0 +1,62 @@
+package btcutils
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func TestNewNonceRFC6979(t *testing.T) {
+	//Test vectors from Bitcoin Core's and python-ecdsa's deterministic signing tests
+	testMessageHash := sha256.Sum256([]byte("Satoshi Nakamoto"))
+	testPrivateKeyNonces := map[string]string{
+		"0000000000000000000000000000000000000000000000000000000000000001": "8f8a276c19f4149656b280621e358cce24f5f52542772691ee69063b74f15d15",
+		"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140": "33a19b60e25fb6f4435af53a3d42d493644827367e6453928554f43e49aa6f90",
+	}
+
+	for privateKeyHex, testNonceHex := range testPrivateKeyNonces {
+		privateKey, _ := hex.DecodeString(privateKeyHex)
+		nonce := newNonceRFC6979(privateKey, testMessageHash[:])
+		nonceHex := hex.EncodeToString(nonce[:])
+		if nonceHex != testNonceHex {
+			testutils.CompareError(t, "RFC6979 nonce different from expected nonce.", testNonceHex, nonceHex)
+		}
+	}
+}
+
+func TestNormalizeLowS(t *testing.T) {
+	//Same signature with S = N - s, as produced by a signer without low-S normalization
+	testLowSSignatureHex := "3045022100b25e4cbe2ffab0e8e1b1669c7bcd656e0d504d8c3ccc202938c7cd1505b6db3b02200d286bffc63f90174be7e3dc7d594da2c9c9336f646f7bfca60f05d50f14b5bd"
+	lowSSignature, _ := hex.DecodeString(testLowSSignatureHex)
+	r, s, err := parseDERSignature(lowSSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	highSSignature := encodeDERSignature(r, new(big.Int).Sub(secp256k1Order, s))
+
+	for _, signature := range [][]byte{lowSSignature, highSSignature} {
+		normalizedSignature, err := normalizeLowS(signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+		normalizedSignatureHex := hex.EncodeToString(normalizedSignature)
+		if normalizedSignatureHex != testLowSSignatureHex {
+			testutils.CompareError(t, "Normalized signature different from expected low-S signature.", testLowSSignatureHex, normalizedSignatureHex)
+		}
+	}
+
+	invalidSignatureHexs := []string{
+		"", //empty signature
+		"3045022100b25e4cbe2ffab0e8e1b1669c7bcd656e0d504d8c3ccc202938c7cd1505b6db3b02200d286bffc63f90174be7e3dc7d594da2c9c9336f646f7bfca60f05d50f14b5",   //truncated S
+		"3145022100b25e4cbe2ffab0e8e1b1669c7bcd656e0d504d8c3ccc202938c7cd1505b6db3b02200d286bffc63f90174be7e3dc7d594da2c9c9336f646f7bfca60f05d50f14b5bd", //not a DER sequence
+	}
+	for _, signatureHex := range invalidSignatureHexs {
+		signature, _ := hex.DecodeString(signatureHex)
+		if _, err := normalizeLowS(signature); err == nil {
+			t.Error("normalizeLowS accepting invalid signature as valid:", signatureHex)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Provides redeem scripts with timelocks, with branches that can only be spent from a given block height or time,
+// or once the output being spent is a given number of blocks or amount of time old.
+// See https://github.com/bitcoin/bips/blob/master/bip-0065.mediawiki for OP_CHECKLOCKTIMEVERIFY,
+// https://github.com/bitcoin/bips/blob/master/bip-0068.mediawiki for relative locktimes in input sequence numbers
+// and https://github.com/bitcoin/bips/blob/master/bip-0112.mediawiki for OP_CHECKSEQUENCEVERIFY.
+package btcutils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// LockTimeThreshold separates the two kinds of locktime: values below it are block heights, and values from it on are Unix timestamps.
+const LockTimeThreshold = 500000000
+
+// BIP68 relative locktime fields of input sequence numbers, enforced for transactions of version 2 and later.
+const (
+	SequenceLockTimeDisabled    = 1 << 31    //Set for inputs without a relative locktime
+	SequenceLockTimeIsSeconds   = 1 << 22    //Set for relative locktimes in units of 512 seconds rather than blocks
+	SequenceLockTimeMask        = 0x0000ffff //Relative locktime, the number of blocks or 512 second units the output being spent must be old
+	SequenceLockTimeGranularity = 9          //Relative locktimes in time are in units of 2^9 = 512 seconds
+)
+
+// NewRecoveryRedeemScript creates a redeem script spendable by M of its N public keys at any time, or by recoveryPublicKey alone
+// once the spending transaction's locktime reaches lockTime, a block height or Unix timestamp (see LockTimeThreshold). Useful for
+// inheritance, or recovering funds after losing too many of the N keys.
+func NewRecoveryRedeemScript(m int, n int, publicKeys [][]byte, recoveryPublicKey []byte, lockTime uint32) ([]byte, error) {
+	multisigScript, err := NewMOfNRedeemScript(m, n, publicKeys)
+	if err != nil {
+		return nil, err
+	}
+	err = CheckPublicKeyIsValid(recoveryPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if lockTime == 0 {
+		return nil, errors.New("Recovery locktime must be a block height or Unix timestamp above 0.")
+	}
+	//Recovery redeemScript format:
+	//OP_IF <M-of-N multisig redeemScript> OP_ELSE <lockTime> OP_CHECKLOCKTIMEVERIFY OP_DROP <recovery pubkey> OP_CHECKSIG OP_ENDIF
+	var redeemScript bytes.Buffer
+	redeemScript.WriteByte(OP_IF)
+	redeemScript.Write(multisigScript)
+	redeemScript.WriteByte(OP_ELSE)
+	WriteScriptNumber(&redeemScript, int64(lockTime))
+	redeemScript.WriteByte(OP_CHECKLOCKTIMEVERIFY)
+	redeemScript.WriteByte(OP_DROP) //OP_CHECKLOCKTIMEVERIFY leaves the locktime on the stack
+	WritePushData(&redeemScript, recoveryPublicKey)
+	redeemScript.WriteByte(OP_CHECKSIG)
+	redeemScript.WriteByte(OP_ENDIF)
+	return redeemScript.Bytes(), nil
+}
+
+// ParseRecoveryRedeemScript recovers m, n, the n public keys, the recovery public key and the locktime from a redeem script
+// in the format created by NewRecoveryRedeemScript, returning an error for any other script.
+func ParseRecoveryRedeemScript(redeemScript []byte) (int, int, [][]byte, []byte, uint32, error) {
+	notRecoveryScript := errors.New("Redeem script is not a recovery script. Expected OP_IF <M-of-N multisig script> OP_ELSE <locktime> OP_CHECKLOCKTIMEVERIFY OP_DROP <recovery pubkey> OP_CHECKSIG OP_ENDIF.")
+	ops, err := ParseScript(redeemScript)
+	if err != nil {
+		return 0, 0, nil, nil, 0, err
+	}
+	//The recovery branch is the last 7 operations, from OP_ELSE to OP_ENDIF
+	if len(ops) < 11 || ops[0].Opcode != OP_IF || ops[len(ops)-7].Opcode != OP_ELSE {
+		return 0, 0, nil, nil, 0, notRecoveryScript
+	}
+	m, n, publicKeys, err := ParseMOfNRedeemScript(serializeScriptOps(ops[1 : len(ops)-7]))
+	if err != nil {
+		return 0, 0, nil, nil, 0, err
+	}
+	lockTime, err := parseScriptNumberOp(ops[len(ops)-6])
+	if err != nil {
+		return 0, 0, nil, nil, 0, err
+	}
+	recoveryPublicKey := ops[len(ops)-3].Data
+	if lockTime <= 0 || lockTime > math.MaxUint32 {
+		return 0, 0, nil, nil, 0, notRecoveryScript
+	}
+	//Anything else out of place makes the script differ from the one built from what was read
+	expectedScript, err := NewRecoveryRedeemScript(m, n, publicKeys, recoveryPublicKey, uint32(lockTime))
+	if err != nil || !bytes.Equal(redeemScript, expectedScript) {
+		return 0, 0, nil, nil, 0, notRecoveryScript
+	}
+	return m, n, publicKeys, recoveryPublicKey, uint32(lockTime), nil
+}
+
+// NewDelayedRedeemScript creates a redeem script spendable by M of its N public keys at any time, or by delayedM of them once
+// the output being spent is as old as the BIP68 relative locktime sequence, eg. "2-of-3 now, or 1-of-3 after 4320 blocks".
+// sequence is a number of blocks up to 65535, or with SequenceLockTimeIsSeconds set, of 512 second units.
+func NewDelayedRedeemScript(m int, n int, publicKeys [][]byte, delayedM int, sequence uint32) ([]byte, error) {
+	multisigScript, err := NewMOfNRedeemScript(m, n, publicKeys)
+	if err != nil {
+		return nil, err
+	}
+	if delayedM < 1 || delayedM >= m {
+		return nil, errors.New(fmt.Sprintf("Delayed M of %d must be between 1 and %d, fewer than the %d keys needed without the delay.", delayedM, m-1, m))
+	}
+	delayedScript, err := NewMOfNRedeemScript(delayedM, n, publicKeys)
+	if err != nil {
+		return nil, err
+	}
+	if sequence&^(SequenceLockTimeIsSeconds|SequenceLockTimeMask) != 0 || sequence&SequenceLockTimeMask == 0 {
+		return nil, errors.New(fmt.Sprintf("Relative locktime 0x%08x must be between 1 and %d blocks or 512 second units.", sequence, SequenceLockTimeMask))
+	}
+	//Delayed redeemScript format:
+	//OP_IF <M-of-N multisig redeemScript> OP_ELSE <sequence> OP_CHECKSEQUENCEVERIFY OP_DROP <delayedM-of-N multisig redeemScript> OP_ENDIF
+	var redeemScript bytes.Buffer
+	redeemScript.WriteByte(OP_IF)
+	redeemScript.Write(multisigScript)
+	redeemScript.WriteByte(OP_ELSE)
+	WriteScriptNumber(&redeemScript, int64(sequence))
+	redeemScript.WriteByte(OP_CHECKSEQUENCEVERIFY)
+	redeemScript.WriteByte(OP_DROP) //OP_CHECKSEQUENCEVERIFY leaves the sequence on the stack
+	redeemScript.Write(delayedScript)
+	redeemScript.WriteByte(OP_ENDIF)
+	return redeemScript.Bytes(), nil
+}
+
+// ParseDelayedRedeemScript recovers m, n, the n public keys, the delayed m and the relative locktime sequence from a redeem
+// script in the format created by NewDelayedRedeemScript, returning an error for any other script.
+func ParseDelayedRedeemScript(redeemScript []byte) (int, int, [][]byte, int, uint32, error) {
+	notDelayedScript := errors.New("Redeem script is not a delayed script. Expected OP_IF <M-of-N multisig script> OP_ELSE <sequence> OP_CHECKSEQUENCEVERIFY OP_DROP <M-of-N multisig script> OP_ENDIF.")
+	ops, err := ParseScript(redeemScript)
+	if err != nil {
+		return 0, 0, nil, 0, 0, err
+	}
+	//Both multisig scripts are N+3 operations long, with the 5 operations OP_IF, OP_ELSE, <sequence>, OP_CHECKSEQUENCEVERIFY and OP_DROP between them and OP_ENDIF at the end
+	if len(ops) < 14 || ops[0].Opcode != OP_IF || len(ops)%2 != 0 {
+		return 0, 0, nil, 0, 0, notDelayedScript
+	}
+	elseIndex := len(ops)/2 - 2
+	if ops[elseIndex].Opcode != OP_ELSE {
+		return 0, 0, nil, 0, 0, notDelayedScript
+	}
+	m, n, publicKeys, err := ParseMOfNRedeemScript(serializeScriptOps(ops[1:elseIndex]))
+	if err != nil {
+		return 0, 0, nil, 0, 0, err
+	}
+	delayedM, _, _, err := ParseMOfNRedeemScript(serializeScriptOps(ops[elseIndex+4 : len(ops)-1]))
+	if err != nil {
+		return 0, 0, nil, 0, 0, err
+	}
+	sequence, err := parseScriptNumberOp(ops[elseIndex+1])
+	if err != nil {
+		return 0, 0, nil, 0, 0, err
+	}
+	if sequence < 0 || sequence > math.MaxUint32 {
+		return 0, 0, nil, 0, 0, notDelayedScript
+	}
+	//Anything else out of place, including different public keys in the delayed multisig script, makes the script differ from the one built from what was read
+	expectedScript, err := NewDelayedRedeemScript(m, n, publicKeys, delayedM, uint32(sequence))
+	if err != nil || !bytes.Equal(redeemScript, expectedScript) {
+		return 0, 0, nil, 0, 0, notDelayedScript
+	}
+	return m, n, publicKeys, delayedM, uint32(sequence), nil
+}
+
+// serializeScriptOps returns the script made of ops.
+func serializeScriptOps(ops []ScriptOp) []byte {
+	var script bytes.Buffer
+	for _, op := range ops {
+		writeScriptOp(&script, op)
+	}
+	return script.Bytes()
+}
+
+// parseScriptNumberOp returns the number op pushes, as written by WriteScriptNumber, up to 5 bytes long.
+func parseScriptNumberOp(op ScriptOp) (int64, error) {
+	switch {
+	case op.Opcode == OP_1NEGATE:
+		return -1, nil
+	case op.Opcode >= OP_1 && op.Opcode <= OP_16:
+		return int64(op.Opcode) - (OP_1 - 1), nil
+	case op.Opcode <= OP_PUSHDATA4:
+		return parseScriptNumber(op.Data, true, 5)
+	}
+	return 0, errors.New(fmt.Sprintf("Script operation %s is not a number.", OpcodeName(op.Opcode)))
+}
@@ -0,0 +1,156 @@
+package btcutils
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+var testRecoveryPublicKeyHexs = []string{
+	"02ff12471208c14bd580709cb2358d98975247d8765f92bc25eab3b2763ed605f8",
+	"02fe6f0a5a297eb38c391581c4413e084773ea23954d93f7753db7dc0adc188b2f",
+	"03a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575", //Recovery key
+}
+
+func TestNewRecoveryRedeemScript(t *testing.T) {
+	testMultisigScriptHex := "522102ff12471208c14bd580709cb2358d98975247d8765f92bc25eab3b2763ed605f82102fe6f0a5a297eb38c391581c4413e084773ea23954d93f7753db7dc0adc188b2f52ae"
+	testCases := []struct {
+		lockTime        uint32
+		lockTimePushHex string
+	}{
+		{16, "60"},                 //OP_16
+		{700000, "0360ae0a"},       //Block height
+		{1700000000, "0400f15365"}, //Unix timestamp, 2023-11-14 22:13:20 UTC
+	}
+	publicKeys := make([][]byte, len(testRecoveryPublicKeyHexs))
+	for i, publicKeyHex := range testRecoveryPublicKeyHexs {
+		publicKeys[i], _ = hex.DecodeString(publicKeyHex)
+	}
+	for _, testCase := range testCases {
+		testRedeemScriptHex := "63" + testMultisigScriptHex + "67" + testCase.lockTimePushHex + "b17521" + testRecoveryPublicKeyHexs[2] + "ac68"
+		redeemScript, err := NewRecoveryRedeemScript(2, 2, publicKeys[:2], publicKeys[2], testCase.lockTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		redeemScriptHex := hex.EncodeToString(redeemScript)
+		if redeemScriptHex != testRedeemScriptHex {
+			testutils.CompareError(t, "Recovery redeem script different from expected script.", testRedeemScriptHex, redeemScriptHex)
+		}
+
+		//Parsing gives back what the script was created from
+		m, n, parsedPublicKeys, recoveryPublicKey, lockTime, err := ParseRecoveryRedeemScript(redeemScript)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m != 2 || n != 2 || lockTime != testCase.lockTime {
+			t.Errorf("Recovery redeem script parsed as %d-of-%d with locktime %d, expected 2-of-2 with locktime %d.", m, n, lockTime, testCase.lockTime)
+		}
+		if !reflect.DeepEqual(parsedPublicKeys, publicKeys[:2]) || !reflect.DeepEqual(recoveryPublicKey, publicKeys[2]) {
+			testutils.CompareError(t, "Parsed public keys different from expected keys.", publicKeys, append(parsedPublicKeys, recoveryPublicKey))
+		}
+	}
+
+	if _, err := NewRecoveryRedeemScript(2, 2, publicKeys[:2], publicKeys[2], 0); err == nil {
+		t.Error("NewRecoveryRedeemScript accepting locktime 0.")
+	}
+	if _, err := NewRecoveryRedeemScript(2, 2, publicKeys[:2], publicKeys[2][1:], 700000); err == nil {
+		t.Error("NewRecoveryRedeemScript accepting invalid recovery public key.")
+	}
+
+	recoveryScriptHex := "63" + testMultisigScriptHex + "670360ae0ab17521" + testRecoveryPublicKeyHexs[2] + "ac68"
+	invalidScriptHexs := []string{
+		testMultisigScriptHex,                        //M-of-N multisig script without recovery branch
+		recoveryScriptHex + "51",                     //extra operation after OP_ENDIF
+		recoveryScriptHex[:len(recoveryScriptHex)-2], //missing OP_ENDIF
+		"63" + testMultisigScriptHex + "670460ae0a00b17521" + testRecoveryPublicKeyHexs[2] + "ac68", //non-minimal locktime
+		"63" + testMultisigScriptHex + "6700b17521" + testRecoveryPublicKeyHexs[2] + "ac68",         //locktime 0
+		"63" + testMultisigScriptHex + "670360ae0ab27521" + testRecoveryPublicKeyHexs[2] + "ac68",   //OP_CHECKSEQUENCEVERIFY instead of OP_CHECKLOCKTIMEVERIFY
+		"64" + recoveryScriptHex[2:], //OP_NOTIF instead of OP_IF
+	}
+	for _, scriptHex := range invalidScriptHexs {
+		script, _ := hex.DecodeString(scriptHex)
+		if _, _, _, _, _, err := ParseRecoveryRedeemScript(script); err == nil {
+			t.Error("ParseRecoveryRedeemScript accepting invalid recovery script:", scriptHex)
+		}
+	}
+}
+
+func TestNewDelayedRedeemScript(t *testing.T) {
+	//2-of-3 now, or 1-of-3 after a delay
+	testPublicKeyHexs := []string{
+		"03a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575",
+		"036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d",
+		"0311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef",
+	}
+	testPublicKeysHex := "2103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef"
+	testCases := []struct {
+		sequence        uint32
+		sequencePushHex string
+	}{
+		{4320, "02e010"}, //About 30 days of blocks
+		{16, "60"},       //OP_16
+		{SequenceLockTimeIsSeconds | 169, "03a90040"}, //169*512 seconds, about a day
+	}
+	publicKeys := make([][]byte, len(testPublicKeyHexs))
+	for i, publicKeyHex := range testPublicKeyHexs {
+		publicKeys[i], _ = hex.DecodeString(publicKeyHex)
+	}
+	for _, testCase := range testCases {
+		testRedeemScriptHex := "6352" + testPublicKeysHex + "53ae67" + testCase.sequencePushHex + "b27551" + testPublicKeysHex + "53ae68"
+		redeemScript, err := NewDelayedRedeemScript(2, 3, publicKeys, 1, testCase.sequence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		redeemScriptHex := hex.EncodeToString(redeemScript)
+		if redeemScriptHex != testRedeemScriptHex {
+			testutils.CompareError(t, "Delayed redeem script different from expected script.", testRedeemScriptHex, redeemScriptHex)
+		}
+
+		//Parsing gives back what the script was created from
+		m, n, parsedPublicKeys, delayedM, sequence, err := ParseDelayedRedeemScript(redeemScript)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m != 2 || n != 3 || delayedM != 1 || sequence != testCase.sequence {
+			t.Errorf("Delayed redeem script parsed as %d-of-%d, or %d after 0x%08x, expected 2-of-3, or 1 after 0x%08x.", m, n, delayedM, sequence, testCase.sequence)
+		}
+		if !reflect.DeepEqual(parsedPublicKeys, publicKeys) {
+			testutils.CompareError(t, "Parsed public keys different from expected keys.", publicKeys, parsedPublicKeys)
+		}
+	}
+
+	invalidDelays := []struct {
+		delayedM int
+		sequence uint32
+	}{
+		{2, 4320},                            //as many delayed keys as without the delay
+		{0, 4320},                            //no delayed keys
+		{1, 0},                               //no delay
+		{1, SequenceLockTimeDisabled | 4320}, //relative locktime disabled
+		{1, 0x10000},                         //more than 65535 blocks
+	}
+	for _, invalidDelay := range invalidDelays {
+		if _, err := NewDelayedRedeemScript(2, 3, publicKeys, invalidDelay.delayedM, invalidDelay.sequence); err == nil {
+			t.Errorf("NewDelayedRedeemScript accepting %d delayed keys after 0x%08x.", invalidDelay.delayedM, invalidDelay.sequence)
+		}
+	}
+
+	delayedScriptHex := "6352" + testPublicKeysHex + "53ae6702e010b27551" + testPublicKeysHex + "53ae68"
+	otherPublicKeysHex := "2103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d2102ff12471208c14bd580709cb2358d98975247d8765f92bc25eab3b2763ed605f8"
+	invalidScriptHexs := []string{
+		"52" + testPublicKeysHex + "53ae", //M-of-N multisig script without delayed branch
+		delayedScriptHex + "51",           //extra operation after OP_ENDIF
+		"6352" + testPublicKeysHex + "53ae6702e010b27551" + otherPublicKeysHex + "53ae68",  //different public keys in the delayed branch
+		"6352" + testPublicKeysHex + "53ae6703e01000b27551" + testPublicKeysHex + "53ae68", //non-minimal sequence
+		"6352" + testPublicKeysHex + "53ae6702e010b17551" + testPublicKeysHex + "53ae68",   //OP_CHECKLOCKTIMEVERIFY instead of OP_CHECKSEQUENCEVERIFY
+		"6352" + testPublicKeysHex + "53ae6702e010b27552" + testPublicKeysHex + "53ae68",   //same M with and without the delay
+	}
+	for _, scriptHex := range invalidScriptHexs {
+		script, _ := hex.DecodeString(scriptHex)
+		if _, _, _, _, _, err := ParseDelayedRedeemScript(script); err == nil {
+			t.Error("ParseDelayedRedeemScript accepting invalid delayed script:", scriptHex)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+// Provides the Bitcoin transaction data model and its wire serialization.
+// See https://en.bitcoin.it/wiki/Protocol_documentation#tx for full specification.
+package btcutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BIP144 marker and flag bytes signalling witness serialization.
+const (
+	witnessMarker = 0x00
+	witnessFlag   = 0x01
+)
+
+var errTruncatedTransaction = errors.New("Transaction is truncated.")
+
+// DefaultTxVersion is the transaction version used for newly created transactions.
+const DefaultTxVersion = 1
+
+// MaxTxInSequenceNum is the default and final sequence number for transaction inputs.
+const MaxTxInSequenceNum = 0xffffffff
+
+// MaxRBFSequenceNum is the highest input sequence number signalling the transaction may be replaced by one paying a higher fee.
+// See https://github.com/bitcoin/bips/blob/master/bip-0125.mediawiki for replace-by-fee.
+const MaxRBFSequenceNum = MaxTxInSequenceNum - 2
+
+// TxIn is a single transaction input, spending output PreviousOutputIndex of the transaction PreviousTxHash.
+type TxIn struct {
+	PreviousTxHash      []byte //Previous transaction hash in internal (little-endian) byte order
+	PreviousOutputIndex uint32
+	ScriptSig           []byte
+	Sequence            uint32
+	Witness             [][]byte //Segregated witness stack items, empty for legacy inputs
+}
+
+// TxOut is a single transaction output paying Value satoshis to ScriptPubKey.
+type TxOut struct {
+	Value        int64
+	ScriptPubKey []byte
+}
+
+// Transaction is a Bitcoin transaction with any number of inputs and outputs.
+type Transaction struct {
+	Version  int32
+	Inputs   []*TxIn
+	Outputs  []*TxOut
+	LockTime uint32
+}
+
+// NewTransaction creates an empty transaction with the default version and a locktime of 0.
+func NewTransaction() *Transaction {
+	return &Transaction{Version: DefaultTxVersion}
+}
+
+// NewTxIn creates a transaction input given the input transaction hash (as hex, in the usual big-endian
+// form shown by block explorers), the output index being spent and the scriptSig.
+func NewTxIn(inputTxHash string, outputIndex uint32, scriptSig []byte) (*TxIn, error) {
+	inputTxBytes, err := hex.DecodeString(inputTxHash)
+	if err != nil {
+		return nil, err
+	}
+	if len(inputTxBytes) != 32 {
+		return nil, errors.New(fmt.Sprintf("Input transaction hash should be 32 bytes long. Provided hash is %d bytes long.", len(inputTxBytes)))
+	}
+	return &TxIn{
+		PreviousTxHash:      ReverseBytes(inputTxBytes), //Convert input transaction hash to little-endian form
+		PreviousOutputIndex: outputIndex,
+		ScriptSig:           scriptSig,
+		Sequence:            MaxTxInSequenceNum,
+	}, nil
+}
+
+// NewTxOut creates a transaction output sending satoshis to scriptPubKey.
+func NewTxOut(satoshis int64, scriptPubKey []byte) *TxOut {
+	return &TxOut{
+		Value:        satoshis,
+		ScriptPubKey: scriptPubKey,
+	}
+}
+
+// AddInput appends txIn to the transaction inputs.
+func (tx *Transaction) AddInput(txIn *TxIn) {
+	tx.Inputs = append(tx.Inputs, txIn)
+}
+
+// AddOutput appends txOut to the transaction outputs.
+func (tx *Transaction) AddOutput(txOut *TxOut) {
+	tx.Outputs = append(tx.Outputs, txOut)
+}
+
+// Copy returns a deep copy of the transaction, so scripts can be substituted (eg. for signing) without
+// modifying the original.
+func (tx *Transaction) Copy() *Transaction {
+	txCopy := &Transaction{
+		Version:  tx.Version,
+		Inputs:   make([]*TxIn, len(tx.Inputs)),
+		Outputs:  make([]*TxOut, len(tx.Outputs)),
+		LockTime: tx.LockTime,
+	}
+	for i, txIn := range tx.Inputs {
+		txCopy.Inputs[i] = &TxIn{
+			PreviousTxHash:      append([]byte(nil), txIn.PreviousTxHash...),
+			PreviousOutputIndex: txIn.PreviousOutputIndex,
+			ScriptSig:           append([]byte(nil), txIn.ScriptSig...),
+			Sequence:            txIn.Sequence,
+		}
+		for _, item := range txIn.Witness {
+			txCopy.Inputs[i].Witness = append(txCopy.Inputs[i].Witness, append([]byte(nil), item...))
+		}
+	}
+	for i, txOut := range tx.Outputs {
+		txCopy.Outputs[i] = NewTxOut(txOut.Value, append([]byte(nil), txOut.ScriptPubKey...))
+	}
+	return txCopy
+}
+
+// HasWitness returns true if any input carries segregated witness data.
+func (tx *Transaction) HasWitness() bool {
+	for _, txIn := range tx.Inputs {
+		if len(txIn.Witness) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SignalsReplacement returns true if any input has a sequence number of at most MaxRBFSequenceNum, opting the transaction in to
+// BIP125 replace-by-fee.
+func (tx *Transaction) SignalsReplacement() bool {
+	for _, txIn := range tx.Inputs {
+		if txIn.Sequence <= MaxRBFSequenceNum {
+			return true
+		}
+	}
+	return false
+}
+
+// Serialize returns the raw transaction bytes as broadcast on the Bitcoin network.
+// Transactions with witness data use the BIP144 serialization with marker and flag bytes.
+func (tx *Transaction) Serialize() []byte {
+	return tx.serialize(tx.HasWitness())
+}
+
+// SerializeNoWitness returns the raw transaction bytes without any witness data,
+// as used to compute the transaction ID.
+func (tx *Transaction) SerializeNoWitness() []byte {
+	return tx.serialize(false)
+}
+
+func (tx *Transaction) serialize(withWitness bool) []byte {
+	var buffer bytes.Buffer
+	writeUint32(&buffer, uint32(tx.Version))
+	if withWitness {
+		buffer.WriteByte(witnessMarker)
+		buffer.WriteByte(witnessFlag)
+	}
+	WriteVarInt(&buffer, uint64(len(tx.Inputs)))
+	for _, txIn := range tx.Inputs {
+		buffer.Write(txIn.PreviousTxHash)
+		writeUint32(&buffer, txIn.PreviousOutputIndex)
+		WriteVarBytes(&buffer, txIn.ScriptSig)
+		writeUint32(&buffer, txIn.Sequence)
+	}
+	WriteVarInt(&buffer, uint64(len(tx.Outputs)))
+	for _, txOut := range tx.Outputs {
+		writeUint64(&buffer, uint64(txOut.Value))
+		WriteVarBytes(&buffer, txOut.ScriptPubKey)
+	}
+	if withWitness {
+		for _, txIn := range tx.Inputs {
+			WriteVarInt(&buffer, uint64(len(txIn.Witness)))
+			for _, item := range txIn.Witness {
+				WriteVarBytes(&buffer, item)
+			}
+		}
+	}
+	writeUint32(&buffer, tx.LockTime)
+	return buffer.Bytes()
+}
+
+// TxHash returns the transaction ID as hex, in the usual big-endian form shown by block explorers.
+func (tx *Transaction) TxHash() string {
+	return hex.EncodeToString(ReverseBytes(DoubleSha256(tx.SerializeNoWitness())))
+}
+
+// WitnessHash returns the BIP141 witness transaction ID (wtxid) as hex. Equal to TxHash for legacy transactions.
+func (tx *Transaction) WitnessHash() string {
+	return hex.EncodeToString(ReverseBytes(DoubleSha256(tx.Serialize())))
+}
+
+// Weight returns the BIP141 transaction weight: 3 times the size without witness plus the full size.
+func (tx *Transaction) Weight() int {
+	return 3*len(tx.SerializeNoWitness()) + len(tx.Serialize())
+}
+
+// VirtualSize returns the transaction virtual size in vbytes, the weight divided by 4 and rounded up.
+func (tx *Transaction) VirtualSize() int {
+	return (tx.Weight() + 3) / 4
+}
+
+// ParseTransaction decodes raw transaction bytes, with or without BIP144 witness data, into a Transaction.
+func ParseTransaction(rawTransaction []byte) (*Transaction, error) {
+	reader := bytes.NewReader(rawTransaction)
+	tx := &Transaction{}
+	version, err := readUint32(reader)
+	if err != nil {
+		return nil, err
+	}
+	tx.Version = int32(version)
+	inputCount, err := ReadVarInt(reader)
+	if err != nil {
+		return nil, err
+	}
+	//A zero input count is the BIP144 marker, followed by a non-zero flag byte
+	withWitness := false
+	if inputCount == witnessMarker {
+		flag, err := reader.ReadByte()
+		if err != nil {
+			return nil, errTruncatedTransaction
+		}
+		if flag != witnessFlag {
+			return nil, errors.New(fmt.Sprintf("Unknown witness flag 0x%02x in transaction.", flag))
+		}
+		withWitness = true
+		inputCount, err = ReadVarInt(reader)
+		if err != nil {
+			return nil, err
+		}
+	}
+	//Every input takes at least 41 bytes, so larger counts can only come from corrupt data
+	if inputCount > uint64(reader.Len()/41) {
+		return nil, errors.New(fmt.Sprintf("Transaction input count %d is larger than the transaction.", inputCount))
+	}
+	for i := uint64(0); i < inputCount; i++ {
+		txIn := &TxIn{PreviousTxHash: make([]byte, 32)}
+		if _, err := io.ReadFull(reader, txIn.PreviousTxHash); err != nil {
+			return nil, errTruncatedTransaction
+		}
+		if txIn.PreviousOutputIndex, err = readUint32(reader); err != nil {
+			return nil, err
+		}
+		if txIn.ScriptSig, err = ReadVarBytes(reader); err != nil {
+			return nil, err
+		}
+		if txIn.Sequence, err = readUint32(reader); err != nil {
+			return nil, err
+		}
+		tx.Inputs = append(tx.Inputs, txIn)
+	}
+	outputCount, err := ReadVarInt(reader)
+	if err != nil {
+		return nil, err
+	}
+	//Every output takes at least 9 bytes
+	if outputCount > uint64(reader.Len()/9) {
+		return nil, errors.New(fmt.Sprintf("Transaction output count %d is larger than the transaction.", outputCount))
+	}
+	for i := uint64(0); i < outputCount; i++ {
+		value, err := readUint64(reader)
+		if err != nil {
+			return nil, err
+		}
+		scriptPubKey, err := ReadVarBytes(reader)
+		if err != nil {
+			return nil, err
+		}
+		tx.AddOutput(NewTxOut(int64(value), scriptPubKey))
+	}
+	if withWitness {
+		for _, txIn := range tx.Inputs {
+			itemCount, err := ReadVarInt(reader)
+			if err != nil {
+				return nil, err
+			}
+			if itemCount > uint64(reader.Len()) {
+				return nil, errTruncatedTransaction
+			}
+			for j := uint64(0); j < itemCount; j++ {
+				item, err := ReadVarBytes(reader)
+				if err != nil {
+					return nil, err
+				}
+				txIn.Witness = append(txIn.Witness, item)
+			}
+		}
+		if !tx.HasWitness() {
+			return nil, errors.New("Transaction uses witness serialization but has no witness data.")
+		}
+	}
+	if tx.LockTime, err = readUint32(reader); err != nil {
+		return nil, err
+	}
+	if reader.Len() != 0 {
+		return nil, errors.New(fmt.Sprintf("Transaction has %d unexpected trailing bytes.", reader.Len()))
+	}
+	return tx, nil
+}
+
+// WriteVarInt writes n to buffer using the variable length integer encoding from the protocol spec.
+func WriteVarInt(buffer *bytes.Buffer, n uint64) {
+	switch {
+	case n < 0xfd:
+		buffer.WriteByte(byte(n))
+	case n <= 0xffff:
+		buffer.WriteByte(0xfd) //Signifies that next two bytes are 2-byte representation of n
+		lengthBytes := make([]byte, 2)
+		binary.LittleEndian.PutUint16(lengthBytes, uint16(n))
+		buffer.Write(lengthBytes)
+	case n <= 0xffffffff:
+		buffer.WriteByte(0xfe) //Next four bytes
+		writeUint32(buffer, uint32(n))
+	default:
+		buffer.WriteByte(0xff) //Next eight bytes
+		writeUint64(buffer, n)
+	}
+}
+
+// WriteVarBytes writes data to buffer prefixed with its length as a variable length integer.
+func WriteVarBytes(buffer *bytes.Buffer, data []byte) {
+	WriteVarInt(buffer, uint64(len(data)))
+	buffer.Write(data)
+}
+
+// ReverseBytes returns a reversed copy of data, used to convert hashes between
+// internal little-endian byte order and the big-endian hex form shown to users.
+func ReverseBytes(data []byte) []byte {
+	reversed := make([]byte, len(data))
+	for i := 0; i < len(data); i++ {
+		reversed[i] = data[len(data)-i-1]
+	}
+	return reversed
+}
+
+// ReadVarInt reads a variable length integer as encoded by WriteVarInt.
+func ReadVarInt(reader io.Reader) (uint64, error) {
+	var prefix [1]byte
+	if _, err := io.ReadFull(reader, prefix[:]); err != nil {
+		return 0, errTruncatedTransaction
+	}
+	switch prefix[0] {
+	case 0xfd:
+		nBytes := make([]byte, 2)
+		if _, err := io.ReadFull(reader, nBytes); err != nil {
+			return 0, errTruncatedTransaction
+		}
+		return uint64(binary.LittleEndian.Uint16(nBytes)), nil
+	case 0xfe:
+		n, err := readUint32(reader)
+		return uint64(n), err
+	case 0xff:
+		return readUint64(reader)
+	}
+	return uint64(prefix[0]), nil
+}
+
+// ReadVarBytes reads data prefixed with its length as a variable length integer, as written by WriteVarBytes.
+func ReadVarBytes(reader *bytes.Reader) ([]byte, error) {
+	length, err := ReadVarInt(reader)
+	if err != nil {
+		return nil, err
+	}
+	if length > uint64(reader.Len()) {
+		return nil, errTruncatedTransaction
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, errTruncatedTransaction
+	}
+	return data, nil
+}
+
+func readUint32(reader io.Reader) (uint32, error) {
+	nBytes := make([]byte, 4)
+	if _, err := io.ReadFull(reader, nBytes); err != nil {
+		return 0, errTruncatedTransaction
+	}
+	return binary.LittleEndian.Uint32(nBytes), nil
+}
+
+func readUint64(reader io.Reader) (uint64, error) {
+	nBytes := make([]byte, 8)
+	if _, err := io.ReadFull(reader, nBytes); err != nil {
+		return 0, errTruncatedTransaction
+	}
+	return binary.LittleEndian.Uint64(nBytes), nil
+}
+
+func writeUint32(buffer *bytes.Buffer, n uint32) {
+	nBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(nBytes, n)
+	buffer.Write(nBytes)
+}
+
+func writeUint64(buffer *bytes.Buffer, n uint64) {
+	nBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(nBytes, n)
+	buffer.Write(nBytes)
+}
@@ -0,0 +1,176 @@
+package btcutils
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestTransactionSerialize(t *testing.T) {
+	//Two inputs (second one spending output index 3) and two outputs
+	testInputTxs := []string{
+		"3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac",
+		"02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d",
+	}
+	testInputIndexes := []uint32{0, 3}
+	testScriptPubKeys := []string{
+		"a9141a8b0026343166625c7475f01e48b5ede8c0252e87",
+		"76a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac",
+	}
+	testAmounts := []int64{65600, 55600}
+	testRawTxHex := "0100000002acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a0000000000ffffffff3dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020300000000ffffffff02400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8730d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000"
+
+	tx := NewTransaction()
+	for i, inputTx := range testInputTxs {
+		txIn, err := NewTxIn(inputTx, testInputIndexes[i], nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx.AddInput(txIn)
+	}
+	for i, scriptPubKeyHex := range testScriptPubKeys {
+		scriptPubKey, _ := hex.DecodeString(scriptPubKeyHex)
+		tx.AddOutput(NewTxOut(testAmounts[i], scriptPubKey))
+	}
+	rawTxHex := hex.EncodeToString(tx.Serialize())
+	if rawTxHex != testRawTxHex {
+		testutils.CompareError(t, "Serialized transaction different from expected transaction.", testRawTxHex, rawTxHex)
+	}
+}
+
+func TestNewTxIn(t *testing.T) {
+	invalidInputTxs := []string{
+		"",                 //empty hash
+		"3ad337270ac0ba14", //wrong length hash
+		"3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6zz", //not hex
+	}
+	for _, inputTx := range invalidInputTxs {
+		if _, err := NewTxIn(inputTx, 0, nil); err == nil {
+			t.Error("NewTxIn accepting invalid input transaction hash as valid.")
+		}
+	}
+}
+
+func TestSignalsReplacement(t *testing.T) {
+	testSequences := []struct {
+		sequences   []uint32
+		replaceable bool
+	}{
+		{[]uint32{MaxTxInSequenceNum}, false},
+		{[]uint32{MaxTxInSequenceNum - 1}, false}, //Enables locktime only
+		{[]uint32{MaxRBFSequenceNum}, true},
+		{[]uint32{4320}, true}, //BIP68 relative locktimes signal too
+		{[]uint32{MaxTxInSequenceNum, MaxRBFSequenceNum}, true},
+	}
+	for _, testSequence := range testSequences {
+		tx := NewTransaction()
+		for _, sequence := range testSequence.sequences {
+			txIn, err := NewTxIn("3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac", 0, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			txIn.Sequence = sequence
+			tx.AddInput(txIn)
+		}
+		if tx.SignalsReplacement() != testSequence.replaceable {
+			t.Errorf("Transaction with input sequence numbers %x signalling replace-by-fee is %v, expected %v.", testSequence.sequences, tx.SignalsReplacement(), testSequence.replaceable)
+		}
+	}
+}
+
+func TestWriteVarInt(t *testing.T) {
+	testCases := []struct {
+		n        uint64
+		expected string
+	}{
+		{0, "00"},
+		{252, "fc"},
+		{253, "fdfd00"},
+		{515, "fd0302"},
+		{0xffff, "fdffff"},
+		{0x10000, "fe00000100"},
+		{0x100000000, "ff0000000001000000"},
+	}
+	for _, testCase := range testCases {
+		var buffer bytes.Buffer
+		WriteVarInt(&buffer, testCase.n)
+		got := hex.EncodeToString(buffer.Bytes())
+		if got != testCase.expected {
+			testutils.CompareError(t, "Variable length integer different from expected encoding.", testCase.expected, got)
+		}
+	}
+}
+
+func TestParseTransaction(t *testing.T) {
+	testCases := []struct {
+		rawTxHex    string
+		txHash      string
+		witnessHash string
+		inputs      int
+		outputs     int
+		witnesses   []int
+	}{
+		{
+			//Legacy transaction
+			rawTxHex:    "0100000001da69765bad9cc46a70480a153b8e229c41f38eecb57699693d5c4444e036e0c20000000000ffffffff01c0380200000000001976a914870212de342646df8eb8874964f78ae2929f063e88ac00000000",
+			txHash:      "09b85fe6c04ed3983d7189f228b5c88ab5018227ee64dcd6b5b62f6ef8fbbadd",
+			witnessHash: "09b85fe6c04ed3983d7189f228b5c88ab5018227ee64dcd6b5b62f6ef8fbbadd",
+			inputs:      1,
+			outputs:     1,
+			witnesses:   []int{0},
+		},
+		{
+			//Segwit transaction, native P2WPKH example from BIP143
+			rawTxHex:    "01000000000102fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f00000000494830450221008b9d1dc26ba6a9cb62127b02742fa9d754cd3bebf337f7a55d114c8e5cdd30be022040529b194ba3f9281a99f2b1c0a19c0489bc22ede944ccf4ecbab4cc618ef3ed01eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac000247304402203609e17b84f6a7d30c80bfa610b5b4542f32a8a0d5447a12fb1366d7f01cc44a0220573a954c4518331561406f90300e8f3358f51928d43c212a8caed02de67eebee0121025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeeb357511000000",
+			txHash:      "e8151a2af31c368a35053ddd4bdb285a8595c769a3ad83e0fa02314a602d4609",
+			witnessHash: "65eac7cf5ef94461af87ced1bccf9bd56ca182697e653600be4fc41a4244d873",
+			inputs:      2,
+			outputs:     2,
+			witnesses:   []int{0, 2},
+		},
+	}
+	for _, testCase := range testCases {
+		rawTx, _ := hex.DecodeString(testCase.rawTxHex)
+		tx, err := ParseTransaction(rawTx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tx.Inputs) != testCase.inputs || len(tx.Outputs) != testCase.outputs {
+			t.Errorf("Parsed transaction has %d inputs and %d outputs, expected %d and %d.", len(tx.Inputs), len(tx.Outputs), testCase.inputs, testCase.outputs)
+		}
+		for i, witnessItems := range testCase.witnesses {
+			if len(tx.Inputs[i].Witness) != witnessItems {
+				t.Errorf("Parsed input #%d has %d witness items, expected %d.", i, len(tx.Inputs[i].Witness), witnessItems)
+			}
+		}
+		if tx.TxHash() != testCase.txHash {
+			testutils.CompareError(t, "Transaction ID different from expected ID.", testCase.txHash, tx.TxHash())
+		}
+		if tx.WitnessHash() != testCase.witnessHash {
+			testutils.CompareError(t, "Witness transaction ID different from expected ID.", testCase.witnessHash, tx.WitnessHash())
+		}
+		//Round trip back to the same bytes
+		if !bytes.Equal(tx.Serialize(), rawTx) {
+			testutils.CompareError(t, "Reserialized transaction different from parsed transaction.", testCase.rawTxHex, hex.EncodeToString(tx.Serialize()))
+		}
+	}
+}
+
+func TestParseTransactionInvalid(t *testing.T) {
+	invalidRawTxHexs := []string{
+		"",         //empty transaction
+		"01000000", //truncated after version
+		"0100000001da69765bad9cc46a70480a153b8e229c41f38eecb57699693d5c4444e036e0c20000000000ffffffff01c0380200000000001976a914870212de342646df8eb8874964f78ae2929f063e88ac000000",     //truncated locktime
+		"0100000001da69765bad9cc46a70480a153b8e229c41f38eecb57699693d5c4444e036e0c20000000000ffffffff01c0380200000000001976a914870212de342646df8eb8874964f78ae2929f063e88ac0000000000", //trailing bytes
+		"0100000001da69765bad9cc46a70480a153b8e229c41f38eecb57699693d5c4444e036e0c200000000ff",                                                                                         //scriptSig longer than transaction
+		"010000000002", //unknown witness flag
+	}
+	for _, rawTxHex := range invalidRawTxHexs {
+		rawTx, _ := hex.DecodeString(rawTxHex)
+		if _, err := ParseTransaction(rawTx); err == nil {
+			t.Error("ParseTransaction accepting invalid transaction as valid:", rawTxHex)
+		}
+	}
+}
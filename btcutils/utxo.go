@@ -0,0 +1,96 @@
+// Provides descriptions of unspent transaction outputs (UTXOs) being spent, with the amount and scriptPubKey that signing and fee calculation need.
+// See https://en.bitcoin.it/wiki/Transaction#Input for how inputs refer to the outputs they spend.
+package btcutils
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MaxSatoshis is the most Satoshis any output can hold, the 21 million Bitcoin supply limit.
+const MaxSatoshis = 21000000 * 100000000
+
+// UTXO is an unspent transaction output being spent by a new transaction.
+type UTXO struct {
+	TxHash       string //Transaction ID as hex, in the usual big-endian form shown by block explorers
+	Index        uint32 //Output index (vout) within the transaction
+	Amount       int64  //Amount in Satoshis, 0 if unknown
+	ScriptPubKey []byte //scriptPubKey locking the output, nil if unknown
+}
+
+// ParseUTXO parses a UTXO descriptor of the form TXID:VOUT, TXID:VOUT:AMOUNT or TXID:VOUT:AMOUNT:SCRIPTPUBKEY,
+// with the amount in Satoshis and the scriptPubKey as hex.
+func ParseUTXO(descriptor string) (*UTXO, error) {
+	parts := strings.Split(strings.TrimSpace(descriptor), ":")
+	if len(parts) < 2 || len(parts) > 4 {
+		return nil, errors.New(fmt.Sprintf("UTXO '%s' should be given as TXID:VOUT, TXID:VOUT:AMOUNT or TXID:VOUT:AMOUNT:SCRIPTPUBKEY.", descriptor))
+	}
+	txHash := strings.ToLower(parts[0])
+	txHashBytes, err := hex.DecodeString(txHash)
+	if err != nil || len(txHashBytes) != 32 {
+		return nil, errors.New(fmt.Sprintf("UTXO transaction ID '%s' should be 64 hex characters.", parts[0]))
+	}
+	index, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("UTXO output index '%s' should be a whole number, eg. 0 for the first output.", parts[1]))
+	}
+	utxo := &UTXO{TxHash: txHash, Index: uint32(index)}
+	if len(parts) > 2 {
+		amount, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil || amount <= 0 || amount > MaxSatoshis {
+			return nil, errors.New(fmt.Sprintf("UTXO amount '%s' should be a whole number of Satoshis from 1 to %d.", parts[2], int64(MaxSatoshis)))
+		}
+		utxo.Amount = amount
+	}
+	if len(parts) > 3 {
+		utxo.ScriptPubKey, err = hex.DecodeString(parts[3])
+		if err != nil || len(utxo.ScriptPubKey) == 0 {
+			return nil, errors.New(fmt.Sprintf("UTXO scriptPubKey '%s' should be given as hex.", parts[3]))
+		}
+	}
+	return utxo, nil
+}
+
+// UTXO returns output index of the transaction as a UTXO, with its amount and scriptPubKey.
+func (tx *Transaction) UTXO(index uint32) (*UTXO, error) {
+	if int(index) >= len(tx.Outputs) {
+		return nil, errors.New(fmt.Sprintf("Transaction %s has %d outputs, so has no output index %d.", tx.TxHash(), len(tx.Outputs), index))
+	}
+	output := tx.Outputs[index]
+	return &UTXO{
+		TxHash:       tx.TxHash(),
+		Index:        index,
+		Amount:       output.Value,
+		ScriptPubKey: append([]byte(nil), output.ScriptPubKey...),
+	}, nil
+}
+
+// Merge fills in the amount and scriptPubKey of the UTXO from other, a description of the same output from elsewhere,
+// returning an error if the two disagree about the output being spent.
+func (utxo *UTXO) Merge(other *UTXO) error {
+	if utxo.TxHash != other.TxHash || utxo.Index != other.Index {
+		return errors.New(fmt.Sprintf("UTXO %s is a different output to UTXO %s.", utxo, other))
+	}
+	if utxo.Amount != 0 && other.Amount != 0 && utxo.Amount != other.Amount {
+		return errors.New(fmt.Sprintf("UTXO %s amount is given as both %d and %d satoshi.", utxo, utxo.Amount, other.Amount))
+	}
+	if utxo.ScriptPubKey != nil && other.ScriptPubKey != nil && !bytes.Equal(utxo.ScriptPubKey, other.ScriptPubKey) {
+		return errors.New(fmt.Sprintf("UTXO %s scriptPubKey is given as both %x and %x.", utxo, utxo.ScriptPubKey, other.ScriptPubKey))
+	}
+	if utxo.Amount == 0 {
+		utxo.Amount = other.Amount
+	}
+	if utxo.ScriptPubKey == nil {
+		utxo.ScriptPubKey = other.ScriptPubKey
+	}
+	return nil
+}
+
+// String returns the UTXO as TXID:VOUT.
+func (utxo *UTXO) String() string {
+	return fmt.Sprintf("%s:%d", utxo.TxHash, utxo.Index)
+}
@@ -0,0 +1,104 @@
+package btcutils
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestParseUTXO(t *testing.T) {
+	testTxHash := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testScriptPubKeyHex := "a9141a8b0026343166625c7475f01e48b5ede8c0252e87"
+	testCases := []struct {
+		descriptor   string
+		index        uint32
+		amount       int64
+		scriptPubKey string
+	}{
+		{testTxHash + ":0", 0, 0, ""},
+		{testTxHash + ":7", 7, 0, ""},
+		{testTxHash + ":1:65600", 1, 65600, ""},
+		{testTxHash + ":0:65600:" + testScriptPubKeyHex, 0, 65600, testScriptPubKeyHex},
+		{"3AD337270AC0BA14FBCE812291B7D95338C878709EA8123A4D88C3C29EFBC6AC:0", 0, 0, ""}, //Upper case transaction ID
+	}
+	for _, testCase := range testCases {
+		utxo, err := ParseUTXO(testCase.descriptor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if utxo.TxHash != testTxHash || utxo.Index != testCase.index || utxo.Amount != testCase.amount || hex.EncodeToString(utxo.ScriptPubKey) != testCase.scriptPubKey {
+			testutils.CompareError(t, "Parsed UTXO different from expected UTXO.", testCase, utxo)
+		}
+	}
+
+	invalidDescriptors := []string{
+		"",                                      //empty
+		testTxHash,                              //missing output index
+		testTxHash + ":",                        //empty output index
+		testTxHash + ":-1",                      //negative output index
+		testTxHash + ":4294967296",              //output index too large
+		testTxHash[2:] + ":0",                   //short transaction ID
+		"zz" + testTxHash[2:] + ":0",            //transaction ID not hex
+		testTxHash + ":0:0",                     //zero amount
+		testTxHash + ":0:0.00065600",            //amount in Bitcoin
+		testTxHash + ":0:2100000000000001",      //amount above supply limit
+		testTxHash + ":0:65600:a914a",           //odd length scriptPubKey
+		testTxHash + ":0:65600:",                //empty scriptPubKey
+		testTxHash + ":0:65600:" + "a914:extra", //too many parts
+	}
+	for _, invalidDescriptor := range invalidDescriptors {
+		if _, err := ParseUTXO(invalidDescriptor); err == nil {
+			t.Error("ParseUTXO accepting invalid UTXO descriptor as valid:", invalidDescriptor)
+		}
+	}
+}
+
+func TestTransactionUTXO(t *testing.T) {
+	//Funding transaction of the 2-of-3 P2SH multisig address 347N1Thc213QqfYCz3PZkjoJpNv5b14kBd
+	testTransactionHex := "0100000001acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000008b483045022100fb244ac83b257f4233920077819dfa5203a11cd330c58a37c984699bc8048e9102200caca5b3772022a5cb5ce8e31f644da4e27e2c4f121cfd9b5291e3bccf7017d701410431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddfffffffff01400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8700000000"
+	testTxHash := "57a3bf6123f1f9ad095f0b285236cd0f9e312225b868cf7e1c8c8d7be4cba08e"
+	testScriptPubKeyHex := "a9141a8b0026343166625c7475f01e48b5ede8c0252e87"
+
+	rawTransaction, _ := hex.DecodeString(testTransactionHex)
+	transaction, err := ParseTransaction(rawTransaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	utxo, err := transaction.UTXO(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if utxo.String() != testTxHash+":0" || utxo.Amount != 65600 || hex.EncodeToString(utxo.ScriptPubKey) != testScriptPubKeyHex {
+		testutils.CompareError(t, "Transaction UTXO different from expected UTXO.", testTxHash+":0:65600:"+testScriptPubKeyHex, utxo)
+	}
+	if _, err := transaction.UTXO(1); err == nil {
+		t.Error("Transaction UTXO accepting output index past the last output.")
+	}
+}
+
+func TestUTXOMerge(t *testing.T) {
+	testTxHash := "57a3bf6123f1f9ad095f0b285236cd0f9e312225b868cf7e1c8c8d7be4cba08e"
+	testScriptPubKey, _ := hex.DecodeString("a9141a8b0026343166625c7475f01e48b5ede8c0252e87")
+
+	utxo := &UTXO{TxHash: testTxHash, Index: 0, Amount: 65600}
+	err := utxo.Merge(&UTXO{TxHash: testTxHash, Index: 0, Amount: 65600, ScriptPubKey: testScriptPubKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if utxo.Amount != 65600 || !bytes.Equal(utxo.ScriptPubKey, testScriptPubKey) {
+		testutils.CompareError(t, "Merged UTXO different from expected UTXO.", testScriptPubKey, utxo.ScriptPubKey)
+	}
+
+	conflictingUTXOs := []*UTXO{
+		{TxHash: testTxHash, Index: 1},                             //different output index
+		{TxHash: testTxHash, Index: 0, Amount: 65601},              //different amount
+		{TxHash: testTxHash, Index: 0, ScriptPubKey: []byte{0x51}}, //different scriptPubKey
+	}
+	for _, conflictingUTXO := range conflictingUTXOs {
+		if err := utxo.Merge(conflictingUTXO); err == nil {
+			t.Error("UTXO Merge accepting conflicting UTXO:", conflictingUTXO)
+		}
+	}
+}
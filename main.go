@@ -10,49 +10,207 @@ import (
 
 // Kingpin configurations for command-line subcommands and their respective flags.
 var (
-	app = kingpin.New("go-bitcoin-multisig", "A Bitcoin multisig transaction builder built in Go")
+	app        = kingpin.New("go-bitcoin-multisig", "A Bitcoin multisig transaction builder built in Go")
+	appNetwork = app.Flag("network", "Bitcoin network to use: mainnet, testnet3, signet or regtest. Keys and addresses of other networks are rejected.").Default("mainnet").String()
 
 	//keys subcommand
-	cmdKeys        = app.Command("keys", "Generate public/private key pairs valid for use on Bitcoin network. **PSEUDORANDOM AND FOR DEMONSTRATION PURPOSES ONLY. DO NOT USE IN PRODUCTION.**")
-	cmdKeysCount   = cmdKeys.Flag("count", "No. of key pairs to generate.").Default("1").Int()
-	cmdKeysConcise = cmdKeys.Flag("concise", "Turn on concise output. Default is off (verbose output).").Default("false").Bool()
+	cmdKeys           = app.Command("keys", "Generate public/private key pairs valid for use on Bitcoin network. **PSEUDORANDOM AND FOR DEMONSTRATION PURPOSES ONLY. DO NOT USE IN PRODUCTION.**")
+	cmdKeysCount      = cmdKeys.Flag("count", "No. of key pairs to generate.").Default("1").Int()
+	cmdKeysConcise    = cmdKeys.Flag("concise", "Turn on concise output. Default is off (verbose output).").Default("false").Bool()
+	cmdKeysCompressed = cmdKeys.Flag("compressed", "Generate 33 byte compressed public keys, which nearly halve the size of multisig scripts. Default is off (65 byte uncompressed keys).").Default("false").Bool()
+	cmdKeysHD         = cmdKeys.Flag("hd", "Derive key pairs from a new BIP32 HD master key, printing its xprv and the hardened BIP48 account xpub (m/48'/0'/0'/2') to share with cosigners. HD keys are always compressed. Default is off (independent random keys).").Default("false").Bool()
+	cmdKeysPassphrase = cmdKeys.Flag("passphrase", "Optional BIP39 passphrase protecting the --hd mnemonic. The same passphrase is needed to restore keys. ASCII characters only, as it is not NFKD normalized.").String()
+	cmdKeysMnemonic   = cmdKeys.Flag("mnemonic", "BIP39 mnemonic printed by 'keys --hd', to recreate its keys with 'keys restore'.").String()
+	cmdKeysAction     = cmdKeys.Arg("action", "Optional action. 'restore' recreates HD key pairs from --mnemonic instead of generating new ones.").String()
 	//address subcommand
-	cmdAddress           = app.Command("address", "Generate a multisig P2SH address with M-of-N requirements and set of public keys.")
-	cmdAddressM          = cmdAddress.Flag("m", "M, the minimum number of keys needed to spend Bitcoin in M-of-N multisig transaction.").Required().Int()
-	cmdAddressN          = cmdAddress.Flag("n", "N, the total number of possible keys that can be used to spend Bitcoin in M-of-N multisig transaction.").Required().Int()
-	cmdAddressPublicKeys = cmdAddress.Flag("public-keys", "Comma separated list of private keys to sign with. Whitespace is stripped and quotes may be placed around keys. Eg. key1,key2,\"key3\"").PlaceHolder("PUBLIC-KEYS(Comma separated)").Required().String()
+	cmdAddress                 = app.Command("address", "Generate a multisig P2SH or P2WSH address with M-of-N requirements and set of public keys.")
+	cmdAddressM                = cmdAddress.Flag("m", "M, the minimum number of keys needed to spend Bitcoin in M-of-N multisig transaction.").Required().Int()
+	cmdAddressN                = cmdAddress.Flag("n", "N, the total number of possible keys that can be used to spend Bitcoin in M-of-N multisig transaction.").Required().Int()
+	cmdAddressPublicKeys       = cmdAddress.Flag("public-keys", "Comma separated list of public keys to create the address from, in hex. Whitespace is stripped and quotes may be placed around keys. Eg. key1,key2,\"key3\"").PlaceHolder("PUBLIC-KEYS(Comma separated)").String()
+	cmdAddressType             = cmdAddress.Flag("type", "Address type: p2sh (legacy, starts with '3'), p2wsh (native SegWit, bech32 starting with 'bc1') or p2sh-p2wsh (SegWit nested in P2SH, starts with '3'). SegWit types need compressed public keys.").Default("p2sh").String()
+	cmdAddressSort             = cmdAddress.Flag("sort", "Sort public keys as per BIP67, so the address depends only on the set of keys and not their order. Requires compressed public keys. Default is off (keys in the order given).").Default("false").Bool()
+	cmdAddressXpubs            = cmdAddress.Flag("xpubs", "Comma separated list of N cosigner account xpubs with key origins, as printed by 'keys --hd', eg. [3442193e/48'/0'/0'/2']xpub..., used instead of --public-keys. Public keys are derived at path 0/INDEX of each account xpub.").PlaceHolder("XPUBS(Comma separated)").String()
+	cmdAddressIndex            = cmdAddress.Flag("index", "With --xpubs, derivation index of the first address to generate.").Default("0").Int()
+	cmdAddressCount            = cmdAddress.Flag("count", "With --xpubs, number of consecutive addresses to generate from --index.").Default("1").Int()
+	cmdAddressRecoveryKey      = cmdAddress.Flag("recovery-key", "Public key, in hex, that can spend alone from --recovery-locktime on, as well as M of the N keys at any time. Default is none (M-of-N multisig only).").String()
+	cmdAddressRecoveryLockTime = cmdAddress.Flag("recovery-locktime", "With --recovery-key, block height (below 500000000) or Unix timestamp from which the recovery key can spend, checked with OP_CHECKLOCKTIMEVERIFY.").Default("0").Int()
+	cmdAddressDelayedM         = cmdAddress.Flag("delayed-m", "Fewer than M keys that can spend once the funds are --delay blocks old, as well as M of the N keys at any time. Default is none (M-of-N multisig only).").Default("0").Int()
+	cmdAddressDelay            = cmdAddress.Flag("delay", "With --delayed-m, number of blocks (up to 65535) funds must be in the blockchain before the delayed M keys can spend, checked with OP_CHECKSEQUENCEVERIFY.").Default("0").Int()
 	//fund subcommand
-	cmdFund            = app.Command("fund", "Fund multisig address from a standard Bitcoin address.")
-	cmdFundPrivateKey  = cmdFund.Flag("private-key", "Private key of bitcoin to send.").Required().String()
-	cmdFundInputTx     = cmdFund.Flag("input-tx", "Input transaction hash of bitcoin to send.").Required().String()
-	cmdFundAmount      = cmdFund.Flag("amount", "Amount of bitcoin to send in satoshi (100,000,000 satoshi = 1 bitcoin).").Required().Int()
-	cmdFundDestination = cmdFund.Flag("destination", "Destination address. For P2SH, this should start with '3'.").Required().String()
+	cmdFund              = app.Command("fund", "Fund multisig address from a standard Bitcoin address.")
+	cmdFundPrivateKey    = cmdFund.Flag("private-key", "Private key of bitcoin to send.").Required().String()
+	cmdFundInputTx       = cmdFund.Flag("input-tx", "Input transaction hash of bitcoin to send. Not needed with --input or --prev-tx.").String()
+	cmdFundInputIndex    = cmdFund.Flag("input-index", "Output index (vout) of bitcoin to send within the input transaction.").Default("0").Int()
+	cmdFundAmount        = cmdFund.Flag("amount", "Amount of bitcoin to send in satoshi (100,000,000 satoshi = 1 bitcoin).").Required().Int()
+	cmdFundDestination   = cmdFund.Flag("destination", "Destination address of any type: P2PKH, P2SH, P2WPKH, P2WSH or P2TR. Usually a multisig address starting with '3' or 'bc1'.").Required().String()
+	cmdFundInputAmount   = cmdFund.Flag("input-amount", "Amount in satoshi of the bitcoin being spent. Needed, unless given with --input or --prev-tx, to work out the fee and check it against --max-fee, or --unknown-fee must be given.").Default("0").Int()
+	cmdFundInput         = cmdFund.Flag("input", "Output being spent as TXID:VOUT, TXID:VOUT:AMOUNT or TXID:VOUT:AMOUNT:SCRIPTPUBKEY, used instead of --input-tx and --input-index. A scriptPubKey given is checked against the private key.").String()
+	cmdFundPrevTx        = cmdFund.Flag("prev-tx", "Raw hex of the input transaction, to read the amount and scriptPubKey of the output being spent from.").String()
+	cmdFundChangeAddress = cmdFund.Flag("change-address", "Address to send the balance left over after the amount and fee to. Needs --fee or --fee-rate. Default is none (balance left over is paid as fee).").String()
+	cmdFundFee           = cmdFund.Flag("fee", "Transaction fee in satoshi.").Default("0").Int()
+	cmdFundFeeRate       = cmdFund.Flag("fee-rate", "Transaction fee rate in satoshi per virtual byte (sat/vB), used instead of --fee.").Default("0").Float()
+	cmdFundMaxFee        = cmdFund.Flag("max-fee", "Highest transaction fee in satoshi allowed. Transactions with a larger fee are refused.").Default("100000").Int()
+	cmdFundSigHash       = cmdFund.Flag("sighash", "Signature hash type: ALL, NONE or SINGLE, optionally followed by |ANYONECANPAY (eg. ALL|ANYONECANPAY). Default is ALL (signatures commit to every input and output).").Default("ALL").String()
+	cmdFundLockTime      = cmdFund.Flag("locktime", "Block height (below 500000000) or Unix timestamp before which the transaction cannot be mined. Default is 0 (none).").Default("0").Int()
+	cmdFundNoRBF         = cmdFund.Flag("no-rbf", "Do not signal replace-by-fee (BIP125). Default is off (the transaction can be replaced by one paying a higher fee until it is mined).").Default("false").Bool()
+	cmdFundUnknownFee    = cmdFund.Flag("unknown-fee", "Build the transaction even though the input amount is unknown, paying all of the input not sent as fee without checking it against --max-fee. Default is off (--input-amount, --input with an amount or --prev-tx is needed).").Default("false").Bool()
 	//spend subcommand
-	cmdSpend             = app.Command("spend", "Spend multisig balance by sending to a standard Bitcoin address.")
-	cmdSpendPrivateKeys  = cmdSpend.Flag("private-keys", "Comma separated list of private keys to sign with. Whitespace is stripped and quotes may be placed around keys. Eg. key1,key2,\"key3\"").PlaceHolder("PRIVATE-KEYS(Comma separated)").Required().String()
-	cmdSpendDestination  = cmdSpend.Flag("destination", "Public destination address to send bitcoins.").Required().String()
-	cmdSpendRedeemScript = cmdSpend.Flag("redeemScript", "Hex representation of redeem script that matches redeem script in P2SH input transaction.").Required().String()
-	cmdSpendInputTx      = cmdSpend.Flag("input-tx", "Input transaction hash of bitcoin to send.").Required().String()
-	cmdSpendAmount       = cmdSpend.Flag("amount", "Amount of bitcoin to send in satoshi (100,000,000 satoshi = 1 bitcoin).").Required().Int()
+	cmdSpend              = app.Command("spend", "Spend multisig balance by sending to a Bitcoin address of any type, including another multisig address.")
+	cmdSpendPrivateKeys   = cmdSpend.Flag("private-keys", "Comma separated list of private keys to sign with, in any order. Only the first M in redeem script order sign. Whitespace is stripped and quotes may be placed around keys. Eg. key1,key2,\"key3\"").PlaceHolder("PRIVATE-KEYS(Comma separated)").Required().String()
+	cmdSpendDestination   = cmdSpend.Flag("destination", "Destination address to send bitcoins, of any type: P2PKH, P2SH, P2WPKH, P2WSH or P2TR.").Required().String()
+	cmdSpendRedeemScript  = cmdSpend.Flag("redeemScript", "Hex representation of redeem script that matches redeem script in P2SH input transaction, or of witness script for P2WSH and P2SH-P2WSH.").Required().String()
+	cmdSpendInputTx       = cmdSpend.Flag("input-tx", "Input transaction hash of bitcoin to send. Not needed with --input or --prev-tx.").String()
+	cmdSpendInputIndex    = cmdSpend.Flag("input-index", "Output index (vout) of P2SH funds within the input transaction.").Default("0").Int()
+	cmdSpendAmount        = cmdSpend.Flag("amount", "Amount of bitcoin to send in satoshi (100,000,000 satoshi = 1 bitcoin).").Required().Int()
+	cmdSpendType          = cmdSpend.Flag("type", "Type of multisig address being spent: p2sh, p2wsh or p2sh-p2wsh.").Default("p2sh").String()
+	cmdSpendInputAmount   = cmdSpend.Flag("input-amount", "Amount in satoshi of the multisig funds being spent. Required for p2wsh and p2sh-p2wsh, since SegWit signatures commit to it. Needed for p2sh too, unless given with --input or --prev-tx, to work out the fee and check it against --max-fee, or --unknown-fee must be given.").Default("0").Int()
+	cmdSpendInput         = cmdSpend.Flag("input", "Output being spent as TXID:VOUT, TXID:VOUT:AMOUNT or TXID:VOUT:AMOUNT:SCRIPTPUBKEY, used instead of --input-tx and --input-index. A scriptPubKey given is checked against the redeem script and --type.").String()
+	cmdSpendPrevTx        = cmdSpend.Flag("prev-tx", "Raw hex of the input transaction, to read the amount and scriptPubKey of the multisig output being spent from.").String()
+	cmdSpendSort          = cmdSpend.Flag("sort", "Check the redeem script is BIP67 sorted, as made with 'address --sort'. Default is off. Private keys may be given in any order either way.").Default("false").Bool()
+	cmdSpendChangeAddress = cmdSpend.Flag("change-address", "Address to send the balance left over after the amount and fee to, eg. back to the multisig address. Needs --fee or --fee-rate. Default is none (balance left over is paid as fee).").String()
+	cmdSpendFee           = cmdSpend.Flag("fee", "Transaction fee in satoshi.").Default("0").Int()
+	cmdSpendFeeRate       = cmdSpend.Flag("fee-rate", "Transaction fee rate in satoshi per virtual byte (sat/vB), used instead of --fee.").Default("0").Float()
+	cmdSpendMaxFee        = cmdSpend.Flag("max-fee", "Highest transaction fee in satoshi allowed. Transactions with a larger fee are refused.").Default("100000").Int()
+	cmdSpendSigHash       = cmdSpend.Flag("sighash", "Signature hash type: ALL, NONE or SINGLE, optionally followed by |ANYONECANPAY (eg. ALL|ANYONECANPAY). Default is ALL (signatures commit to every input and output).").Default("ALL").String()
+	cmdSpendLockTime      = cmdSpend.Flag("locktime", "Block height (below 500000000) or Unix timestamp before which the transaction cannot be mined. Default is 0 (none), or the redeem script's recovery locktime with --recovery.").Default("0").Int()
+	cmdSpendDelayed       = cmdSpend.Flag("delayed", "Sign with the delayed M keys of a redeem script made with 'address --delayed-m', once the multisig funds are as old as its delay. Default is off.").Default("false").Bool()
+	cmdSpendNoRBF         = cmdSpend.Flag("no-rbf", "Do not signal replace-by-fee (BIP125). Default is off (the transaction can be replaced by one paying a higher fee until it is mined).").Default("false").Bool()
+	cmdSpendUnknownFee    = cmdSpend.Flag("unknown-fee", "Build the transaction even though the input amount is unknown, paying all of the input not sent as fee without checking it against --max-fee. Default is off (--input-amount, --input with an amount or --prev-tx is needed).").Default("false").Bool()
+	cmdSpendRecovery      = cmdSpend.Flag("recovery", "Sign with the recovery key of a redeem script made with 'address --recovery-key', instead of M of its N keys. Default is off.").Default("false").Bool()
+	//decode subcommand
+	cmdDecode            = app.Command("decode", "Decode a raw transaction into human-readable form.")
+	cmdDecodeTransaction = cmdDecode.Flag("transaction", "Hex representation of raw transaction, eg. as output by fund or spend.").Required().String()
+	cmdDecodeJSON        = cmdDecode.Flag("json", "Output JSON in the format of Bitcoin Core's decoderawtransaction. Default is off (human-readable output).").Default("false").Bool()
+	//verify subcommand
+	cmdVerify            = app.Command("verify", "Verify a signed transaction by running each input's scriptSig and witness against the output it spends.")
+	cmdVerifyTransaction = cmdVerify.Flag("transaction", "Hex representation of signed raw transaction, eg. as output by fund or spend.").Required().String()
+	cmdVerifyPrevTxs     = cmdVerify.Flag("prev-txs", "Comma separated list of raw hex transactions whose outputs the transaction spends.").PlaceHolder("PREV-TXS(Comma separated)").String()
+	cmdVerifyUtxos       = cmdVerify.Flag("utxos", "Comma separated list of outputs the transaction spends, as TXID:VOUT:AMOUNT:SCRIPTPUBKEY. Used instead of --prev-txs.").PlaceHolder("UTXOS(Comma separated)").String()
+	cmdVerifyConsensus   = cmdVerify.Flag("consensus", "Check only consensus rules. Default is off (standardness rules nodes relay by are checked too).").Default("false").Bool()
+	//inspect subcommand
+	cmdInspect             = app.Command("inspect", "Show the M, N, public keys and addresses of a multisig redeem script, to confirm what it can be spent by.")
+	cmdInspectRedeemScript = cmdInspect.Flag("redeemScript", "Hex representation of multisig redeem script or witness script, as output by address.").Required().String()
+	//script subcommands
+	cmdScript             = app.Command("script", "Convert scripts, eg. redeem scripts, between hex and human-readable ASM.")
+	cmdScriptDecode       = cmdScript.Command("decode", "Disassemble a hex script into ASM.")
+	cmdScriptDecodeScript = cmdScriptDecode.Flag("script", "Hex representation of script, eg. a redeem script as output by address.").Required().String()
+	cmdScriptEncode       = cmdScript.Command("encode", "Assemble ASM into a hex script.")
+	cmdScriptEncodeAsm    = cmdScriptEncode.Flag("asm", "Script in ASM: opcode names (eg. OP_CHECKMULTISIG) and hex data pushes separated by spaces.").Required().String()
+	//psbt subcommands
+	cmdPsbt                   = app.Command("psbt", "Spend multisig balance with Partially Signed Bitcoin Transactions (BIP174), so each cosigner signs separately.")
+	cmdPsbtCreate             = cmdPsbt.Command("create", "Create an unsigned PSBT spending multisig P2SH funds to a Bitcoin address of any type.")
+	cmdPsbtCreateDestination  = cmdPsbtCreate.Flag("destination", "Destination address to send bitcoins, of any type: P2PKH, P2SH, P2WPKH, P2WSH or P2TR.").Required().String()
+	cmdPsbtCreateRedeemScript = cmdPsbtCreate.Flag("redeemScript", "Hex representation of redeem script that matches redeem script in P2SH input transaction.").Required().String()
+	cmdPsbtCreateInputTx      = cmdPsbtCreate.Flag("input-tx", "Input transaction hash of bitcoin to send. Not needed with --input or --prev-tx.").String()
+	cmdPsbtCreateInputIndex   = cmdPsbtCreate.Flag("input-index", "Output index (vout) of P2SH funds within the input transaction.").Default("0").Int()
+	cmdPsbtCreateAmount       = cmdPsbtCreate.Flag("amount", "Amount of bitcoin to send in satoshi (100,000,000 satoshi = 1 bitcoin).").Required().Int()
+	cmdPsbtCreateInput        = cmdPsbtCreate.Flag("input", "Output being spent as TXID:VOUT, TXID:VOUT:AMOUNT or TXID:VOUT:AMOUNT:SCRIPTPUBKEY, used instead of --input-tx and --input-index.").String()
+	cmdPsbtCreatePrevTx       = cmdPsbtCreate.Flag("prev-tx", "Raw hex of the input transaction, included in the PSBT so cosigners can check the output they sign.").String()
+	cmdPsbtCreateChange       = cmdPsbtCreate.Flag("change-address", "Address to send the balance left over after the amount and fee to, eg. back to the multisig address. Needs --fee or --fee-rate. Needed to raise the fee later with bump-fee. Default is none (balance left over is paid as fee).").String()
+	cmdPsbtCreateFee          = cmdPsbtCreate.Flag("fee", "Transaction fee in satoshi. Needs the input amount, from --input or --prev-tx.").Default("0").Int()
+	cmdPsbtCreateFeeRate      = cmdPsbtCreate.Flag("fee-rate", "Transaction fee rate in satoshi per virtual byte (sat/vB) once cosigners have signed, used instead of --fee.").Default("0").Float()
+	cmdPsbtCreateMaxFee       = cmdPsbtCreate.Flag("max-fee", "Highest transaction fee in satoshi allowed. Transactions with a larger fee are refused.").Default("100000").Int()
+	cmdPsbtCreateSigHash      = cmdPsbtCreate.Flag("sighash", "Signature hash type: ALL, NONE or SINGLE, optionally followed by |ANYONECANPAY (eg. ALL|ANYONECANPAY). Cosigners sign with this type. Default is ALL (signatures commit to every input and output).").Default("ALL").String()
+	cmdPsbtCreateNoRBF        = cmdPsbtCreate.Flag("no-rbf", "Do not signal replace-by-fee (BIP125), so the fee cannot be raised with bump-fee. Default is off (the transaction can be replaced by one paying a higher fee until it is mined).").Default("false").Bool()
+	cmdPsbtCreateUnknownFee   = cmdPsbtCreate.Flag("unknown-fee", "Build the transaction even though the input amount is unknown, paying all of the input not sent as fee without checking it against --max-fee. Default is off (--input-amount, --input with an amount or --prev-tx is needed).").Default("false").Bool()
+	cmdPsbtCreateHex          = cmdPsbtCreate.Flag("hex", "Output PSBT as hex of the BIP174 binary format. Default is off (base64 output).").Default("false").Bool()
+	cmdPsbtSign               = cmdPsbt.Command("sign", "Add one cosigner's signature to a PSBT.")
+	cmdPsbtSignPsbt           = cmdPsbtSign.Flag("psbt", "PSBT to sign, in base64 or hex.").Required().String()
+	cmdPsbtSignPrivateKey     = cmdPsbtSign.Flag("private-key", "Private key of the cosigner signing.").Required().String()
+	cmdPsbtSignSigHash        = cmdPsbtSign.Flag("sighash", "Signature hash type the PSBT asks for, confirming signatures that leave outputs or inputs open to change (eg. NONE). PSBTs asking for any other type are refused. Default is ALL (signatures commit to every input and output).").Default("ALL").String()
+	cmdPsbtSignHex            = cmdPsbtSign.Flag("hex", "Output PSBT as hex of the BIP174 binary format. Default is off (base64 output).").Default("false").Bool()
+	cmdPsbtCombine            = cmdPsbt.Command("combine", "Combine PSBTs for the same transaction signed by different cosigners.")
+	cmdPsbtCombinePsbts       = cmdPsbtCombine.Flag("psbts", "Comma separated list of PSBTs to combine, in base64 or hex.").PlaceHolder("PSBTS(Comma separated)").Required().String()
+	cmdPsbtCombineHex         = cmdPsbtCombine.Flag("hex", "Output PSBT as hex of the BIP174 binary format. Default is off (base64 output).").Default("false").Bool()
+	cmdPsbtFinalize           = cmdPsbt.Command("finalize", "Build the final scriptSig of each input once enough cosigners have signed.")
+	cmdPsbtFinalizePsbt       = cmdPsbtFinalize.Flag("psbt", "PSBT to finalize, in base64 or hex.").Required().String()
+	cmdPsbtFinalizeHex        = cmdPsbtFinalize.Flag("hex", "Output PSBT as hex of the BIP174 binary format. Default is off (base64 output).").Default("false").Bool()
+	cmdPsbtExtract            = cmdPsbt.Command("extract", "Extract the raw transaction to broadcast from a finalized PSBT.")
+	cmdPsbtExtractPsbt        = cmdPsbtExtract.Flag("psbt", "Finalized PSBT, in base64 or hex.").Required().String()
+	//bump-fee subcommand
+	cmdBumpFee              = app.Command("bump-fee", "Raise the fee of an unsigned or partially signed PSBT spend by lowering its change, so cosigners can sign a replacement (BIP125).")
+	cmdBumpFeePsbt          = cmdBumpFee.Flag("psbt", "PSBT to raise the fee of, in base64 or hex, as made with 'psbt create' and before 'psbt finalize'.").Required().String()
+	cmdBumpFeeFeeRate       = cmdBumpFee.Flag("fee-rate", "New transaction fee rate in satoshi per virtual byte (sat/vB) once cosigners have signed. Must raise the fee by at least 1 sat/vB.").Required().Float()
+	cmdBumpFeeChangeAddress = cmdBumpFee.Flag("change-address", "Address of the change output to take the higher fee from. Change left below the 546 satoshi dust limit is removed and paid as fee.").Required().String()
+	cmdBumpFeeInputAmount   = cmdBumpFee.Flag("input-amount", "Amount in satoshi of the multisig funds being spent. Not needed if the PSBT was created with --prev-tx.").Default("0").Int()
+	cmdBumpFeeMaxFee        = cmdBumpFee.Flag("max-fee", "Highest transaction fee in satoshi allowed. Transactions with a larger fee are refused.").Default("100000").Int()
+	cmdBumpFeeHex           = cmdBumpFee.Flag("hex", "Output PSBT as hex of the BIP174 binary format. Default is off (base64 output).").Default("false").Bool()
 )
 
 func main() {
 	switch kingpin.MustParse(app.Parse(os.Args[1:])) {
 
-	//keys -- Generate public/private key pairs
+	//keys -- Generate public/private key pairs, or restore HD key pairs from a mnemonic
 	case cmdKeys.FullCommand():
-		multisig.OutputKeys(*cmdKeysCount, *cmdKeysConcise)
+		multisig.OutputKeys(*cmdKeysCount, *cmdKeysConcise, *cmdKeysCompressed, *cmdKeysHD, *cmdKeysAction, *cmdKeysMnemonic, *cmdKeysPassphrase, *appNetwork)
 
-	//address -- Create a multisig P2SH address
+	//address -- Create a multisig P2SH or P2WSH address
 	case cmdAddress.FullCommand():
-		multisig.OutputAddress(*cmdAddressM, *cmdAddressN, *cmdAddressPublicKeys)
+		multisig.OutputAddress(*cmdAddressM, *cmdAddressN, *cmdAddressPublicKeys, *cmdAddressType, *cmdAddressSort, *cmdAddressXpubs, *cmdAddressIndex, *cmdAddressCount, *cmdAddressRecoveryKey, *cmdAddressRecoveryLockTime, *cmdAddressDelayedM, *cmdAddressDelay, *appNetwork)
 
 	//address -- Fund a P2SH address
 	case cmdFund.FullCommand():
-		multisig.OutputFund(*cmdFundPrivateKey, *cmdFundInputTx, *cmdFundAmount, *cmdFundDestination)
+		multisig.OutputFund(*cmdFundPrivateKey, *cmdFundInputTx, *cmdFundInputIndex, *cmdFundAmount, *cmdFundDestination, *cmdFundInputAmount, *cmdFundInput, *cmdFundPrevTx, *cmdFundSigHash, multisig.TransactionOptions{
+			ChangeAddress: *cmdFundChangeAddress,
+			Fee:           *cmdFundFee,
+			FeeRate:       *cmdFundFeeRate,
+			MaxFee:        *cmdFundMaxFee,
+			LockTime:      *cmdFundLockTime,
+			NoRBF:         *cmdFundNoRBF,
+			UnknownFee:    *cmdFundUnknownFee,
+		}, *appNetwork)
 
-	//address -- Spend a multisig P2SH address
+	//address -- Spend a multisig P2SH or P2WSH address
 	case cmdSpend.FullCommand():
-		multisig.OutputSpend(*cmdSpendPrivateKeys, *cmdSpendDestination, *cmdSpendRedeemScript, *cmdSpendInputTx, *cmdSpendAmount)
+		multisig.OutputSpend(*cmdSpendPrivateKeys, *cmdSpendDestination, *cmdSpendRedeemScript, *cmdSpendInputTx, *cmdSpendInputIndex, *cmdSpendAmount, *cmdSpendType, *cmdSpendInputAmount, *cmdSpendInput, *cmdSpendPrevTx, *cmdSpendSort, *cmdSpendRecovery, *cmdSpendDelayed, *cmdSpendSigHash, multisig.TransactionOptions{
+			ChangeAddress: *cmdSpendChangeAddress,
+			Fee:           *cmdSpendFee,
+			FeeRate:       *cmdSpendFeeRate,
+			MaxFee:        *cmdSpendMaxFee,
+			LockTime:      *cmdSpendLockTime,
+			NoRBF:         *cmdSpendNoRBF,
+			UnknownFee:    *cmdSpendUnknownFee,
+		}, *appNetwork)
+
+	//decode -- Decode a raw transaction
+	case cmdDecode.FullCommand():
+		multisig.OutputDecode(*cmdDecodeTransaction, *cmdDecodeJSON)
+
+	//verify -- Verify a signed transaction
+	case cmdVerify.FullCommand():
+		multisig.OutputVerify(*cmdVerifyTransaction, *cmdVerifyPrevTxs, *cmdVerifyUtxos, *cmdVerifyConsensus)
+
+	//inspect -- Show what a multisig redeem script commits to
+	case cmdInspect.FullCommand():
+		multisig.OutputInspect(*cmdInspectRedeemScript, *appNetwork)
+
+	//script -- Convert scripts between hex and ASM
+	case cmdScriptDecode.FullCommand():
+		multisig.OutputScriptDecode(*cmdScriptDecodeScript)
+	case cmdScriptEncode.FullCommand():
+		multisig.OutputScriptEncode(*cmdScriptEncodeAsm)
+
+	//psbt -- Spend a multisig P2SH address one cosigner at a time
+	case cmdPsbtCreate.FullCommand():
+		multisig.OutputPsbtCreate(*cmdPsbtCreateDestination, *cmdPsbtCreateRedeemScript, *cmdPsbtCreateInputTx, *cmdPsbtCreateInputIndex, *cmdPsbtCreateAmount, *cmdPsbtCreateInput, *cmdPsbtCreatePrevTx, *cmdPsbtCreateSigHash, multisig.TransactionOptions{
+			ChangeAddress: *cmdPsbtCreateChange,
+			Fee:           *cmdPsbtCreateFee,
+			FeeRate:       *cmdPsbtCreateFeeRate,
+			MaxFee:        *cmdPsbtCreateMaxFee,
+			NoRBF:         *cmdPsbtCreateNoRBF,
+			UnknownFee:    *cmdPsbtCreateUnknownFee,
+		}, *cmdPsbtCreateHex, *appNetwork)
+	case cmdPsbtSign.FullCommand():
+		multisig.OutputPsbtSign(*cmdPsbtSignPsbt, *cmdPsbtSignPrivateKey, *cmdPsbtSignSigHash, *cmdPsbtSignHex, *appNetwork)
+	case cmdPsbtCombine.FullCommand():
+		multisig.OutputPsbtCombine(*cmdPsbtCombinePsbts, *cmdPsbtCombineHex)
+	case cmdPsbtFinalize.FullCommand():
+		multisig.OutputPsbtFinalize(*cmdPsbtFinalizePsbt, *cmdPsbtFinalizeHex)
+	case cmdPsbtExtract.FullCommand():
+		multisig.OutputPsbtExtract(*cmdPsbtExtractPsbt)
+
+	//bump-fee -- Raise the fee of a PSBT spend for cosigners to sign again
+	case cmdBumpFee.FullCommand():
+		multisig.OutputBumpFee(*cmdBumpFeePsbt, *cmdBumpFeeFeeRate, *cmdBumpFeeChangeAddress, *cmdBumpFeeInputAmount, *cmdBumpFeeMaxFee, *cmdBumpFeeHex, *appNetwork)
 	}
 }
@@ -1,30 +1,79 @@
 // Package multisig contains the main starting threads for each of the subcommands for go-bitcoin-multisig.
 //
-// address.go - Generating P2SH addresses.
+// address.go - Generating P2SH, P2WSH and nested P2SH-P2WSH addresses.
 package multisig
 
 import (
-	"github.com/prettymuchbryce/hellobitcoin/base58check"
 	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
 
+	"crypto/sha256"
 	"encoding/csv"
 	"encoding/hex"
 	"fmt"
 	"log"
+	"math"
 	"strings"
 )
 
+// Multisig address types, as given to the --type flag of the address and spend subcommands
+const (
+	addressTypeP2SH      = "p2sh"       //Legacy P2SH, address starting with '3'
+	addressTypeP2WSH     = "p2wsh"      //Native SegWit P2WSH, bech32 address starting with 'bc1'
+	addressTypeP2SHP2WSH = "p2sh-p2wsh" //P2WSH nested in P2SH, address starting with '3' payable by wallets without bech32 support
+)
+
+// checkAddressType exits with a helpful message if flagType is not a supported multisig address type.
+func checkAddressType(flagType string) {
+	switch flagType {
+	case addressTypeP2SH, addressTypeP2WSH, addressTypeP2SHP2WSH:
+	default:
+		log.Fatalf("Unknown address type '%s'. Must be one of: %s, %s, %s.", flagType, addressTypeP2SH, addressTypeP2WSH, addressTypeP2SHP2WSH)
+	}
+}
+
 //OutputAddress formats and prints relevant outputs to the user.
-func OutputAddress(flagM int, flagN int, flagPublicKeys string) {
-	P2SHAddress, redeemScriptHex := generateAddress(flagM, flagN, flagPublicKeys)
+func OutputAddress(flagM int, flagN int, flagPublicKeys string, flagType string, flagSort bool, flagXpubs string, flagIndex int, flagCount int, flagRecoveryKey string, flagRecoveryLockTime int, flagDelayedM int, flagDelay int, flagNetwork string) {
+	network := parseNetwork(flagNetwork)
+	if (flagPublicKeys == "") == (flagXpubs == "") {
+		log.Fatal("Provide exactly one of --public-keys or --xpubs.")
+	}
+	if flagXpubs == "" {
+		multisigAddress, redeemScriptHex := generateAddress(flagM, flagN, flagPublicKeys, flagType, flagSort, flagRecoveryKey, flagRecoveryLockTime, flagDelayedM, flagDelay, network)
+		outputAddressWarnings(flagM, flagN, redeemScriptHex, flagType)
+		outputScriptBranches(redeemScriptHex)
+		outputAddress(multisigAddress, redeemScriptHex, flagType, "")
+		return
+	}
+	paths, multisigAddresses, redeemScriptHexs := generateXpubAddresses(flagM, flagN, flagXpubs, flagType, flagSort, flagIndex, flagCount, flagRecoveryKey, flagRecoveryLockTime, flagDelayedM, flagDelay, network)
+	//Every address has the same M, N and key sizes, so the same warnings apply to all
+	outputAddressWarnings(flagM, flagN, redeemScriptHexs[0], flagType)
+	outputScriptBranches(redeemScriptHexs[0])
+	for i := range multisigAddresses {
+		outputAddress(multisigAddresses[i], redeemScriptHexs[i], flagType, paths[i])
+	}
+}
 
-	if flagM*73+flagN*66 > 496 {
+// outputAddressWarnings prints warnings for multisig scripts that are valid but non-standard.
+func outputAddressWarnings(flagM int, flagN int, redeemScriptHex string, flagType string) {
+	//Estimate scriptSig size from the public keys actually used: 65 byte uncompressed keys take nearly twice the space of 33 byte compressed keys
+	redeemScript, err := hex.DecodeString(redeemScriptHex)
+	if err != nil {
+		log.Fatal(err)
+	}
+	scriptSigSize := flagM * 73
+	for _, branch := range scriptBranches(redeemScript) {
+		for _, publicKey := range branch.publicKeys {
+			scriptSigSize += len(publicKey) + 1
+		}
+	}
+
+	if flagType == addressTypeP2SH && scriptSigSize > 496 {
 		fmt.Printf(`
 -----------------------------------------------------------------------------------------------------------------------------------
 WARNING: 
 %d-of-%d multisig transaction is valid but *non-standard* for Bitcoin v0.9.x and earlier.
 It may take a very long time (possibly never) for transaction spending multisig funds to be included in a block.
-To remain valid, choose smaller m and n values such that m*73+n*66 <= 496, as per standardness rules.
+To remain valid, choose smaller m and n values such that m*73+n*66 <= 496 (m*73+n*34 <= 496 with compressed public keys), as per standardness rules.
 See http://bitcoin.stackexchange.com/questions/23893/what-are-the-limits-of-m-and-n-in-m-of-n-multisig-addresses for more details.
 ------------------------------------------------------------------------------------------------------------------------------------
 `,
@@ -32,28 +81,93 @@ See http://bitcoin.stackexchange.com/questions/23893/what-are-the-limits-of-m-an
 			flagN,
 		)
 	}
-	//Output P2SH and redeemScript
+	//Only reached by 'inspect', since 'address' refuses to create these
+	if (flagType == addressTypeP2WSH || flagType == addressTypeP2SHP2WSH) && hasUncompressedPublicKeys(redeemScript) {
+		fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+WARNING: 
+P2WSH multisig transactions with uncompressed public keys are valid but *non-standard* for Bitcoin Core v0.13.1 and later.
+It may take a very long time (possibly never) for transaction spending multisig funds to be included in a block.
+To remain valid, use compressed public keys (generated with 'keys --compressed').
+-----------------------------------------------------------------------------------------------------------------------------------
+`)
+	}
+}
+
+// outputScriptBranches prints who can spend from a recovery or delayed redeem script, and when.
+func outputScriptBranches(redeemScriptHex string) {
+	redeemScript, err := hex.DecodeString(redeemScriptHex)
+	if err != nil {
+		log.Fatal(err)
+	}
+	branches := scriptBranches(redeemScript)
+	switch {
+	case len(branches) < 2:
+	case branches[1].lockTime != 0:
+		fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Any %d of the %d public keys can spend at any time. The recovery key can also spend alone from %v on,
+with 'spend --recovery'.
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+			branches[0].m,
+			len(branches[0].publicKeys),
+			formatLockTime(branches[1].lockTime),
+		)
+	default:
+		fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Any %d of the %d public keys can spend at any time. Any %d can also spend funds that have been in the blockchain for %v,
+with 'spend --delayed'.
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+			branches[0].m,
+			len(branches[0].publicKeys),
+			branches[1].m,
+			formatSequence(branches[1].sequence),
+		)
+	}
+}
+
+// outputAddress prints a multisig address and its redeem or witness script, with the derivation path of its keys if derived from xpubs.
+func outputAddress(multisigAddress string, redeemScriptHex string, flagType string, path string) {
+	scriptName := "REDEEM SCRIPT"
+	if flagType == addressTypeP2WSH || flagType == addressTypeP2SHP2WSH {
+		scriptName = "WITNESS SCRIPT"
+	}
+	addressName := strings.ToUpper(flagType) + " ADDRESS"
+	if path != "" {
+		addressName += " at path " + path
+	}
+	//Output multisig address and redeemScript
 	fmt.Printf(`
 -----------------------------------------------------------------------------------------------------------------------------------
-Your *P2SH ADDRESS* is:
+Your *%v* is:
 %v
 Give this to sender funding multisig address with Bitcoin.
 -----------------------------------------------------------------------------------------------------------------------------------
 -----------------------------------------------------------------------------------------------------------------------------------
-Your *REDEEM SCRIPT* is:
+Your *%v* is:
 %v
 Keep private and provide this to redeem multisig balance later.
 -----------------------------------------------------------------------------------------------------------------------------------
 `,
-		P2SHAddress,
+		addressName,
+		multisigAddress,
+		scriptName,
 		redeemScriptHex,
 	)
 }
 
-// generateAddress is the high-level logic for creating P2SH multisig addresses with the 'go-bitcoin-multisig address' subcommand.
-// Takes flagM (number of keys required to spend), flagN (total number of keys)
-// and flagPublicKeys (comma separated list of N public keys) as arguments.
-func generateAddress(flagM int, flagN int, flagPublicKeys string) (string, string) {
+// generateAddress is the high-level logic for creating multisig addresses with the 'go-bitcoin-multisig address' subcommand.
+// Takes flagM (number of keys required to spend), flagN (total number of keys), flagPublicKeys (comma separated list of N public keys),
+// flagType (address type, p2sh, p2wsh or p2sh-p2wsh), flagSort (true sorts public keys as per BIP67, so the address
+// does not depend on the order keys are given in), flagRecoveryKey (optional public key that can spend alone once a transaction's
+// locktime reaches flagRecoveryLockTime, a block height or Unix timestamp), flagDelayedM (optional number of keys, fewer than flagM,
+// that can spend funds at least flagDelay blocks old) and network (network to encode the address for) as arguments.
+// For p2wsh and p2sh-p2wsh, the returned script is the witness script, which is built exactly like a P2SH redeem script.
+func generateAddress(flagM int, flagN int, flagPublicKeys string, flagType string, flagSort bool, flagRecoveryKey string, flagRecoveryLockTime int, flagDelayedM int, flagDelay int, network *btcutils.Network) (string, string) {
+	checkAddressType(flagType)
 	//Convert public keys argument into slice of public key bytes with necessary tidying
 	flagPublicKeys = strings.Replace(flagPublicKeys, "'", "\"", -1) //Replace single quotes with double since csv package only recognizes double quotes
 	publicKeyStrings, err := csv.NewReader(strings.NewReader(flagPublicKeys)).Read()
@@ -69,18 +183,217 @@ func generateAddress(flagM int, flagN int, flagPublicKeys string) (string, strin
 		}
 	}
 	//Create redeemScript from public keys
-	redeemScript, err := btcutils.NewMOfNRedeemScript(flagM, flagN, publicKeys)
-	if err != nil {
-		log.Fatal(err)
+	var redeemScript []byte
+	if flagSort {
+		redeemScript, err = btcutils.NewSortedMOfNRedeemScript(flagM, flagN, publicKeys)
+	} else {
+		redeemScript, err = btcutils.NewMOfNRedeemScript(flagM, flagN, publicKeys)
 	}
-	redeemScriptHash, err := btcutils.Hash160(redeemScript)
 	if err != nil {
 		log.Fatal(err)
 	}
-	//Get P2SH address by base58 encoding with P2SH prefix 0x05
-	P2SHAddress := base58check.Encode("05", redeemScriptHash)
+	switch {
+	case flagRecoveryKey != "" && flagDelayedM != 0:
+		log.Fatal("Give at most one of --recovery-key and --delayed-m.")
+	case flagRecoveryKey != "":
+		redeemScript = newRecoveryRedeemScript(redeemScript, flagRecoveryKey, flagRecoveryLockTime)
+	case flagDelayedM != 0:
+		redeemScript = newDelayedRedeemScript(redeemScript, flagDelayedM, flagDelay)
+	case flagRecoveryLockTime != 0:
+		log.Fatal("--recovery-locktime needs a --recovery-key to spend with.")
+	case flagDelay != 0:
+		log.Fatal("--delay needs a --delayed-m number of keys to spend with.")
+	}
+	//Consensus rules limit the redeem script pushed in a P2SH scriptSig to 520 bytes, so a longer one could never be spent
+	if flagType == addressTypeP2SH && len(redeemScript) > 520 {
+		log.Fatalf("Redeem script is %d bytes, more than the 520 bytes P2SH allows. Use fewer or compressed public keys, or --type p2wsh.", len(redeemScript))
+	}
+	//Bitcoin Core v0.13.1 and later do not relay spends of witness scripts with uncompressed public keys, so funds sent there could be stuck
+	if (flagType == addressTypeP2WSH || flagType == addressTypeP2SHP2WSH) && hasUncompressedPublicKeys(redeemScript) {
+		log.Fatalf("%v needs compressed public keys (generated with 'keys --compressed'), as spends with uncompressed public keys are non-standard.", strings.ToUpper(flagType))
+	}
+	multisigAddress := generateMultisigAddress(redeemScript, flagType, network)
 	//Get redeemScript in Hex
 	redeemScriptHex := hex.EncodeToString(redeemScript)
 
-	return P2SHAddress, redeemScriptHex
+	return multisigAddress, redeemScriptHex
+}
+
+// hasUncompressedPublicKeys returns true if any branch of redeemScript has a 65 byte uncompressed public key.
+func hasUncompressedPublicKeys(redeemScript []byte) bool {
+	for _, branch := range scriptBranches(redeemScript) {
+		for _, publicKey := range branch.publicKeys {
+			if len(publicKey) == 65 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newRecoveryRedeemScript adds a recovery key branch to a M-of-N multisig redeem script, spendable by flagRecoveryKey alone
+// from the block height or Unix timestamp flagRecoveryLockTime on.
+func newRecoveryRedeemScript(multisigScript []byte, flagRecoveryKey string, flagRecoveryLockTime int) []byte {
+	m, n, publicKeys, err := btcutils.ParseMOfNRedeemScript(multisigScript)
+	if err != nil {
+		log.Fatal(err)
+	}
+	recoveryPublicKey, err := hex.DecodeString(strings.TrimSpace(flagRecoveryKey))
+	if err != nil {
+		log.Fatal(err, "\n", "Offending recovery key: \n", flagRecoveryKey)
+	}
+	if flagRecoveryLockTime <= 0 || int64(flagRecoveryLockTime) > math.MaxUint32 {
+		log.Fatalf("--recovery-locktime must be a block height below %d or a Unix timestamp, up to %d.", btcutils.LockTimeThreshold, uint32(math.MaxUint32))
+	}
+	redeemScript, err := btcutils.NewRecoveryRedeemScript(m, n, publicKeys, recoveryPublicKey, uint32(flagRecoveryLockTime))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return redeemScript
+}
+
+// newDelayedRedeemScript adds a delayed branch to a M-of-N multisig redeem script, spendable by flagDelayedM of the same N keys
+// once the output being spent is flagDelay blocks old.
+func newDelayedRedeemScript(multisigScript []byte, flagDelayedM int, flagDelay int) []byte {
+	m, n, publicKeys, err := btcutils.ParseMOfNRedeemScript(multisigScript)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if flagDelay < 1 || flagDelay > btcutils.SequenceLockTimeMask {
+		log.Fatalf("--delay must be between 1 and %d blocks.", btcutils.SequenceLockTimeMask)
+	}
+	redeemScript, err := btcutils.NewDelayedRedeemScript(m, n, publicKeys, flagDelayedM, uint32(flagDelay))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return redeemScript
+}
+
+// generateMultisigAddress creates the multisig address of type flagType (p2sh, p2wsh or p2sh-p2wsh) for redeemScript,
+// the witness script for p2wsh and p2sh-p2wsh, encoded for network.
+func generateMultisigAddress(redeemScript []byte, flagType string, network *btcutils.Network) string {
+	var multisigAddress string
+	var err error
+	switch flagType {
+	case addressTypeP2SH:
+		redeemScriptHash, err := btcutils.Hash160(redeemScript)
+		if err != nil {
+			log.Fatal(err)
+		}
+		//Get P2SH address by base58 encoding with the network's P2SH prefix, 0x05 for mainnet
+		multisigAddress = btcutils.NewP2SHAddress(redeemScriptHash, network)
+	case addressTypeP2WSH:
+		witnessScriptHash := sha256.Sum256(redeemScript)
+		//Get P2WSH address by bech32 encoding version 0 witness program (SHA256 of witness script) with the network's prefix, "bc" for mainnet
+		multisigAddress, err = btcutils.NewP2WSHAddress(witnessScriptHash[:], network)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case addressTypeP2SHP2WSH:
+		//The P2SH redeem script is the P2WSH scriptPubKey, ie. version 0 witness program (SHA256 of witness script)
+		witnessProgramHash, err := btcutils.Hash160(multisigScriptPubKey(addressTypeP2WSH, redeemScript))
+		if err != nil {
+			log.Fatal(err)
+		}
+		//Get P2SH address by base58 encoding with the network's P2SH prefix, 0x05 for mainnet
+		multisigAddress = btcutils.NewP2SHAddress(witnessProgramHash, network)
+	}
+	return multisigAddress
+}
+
+// generateXpubAddresses is the high-level logic for creating multisig addresses from cosigners' BIP32 extended public keys
+// with the 'go-bitcoin-multisig address --xpubs' subcommand.
+// Takes flagM, flagN, flagType, flagSort, flagRecoveryKey, flagRecoveryLockTime, flagDelayedM, flagDelay and network as for generateAddress, flagXpubs (comma separated list of N
+// account xpubs with their key origins, as printed by 'keys --hd'), flagIndex (first address index) and flagCount (number of consecutive addresses) as arguments.
+// The public keys of address index i are derived at path 0/i (see hdReceivePath) of each account xpub, in the order xpubs are given
+// unless flagSort is set, as in BIP48 wallets.
+// Returns the full derivation path from the cosigners' master keys (several, comma separated, if their account paths differ), multisig
+// address and redeem or witness script of each address.
+func generateXpubAddresses(flagM int, flagN int, flagXpubs string, flagType string, flagSort bool, flagIndex int, flagCount int, flagRecoveryKey string, flagRecoveryLockTime int, flagDelayedM int, flagDelay int, network *btcutils.Network) ([]string, []string, []string) {
+	if flagIndex < 0 || int64(flagIndex)+int64(flagCount) > int64(btcutils.HardenedKeyStart) {
+		log.Fatalf("--index must be between 0 and %d.", btcutils.HardenedKeyStart-1)
+	}
+	if flagCount < 1 || flagCount > 1000 {
+		log.Fatal("--count must be between 1 and 1000.")
+	}
+	//Convert xpubs argument into extended keys. Unlike public keys in generateAddress, ' marks hardened steps of key origins, not quotes
+	xpubStrings := strings.Split(flagXpubs, ",")
+	receiveKeys := make([]*btcutils.ExtendedKey, len(xpubStrings))
+	var accountPaths []string
+	for i, xpubString := range xpubStrings {
+		xpub, accountPath := parseAccountXpub(xpubString, network)
+		var err error
+		receiveKeys[i], err = xpub.DerivePath(hdReceivePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		//Cosigners using the same wallet layout share one account path, printed once
+		seen := false
+		for _, path := range accountPaths {
+			seen = seen || path == accountPath
+		}
+		if !seen {
+			accountPaths = append(accountPaths, accountPath)
+		}
+	}
+
+	paths := make([]string, flagCount)
+	multisigAddresses := make([]string, flagCount)
+	redeemScriptHexs := make([]string, flagCount)
+	for i := 0; i < flagCount; i++ {
+		index := uint32(flagIndex + i)
+		publicKeyHexs := make([]string, len(receiveKeys))
+		for j, receiveKey := range receiveKeys {
+			childKey, err := receiveKey.Child(index)
+			if err != nil {
+				log.Fatal(err)
+			}
+			publicKeyHexs[j] = hex.EncodeToString(childKey.Key)
+		}
+		keyPaths := make([]string, len(accountPaths))
+		for j, accountPath := range accountPaths {
+			keyPaths[j] = fmt.Sprintf("%s/%s/%d", accountPath, hdReceivePath, index)
+		}
+		paths[i] = strings.Join(keyPaths, ", ")
+		multisigAddresses[i], redeemScriptHexs[i] = generateAddress(flagM, flagN, strings.Join(publicKeyHexs, ","), flagType, flagSort, flagRecoveryKey, flagRecoveryLockTime, flagDelayedM, flagDelay, network)
+	}
+
+	return paths, multisigAddresses, redeemScriptHexs
+}
+
+// parseAccountXpub parses a cosigner's account xpub with its key origin, as printed by 'keys --hd', eg. [3442193e/48'/0'/0'/2']xpub...
+// Returns the extended public key and the path it was derived at from the cosigner's master key.
+func parseAccountXpub(flagXpub string, network *btcutils.Network) (*btcutils.ExtendedKey, string) {
+	flagXpub = strings.TrimSpace(flagXpub)
+	originEnd := strings.Index(flagXpub, "]")
+	if !strings.HasPrefix(flagXpub, "[") || originEnd == -1 {
+		log.Fatal("Give each xpub with its key origin, as '[fingerprint/path]xpub' printed by 'keys --hd', so the full path of every key is known.", "\n", "Offending xpub: \n", flagXpub)
+	}
+	origin := strings.SplitN(flagXpub[1:originEnd], "/", 2)
+	xpub, err := btcutils.ParseExtendedKey(flagXpub[originEnd+1:], network)
+	if err != nil {
+		log.Fatal(err, "\n", "Offending xpub: \n", flagXpub)
+	}
+	if xpub.IsPrivate {
+		log.Fatal("Give cosigners' extended public keys (xpub), not extended private keys (xprv).")
+	}
+	//With a master xpub and any one private key derived from it without hardening, every key of the cosigner can be worked out
+	if xpub.Depth == 0 {
+		log.Fatal("xpub is a master key (depth 0), which should never be shared. Give the account xpub printed by 'keys --hd' instead.", "\n", "Offending xpub: \n", flagXpub)
+	}
+	if fingerprint, err := hex.DecodeString(origin[0]); err != nil || len(fingerprint) != 4 {
+		log.Fatal("Key origin must start with the 8 hex digit fingerprint of the master key.", "\n", "Offending xpub: \n", flagXpub)
+	}
+	accountPath := "m"
+	if len(origin) == 2 {
+		accountPath += "/" + origin[1]
+	}
+	indexes, err := btcutils.ParseDerivationPath(accountPath)
+	if err != nil {
+		log.Fatal(err, "\n", "Offending xpub: \n", flagXpub)
+	}
+	if len(indexes) != int(xpub.Depth) || indexes[len(indexes)-1] != xpub.ChildNumber {
+		log.Fatalf("Key origin path %s does not match the depth and child number of its xpub.\nOffending xpub: \n%s", accountPath, flagXpub)
+	}
+	return xpub, accountPath
 }
@@ -1,7 +1,10 @@
 package multisig
 
 import (
+	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
 	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
 	"testing"
 )
 
@@ -14,7 +17,7 @@ func TestGenerateAddress(t *testing.T) {
 		testAddress := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
 		testRedeemScriptHex := "524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae"
 
-		P2SHAddress, redeemScriptHex := generateAddress(testM, testN, testPublicKeys)
+		P2SHAddress, redeemScriptHex := generateAddress(testM, testN, testPublicKeys, addressTypeP2SH, false, "", 0, 0, 0, btcutils.MainNet)
 		if testAddress != P2SHAddress {
 			testutils.CompareError(t, "Generated P2SH address different from expected address.", testAddress, P2SHAddress)
 		}
@@ -30,7 +33,7 @@ func TestGenerateAddress(t *testing.T) {
 		testAddress := "3ErDPiDD7AsJDqKkayMA39iLJevTjDCjUa"
 		testRedeemScriptHex := "57410446f1c8de232a065da428bf76e44b41f59a46620dec0aedfc9b5ab651e91f2051d610fddc78b8eba38a634bfe9a74bb015a88c52b9b844c74997035e08a695ce94104704e19d4fc234a42d707d41053c87011f990b564949532d72cab009e136bd60d7d0602f925fce79da77c0dfef4a49c6f44bd0540faef548e37557d74b36da1244104b75a8cb10fd3f1785addbafdb41b409ecd6ffd50d5ad71d8a3cdc5503bcb35d3d13cdf23f6d0eb6ab88446276e2ba5b92d8786da7e5c0fb63aafb62f87443d284104033a82ccb1291bbc27cf541c6c487c213f25db85c620ecb9cbb76ca461ef13db5a80b90c3ae7d2a5e47623cdf520a2586cac7e41f779103a71a1fe177189781e41045e3b4030be5fd9c4c40e7076bd49f022118d90ae9182de61f3a1adb2ff511c97e8a6a82a9292b01878a18c08b7cd658ebdf80e6ed3f26783b25ba1a52fa9e52d4104c93ceb8f4482e131addc58d3efa0b4967bb7c574de15786d55379cc4a43a61571518abe0f05ebf188bcce9580aa70b3f5b1024ca579819c8810ff79967de3f234104a66f63d2941f0befcfba4b73495a7b99fc7ed28cb41e7934e1de82d852628766dc96ee1e196387a68e7fd8898862c2260f1f2557ac2147af07900695f15abd3f57ae"
 
-		P2SHAddress, redeemScriptHex := generateAddress(testM, testN, testPublicKeys)
+		P2SHAddress, redeemScriptHex := generateAddress(testM, testN, testPublicKeys, addressTypeP2SH, false, "", 0, 0, 0, btcutils.MainNet)
 		if testAddress != P2SHAddress {
 			testutils.CompareError(t, "Generated P2SH address different from expected address.", testAddress, P2SHAddress)
 		}
@@ -46,7 +49,7 @@ func TestGenerateAddress(t *testing.T) {
 		testAddress := "34wgSuG9qtaNEV4MGye9UJcffcFTxnmXSC"
 		testRedeemScriptHex := "554104c22e4293d1d462eef905e592ad4aff332aa52c3415b824cd85cf594258d92c836fe797187bc2459261e0597c4ef351c5d0c26f7a60165221e221a38e448ad08c4104bb28684dfe23852a7c276827dd448c955007e7ccbfacbf536e13f1097b30430ebec5af0bc001e50d3f0e796d52ba43e3c07337bfed2a842659d51632f2b21d2841048f8551173f8e7414ff0e144899b3f70accd957e6913f5cf877bd576f6c16f0aa67fb9b96e0df10562b4f7ba4060acd22f142329ff83f1d96e27f4e4394adeda24104aa81def7dda6a4f40be2f3287ee3423f255b07965104a7888df075217c9ee5b3e9e2e70115d43bfecbff8062f8289f5cab3d0ebd96c9f55c85f6147ff3a5e9494104493aa5f89ec34184a235b2c9f608eade1634636f94f64b59419875e15cb86a6d8c708a9d5eda3304cb983b2325a57af881ed75f28179f5f263d7758039b68d894104dc284f749208d7fec57937bc5e72187b064df7d29b7aa82cae273e9a1c91beae9c510e0fd632a3db272c67db04061ea761d1ed91fdb8ab07e354047c64ce405d41042fc7796f54dd482db20f1bcce584f930ae74d5f27fc8336e2701bd0243d681281810c57e079947ebdfdfc8860ed34b0ba32db82a85249adc7c64ab547d48af6457ae"
 
-		P2SHAddress, redeemScriptHex := generateAddress(testM, testN, testPublicKeys)
+		P2SHAddress, redeemScriptHex := generateAddress(testM, testN, testPublicKeys, addressTypeP2SH, false, "", 0, 0, 0, btcutils.MainNet)
 		if testAddress != P2SHAddress {
 			testutils.CompareError(t, "Generated P2SH address different from expected address.", testAddress, P2SHAddress)
 		}
@@ -54,4 +57,236 @@ func TestGenerateAddress(t *testing.T) {
 			testutils.CompareError(t, "Generated P2SH address different from expected address.", testRedeemScriptHex, redeemScriptHex)
 		}
 	}
+	{
+		//2-of-3 P2SH-P2WSH multisig test, same public keys as the 2-of-3 compressed public key test, as witness scripts need compressed keys
+		testM := 2
+		testN := 3
+		testPublicKeys := "03a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575,036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d,0311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef"
+		testAddress := "3F7ULEo5xPC4B9rkN3T8tWL8ZfTCvycAx4"
+		testWitnessScriptHex := "522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae"
+
+		P2SHP2WSHAddress, witnessScriptHex := generateAddress(testM, testN, testPublicKeys, addressTypeP2SHP2WSH, false, "", 0, 0, 0, btcutils.MainNet)
+		if testAddress != P2SHP2WSHAddress {
+			testutils.CompareError(t, "Generated P2SH-P2WSH address different from expected address.", testAddress, P2SHP2WSHAddress)
+		}
+		if testWitnessScriptHex != witnessScriptHex {
+			testutils.CompareError(t, "Generated witness script different from expected script.", testWitnessScriptHex, witnessScriptHex)
+		}
+	}
+	{
+		//2-of-3 compressed public key multisig test, compressed forms of the 2-of-3 P2SH test public keys
+		testM := 2
+		testN := 3
+		testPublicKeys := "03a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575,036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d,0311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef"
+		testP2SHAddress := "3MsXykid1v9i3FWP4Fbj8vVLKrjPbBQCbP"
+		testP2WSHAddress := "bc1qznadz5sy773nv3pr2n2jmymcyzkrn64tttz5640nz6988zmemzfs3dn6jt"
+		testRedeemScriptHex := "522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae"
+
+		P2SHAddress, redeemScriptHex := generateAddress(testM, testN, testPublicKeys, addressTypeP2SH, false, "", 0, 0, 0, btcutils.MainNet)
+		if testP2SHAddress != P2SHAddress {
+			testutils.CompareError(t, "Generated P2SH address different from expected address.", testP2SHAddress, P2SHAddress)
+		}
+		if testRedeemScriptHex != redeemScriptHex {
+			testutils.CompareError(t, "Generated redeem script different from expected script.", testRedeemScriptHex, redeemScriptHex)
+		}
+		P2WSHAddress, witnessScriptHex := generateAddress(testM, testN, testPublicKeys, addressTypeP2WSH, false, "", 0, 0, 0, btcutils.MainNet)
+		if testP2WSHAddress != P2WSHAddress {
+			testutils.CompareError(t, "Generated P2WSH address different from expected address.", testP2WSHAddress, P2WSHAddress)
+		}
+		if testRedeemScriptHex != witnessScriptHex {
+			testutils.CompareError(t, "Generated witness script different from expected script.", testRedeemScriptHex, witnessScriptHex)
+		}
+	}
+	{
+		//2-of-3 BIP67 sorted multisig test, same compressed public keys as above in two different orders
+		testM := 2
+		testN := 3
+		testPublicKeyOrders := []string{
+			"03a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575,036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d,0311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef",
+			"036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d,0311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef,03a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575",
+		}
+		testP2SHAddress := "39NqPn6kKbiE8ojF9D71mGCGwfGN3gYAdo"
+		testP2WSHAddress := "bc1q99043vxxfrecllzdmamcmr5g3epxrn6wkavfnm2aa63gzmnqh8aqylrez4"
+		testRedeemScriptHex := "52210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef21036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d2103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957553ae"
+
+		for _, testPublicKeys := range testPublicKeyOrders {
+			P2SHAddress, redeemScriptHex := generateAddress(testM, testN, testPublicKeys, addressTypeP2SH, true, "", 0, 0, 0, btcutils.MainNet)
+			if testP2SHAddress != P2SHAddress {
+				testutils.CompareError(t, "Generated sorted P2SH address different from expected address.", testP2SHAddress, P2SHAddress)
+			}
+			if testRedeemScriptHex != redeemScriptHex {
+				testutils.CompareError(t, "Generated sorted redeem script different from expected script.", testRedeemScriptHex, redeemScriptHex)
+			}
+			P2WSHAddress, _ := generateAddress(testM, testN, testPublicKeys, addressTypeP2WSH, true, "", 0, 0, 0, btcutils.MainNet)
+			if testP2WSHAddress != P2WSHAddress {
+				testutils.CompareError(t, "Generated sorted P2WSH address different from expected address.", testP2WSHAddress, P2WSHAddress)
+			}
+		}
+	}
+	{
+		//2-of-3 multisig test on testnet3 and regtest, same public keys as the 2-of-3 compressed public key test
+		testM := 2
+		testN := 3
+		testPublicKeys := "03a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575,036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d,0311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef"
+		testAddresses := []struct {
+			network     *btcutils.Network
+			addressType string
+			address     string
+		}{
+			{btcutils.TestNet3, addressTypeP2SH, "2NDRk3VeedNf4F38vjPDbksUbYCwZJpRPra"},
+			{btcutils.TestNet3, addressTypeP2WSH, "tb1qznadz5sy773nv3pr2n2jmymcyzkrn64tttz5640nz6988zmemzfsx994gy"},
+			{btcutils.RegTest, addressTypeP2SH, "2NDRk3VeedNf4F38vjPDbksUbYCwZJpRPra"},
+			{btcutils.RegTest, addressTypeP2WSH, "bcrt1qznadz5sy773nv3pr2n2jmymcyzkrn64tttz5640nz6988zmemzfstu0na7"},
+		}
+
+		for _, testAddress := range testAddresses {
+			address, _ := generateAddress(testM, testN, testPublicKeys, testAddress.addressType, false, "", 0, 0, 0, testAddress.network)
+			if testAddress.address != address {
+				testutils.CompareError(t, "Generated "+testAddress.addressType+" address on "+testAddress.network.Name+" different from expected address.", testAddress.address, address)
+			}
+		}
+	}
+}
+
+func TestGenerateXpubAddresses(t *testing.T) {
+	{
+		//2-of-2 P2WSH multisig test from the BIP48 account xpubs at m/48'/0'/0'/2' of the BIP32 test vector 1 and 2 seeds, at indexes 0 and 1.
+		//Addresses were cross-checked with btcsuite.
+		testM := 2
+		testN := 2
+		testXpubs := "[3442193e/48'/0'/0'/2']xpub6E64WfdQwBGz85XhbZryr9gUGUPBgoSu5WV6tJWpzAvgAmpVpdPHkT3XYm9R5J6MeWzvLQoz4q845taC9Q28XutbptxAmg7q8QPkjvTL4oi,[bd16bee5/48'/0'/0'/2']xpub6DwQ4gBCmJZM3TaKogP41tpjuEwnMH2nWEi3PFev37LfsWPvjZrh1GfAG8xvoDYMPWGKG1oBPMCfKpkVbJtUHRaqRdCb6X6o1e9PQTVK88a"
+		testPaths := []string{"m/48'/0'/0'/2'/0/0", "m/48'/0'/0'/2'/0/1"}
+		testAddresses := []string{
+			"bc1qxpw5x2gdt3yh7pqy38carcts2jg328m4yy5q8c8trjyw2n7rua6sjjete4",
+			"bc1qwnhft38pv94t42wxna0tvcph44zdg2uttf05vm6l9cf6uw0xfkwqu28czs",
+		}
+		testWitnessScriptHexs := []string{
+			"5221039313c80a98460104a4e9abc86f1f73d1fa982ecb3172269d917415e6aeee26752102115b29a5b4c2e648bbafc10046df00e8311710963efd15a87534c670dd0eb0fa52ae",
+			"52210270a8ff2665558eafec65807395de199470e3618810eff48ec35b59fad2f09acc2102711e44fa6f6f9b39f27274da000abed8765ba17643e7a4b654493f8b9391ff0a52ae",
+		}
+
+		paths, addresses, witnessScriptHexs := generateXpubAddresses(testM, testN, testXpubs, addressTypeP2WSH, false, 0, 2, "", 0, 0, 0, btcutils.MainNet)
+		if len(addresses) != len(testAddresses) {
+			t.Fatalf("Generated %d addresses, expected %d.", len(addresses), len(testAddresses))
+		}
+		for i := range testAddresses {
+			if testPaths[i] != paths[i] {
+				testutils.CompareError(t, "Generated derivation path different from expected path.", testPaths[i], paths[i])
+			}
+			if testAddresses[i] != addresses[i] {
+				testutils.CompareError(t, "Generated P2WSH address different from expected address.", testAddresses[i], addresses[i])
+			}
+			if testWitnessScriptHexs[i] != witnessScriptHexs[i] {
+				testutils.CompareError(t, "Generated witness script different from expected script.", testWitnessScriptHexs[i], witnessScriptHexs[i])
+			}
+		}
+	}
+	{
+		//2-of-2 P2SH multisig test from the same xpubs, at index 5 only
+		testM := 2
+		testN := 2
+		testXpubs := "[3442193e/48'/0'/0'/2']xpub6E64WfdQwBGz85XhbZryr9gUGUPBgoSu5WV6tJWpzAvgAmpVpdPHkT3XYm9R5J6MeWzvLQoz4q845taC9Q28XutbptxAmg7q8QPkjvTL4oi,[bd16bee5/48'/0'/0'/2']xpub6DwQ4gBCmJZM3TaKogP41tpjuEwnMH2nWEi3PFev37LfsWPvjZrh1GfAG8xvoDYMPWGKG1oBPMCfKpkVbJtUHRaqRdCb6X6o1e9PQTVK88a"
+		testPath := "m/48'/0'/0'/2'/0/5"
+		testAddress := "353Px8Y6VjbpMdfRZzF3GeAkpJX1iBpGBS"
+		testRedeemScriptHex := "522102e050fa718ec648918658d6a94c17a70e86f405549651117d78516576e0e70e6d21034c71732db6261481013f929c16b68be851695931cdcb89f61df9630b14bcb6b652ae"
+
+		paths, addresses, redeemScriptHexs := generateXpubAddresses(testM, testN, testXpubs, addressTypeP2SH, false, 5, 1, "", 0, 0, 0, btcutils.MainNet)
+		if len(addresses) != 1 {
+			t.Fatalf("Generated %d addresses, expected 1.", len(addresses))
+		}
+		if testPath != paths[0] {
+			testutils.CompareError(t, "Generated derivation path different from expected path.", testPath, paths[0])
+		}
+		if testAddress != addresses[0] {
+			testutils.CompareError(t, "Generated P2SH address different from expected address.", testAddress, addresses[0])
+		}
+		if testRedeemScriptHex != redeemScriptHexs[0] {
+			testutils.CompareError(t, "Generated redeem script different from expected script.", testRedeemScriptHex, redeemScriptHexs[0])
+		}
+	}
+	{
+		//2-of-2 BIP67 sorted P2SH multisig test from the same xpubs at index 0, where the second xpub's key sorts first
+		testM := 2
+		testN := 2
+		testXpubs := "[3442193e/48'/0'/0'/2']xpub6E64WfdQwBGz85XhbZryr9gUGUPBgoSu5WV6tJWpzAvgAmpVpdPHkT3XYm9R5J6MeWzvLQoz4q845taC9Q28XutbptxAmg7q8QPkjvTL4oi,[bd16bee5/48'/0'/0'/2']xpub6DwQ4gBCmJZM3TaKogP41tpjuEwnMH2nWEi3PFev37LfsWPvjZrh1GfAG8xvoDYMPWGKG1oBPMCfKpkVbJtUHRaqRdCb6X6o1e9PQTVK88a"
+		testAddress := "3A3XXm7YkJvEfYygjX3EnrpuRf6rfWY1hk"
+		testRedeemScriptHex := "522102115b29a5b4c2e648bbafc10046df00e8311710963efd15a87534c670dd0eb0fa21039313c80a98460104a4e9abc86f1f73d1fa982ecb3172269d917415e6aeee267552ae"
+
+		_, addresses, redeemScriptHexs := generateXpubAddresses(testM, testN, testXpubs, addressTypeP2SH, true, 0, 1, "", 0, 0, 0, btcutils.MainNet)
+		if testAddress != addresses[0] {
+			testutils.CompareError(t, "Generated sorted P2SH address different from expected address.", testAddress, addresses[0])
+		}
+		if testRedeemScriptHex != redeemScriptHexs[0] {
+			testutils.CompareError(t, "Generated sorted redeem script different from expected script.", testRedeemScriptHex, redeemScriptHexs[0])
+		}
+	}
+	{
+		//2-of-2 P2WSH multisig test where the second cosigner shares account 1' of the BIP32 test vector 1 seed, so both full paths are printed
+		testM := 2
+		testN := 2
+		testSeed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+		testPath := "m/48'/0'/0'/2'/0/0, m/48'/0'/1'/2'/0/0"
+
+		masterKey, err := btcutils.NewMasterKey(testSeed, btcutils.MainNet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		accountKey, err := masterKey.DerivePath("m/48'/0'/1'/2'")
+		if err != nil {
+			t.Fatal(err)
+		}
+		testXpubs := "[3442193e/48'/0'/0'/2']xpub6E64WfdQwBGz85XhbZryr9gUGUPBgoSu5WV6tJWpzAvgAmpVpdPHkT3XYm9R5J6MeWzvLQoz4q845taC9Q28XutbptxAmg7q8QPkjvTL4oi," + formatAccountXpub(masterKey, accountKey, "m/48'/0'/1'/2'")
+		paths, _, _ := generateXpubAddresses(testM, testN, testXpubs, addressTypeP2WSH, false, 0, 1, "", 0, 0, 0, btcutils.MainNet)
+		if testPath != paths[0] {
+			testutils.CompareError(t, "Generated derivation paths different from expected paths.", testPath, paths[0])
+		}
+	}
+}
+
+func TestGenerateAddressRecovery(t *testing.T) {
+	//2-of-3 compressed key multisig, or the recovery key alone from block height 700000. The P2WSH address was cross-checked with btcsuite.
+	testPublicKeys := "03a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575,036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d,0311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef"
+	testRecoveryKey := "0331393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701b"
+	testRedeemScriptHex := "63522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae670360ae0ab175210331393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bac68"
+	testAddresses := map[string]string{
+		addressTypeP2SH:      "38GekwGybvGw3kiYEc3AHwm4burWvRq6Rt",
+		addressTypeP2WSH:     "bc1qvftp98htmyc78aq5k3t8vu5sncu7xand42xm77rjtwx0xexqdueq2tzazn",
+		addressTypeP2SHP2WSH: "3NSo9ooFFZF3qBBxzERt6CtaEseoDFZSZK",
+	}
+	for addressType, testAddress := range testAddresses {
+		address, redeemScriptHex := generateAddress(2, 3, testPublicKeys, addressType, false, testRecoveryKey, 700000, 0, 0, btcutils.MainNet)
+		if address != testAddress {
+			testutils.CompareError(t, "Generated recovery "+addressType+" address different from expected address.", testAddress, address)
+		}
+		if redeemScriptHex != testRedeemScriptHex {
+			testutils.CompareError(t, "Generated recovery redeem script different from expected script.", testRedeemScriptHex, redeemScriptHex)
+		}
+	}
+
+	//With --sort, only the M-of-N public keys are sorted, and the recovery key follows them
+	testSortedRedeemScriptHex := "6352210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef21036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d2103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957553ae670400f15365b175210331393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bac68"
+	_, sortedRedeemScriptHex := generateAddress(2, 3, testPublicKeys, addressTypeP2WSH, true, testRecoveryKey, 1700000000, 0, 0, btcutils.MainNet)
+	if sortedRedeemScriptHex != testSortedRedeemScriptHex {
+		testutils.CompareError(t, "Generated sorted recovery redeem script different from expected script.", testSortedRedeemScriptHex, sortedRedeemScriptHex)
+	}
+}
+
+func TestGenerateAddressDelayed(t *testing.T) {
+	//2-of-3 compressed key multisig, or 1-of-3 once funds are 4320 blocks old. The P2WSH address was cross-checked with btcsuite.
+	testPublicKeys := "03a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575,036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d,0311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef"
+	testRedeemScriptHex := "63522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae6702e010b275512103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae68"
+	testAddresses := map[string]string{
+		addressTypeP2SH:      "34zxcUfYH3AFn76ZfTVjj24hUU1yvrTNvv",
+		addressTypeP2WSH:     "bc1qjnyhns66wfdeswxeta738trwszeerzz597m2ya2p2nzvr2dj4y3q2glfa2",
+		addressTypeP2SHP2WSH: "3PmKNSZnXfs17TUMZnioceWQEvZT6538n5",
+	}
+	for addressType, testAddress := range testAddresses {
+		address, redeemScriptHex := generateAddress(2, 3, testPublicKeys, addressType, false, "", 0, 1, 4320, btcutils.MainNet)
+		if address != testAddress {
+			testutils.CompareError(t, "Generated delayed "+addressType+" address different from expected address.", testAddress, address)
+		}
+		if redeemScriptHex != testRedeemScriptHex {
+			testutils.CompareError(t, "Generated delayed redeem script different from expected script.", testRedeemScriptHex, redeemScriptHex)
+		}
+	}
 }
@@ -0,0 +1,150 @@
+// bumpfee.go - Raising the fee of a replaceable PSBT spend by lowering its change, for cosigners to sign again.
+package multisig
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
+
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"log"
+)
+
+// incrementalRelayFeeRate is the fee rate in Satoshis per virtual byte a replacement must pay on top of the fee of the
+// transaction it replaces, for its own relay (BIP125 rule 4).
+const incrementalRelayFeeRate = 1
+
+// bumpedPsbt is a PSBT whose fee has been raised, with the fees before and after and the cosigners whose signatures were removed.
+type bumpedPsbt struct {
+	Psbt            *btcutils.Psbt
+	OriginalFee     int64
+	Fee             int64
+	VirtualSize     int
+	PreviousSigners []string
+}
+
+// OutputBumpFee formats and prints relevant outputs to the user.
+func OutputBumpFee(flagPsbt string, flagFeeRate float64, flagChangeAddress string, flagInputAmount int, flagMaxFee int, flagHex bool, flagNetwork string) {
+	bumped := generateBumpFee(flagPsbt, flagFeeRate, flagChangeAddress, flagInputAmount, flagMaxFee, parseNetwork(flagNetwork))
+	instructions := fmt.Sprintf("Transaction fee raised from %d to %d satoshi (%.2f sat/vB, %d vB once signed).\n", bumped.OriginalFee, bumped.Fee, float64(bumped.Fee)/float64(bumped.VirtualSize), bumped.VirtualSize)
+	instructions += "Give this to each cosigner to sign again with 'psbt sign', since signatures of the original transaction do not cover the new fee."
+	if len(bumped.PreviousSigners) > 0 {
+		instructions += "\nSignatures removed, from the cosigners with public keys:"
+		for _, publicKey := range bumped.PreviousSigners {
+			instructions += "\n" + publicKey
+		}
+	}
+	outputPsbt(bumped.Psbt, flagHex, instructions)
+}
+
+// generateBumpFee is the high-level logic for raising the fee of an unsigned or partially signed PSBT spend with the
+// 'go-bitcoin-multisig bump-fee' subcommand, so it can replace the original once broadcast (BIP125).
+// Takes flagPsbt (PSBT in base64 or hex, signalling replace-by-fee), flagFeeRate (new fee rate in Satoshis per virtual byte, worked
+// out from the size once cosigners have signed), flagChangeAddress (address of the change output the higher fee is taken from),
+// flagInputAmount (amount in Satoshis of the input being spent, needed if the PSBT has no previous transaction), flagMaxFee (highest
+// fee in Satoshis allowed) and network (network the change address must belong to) as arguments.
+// Change that would fall below the dust limit is removed and paid as fee. Partial signatures are removed, as the new transaction needs new ones.
+func generateBumpFee(flagPsbt string, flagFeeRate float64, flagChangeAddress string, flagInputAmount int, flagMaxFee int, network *btcutils.Network) bumpedPsbt {
+	psbt := decodePsbt(flagPsbt)
+	if flagFeeRate <= 0 {
+		log.Fatal("--fee-rate must be above 0.")
+	}
+	if !psbt.UnsignedTx.SignalsReplacement() {
+		log.Fatal("PSBT transaction does not signal replace-by-fee (BIP125), as it was created with --no-rbf, so nodes will not relay a replacement.")
+	}
+	inputAmount := psbtInputAmount(psbt, flagInputAmount)
+	originalFee := inputAmount - sumOutputs(psbt.UnsignedTx.Outputs)
+	if originalFee < 0 {
+		log.Fatalf("PSBT outputs pay %d satoshi, more than the input amount of %d satoshi.", sumOutputs(psbt.UnsignedTx.Outputs), inputAmount)
+	}
+	//The higher fee comes out of the change output
+	changeScriptPubKey := destinationScriptPubKey(flagChangeAddress, network)
+	changeIndex := -1
+	for i, output := range psbt.UnsignedTx.Outputs {
+		if changeIndex == -1 && bytes.Equal(output.ScriptPubKey, changeScriptPubKey) {
+			changeIndex = i
+		}
+	}
+	if changeIndex == -1 {
+		log.Fatal("PSBT transaction has no output to --change-address to take the higher fee from.")
+	}
+	//Signatures of the original transaction are invalid once its change changes, so every cosigner signs again
+	var previousSigners []string
+	for i, input := range psbt.Inputs {
+		if input.FinalScriptSig != nil || input.FinalScriptWitness != nil {
+			log.Fatalf("PSBT input #%d is already finalized. Bump the fee of the PSBT from before 'psbt finalize'.", i)
+		}
+		for _, partialSig := range input.PartialSigs {
+			previousSigners = append(previousSigners, hex.EncodeToString(partialSig.PublicKey))
+		}
+		input.PartialSigs = nil
+	}
+
+	//A replacement must pay more than the original, by enough to pay for its own relay too
+	virtualSize := estimatedVirtualSize(psbt)
+	fee := feeForRate(flagFeeRate, estimateSignedTransaction(psbt))
+	minimumFee := originalFee + int64(incrementalRelayFeeRate*virtualSize)
+	if fee < minimumFee {
+		log.Fatalf("Fee rate of %v sat/vB gives a fee of %d satoshi, but a replacement must pay at least the original fee of %d satoshi plus %d sat/vB, %d satoshi. Raise --fee-rate.", flagFeeRate, fee, originalFee, incrementalRelayFeeRate, minimumFee)
+	}
+	change := psbt.UnsignedTx.Outputs[changeIndex].Value - (fee - originalFee)
+	switch {
+	case change < 0 || (change < changeDustLimit && len(psbt.UnsignedTx.Outputs) == 1):
+		log.Fatalf("Change of %d satoshi is not enough to raise the fee from %d to %d satoshi.", psbt.UnsignedTx.Outputs[changeIndex].Value, originalFee, fee)
+	case change >= changeDustLimit:
+		psbt.UnsignedTx.Outputs[changeIndex].Value = change
+	default:
+		//Change below the dust limit is added to the fee instead, making the transaction smaller too
+		psbt.UnsignedTx.Outputs = append(psbt.UnsignedTx.Outputs[:changeIndex], psbt.UnsignedTx.Outputs[changeIndex+1:]...)
+		psbt.Outputs = append(psbt.Outputs[:changeIndex], psbt.Outputs[changeIndex+1:]...)
+		fee = inputAmount - sumOutputs(psbt.UnsignedTx.Outputs)
+		virtualSize = estimatedVirtualSize(psbt)
+	}
+	if fee > int64(flagMaxFee) {
+		log.Fatalf("Fee of %d satoshi is more than the maximum fee of %d satoshi. Check --fee-rate, or raise --max-fee if this fee is intended.", fee, flagMaxFee)
+	}
+
+	return bumpedPsbt{
+		Psbt:            psbt,
+		OriginalFee:     originalFee,
+		Fee:             fee,
+		VirtualSize:     virtualSize,
+		PreviousSigners: previousSigners,
+	}
+}
+
+// psbtInputAmount returns the total amount in Satoshis of the outputs spent by psbt, read from the previous transactions it
+// carries, or flagInputAmount for a single input without one.
+func psbtInputAmount(psbt *btcutils.Psbt, flagInputAmount int) int64 {
+	var total int64
+	for i, input := range psbt.Inputs {
+		txIn := psbt.UnsignedTx.Inputs[i]
+		switch {
+		case input.WitnessUtxo != nil:
+			total += input.WitnessUtxo.Value
+		case input.NonWitnessUtxo != nil:
+			if input.NonWitnessUtxo.TxHash() != hex.EncodeToString(btcutils.ReverseBytes(txIn.PreviousTxHash)) {
+				log.Fatalf("PSBT input #%d previous transaction does not match the transaction being spent.", i)
+			}
+			utxo, err := input.NonWitnessUtxo.UTXO(txIn.PreviousOutputIndex)
+			if err != nil {
+				log.Fatal(err)
+			}
+			total += utxo.Amount
+		case len(psbt.Inputs) == 1 && flagInputAmount > 0:
+			total += int64(flagInputAmount)
+		default:
+			log.Fatalf("Amount of PSBT input #%d is unknown. Give --input-amount, or create the PSBT with --prev-tx.", i)
+		}
+	}
+	return total
+}
+
+// estimatedVirtualSize returns the virtual size of the transaction of psbt once cosigners have signed, as estimated by estimateSignedTransaction.
+func estimatedVirtualSize(psbt *btcutils.Psbt) int {
+	transaction, err := btcutils.ParseTransaction(estimateSignedTransaction(psbt))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return transaction.VirtualSize()
+}
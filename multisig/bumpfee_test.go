@@ -0,0 +1,57 @@
+package multisig
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+func TestGenerateBumpFee(t *testing.T) {
+	//2-of-3 PSBT spend of the output of testPrevTxHex at 2 sat/vB, with change back to the multisig address, signed by the first
+	//cosigner before its fee is raised. The estimated size once signed is 469 vB, so the original fee is 938 satoshi.
+	testPrivateKeys := []string{"5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3", "5JjHVMwJdjPEPQhq34WMUhzLcEd4SD7HgZktEh8WHstWcCLRceV"}
+	testRedeemScript := "524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae"
+	testChangeAddress := "347N1Thc213QqfYCz3PZkjoJpNv5b14kBd"
+	testFirstPublicKey := "04a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd"
+
+	input := parseInput("", "", 0, 0, testPrevTxHex)
+	psbt := generatePsbtCreate("18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx", testRedeemScript, input, 30000, parsePrevTx(testPrevTxHex), btcutils.SIGHASH_ALL, TransactionOptions{ChangeAddress: testChangeAddress, FeeRate: 2, MaxFee: 100000}, btcutils.MainNet)
+	if psbt.UnsignedTx.Inputs[0].Sequence != btcutils.MaxRBFSequenceNum {
+		t.Fatalf("PSBT input sequence number 0x%08x does not signal replace-by-fee.", psbt.UnsignedTx.Inputs[0].Sequence)
+	}
+	signedPsbt := generatePsbtSign(psbt.Base64(), testPrivateKeys[0], btcutils.SIGHASH_ALL, btcutils.MainNet).Base64()
+
+	testBumps := []struct {
+		feeRate float64
+		fee     int64
+		outputs int
+	}{
+		{10, 4690, 2},
+		//Change of 425 satoshi left at 75 sat/vB is below the dust limit, so it is paid as fee too
+		{75, 35600, 1},
+	}
+	for _, testBump := range testBumps {
+		bumped := generateBumpFee(signedPsbt, testBump.feeRate, testChangeAddress, 0, 100000, btcutils.MainNet)
+		if bumped.OriginalFee != 938 || bumped.Fee != testBump.fee || len(bumped.Psbt.UnsignedTx.Outputs) != testBump.outputs || len(bumped.Psbt.Outputs) != testBump.outputs {
+			t.Errorf("PSBT bumped to %v sat/vB pays fee %d (from %d) with %d outputs, expected %d (from 938) with %d.", testBump.feeRate, bumped.Fee, bumped.OriginalFee, len(bumped.Psbt.UnsignedTx.Outputs), testBump.fee, testBump.outputs)
+		}
+		if len(bumped.Psbt.Inputs[0].PartialSigs) != 0 || len(bumped.PreviousSigners) != 1 || bumped.PreviousSigners[0] != testFirstPublicKey {
+			t.Error("Bumped PSBT signatures not removed, or their cosigners not reported:", bumped.PreviousSigners)
+		}
+		//Cosigners sign the bumped PSBT again, giving a valid transaction at no less than the fee rate
+		firstSignedPsbt := generatePsbtSign(bumped.Psbt.Base64(), testPrivateKeys[0], btcutils.SIGHASH_ALL, btcutils.MainNet).Base64()
+		secondSignedPsbt := generatePsbtSign(bumped.Psbt.Base64(), testPrivateKeys[1], btcutils.SIGHASH_ALL, btcutils.MainNet).Base64()
+		finalTransactionHex := generatePsbtExtract(generatePsbtFinalize(generatePsbtCombine(firstSignedPsbt + "," + secondSignedPsbt).Base64()).Base64())
+		_, results := generateVerify(finalTransactionHex, testPrevTxHex, "", false)
+		if results[0] != nil {
+			t.Error("Bumped PSBT transaction is invalid:", results[0])
+		}
+		checkTransactionFee(t, finalTransactionHex, 65600, testBump.fee)
+		rawTransaction, _ := hex.DecodeString(finalTransactionHex)
+		if requiredFee := feeForRate(testBump.feeRate, rawTransaction); requiredFee > bumped.Fee {
+			testutils.CompareError(t, "Bumped PSBT transaction fee lower than its fee rate needs.", requiredFee, bumped.Fee)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// decode.go - Decoding raw transactions into human-readable form.
+package multisig
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
+
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// decodedTransaction is the structured form of a raw transaction, with JSON field names
+// following Bitcoin Core's decoderawtransaction.
+type decodedTransaction struct {
+	TxID     string         `json:"txid"`
+	Hash     string         `json:"hash"`
+	Version  int32          `json:"version"`
+	Size     int            `json:"size"`
+	VSize    int            `json:"vsize"`
+	Weight   int            `json:"weight"`
+	LockTime uint32         `json:"locktime"`
+	Inputs   []decodedTxIn  `json:"vin"`
+	Outputs  []decodedTxOut `json:"vout"`
+}
+
+type decodedTxIn struct {
+	Coinbase  string         `json:"coinbase,omitempty"`
+	TxID      string         `json:"txid,omitempty"`
+	Vout      *uint32        `json:"vout,omitempty"`
+	ScriptSig *decodedScript `json:"scriptSig,omitempty"`
+	Witness   []string       `json:"txinwitness,omitempty"`
+	Sequence  uint32         `json:"sequence"`
+}
+
+type decodedTxOut struct {
+	Value        json.Number   `json:"value"`
+	Satoshis     int64         `json:"-"`
+	N            int           `json:"n"`
+	ScriptPubKey decodedScript `json:"scriptPubKey"`
+}
+
+type decodedScript struct {
+	Hex string `json:"hex"`
+}
+
+// OutputDecode formats and prints relevant outputs to the user.
+func OutputDecode(flagTransaction string, flagJSON bool) {
+	decoded := generateDecode(flagTransaction)
+
+	if flagJSON {
+		decodedJSON, err := json.MarshalIndent(decoded, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(string(decodedJSON))
+		return
+	}
+	fmt.Println("-----------------------------------------------------------------------------------------------------------------------------------")
+	fmt.Println("Transaction ID: ")
+	fmt.Println(decoded.TxID)
+	if decoded.Hash != decoded.TxID {
+		fmt.Println("Witness hash: ")
+		fmt.Println(decoded.Hash)
+	}
+	fmt.Printf("Version: %d\n", decoded.Version)
+	fmt.Printf("Size: %d bytes (virtual size %d vbytes, weight %d)\n", decoded.Size, decoded.VSize, decoded.Weight)
+	fmt.Printf("Locktime: %d\n", decoded.LockTime)
+	fmt.Println("-----------------------------------------------------------------------------------------------------------------------------------")
+	fmt.Printf("INPUTS (%d)\n", len(decoded.Inputs))
+	for i, input := range decoded.Inputs {
+		fmt.Printf("#%d\n", i)
+		if input.Coinbase != "" {
+			fmt.Printf("\tCoinbase: %v\n", input.Coinbase)
+		} else {
+			fmt.Printf("\tOutpoint: %v:%d\n", input.TxID, *input.Vout)
+			fmt.Printf("\tscriptSig: %v\n", input.ScriptSig.Hex)
+		}
+		for j, item := range input.Witness {
+			fmt.Printf("\tWitness #%d: %v\n", j, item)
+		}
+		fmt.Printf("\tSequence: 0x%08x\n", input.Sequence)
+	}
+	fmt.Println("-----------------------------------------------------------------------------------------------------------------------------------")
+	fmt.Printf("OUTPUTS (%d)\n", len(decoded.Outputs))
+	for _, output := range decoded.Outputs {
+		fmt.Printf("#%d\n", output.N)
+		fmt.Printf("\tAmount: %d satoshi (%v BTC)\n", output.Satoshis, output.Value)
+		fmt.Printf("\tscriptPubKey: %v\n", output.ScriptPubKey.Hex)
+	}
+	fmt.Println("-----------------------------------------------------------------------------------------------------------------------------------")
+}
+
+// generateDecode is the high-level logic for decoding raw transactions with the 'go-bitcoin-multisig decode' subcommand.
+// Takes flagTransaction (hex representation of raw transaction, eg. as output by fund or spend) as argument.
+func generateDecode(flagTransaction string) decodedTransaction {
+	rawTransaction, err := hex.DecodeString(strings.TrimSpace(flagTransaction))
+	if err != nil {
+		log.Fatal(err)
+	}
+	transaction, err := btcutils.ParseTransaction(rawTransaction)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	decoded := decodedTransaction{
+		TxID:     transaction.TxHash(),
+		Hash:     transaction.WitnessHash(),
+		Version:  transaction.Version,
+		Size:     len(rawTransaction),
+		VSize:    transaction.VirtualSize(),
+		Weight:   transaction.Weight(),
+		LockTime: transaction.LockTime,
+		Inputs:   make([]decodedTxIn, len(transaction.Inputs)),
+		Outputs:  make([]decodedTxOut, len(transaction.Outputs)),
+	}
+	for i, txIn := range transaction.Inputs {
+		input := decodedTxIn{Sequence: txIn.Sequence}
+		//Coinbase inputs spend the null outpoint and carry arbitrary data in place of a scriptSig
+		if bytes.Equal(txIn.PreviousTxHash, make([]byte, 32)) && txIn.PreviousOutputIndex == 0xffffffff {
+			input.Coinbase = hex.EncodeToString(txIn.ScriptSig)
+		} else {
+			vout := txIn.PreviousOutputIndex
+			input.TxID = hex.EncodeToString(btcutils.ReverseBytes(txIn.PreviousTxHash))
+			input.Vout = &vout
+			input.ScriptSig = &decodedScript{Hex: hex.EncodeToString(txIn.ScriptSig)}
+		}
+		for _, item := range txIn.Witness {
+			input.Witness = append(input.Witness, hex.EncodeToString(item))
+		}
+		decoded.Inputs[i] = input
+	}
+	for i, txOut := range transaction.Outputs {
+		decoded.Outputs[i] = decodedTxOut{
+			Value:        json.Number(formatBTC(txOut.Value)),
+			Satoshis:     txOut.Value,
+			N:            i,
+			ScriptPubKey: decodedScript{Hex: hex.EncodeToString(txOut.ScriptPubKey)},
+		}
+	}
+
+	return decoded
+}
+
+// formatBTC formats a satoshi amount as a decimal bitcoin amount with 8 decimal places.
+func formatBTC(satoshis int64) string {
+	sign := ""
+	if satoshis < 0 {
+		sign = "-"
+		satoshis = -satoshis
+	}
+	return fmt.Sprintf("%s%d.%08d", sign, satoshis/100000000, satoshis%100000000)
+}
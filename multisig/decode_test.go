@@ -0,0 +1,31 @@
+package multisig
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"testing"
+)
+
+func TestGenerateDecode(t *testing.T) {
+	testRawTxHex := "01000000013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000ffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000"
+	testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+	testValue := "0.00055600"
+	testScriptPubKeyHex := "76a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac"
+
+	decoded := generateDecode(testRawTxHex)
+	if decoded.Size != len(testRawTxHex)/2 || decoded.VSize != decoded.Size || decoded.Weight != 4*decoded.Size {
+		t.Error("Decoded legacy transaction size, virtual size and weight inconsistent.", decoded.Size, decoded.VSize, decoded.Weight)
+	}
+	if decoded.TxID != decoded.Hash {
+		testutils.CompareError(t, "Decoded legacy transaction hash different from transaction ID.", decoded.TxID, decoded.Hash)
+	}
+	if len(decoded.Inputs) != 1 || decoded.Inputs[0].TxID != testInputTx || *decoded.Inputs[0].Vout != 0 {
+		testutils.CompareError(t, "Decoded input different from expected input.", testInputTx, decoded.Inputs)
+	}
+	if len(decoded.Outputs) != 1 || string(decoded.Outputs[0].Value) != testValue {
+		testutils.CompareError(t, "Decoded output value different from expected value.", testValue, decoded.Outputs)
+	}
+	if decoded.Outputs[0].ScriptPubKey.Hex != testScriptPubKeyHex {
+		testutils.CompareError(t, "Decoded scriptPubKey different from expected script.", testScriptPubKeyHex, decoded.Outputs[0].ScriptPubKey.Hex)
+	}
+}
@@ -0,0 +1,156 @@
+// fee.go - Working out change and transaction fees for fund and spend, and refusing fees above a ceiling.
+package multisig
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
+
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+)
+
+// changeDustLimit is the smallest change output created, in Satoshis. Smaller change is added to the fee instead,
+// since outputs below the dust limit are non-standard and cost more in fees to spend than they are worth.
+const changeDustLimit = 546
+
+// TransactionOptions are the change, fee, locktime and replace-by-fee flags of the fund, spend and psbt create subcommands.
+type TransactionOptions struct {
+	ChangeAddress string  //Address receiving the balance left over after the fee, if any
+	Fee           int     //Fee in Satoshis
+	FeeRate       float64 //Fee rate in Satoshis per virtual byte, worked out from the size of the signed transaction
+	MaxFee        int     //Highest fee in Satoshis allowed
+	LockTime      int     //Block height or Unix timestamp before which the transaction cannot be mined, 0 for none
+	NoRBF         bool    //Do not signal the transaction can be replaced by one paying a higher fee (BIP125)
+	UnknownFee    bool    //Build the transaction without the input amount, paying all of the input not sent as fee
+}
+
+// signOutputs signs a transaction with the given outputs and returns the serialized signed transaction.
+type signOutputs func(outputs []*btcutils.TxOut) []byte
+
+// payWithFee builds and signs the transaction paying flagAmount to scriptPubKey out of an input of inputAmount Satoshis.
+// Takes options (the change address, which needs a fee or fee rate, the fee or fee rate and the maximum fee), network (network the
+// change address must belong to) and sign, which signs the transaction for a set of outputs, as arguments.
+// Without an input amount the fee cannot be known or checked against the maximum fee, so the transaction is refused unless
+// options.UnknownFee is set, in which case only flagAmount is paid, leaving the rest of the input as fee.
+func payWithFee(inputAmount int64, flagAmount int, scriptPubKey []byte, options TransactionOptions, network *btcutils.Network, sign signOutputs) []byte {
+	amount := int64(flagAmount)
+	if options.Fee < 0 || options.FeeRate < 0 || options.MaxFee < 0 {
+		log.Fatal("--fee, --fee-rate and --max-fee cannot be negative.")
+	}
+	if options.Fee > 0 && options.FeeRate > 0 {
+		log.Fatal("Give either --fee or --fee-rate, not both.")
+	}
+	//Change would otherwise take the whole balance, paying no fee at all
+	if options.ChangeAddress != "" && options.Fee == 0 && options.FeeRate == 0 {
+		log.Fatal("--change-address needs --fee or --fee-rate, or the transaction would pay no fee.")
+	}
+	if err := checkInputAmount(inputAmount, options); err != nil {
+		log.Fatal(err)
+	}
+	if inputAmount <= 0 {
+		return sign([]*btcutils.TxOut{btcutils.NewTxOut(amount, scriptPubKey)})
+	}
+	if amount > inputAmount {
+		log.Fatalf("Amount of %d satoshi is more than the input amount of %d satoshi.", amount, inputAmount)
+	}
+	var changeScriptPubKey []byte
+	if options.ChangeAddress != "" {
+		changeScriptPubKey = destinationScriptPubKey(options.ChangeAddress, network)
+	}
+	//Outputs paying flagAmount, with the balance after fee going to change if it is not dust
+	outputsForFee := func(fee int64) []*btcutils.TxOut {
+		outputs := []*btcutils.TxOut{btcutils.NewTxOut(amount, scriptPubKey)}
+		change := inputAmount - amount - fee
+		if changeScriptPubKey != nil && change >= changeDustLimit {
+			outputs = append(outputs, btcutils.NewTxOut(change, changeScriptPubKey))
+		}
+		return outputs
+	}
+
+	//A fee rate needs the size of the signed transaction, so sign until the fee covers the size it was signed at.
+	//Signatures differ in length by a byte or so, so this takes two or three rounds at most.
+	fee := int64(options.Fee)
+	signedTransaction := sign(outputsForFee(fee))
+	for round := 0; options.FeeRate > 0 && round < 5; round++ {
+		requiredFee := feeForRate(options.FeeRate, signedTransaction)
+		if requiredFee <= fee {
+			break
+		}
+		fee = requiredFee
+		signedTransaction = sign(outputsForFee(fee))
+	}
+
+	err := checkFee(inputAmount, amount, outputsForFee(fee), fee, changeScriptPubKey == nil && (options.Fee > 0 || options.FeeRate > 0), int64(options.MaxFee))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return signedTransaction
+}
+
+// checkInputAmount checks inputAmount is known, as needed to work out change and fees and to check the fee against the
+// maximum fee, unless options.UnknownFee allows paying all of the input not sent as fee.
+func checkInputAmount(inputAmount int64, options TransactionOptions) error {
+	switch {
+	case inputAmount > 0:
+		return nil
+	case options.ChangeAddress != "" || options.Fee > 0 || options.FeeRate > 0:
+		return errors.New("--input-amount is needed to work out change and fees.")
+	case !options.UnknownFee:
+		return errors.New("Input amount is unknown, so all of the input not sent would be paid as fee, without checking it against --max-fee. Give --input-amount, --input with an amount or --prev-tx, or --unknown-fee to build the transaction anyway.")
+	}
+	return nil
+}
+
+// checkFee checks the fee paid by outputs out of inputAmount Satoshis covers the intended fee, and is no more than maxFee.
+// If noChange is set, the fee was given without a change address, so any balance above the intended fee that is not dust is refused too.
+func checkFee(inputAmount int64, amount int64, outputs []*btcutils.TxOut, fee int64, noChange bool, maxFee int64) error {
+	actualFee := inputAmount - sumOutputs(outputs)
+	switch {
+	case actualFee < fee:
+		return errors.New(fmt.Sprintf("Input amount of %d satoshi does not cover the amount of %d satoshi plus a fee of %d satoshi.", inputAmount, amount, fee))
+	case noChange && actualFee-fee >= changeDustLimit:
+		return errors.New(fmt.Sprintf("Input amount leaves %d satoshi above the %d satoshi fee, which would be paid as fee too. Give a --change-address or a larger --amount.", actualFee-fee, fee))
+	case actualFee > maxFee:
+		return errors.New(fmt.Sprintf("Fee of %d satoshi is more than the maximum fee of %d satoshi. Check --amount, --input-amount and --change-address, or raise --max-fee if this fee is intended.", actualFee, maxFee))
+	}
+	return nil
+}
+
+// feeForRate returns the fee in Satoshis for a serialized signed transaction at feeRate Satoshis per virtual byte, rounded up.
+func feeForRate(feeRate float64, signedTransaction []byte) int64 {
+	transaction, err := btcutils.ParseTransaction(signedTransaction)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return int64(math.Ceil(feeRate * float64(transaction.VirtualSize())))
+}
+
+// sumOutputs returns the total amount in Satoshis paid by outputs.
+func sumOutputs(outputs []*btcutils.TxOut) int64 {
+	var total int64
+	for _, output := range outputs {
+		total += output.Value
+	}
+	return total
+}
+
+// outputFee prints the fee of a serialized signed transaction spending inputAmount Satoshis, or a warning if inputAmount is unknown,
+// as allowed by --unknown-fee.
+func outputFee(finalTransactionHex string, inputAmount int64) {
+	if inputAmount <= 0 {
+		fmt.Println("WARNING: with --unknown-fee the transaction fee is unknown. All of the input not sent is paid as fee.")
+		return
+	}
+	finalTransaction, err := hex.DecodeString(finalTransactionHex)
+	if err != nil {
+		log.Fatal(err)
+	}
+	transaction, err := btcutils.ParseTransaction(finalTransaction)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fee := inputAmount - sumOutputs(transaction.Outputs)
+	fmt.Printf("Transaction fee: %d satoshi (%.2f sat/vB, %d vB)\n", fee, float64(fee)/float64(transaction.VirtualSize()), transaction.VirtualSize())
+}
@@ -0,0 +1,123 @@
+package multisig
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+// testTransactionOptions pays no change or fee, does not signal replace-by-fee and allows an unknown input amount, as transactions
+// did before any of these existed
+var testTransactionOptions = TransactionOptions{MaxFee: 100000, NoRBF: true, UnknownFee: true}
+
+func TestGenerateSpendWithChange(t *testing.T) {
+	//2-of-3 P2WSH compressed spending multisig test, sending change back to the multisig address
+	testPrivateKeys := "L1qH5hF9PBBTEx8fyeSDiFp2ti7eSCsj3itBFTNV7a2w2ZkJWBXK,L1FdnBQgq8585udD26UZPHv5YX8AAGSkddah9S88HVcMfumhRQkt"
+	testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
+	testChangeAddress := "bc1qznadz5sy773nv3pr2n2jmymcyzkrn64tttz5640nz6988zmemzfs3dn6jt"
+	testWitnessScript := "522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae"
+	testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+	testInputIndex := 0
+	testAmount := 30000
+	testInputAmount := 65600
+	{
+		//Fixed fee of 1000 satoshi, leaving 34600 satoshi change
+		testFinalTransactionHex := "010000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000ffffffff0230750000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac288700000000000022002014fad15204f7a336442354d52d937820ac39eaab5ac54d55f3168a738b79d893040047304402204701a5e28abeff735a8bdac32f35e48cfe9aa52fa80561011635d9dc75cd02e80220078a311ae8aefafae3b6650a07f3eb302ef3395973f95196e9a741e6a1c0a86601473044022009a371ffb4ed690e30b54b051617ffe77e7d081fe330db64263c5be12569e62c02207a1524d1ff23e40db5034c4613a77ea5bfdc6d94c6b6e0e96745c03815b115eb0169522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae00000000"
+
+		finalTransactionHex := generateSpend(testPrivateKeys, testDestination, testWitnessScript, &btcutils.UTXO{TxHash: testInputTx, Index: uint32(testInputIndex), Amount: int64(testInputAmount)}, testAmount, addressTypeP2WSH, false, false, false, btcutils.SIGHASH_ALL, TransactionOptions{ChangeAddress: testChangeAddress, Fee: 1000, MaxFee: 100000, NoRBF: true}, btcutils.MainNet)
+		if testFinalTransactionHex != finalTransactionHex {
+			testutils.CompareError(t, "Generated spend transaction with change different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
+		}
+		checkTransactionFee(t, finalTransactionHex, int64(testInputAmount), 1000)
+	}
+	{
+		//Fee rate of 2.5 sat/vB
+		testFinalTransactionHex := "010000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000ffffffff0230750000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac308900000000000022002014fad15204f7a336442354d52d937820ac39eaab5ac54d55f3168a738b79d893040047304402201f87af27c85d2c1434af3533b4e99299c953ca8697e3a5d4567a9866b5a2dbed0220223d092a7f8a7ab4aef58aa0a9262e0fa19c1529388eca07adb378b02d023a2a014830450221009fd76e78001b4db07d1de6ed687d18fc28ec1d950ad756eb5a2226d0cbaab045022045717d9785da6ce3d8b2c0dce774284c10dc665ce975cbe429a50cc0a68e88020169522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae00000000"
+
+		finalTransactionHex := generateSpend(testPrivateKeys, testDestination, testWitnessScript, &btcutils.UTXO{TxHash: testInputTx, Index: uint32(testInputIndex), Amount: int64(testInputAmount)}, testAmount, addressTypeP2WSH, false, false, false, btcutils.SIGHASH_ALL, TransactionOptions{ChangeAddress: testChangeAddress, FeeRate: 2.5, MaxFee: 100000, NoRBF: true}, btcutils.MainNet)
+		if testFinalTransactionHex != finalTransactionHex {
+			testutils.CompareError(t, "Generated spend transaction at fee rate different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
+		}
+		rawTransaction, _ := hex.DecodeString(finalTransactionHex)
+		checkTransactionFee(t, finalTransactionHex, int64(testInputAmount), feeForRate(2.5, rawTransaction))
+	}
+}
+
+func TestGenerateFundWithChange(t *testing.T) {
+	//Funding a P2WSH multisig address from a 100000 satoshi input at 10 sat/vB, with change back to the funding address
+	testPrivateKeyWIF := "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"
+	testInputTx := "3ad337270ac0ba14fbce812291b7d95338c878709ea8123a4d88c3c29efbc6ac"
+	testInputIndex := 0
+	testAmount := 65600
+	testInputAmount := 100000
+	testDestination := "bc1quwq6xpy9ret6e7velxsnfddmj8f5plfn9z09fsaqsz44704l24kswgzmak"
+	testChangeAddress := "1EK4KToKVHdz787e26JCQuSTtnPAvJZRC5"
+	testFinalTransactionHex := "0100000001acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000008b483045022100ab94497aec1da1a7367c1a5545650f0214f759ea5ea0838d771e1e1f7fb06c1f022041661b63d035354ebeb9f8efdd90cd4bd33d36804d746207f256044326cfc82801410431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddfffffffff024000010000000000220020e381a304851e57acf999f9a134b5bb91d340fd33289e54c3a080ab5f3ebf556df27b0000000000001976a9149203e47a16f799ded03532e3e452606fdc52007e88ac00000000"
+
+	finalTransactionHex := generateFund(testPrivateKeyWIF, &btcutils.UTXO{TxHash: testInputTx, Index: uint32(testInputIndex), Amount: int64(testInputAmount)}, testAmount, testDestination, btcutils.SIGHASH_ALL, TransactionOptions{ChangeAddress: testChangeAddress, FeeRate: 10, MaxFee: 100000, NoRBF: true}, btcutils.MainNet)
+	if testFinalTransactionHex != finalTransactionHex {
+		testutils.CompareError(t, "Generated funding transaction with change different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
+	}
+	rawTransaction, _ := hex.DecodeString(finalTransactionHex)
+	checkTransactionFee(t, finalTransactionHex, int64(testInputAmount), feeForRate(10, rawTransaction))
+}
+
+func TestCheckFee(t *testing.T) {
+	testScriptPubKey, _ := hex.DecodeString("76a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac")
+	testOutputs := []*btcutils.TxOut{btcutils.NewTxOut(55600, testScriptPubKey)}
+	testCases := []struct {
+		inputAmount int64
+		fee         int64
+		noChange    bool
+		maxFee      int64
+		valid       bool
+	}{
+		{65600, 10000, false, 100000, true},
+		{65600, 9800, true, 100000, true},    //Balance above fee is dust
+		{65600, 0, false, 10000, true},       //Fee at the ceiling
+		{65600, 10001, false, 100000, false}, //Input does not cover fee
+		{65600, 1000, true, 100000, false},   //Balance above fee would be paid as fee without change
+		{65600, 0, false, 9999, false},       //Fee above the ceiling
+		{1055600, 0, false, 100000, false},   //Forgotten change
+	}
+	for _, testCase := range testCases {
+		err := checkFee(testCase.inputAmount, 55600, testOutputs, testCase.fee, testCase.noChange, testCase.maxFee)
+		if (err == nil) != testCase.valid {
+			t.Errorf("checkFee with input amount %d, fee %d and maximum fee %d returned error %v, expected valid %v.", testCase.inputAmount, testCase.fee, testCase.maxFee, err, testCase.valid)
+		}
+	}
+}
+
+func TestCheckInputAmount(t *testing.T) {
+	testCases := []struct {
+		inputAmount int64
+		options     TransactionOptions
+		valid       bool
+	}{
+		{65600, TransactionOptions{MaxFee: 100000}, true},
+		{0, TransactionOptions{MaxFee: 100000}, false},                                                                 //Missing input amount would pay the rest of the input as fee unchecked
+		{0, TransactionOptions{MaxFee: 100000, UnknownFee: true}, true},                                                //Unless --unknown-fee is given
+		{0, TransactionOptions{FeeRate: 2, MaxFee: 100000, UnknownFee: true}, false},                                   //Fee rate needs the input amount
+		{0, TransactionOptions{ChangeAddress: "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx", Fee: 1000, MaxFee: 100000}, false}, //Change needs the input amount
+	}
+	for _, testCase := range testCases {
+		err := checkInputAmount(testCase.inputAmount, testCase.options)
+		if (err == nil) != testCase.valid {
+			t.Errorf("checkInputAmount with input amount %d and options %+v returned error %v, expected valid %v.", testCase.inputAmount, testCase.options, err, testCase.valid)
+		}
+	}
+}
+
+// checkTransactionFee checks a transaction spending inputAmount Satoshis pays a fee of exactly fee Satoshis.
+func checkTransactionFee(t *testing.T, finalTransactionHex string, inputAmount int64, fee int64) {
+	rawTransaction, _ := hex.DecodeString(finalTransactionHex)
+	transaction, err := btcutils.ParseTransaction(rawTransaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actualFee := inputAmount - sumOutputs(transaction.Outputs); actualFee != fee {
+		testutils.CompareError(t, "Transaction fee different from expected fee.", fee, actualFee)
+	}
+}
@@ -1,8 +1,7 @@
-// fund.go - Funding P2SH address from a Bitcoin address.
+// fund.go - Funding a multisig or any other address from a Bitcoin address.
 package multisig
 
 import (
-	"github.com/prettymuchbryce/hellobitcoin/base58check"
 	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
 
 	"bytes"
@@ -12,32 +11,43 @@ import (
 )
 
 //OutputFund formats and prints relevant outputs to the user.
-func OutputFund(flagPrivateKey string, flagInputTx string, flagAmount int, flagP2SHDestination string) {
-	finalTransactionHex := generateFund(flagPrivateKey, flagInputTx, flagAmount, flagP2SHDestination)
+func OutputFund(flagPrivateKey string, flagInputTx string, flagInputIndex int, flagAmount int, flagDestination string, flagInputAmount int, flagInput string, flagPrevTx string, flagSigHash string, options TransactionOptions, flagNetwork string) {
+	input := parseInput(flagInput, flagInputTx, flagInputIndex, flagInputAmount, flagPrevTx)
+	hashType := parseSigHashType(flagSigHash)
+	finalTransactionHex := generateFund(flagPrivateKey, input, flagAmount, flagDestination, hashType, options, parseNetwork(flagNetwork))
 
 	//Output our final transaction
 	fmt.Printf(`
 -----------------------------------------------------------------------------------------------------------------------------------
 Your raw funding transaction is:
 %v
-Broadcast this transaction to fund your P2SH address.
------------------------------------------------------------------------------------------------------------------------------------
+Broadcast this transaction to fund your multisig address.
 `,
 		finalTransactionHex,
 	)
+	outputFee(finalTransactionHex, input.Amount)
+	outputLockTime(finalTransactionHex)
+	fmt.Println("-----------------------------------------------------------------------------------------------------------------------------------")
+	outputSigHashWarning(hashType)
 }
 
-// generateFund is the high-level logic for funding any P2SH address with the 'go-bitcoin-multisig fund' subcommand.
-// Takes flagPrivateKey (private key of input Bitcoins to fund with), flagInputTx (input transaction hash of
-// Bitcoins to fund with), flagAmount (amount in Satoshis to send, with balance left over from input being used
-// as transaction fee) and flagP2SHDestination (destination P2SH multisig address which is being funded) as arguments.
-func generateFund(flagPrivateKey string, flagInputTx string, flagAmount int, flagP2SHDestination string) string {
-	//Get private key as decoded raw bytes
-	privateKey := base58check.Decode(flagPrivateKey)
+// generateFund is the high-level logic for funding any address with the 'go-bitcoin-multisig fund' subcommand.
+// Takes flagPrivateKey (private key of input Bitcoins to fund with), input (output being spent, with its amount needed to work
+// out change and fees, and its scriptPubKey checked against the private key when known), flagAmount (amount in Satoshis to send),
+// flagDestination (destination address which is being funded, usually a multisig address but of any type), hashType (signature hash
+// type, eg. SIGHASH_ALL), options (change address, fee, locktime and replace-by-fee) and network (network the private key and addresses
+// must belong to) as arguments.
+// Without the input amount, the transaction is refused unless options.UnknownFee is set, when balance left over from input is used as transaction fee.
+func generateFund(flagPrivateKey string, input *btcutils.UTXO, flagAmount int, flagDestination string, hashType uint32, options TransactionOptions, network *btcutils.Network) string {
+	//Get private key as decoded raw bytes, and whether its public key is compressed
+	privateKey, compressed, err := btcutils.ParseWIF(flagPrivateKey, network)
+	if err != nil {
+		log.Fatal(err)
+	}
 	//In order to construct the raw transaction we need the input transaction hash,
-	//the P2SH destination address, the number of satoshis to send, and the scriptSig
+	//the destination address, the number of satoshis to send, and the scriptSig
 	//which is temporarily (prior to signing) the ScriptPubKey of the input transaction.
-	publicKey, err := btcutils.NewPublicKey(privateKey)
+	publicKey, err := btcutils.NewPublicKey(privateKey, compressed)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -49,66 +59,55 @@ func generateFund(flagPrivateKey string, flagInputTx string, flagAmount int, fla
 	if err != nil {
 		log.Fatal(err)
 	}
-	redeemScriptHash := base58check.Decode(flagP2SHDestination)
-	//Create our scriptPubKey
-	scriptPubKey, err := btcutils.NewP2SHScriptPubKey(redeemScriptHash)
-	if err != nil {
-		log.Fatal(err)
-	}
-	//Create unsigned raw transaction
-	rawTransaction, err := btcutils.NewRawTransaction(flagInputTx, flagAmount, tempScriptSig, scriptPubKey)
-	if err != nil {
-		log.Fatal(err)
-	}
-	//After completing the raw transaction, we append
-	//SIGHASH_ALL in little-endian format to the end of the raw transaction.
-	hashCodeType, err := hex.DecodeString("01000000")
-	if err != nil {
-		log.Fatal(err)
-	}
-	var rawTransactionBuffer bytes.Buffer
-	rawTransactionBuffer.Write(rawTransaction)
-	rawTransactionBuffer.Write(hashCodeType)
-	rawTransactionWithHashCodeType := rawTransactionBuffer.Bytes()
-	//Sign the raw transaction, and output it to the console.
-	finalTransaction, err := signP2PKHTransaction(rawTransactionWithHashCodeType, privateKey, scriptPubKey, flagInputTx, flagAmount)
-	if err != nil {
-		log.Fatal(err)
+	checkInputScriptPubKey(input, tempScriptSig, "a P2PKH output of the private key")
+	//Create our scriptPubKey, matching the type of the destination address
+	scriptPubKey := destinationScriptPubKey(flagDestination, network)
+	//Create and sign the raw transaction, with change and fee outputs worked out by payWithFee
+	lockTime := parseLockTime(options.LockTime)
+	sign := func(outputs []*btcutils.TxOut) []byte {
+		transaction := newUnsignedTransaction(input, tempScriptSig, outputs, lockTime, 0, !options.NoRBF)
+		//The signature hash commits to the parts of the transaction selected by hashType, with the scriptPubKey being spent in place of the scriptSig
+		hash, err := btcutils.SignatureHash(transaction, 0, tempScriptSig, hashType)
+		if err != nil {
+			log.Fatal(err)
+		}
+		//Sign the transaction
+		finalTransaction, err := signP2PKHTransaction(hash, hashType, privateKey, compressed, transaction)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return finalTransaction
 	}
+	finalTransaction := payWithFee(input.Amount, flagAmount, scriptPubKey, options, network, sign)
+	verifySignedTransaction(finalTransaction, tempScriptSig, input.Amount)
 	finalTransactionHex := hex.EncodeToString(finalTransaction)
 
 	return finalTransactionHex
 }
 
-// signP2PKHTransaction signs a raw P2PKH transaction, given a private key and the scriptPubKey, inputTx and amount
-// to construct the final transaction.
-func signP2PKHTransaction(rawTransaction []byte, privateKey []byte, scriptPubKey []byte, inputTx string, amount int) ([]byte, error) {
-	publicKey, err := btcutils.NewPublicKey(privateKey)
-	if err != nil {
-		return nil, err
-	}
-	signature, err := btcutils.NewSignature(rawTransaction, privateKey)
+// signP2PKHTransaction signs a P2PKH transaction, given its signature hash for hashType, a private key, whether its public key
+// is compressed and the unsigned transaction whose first input receives the final scriptSig.
+func signP2PKHTransaction(hash []byte, hashType uint32, privateKey []byte, compressed bool, transaction *btcutils.Transaction) ([]byte, error) {
+	publicKey, err := btcutils.NewPublicKey(privateKey, compressed)
 	if err != nil {
 		return nil, err
 	}
-	hashCodeType, err := hex.DecodeString("01")
+	signature, err := btcutils.NewSignatureForHash(hash, privateKey)
 	if err != nil {
 		return nil, err
 	}
-	//signatureLength is +1 to add hashCodeType
+	//signatureLength is +1 to add hashType
 	signatureLength := byte(len(signature) + 1)
 	//Create scriptSig
 	var buffer bytes.Buffer
 	buffer.WriteByte(signatureLength)
 	buffer.Write(signature)
-	buffer.WriteByte(hashCodeType[0])
+	buffer.WriteByte(byte(hashType))
 	buffer.WriteByte(byte(len(publicKey)))
 	buffer.Write(publicKey)
 	scriptSig := buffer.Bytes()
 	//Finally create transaction with actual scriptSig
-	signedRawTransaction, err := btcutils.NewRawTransaction(inputTx, amount, scriptSig, scriptPubKey)
-	if err != nil {
-		return nil, err
-	}
-	return signedRawTransaction, nil
+	signedTransaction := transaction.Copy()
+	signedTransaction.Inputs[0].ScriptSig = scriptSig
+	return signedTransaction.Serialize(), nil
 }
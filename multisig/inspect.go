@@ -0,0 +1,85 @@
+// inspect.go - Reading M, N, public keys and addresses back out of a multisig redeem script.
+package multisig
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
+
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// inspectedRedeemScript is what a multisig redeem script commits to: M of its N public keys must sign to spend
+// from any of its addresses, or for a recovery script, the recovery public key alone from the recovery locktime on, or for a
+// delayed script, DelayedM of the public keys once funds are as old as the Delay relative locktime.
+type inspectedRedeemScript struct {
+	M                 int
+	N                 int
+	PublicKeys        []string
+	RecoveryPublicKey string
+	RecoveryLockTime  uint32
+	DelayedM          int
+	Delay             uint32
+	Addresses         map[string]string
+}
+
+// OutputInspect formats and prints relevant outputs to the user.
+func OutputInspect(flagRedeemScript string, flagNetwork string) {
+	inspected := generateInspect(flagRedeemScript, parseNetwork(flagNetwork))
+
+	fmt.Println("-----------------------------------------------------------------------------------------------------------------------------------")
+	fmt.Printf("%d-of-%d multisig redeem script. Signatures from %d of these public keys, in this order, spend from its addresses:\n", inspected.M, inspected.N, inspected.M)
+	for i, publicKey := range inspected.PublicKeys {
+		fmt.Printf("#%d %v\n", i+1, publicKey)
+	}
+	if inspected.RecoveryPublicKey != "" {
+		fmt.Printf("Or from %v on, a signature from the recovery public key alone:\n%v\n", formatLockTime(inspected.RecoveryLockTime), inspected.RecoveryPublicKey)
+	}
+	if inspected.DelayedM != 0 {
+		fmt.Printf("Or once funds have been in the blockchain for %v, signatures from %d of the same public keys.\n", formatSequence(inspected.Delay), inspected.DelayedM)
+	}
+	fmt.Println("-----------------------------------------------------------------------------------------------------------------------------------")
+	for _, addressType := range []string{addressTypeP2SH, addressTypeP2WSH, addressTypeP2SHP2WSH} {
+		fmt.Printf("%v address: %v\n", strings.ToUpper(addressType), inspected.Addresses[addressType])
+	}
+	fmt.Println("-----------------------------------------------------------------------------------------------------------------------------------")
+	redeemScriptHex := strings.TrimSpace(flagRedeemScript)
+	outputAddressWarnings(inspected.M, inspected.N, redeemScriptHex, addressTypeP2SH)
+	outputAddressWarnings(inspected.M, inspected.N, redeemScriptHex, addressTypeP2WSH)
+}
+
+// generateInspect is the high-level logic for inspecting a redeem script with the 'go-bitcoin-multisig inspect' subcommand.
+// Takes flagRedeemScript (hex representation of a M-of-N multisig, recovery or delayed redeem or witness script, as output by address) and
+// network (network to encode addresses for) as arguments.
+func generateInspect(flagRedeemScript string, network *btcutils.Network) inspectedRedeemScript {
+	redeemScript, err := hex.DecodeString(strings.TrimSpace(flagRedeemScript))
+	if err != nil {
+		log.Fatal(err)
+	}
+	branches := scriptBranches(redeemScript)
+
+	inspected := inspectedRedeemScript{
+		M:          branches[0].m,
+		N:          len(branches[0].publicKeys),
+		PublicKeys: make([]string, len(branches[0].publicKeys)),
+		Addresses:  make(map[string]string),
+	}
+	for i, publicKey := range branches[0].publicKeys {
+		inspected.PublicKeys[i] = hex.EncodeToString(publicKey)
+	}
+	switch {
+	case len(branches) < 2:
+	case branches[1].lockTime != 0:
+		inspected.RecoveryPublicKey = hex.EncodeToString(branches[1].publicKeys[0])
+		inspected.RecoveryLockTime = branches[1].lockTime
+	default:
+		inspected.DelayedM = branches[1].m
+		inspected.Delay = branches[1].sequence
+	}
+	for _, addressType := range []string{addressTypeP2SH, addressTypeP2WSH, addressTypeP2SHP2WSH} {
+		inspected.Addresses[addressType] = generateMultisigAddress(redeemScript, addressType, network)
+	}
+
+	return inspected
+}
@@ -0,0 +1,80 @@
+package multisig
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"testing"
+)
+
+func TestGenerateInspect(t *testing.T) {
+	//2-of-3 multisig witness script with compressed public keys, as in TestMultisigScriptPubKey
+	testRedeemScript := "522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae"
+	testPublicKeys := []string{
+		"03a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575",
+		"036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d",
+		"0311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef",
+	}
+	testAddresses := map[string]string{
+		addressTypeP2SH:      "3MsXykid1v9i3FWP4Fbj8vVLKrjPbBQCbP",
+		addressTypeP2WSH:     "bc1qznadz5sy773nv3pr2n2jmymcyzkrn64tttz5640nz6988zmemzfs3dn6jt",
+		addressTypeP2SHP2WSH: "3F7ULEo5xPC4B9rkN3T8tWL8ZfTCvycAx4",
+	}
+
+	inspected := generateInspect(testRedeemScript, btcutils.MainNet)
+	if inspected.M != 2 || inspected.N != 3 {
+		t.Errorf("Inspected %d-of-%d redeem script, expected 2-of-3.", inspected.M, inspected.N)
+	}
+	if len(inspected.PublicKeys) != len(testPublicKeys) {
+		t.Fatal("Inspected redeem script has wrong number of public keys:", inspected.PublicKeys)
+	}
+	for i, testPublicKey := range testPublicKeys {
+		if inspected.PublicKeys[i] != testPublicKey {
+			testutils.CompareError(t, "Inspected public key different from expected key.", testPublicKey, inspected.PublicKeys[i])
+		}
+	}
+	for addressType, testAddress := range testAddresses {
+		if inspected.Addresses[addressType] != testAddress {
+			testutils.CompareError(t, "Inspected "+addressType+" address different from expected address.", testAddress, inspected.Addresses[addressType])
+		}
+	}
+}
+
+func TestGenerateInspectRecovery(t *testing.T) {
+	//Recovery witness script from TestGenerateAddressRecovery
+	testRedeemScript := "63522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae670360ae0ab175210331393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bac68"
+	testRecoveryPublicKey := "0331393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701b"
+
+	inspected := generateInspect(testRedeemScript, btcutils.MainNet)
+	if inspected.M != 2 || inspected.N != 3 || len(inspected.PublicKeys) != 3 {
+		t.Errorf("Inspected %d-of-%d redeem script with %d public keys, expected 2-of-3.", inspected.M, inspected.N, len(inspected.PublicKeys))
+	}
+	if inspected.RecoveryPublicKey != testRecoveryPublicKey {
+		testutils.CompareError(t, "Inspected recovery public key different from expected key.", testRecoveryPublicKey, inspected.RecoveryPublicKey)
+	}
+	if inspected.RecoveryLockTime != 700000 {
+		testutils.CompareError(t, "Inspected recovery locktime different from expected locktime.", 700000, inspected.RecoveryLockTime)
+	}
+	if inspected.Addresses[addressTypeP2WSH] != "bc1qvftp98htmyc78aq5k3t8vu5sncu7xand42xm77rjtwx0xexqdueq2tzazn" {
+		testutils.CompareError(t, "Inspected P2WSH address different from expected address.", "bc1qvftp98htmyc78aq5k3t8vu5sncu7xand42xm77rjtwx0xexqdueq2tzazn", inspected.Addresses[addressTypeP2WSH])
+	}
+}
+
+func TestGenerateInspectDelayed(t *testing.T) {
+	//Delayed witness script from TestGenerateAddressDelayed
+	testRedeemScript := "63522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae6702e010b275512103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae68"
+
+	inspected := generateInspect(testRedeemScript, btcutils.MainNet)
+	if inspected.M != 2 || inspected.N != 3 || len(inspected.PublicKeys) != 3 {
+		t.Errorf("Inspected %d-of-%d redeem script with %d public keys, expected 2-of-3.", inspected.M, inspected.N, len(inspected.PublicKeys))
+	}
+	if inspected.DelayedM != 1 || inspected.Delay != 4320 {
+		t.Errorf("Inspected delayed redeem script spendable by %d keys after 0x%08x, expected 1 after 4320 blocks.", inspected.DelayedM, inspected.Delay)
+	}
+	if inspected.RecoveryPublicKey != "" {
+		t.Error("Inspected delayed redeem script with recovery public key", inspected.RecoveryPublicKey)
+	}
+	if inspected.Addresses[addressTypeP2WSH] != "bc1qjnyhns66wfdeswxeta738trwszeerzz597m2ya2p2nzvr2dj4y3q2glfa2" {
+		testutils.CompareError(t, "Inspected P2WSH address different from expected address.", "bc1qjnyhns66wfdeswxeta738trwszeerzz597m2ya2p2nzvr2dj4y3q2glfa2", inspected.Addresses[addressTypeP2WSH])
+	}
+}
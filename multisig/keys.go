@@ -1,20 +1,48 @@
-// keys.go - Generating public/private key pairs.
+// keys.go - Generating public/private key pairs, either independently or from a BIP32 HD master key backed up as a BIP39 mnemonic.
 package multisig
 
 import (
-	"github.com/prettymuchbryce/hellobitcoin/base58check"
 	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
 
 	"encoding/hex"
 	"fmt"
 	"log"
+	"strings"
 )
 
+// hdAccountPath returns the derivation path of the account key each cosigner shares, as in BIP48 wallets: purpose 48', the coin type
+// of network, account 0' and script type 2' (native SegWit). Every step is hardened, so the shared account xpub, even together with a
+// leaked private key derived below it, cannot reveal the master key.
+func hdAccountPath(network *btcutils.Network) string {
+	return fmt.Sprintf("m/48'/%d'/0'/2'", network.CoinType)
+}
+
+// hdReceivePath is the derivation path, relative to a cosigner's shared account xpub, of the chain their multisig public keys come from.
+// As in BIP44/BIP48 wallets, this is the external (receive) chain 0, so index i is at path 0/i.
+const hdReceivePath = "0"
+
+// keysActionRestore is the 'go-bitcoin-multisig keys restore' action, recreating HD key pairs from a BIP39 mnemonic.
+const keysActionRestore = "restore"
+
 //OutputKeys formats and prints relevant outputs to the user.
-func OutputKeys(flagKeyCount int, flagConcise bool) {
+func OutputKeys(flagKeyCount int, flagConcise bool, flagCompressed bool, flagHD bool, flagAction string, flagMnemonic string, flagPassphrase string, flagNetwork string) {
+	network := parseNetwork(flagNetwork)
 	if flagKeyCount < 1 || flagKeyCount > 100 {
 		log.Fatal("--count <count> must be between 1 and 100")
 	}
+	if flagAction != "" && flagAction != keysActionRestore {
+		log.Fatalf("Unknown keys action '%s'. Only 'restore' is supported.", flagAction)
+	}
+	restore := flagAction == keysActionRestore
+	if restore && flagMnemonic == "" {
+		log.Fatal("--mnemonic is required to restore keys.")
+	}
+	if !restore && flagMnemonic != "" {
+		log.Fatal("--mnemonic is only used to restore keys with 'keys restore'.")
+	}
+	if !restore && !flagHD && flagPassphrase != "" {
+		log.Fatal("--passphrase is only used with --hd or 'keys restore'.")
+	}
 
 	if !flagConcise {
 		fmt.Println("----------------------------------------------------------------------")
@@ -27,7 +55,41 @@ func OutputKeys(flagKeyCount int, flagConcise bool) {
 		fmt.Println("----------------------------------------------------------------------")
 	}
 
-	privateKeyWIFs, publicKeyHexs, publicAddresses := generateKeys(flagKeyCount)
+	var privateKeyWIFs, publicKeyHexs, publicAddresses []string
+	if flagHD || restore {
+		var mnemonic, masterXprv, accountXpub string
+		if restore {
+			masterXprv, accountXpub, privateKeyWIFs, publicKeyHexs, publicAddresses = restoreHDKeys(flagMnemonic, flagPassphrase, flagKeyCount, network)
+		} else {
+			mnemonic, masterXprv, accountXpub, privateKeyWIFs, publicKeyHexs, publicAddresses = generateHDKeys(flagKeyCount, flagPassphrase, network)
+		}
+		//Output mnemonic, HD master private key and account public key, from which all the key pairs below are derived
+		fmt.Println("-------------------------------------------------------------")
+		if !restore {
+			fmt.Println("BIP39 mnemonic: ")
+			fmt.Println(mnemonic)
+			if !flagConcise {
+				fmt.Println("-- Write these words down in order and keep them private. With your passphrase, if any, they recreate every key below with 'keys restore'.")
+				fmt.Println("")
+			}
+		}
+		fmt.Println("HD master private key (xprv): ")
+		fmt.Println(masterXprv)
+		if !flagConcise {
+			fmt.Println("-- Keep this private and backed up, it recreates every key pair below.")
+			fmt.Println("")
+		}
+		fmt.Println("HD account public key (xpub), with master key fingerprint and derivation path: ")
+		fmt.Println(accountXpub)
+		if !flagConcise {
+			fmt.Println("-- Share this once with cosigners, who can then derive your public key for any multisig address with 'address --xpubs'.")
+			fmt.Printf("-- It is derived at hardened path %s, so it cannot reveal the master key, which must never be shared.\n", hdAccountPath(network))
+			fmt.Printf("-- KEY #1 below is derived at path %s/%s/0, KEY #2 at %s/%s/1 and so on.\n", hdAccountPath(network), hdReceivePath, hdAccountPath(network), hdReceivePath)
+		}
+		fmt.Println("-------------------------------------------------------------")
+	} else {
+		privateKeyWIFs, publicKeyHexs, publicAddresses = generateKeys(flagKeyCount, flagCompressed, network)
+	}
 
 	for i := 0; i <= flagKeyCount-1; i++ {
 
@@ -54,9 +116,9 @@ func OutputKeys(flagKeyCount int, flagConcise bool) {
 }
 
 // generateKeys is the high-level logic for generating public/private key pairs with the 'go-bitcoin-multisig keys' subcommand.
-// Takes flagCount (desired number of key pairs) and flagConcise (true hides warnings and helpful messages for conciseness)
-// as arguments.
-func generateKeys(flagKeyCount int) ([]string, []string, []string) {
+// Takes flagCount (desired number of key pairs), flagCompressed (true generates 33 byte compressed public keys
+// and matching compressed WIF private keys) and network (network to encode keys and addresses for) as arguments.
+func generateKeys(flagKeyCount int, flagCompressed bool, network *btcutils.Network) ([]string, []string, []string) {
 	publicKeyHexs := make([]string, flagKeyCount)
 	publicAddresses := make([]string, flagKeyCount)
 	privateKeyWIFs := make([]string, flagKeyCount)
@@ -64,22 +126,101 @@ func generateKeys(flagKeyCount int) ([]string, []string, []string) {
 	for i := 0; i <= flagKeyCount-1; i++ {
 		//Generate private key
 		privateKey := btcutils.NewPrivateKey()
-		//Generate public key from private key
-		publicKey, err := btcutils.NewPublicKey(privateKey)
-		if err != nil {
-			log.Fatal(err)
-		}
-		//Get hex encoded version of public key
-		publicKeyHexs[i] = hex.EncodeToString(publicKey)
-		//Get public address by hashing with SHA256 and RIPEMD160 and base58 encoding with mainnet prefix 00
-		publicKeyHash, err := btcutils.Hash160(publicKey)
+		privateKeyWIFs[i], publicKeyHexs[i], publicAddresses[i] = encodeKeyPair(privateKey, flagCompressed, network)
+	}
+
+	return privateKeyWIFs, publicKeyHexs, publicAddresses
+}
+
+// generateHDKeys is the high-level logic for generating key pairs from a new BIP32 HD master key with the
+// 'go-bitcoin-multisig keys --hd' subcommand. Takes flagCount (desired number of key pairs), flagPassphrase
+// (optional BIP39 passphrase) and network (network to encode keys and addresses for) as arguments.
+// HD key pairs always use compressed public keys, as BIP32 does.
+// Returns the new 24 word BIP39 mnemonic, followed by the results of restoreHDKeys for it.
+func generateHDKeys(flagKeyCount int, flagPassphrase string, network *btcutils.Network) (string, string, string, []string, []string, []string) {
+	//Generate mnemonic from 256 bits of entropy
+	entropy, err := btcutils.NewRandomBytes(32)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mnemonic, err := btcutils.NewMnemonic(entropy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	masterXprv, accountXpub, privateKeyWIFs, publicKeyHexs, publicAddresses := restoreHDKeys(mnemonic, flagPassphrase, flagKeyCount, network)
+	return mnemonic, masterXprv, accountXpub, privateKeyWIFs, publicKeyHexs, publicAddresses
+}
+
+// restoreHDKeys is the high-level logic for recreating HD key pairs from a BIP39 mnemonic with the
+// 'go-bitcoin-multisig keys restore' subcommand. Takes flagMnemonic, flagPassphrase (as given when the keys
+// were generated), flagCount (desired number of key pairs) and network (network to encode keys and addresses for) as arguments.
+func restoreHDKeys(flagMnemonic string, flagPassphrase string, flagKeyCount int, network *btcutils.Network) (string, string, []string, []string, []string) {
+	seed, err := btcutils.NewSeedFromMnemonic(flagMnemonic, flagPassphrase)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return deriveHDKeys(seed, flagKeyCount, network)
+}
+
+// deriveHDKeys derives the master xprv on network from seed and the account xpub at hdAccountPath, with its key origin (see
+// formatAccountXpub), followed by keyCount key pairs at hdReceivePath/0, hdReceivePath/1 and so on below the account key.
+func deriveHDKeys(seed []byte, keyCount int, network *btcutils.Network) (string, string, []string, []string, []string) {
+	masterKey, err := btcutils.NewMasterKey(seed, network)
+	if err != nil {
+		log.Fatal(err)
+	}
+	accountKey, err := masterKey.DerivePath(hdAccountPath(network))
+	if err != nil {
+		log.Fatal(err)
+	}
+	receiveKey, err := accountKey.DerivePath(hdReceivePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	publicKeyHexs := make([]string, keyCount)
+	publicAddresses := make([]string, keyCount)
+	privateKeyWIFs := make([]string, keyCount)
+	for i := 0; i <= keyCount-1; i++ {
+		childKey, err := receiveKey.Child(uint32(i))
 		if err != nil {
 			log.Fatal(err)
 		}
-		publicAddresses[i] = base58check.Encode("00", publicKeyHash)
-		//Get private key in Wallet Import Format (WIF) by base58 encoding with prefix 80
-		privateKeyWIFs[i] = base58check.Encode("80", privateKey)
+		privateKeyWIFs[i], publicKeyHexs[i], publicAddresses[i] = encodeKeyPair(childKey.Key, true, network)
 	}
 
-	return privateKeyWIFs, publicKeyHexs, publicAddresses
+	return masterKey.String(), formatAccountXpub(masterKey, accountKey, hdAccountPath(network)), privateKeyWIFs, publicKeyHexs, publicAddresses
+}
+
+// formatAccountXpub returns the xpub of accountKey, derived from masterKey at accountPath, with its key origin as in output
+// descriptors, eg. [3442193e/48'/0'/0'/2']xpub..., so cosigners know the full derivation path of each multisig public key.
+func formatAccountXpub(masterKey *btcutils.ExtendedKey, accountKey *btcutils.ExtendedKey, accountPath string) string {
+	masterFingerprint, err := masterKey.Fingerprint()
+	if err != nil {
+		log.Fatal(err)
+	}
+	accountPublicKey, err := accountKey.Neuter()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return fmt.Sprintf("[%x%s]%s", masterFingerprint, strings.TrimPrefix(accountPath, "m"), accountPublicKey.String())
+}
+
+// encodeKeyPair returns the WIF private key, hex public key and P2PKH public address on network for privateKey.
+func encodeKeyPair(privateKey []byte, compressed bool, network *btcutils.Network) (string, string, string) {
+	//Generate public key from private key
+	publicKey, err := btcutils.NewPublicKey(privateKey, compressed)
+	if err != nil {
+		log.Fatal(err)
+	}
+	//Get public address by hashing with SHA256 and RIPEMD160 and base58 encoding with the network's prefix, 00 for mainnet
+	publicKeyHash, err := btcutils.Hash160(publicKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+	publicAddress := btcutils.NewP2PKHAddress(publicKeyHash, network)
+	//Get private key in Wallet Import Format (WIF) by base58 encoding with the network's prefix, 80 for mainnet, and suffix 01 if compressed
+	privateKeyWIF := btcutils.NewWIF(privateKey, compressed, network)
+
+	return privateKeyWIF, hex.EncodeToString(publicKey), publicAddress
 }
@@ -2,13 +2,16 @@ package multisig
 
 import (
 	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
 
 	"encoding/hex"
+	"reflect"
+	"strings"
 	"testing"
 )
 
 func TestGenerateKeys(t *testing.T) {
-	privateKeyWIFs, publicKeyHexs, publicAddresses := generateKeys(1)
+	privateKeyWIFs, publicKeyHexs, publicAddresses := generateKeys(1, false, btcutils.MainNet)
 	publicKey, err := hex.DecodeString(publicKeyHexs[0])
 	if err != nil {
 		t.Error(err)
@@ -37,3 +40,152 @@ func TestGenerateKeys(t *testing.T) {
 		t.Error("Generated public address has wrong prefix. Should be '5' for mainnet P2PKH addresses.")
 	}
 }
+
+func TestGenerateKeysCompressed(t *testing.T) {
+	privateKeyWIFs, publicKeyHexs, publicAddresses := generateKeys(1, true, btcutils.MainNet)
+	publicKey, err := hex.DecodeString(publicKeyHexs[0])
+	if err != nil {
+		t.Error(err)
+	}
+	err = btcutils.CheckPublicKeyIsValid(publicKey)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(publicKey) != 33 {
+		t.Error("Generated public key is wrong length. Should be 33 bytes long when compressed.")
+	}
+	if len(privateKeyWIFs[0]) != 52 {
+		t.Error("Generated private key is wrong length. Should be 52 characters long when compressed.")
+	}
+	if privateKeyWIFs[0][0:1] != "K" && privateKeyWIFs[0][0:1] != "L" {
+		t.Error("Generated private key has wrong prefix. Should be 'K' or 'L' for compressed mainnet private key.")
+	}
+	_, compressed, err := btcutils.ParseWIF(privateKeyWIFs[0], btcutils.MainNet)
+	if err != nil {
+		t.Error(err)
+	}
+	if !compressed {
+		t.Error("Generated private key is not marked as compressed.")
+	}
+	if publicAddresses[0][0:1] != "1" {
+		t.Error("Generated public address has wrong prefix. Should be '1' for mainnet P2PKH addresses.")
+	}
+}
+
+func TestGenerateKeysTestnet(t *testing.T) {
+	privateKeyWIFs, _, publicAddresses := generateKeys(1, true, btcutils.TestNet3)
+	if privateKeyWIFs[0][0:1] != "c" {
+		t.Error("Generated private key has wrong prefix. Should be 'c' for compressed testnet private key.")
+	}
+	if _, _, err := btcutils.ParseWIF(privateKeyWIFs[0], btcutils.TestNet3); err != nil {
+		t.Error(err)
+	}
+	if publicAddresses[0][0:1] != "m" && publicAddresses[0][0:1] != "n" {
+		t.Error("Generated public address has wrong prefix. Should be 'm' or 'n' for testnet P2PKH addresses.")
+	}
+}
+
+func TestDeriveHDKeys(t *testing.T) {
+	//BIP32 test vector 1 seed, with the account xpub at m/48'/0'/0'/2' and key pairs at its paths 0/0 and 0/1. Keys were cross-checked with btcsuite.
+	testSeedHex := "000102030405060708090a0b0c0d0e0f"
+	testMasterXprv := "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi"
+	testAccountXpub := "[3442193e/48'/0'/0'/2']xpub6E64WfdQwBGz85XhbZryr9gUGUPBgoSu5WV6tJWpzAvgAmpVpdPHkT3XYm9R5J6MeWzvLQoz4q845taC9Q28XutbptxAmg7q8QPkjvTL4oi"
+	testPublicKeyHexs := []string{
+		"039313c80a98460104a4e9abc86f1f73d1fa982ecb3172269d917415e6aeee2675",
+		"0270a8ff2665558eafec65807395de199470e3618810eff48ec35b59fad2f09acc",
+	}
+
+	seed, _ := hex.DecodeString(testSeedHex)
+	masterXprv, accountXpub, privateKeyWIFs, publicKeyHexs, _ := deriveHDKeys(seed, len(testPublicKeyHexs), btcutils.MainNet)
+	if masterXprv != testMasterXprv {
+		testutils.CompareError(t, "Generated HD master private key different from expected key.", testMasterXprv, masterXprv)
+	}
+	if accountXpub != testAccountXpub {
+		testutils.CompareError(t, "Generated HD account public key different from expected key.", testAccountXpub, accountXpub)
+	}
+	for i, testPublicKeyHex := range testPublicKeyHexs {
+		if publicKeyHexs[i] != testPublicKeyHex {
+			testutils.CompareError(t, "Derived HD public key different from expected key.", testPublicKeyHex, publicKeyHexs[i])
+		}
+		//Private key must be the compressed WIF of the same key pair
+		privateKey, compressed, err := btcutils.ParseWIF(privateKeyWIFs[i], btcutils.MainNet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		publicKey, err := btcutils.NewPublicKey(privateKey, compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(publicKey) != testPublicKeyHex {
+			testutils.CompareError(t, "Derived HD private key does not match public key.", testPublicKeyHex, hex.EncodeToString(publicKey))
+		}
+	}
+}
+
+func TestRestoreHDKeys(t *testing.T) {
+	//BIP39 test vector mnemonic and passphrase, with key pairs at m/48'/0'/0'/2'/0/0 and m/48'/0'/0'/2'/0/1 of its seed
+	testMnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	testPassphrase := "TREZOR"
+	testMasterXprv := "xprv9s21ZrQH143K3h3fDYiay8mocZ3afhfULfb5GX8kCBdno77K4HiA15Tg23wpbeF1pLfs1c5SPmYHrEpTuuRhxMwvKDwqdKiGJS9XFKzUsAF"
+	testAccountXpub := "[b4e3f5ed/48'/0'/0'/2']xpub6EvUNn2FJ1EvJtzDBLGqky8EypxyrcqzPGtLvXrYGMvJXB1DsJnDkSK9hmrJJbC1hXJhQxdarj4ELYX7YY5QSwVwe3PxQrkfMuXy9nFcKrg"
+	testPublicKeyHexs := []string{
+		"0296436a667c034da89e3bae8cf5976d9c5c61c1b17508550c76a517f8fb4e8c48",
+		"02594f7127f33912c4683ee0718cfb7734189deac5c464687001b1c40ab2165e16",
+	}
+
+	masterXprv, accountXpub, _, publicKeyHexs, _ := restoreHDKeys(testMnemonic, testPassphrase, len(testPublicKeyHexs), btcutils.MainNet)
+	if masterXprv != testMasterXprv {
+		testutils.CompareError(t, "Restored HD master private key different from expected key.", testMasterXprv, masterXprv)
+	}
+	if accountXpub != testAccountXpub {
+		testutils.CompareError(t, "Restored HD account public key different from expected key.", testAccountXpub, accountXpub)
+	}
+	for i, testPublicKeyHex := range testPublicKeyHexs {
+		if publicKeyHexs[i] != testPublicKeyHex {
+			testutils.CompareError(t, "Restored HD public key different from expected key.", testPublicKeyHex, publicKeyHexs[i])
+		}
+	}
+}
+
+func TestGenerateHDKeysRestore(t *testing.T) {
+	//Keys restored from the generated mnemonic and passphrase must match the generated keys
+	testPassphrase := "correct horse battery staple"
+	mnemonic, masterXprv, _, privateKeyWIFs, _, _ := generateHDKeys(3, testPassphrase, btcutils.MainNet)
+	if len(strings.Fields(mnemonic)) != 24 {
+		t.Errorf("Generated mnemonic has %d words. Should have 24 words.", len(strings.Fields(mnemonic)))
+	}
+	restoredMasterXprv, _, restoredPrivateKeyWIFs, _, _ := restoreHDKeys(mnemonic, testPassphrase, 3, btcutils.MainNet)
+	if restoredMasterXprv != masterXprv {
+		testutils.CompareError(t, "Restored HD master private key different from generated key.", masterXprv, restoredMasterXprv)
+	}
+	if !reflect.DeepEqual(restoredPrivateKeyWIFs, privateKeyWIFs) {
+		testutils.CompareError(t, "Restored HD private keys different from generated keys.", privateKeyWIFs, restoredPrivateKeyWIFs)
+	}
+}
+
+func TestDeriveHDKeysAccountXpub(t *testing.T) {
+	//The shared xpub must be the hardened BIP48 account key, not the master key, on each network
+	testSeedHex := "000102030405060708090a0b0c0d0e0f"
+	testMasterXpub := "xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8"
+	testAccountPaths := map[*btcutils.Network]string{
+		btcutils.MainNet:  "48'/0'/0'/2'",
+		btcutils.TestNet3: "48'/1'/0'/2'",
+	}
+
+	seed, _ := hex.DecodeString(testSeedHex)
+	for network, testAccountPath := range testAccountPaths {
+		_, accountXpub, _, _, _ := deriveHDKeys(seed, 1, network)
+		testOrigin := "[3442193e/" + testAccountPath + "]"
+		if !strings.HasPrefix(accountXpub, testOrigin) {
+			testutils.CompareError(t, "Generated HD account public key origin different from expected origin.", testOrigin, accountXpub)
+			continue
+		}
+		xpub, err := btcutils.ParseExtendedKey(strings.TrimPrefix(accountXpub, testOrigin), network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if xpub.String() == testMasterXpub || xpub.Depth != 4 || xpub.ChildNumber != btcutils.HardenedKeyStart+2 {
+			t.Errorf("Shared xpub %v on %v is not the hardened account key at depth 4.", xpub, network.Name)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// network.go - Selecting the Bitcoin network that keys and addresses are generated for and checked against, and decoding destination addresses.
+package multisig
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
+
+	"log"
+)
+
+// parseNetwork returns the network named by the global --network flag: mainnet, testnet3, signet or regtest.
+func parseNetwork(flagNetwork string) *btcutils.Network {
+	network, err := btcutils.ParseNetwork(flagNetwork)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return network
+}
+
+// destinationScriptPubKey decodes a destination address of any type on network (P2PKH, P2SH, P2WPKH, P2WSH or P2TR)
+// and returns the scriptPubKey paying to it.
+func destinationScriptPubKey(flagDestination string, network *btcutils.Network) []byte {
+	address, err := btcutils.DecodeAddress(flagDestination, network)
+	if err != nil {
+		log.Fatal(err)
+	}
+	scriptPubKey, err := address.ScriptPubKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return scriptPubKey
+}
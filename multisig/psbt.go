@@ -0,0 +1,283 @@
+// psbt.go - Spending P2SH multisig funds with Partially Signed Bitcoin Transactions (BIP174), one cosigner at a time.
+package multisig
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
+
+	"bytes"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// outputPsbt formats and prints a PSBT to the user, as base64 or as hex of the binary format if flagHex is set.
+func outputPsbt(psbt *btcutils.Psbt, flagHex bool, instructions string) {
+	encodedPsbt := psbt.Base64()
+	if flagHex {
+		encodedPsbt = hex.EncodeToString(psbt.Serialize())
+	}
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Your PSBT is:
+%v
+%v
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+		encodedPsbt,
+		instructions,
+	)
+}
+
+// OutputPsbtCreate formats and prints relevant outputs to the user.
+func OutputPsbtCreate(flagDestination string, flagRedeemScript string, flagInputTx string, flagInputIndex int, flagAmount int, flagInput string, flagPrevTx string, flagSigHash string, options TransactionOptions, flagHex bool, flagNetwork string) {
+	input := parseInput(flagInput, flagInputTx, flagInputIndex, 0, flagPrevTx)
+	hashType := parseSigHashType(flagSigHash)
+	psbt := generatePsbtCreate(flagDestination, flagRedeemScript, input, flagAmount, parsePrevTx(flagPrevTx), hashType, options, parseNetwork(flagNetwork))
+	outputPsbt(psbt, flagHex, "Give this to each cosigner to add their signature with 'psbt sign'.")
+	if input.Amount > 0 {
+		outputFee(hex.EncodeToString(estimateSignedTransaction(psbt)), input.Amount)
+	}
+	outputSigHashWarning(hashType)
+}
+
+// OutputPsbtSign formats and prints relevant outputs to the user.
+func OutputPsbtSign(flagPsbt string, flagPrivateKey string, flagSigHash string, flagHex bool, flagNetwork string) {
+	hashType := parseSigHashType(flagSigHash)
+	psbt := generatePsbtSign(flagPsbt, flagPrivateKey, hashType, parseNetwork(flagNetwork))
+	outputPsbt(psbt, flagHex, "Pass this on to the next cosigner, or combine it with other cosigners' PSBTs using 'psbt combine'.")
+	outputSigHashWarning(hashType)
+}
+
+// OutputPsbtCombine formats and prints relevant outputs to the user.
+func OutputPsbtCombine(flagPsbts string, flagHex bool) {
+	psbt := generatePsbtCombine(flagPsbts)
+	outputPsbt(psbt, flagHex, "Once enough cosigners have signed, build the final scriptSig with 'psbt finalize'.")
+}
+
+// OutputPsbtFinalize formats and prints relevant outputs to the user.
+func OutputPsbtFinalize(flagPsbt string, flagHex bool) {
+	psbt := generatePsbtFinalize(flagPsbt)
+	outputPsbt(psbt, flagHex, "Get the raw transaction to broadcast with 'psbt extract'.")
+}
+
+// OutputPsbtExtract formats and prints relevant outputs to the user.
+func OutputPsbtExtract(flagPsbt string) {
+	finalTransactionHex := generatePsbtExtract(flagPsbt)
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Your raw spending transaction is:
+%v
+Broadcast this transaction to spend your multisig P2SH funds.
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+		finalTransactionHex,
+	)
+}
+
+// generatePsbtCreate is the high-level logic for creating an unsigned PSBT with the 'go-bitcoin-multisig psbt create' subcommand.
+// Takes flagDestination (destination address of spent funds), flagRedeemScript (redeemScript that matches P2SH script),
+// input (P2SH output to spend, with its amount needed to work out change and fees, and its scriptPubKey checked against the redeem
+// script when known), flagAmount (amount in Satoshis to send), prevTx (optional input transaction, included in the PSBT so cosigners
+// can check what they sign), hashType (signature hash type cosigners sign with, eg. SIGHASH_ALL), options (change address, fee, with
+// the fee rate worked out from the size once cosigners have signed, locktime and replace-by-fee, so the fee can be raised with
+// 'bump-fee') and network (network the destination and change address must belong to) as arguments.
+// Without the input amount, the transaction is refused unless options.UnknownFee is set, when balance left over from input is used as transaction fee.
+func generatePsbtCreate(flagDestination string, flagRedeemScript string, input *btcutils.UTXO, flagAmount int, prevTx *btcutils.Transaction, hashType uint32, options TransactionOptions, network *btcutils.Network) *btcutils.Psbt {
+	//Convert redeemScript hex to raw bytes and check it is a multisig script we can finalize later
+	redeemScript, err := hex.DecodeString(flagRedeemScript)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, _, _, err := btcutils.ParseMOfNRedeemScript(redeemScript); err != nil {
+		log.Fatal(err)
+	}
+	checkMultisigInput(input, addressTypeP2SH, redeemScript)
+	//Create scriptPubKey matching the type of the destination address
+	scriptPubKey := destinationScriptPubKey(flagDestination, network)
+	//Create the PSBT, with change and fee outputs worked out by payWithFee from the size of the transaction once signed
+	var psbt *btcutils.Psbt
+	lockTime := parseLockTime(options.LockTime)
+	estimate := func(outputs []*btcutils.TxOut) []byte {
+		//Create unsigned transaction. Unlike 'spend', scriptSigs stay empty: the PSBT carries the redeemScript separately.
+		transaction := newUnsignedTransaction(input, nil, outputs, lockTime, 0, !options.NoRBF)
+		psbt, err = btcutils.NewPsbt(transaction)
+		if err != nil {
+			log.Fatal(err)
+		}
+		psbt.Inputs[0].RedeemScript = redeemScript
+		psbt.Inputs[0].NonWitnessUtxo = prevTx
+		//SIGHASH_ALL is the default, so only other hash types are recorded for cosigners to sign with
+		if hashType != btcutils.SIGHASH_ALL {
+			psbt.Inputs[0].SighashType = hashType
+		}
+		return estimateSignedTransaction(psbt)
+	}
+	payWithFee(input.Amount, flagAmount, scriptPubKey, options, network, estimate)
+
+	return psbt
+}
+
+// generatePsbtSign is the high-level logic for adding one cosigner's signature with the 'go-bitcoin-multisig psbt sign' subcommand.
+// Takes flagPsbt (PSBT in base64 or hex), flagPrivateKey (private key of one cosigner), hashType (signature hash type the cosigner confirmed) and network (network the private key must belong to) as arguments.
+// Every input whose redeemScript contains the matching public key is signed, as long as the PSBT asks for hashType signatures on it.
+func generatePsbtSign(flagPsbt string, flagPrivateKey string, hashType uint32, network *btcutils.Network) *btcutils.Psbt {
+	psbt := decodePsbt(flagPsbt)
+	privateKey, compressed, err := btcutils.ParseWIF(strings.TrimSpace(flagPrivateKey), network)
+	if err != nil {
+		log.Fatal(err)
+	}
+	publicKey, err := btcutils.NewPublicKey(privateKey, compressed)
+	if err != nil {
+		log.Fatal(err)
+	}
+	signedInputs := 0
+	for i, input := range psbt.Inputs {
+		if input.RedeemScript == nil {
+			continue
+		}
+		_, _, publicKeys, err := btcutils.ParseMOfNRedeemScript(input.RedeemScript)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !containsPublicKey(publicKeys, publicKey) {
+			continue
+		}
+		//If the full previous transaction is provided, make sure it is the one actually being spent
+		txIn := psbt.UnsignedTx.Inputs[i]
+		if input.NonWitnessUtxo != nil && input.NonWitnessUtxo.TxHash() != hex.EncodeToString(btcutils.ReverseBytes(txIn.PreviousTxHash)) {
+			log.Fatalf("PSBT input #%d previous transaction does not match the transaction being spent.", i)
+		}
+		err = checkPsbtSigHash(i, input.SighashType, hashType)
+		if err != nil {
+			log.Fatal(err)
+		}
+		hash, err := btcutils.SignatureHash(psbt.UnsignedTx, i, input.RedeemScript, hashType)
+		if err != nil {
+			log.Fatal(err)
+		}
+		signature, err := btcutils.NewSignatureForHash(hash, privateKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = psbt.AddPartialSig(i, publicKey, append(signature, byte(hashType)))
+		if err != nil {
+			log.Fatal(err)
+		}
+		signedInputs++
+	}
+	if signedInputs == 0 {
+		log.Fatal("Private key does not match a public key in the redeem script of any PSBT input.")
+	}
+
+	return psbt
+}
+
+// checkPsbtSigHash returns an error unless PSBT input #index asks for psbtHashType signatures (0 for SIGHASH_ALL) of the hashType the cosigner confirmed.
+// Signatures other than SIGHASH_ALL leave outputs or inputs open to change, so a PSBT must not get them without the cosigner knowing.
+func checkPsbtSigHash(index int, psbtHashType uint32, hashType uint32) error {
+	if psbtHashType == 0 {
+		psbtHashType = btcutils.SIGHASH_ALL
+	}
+	if psbtHashType != hashType {
+		return errors.New(fmt.Sprintf("PSBT input #%d asks for SIGHASH_%v signatures, not SIGHASH_%v. Check with the PSBT creator and give --sighash \"%v\" to sign it anyway.", index, btcutils.SigHashTypeName(psbtHashType), btcutils.SigHashTypeName(hashType), btcutils.SigHashTypeName(psbtHashType)))
+	}
+	return nil
+}
+
+// generatePsbtCombine is the high-level logic for merging cosigners' PSBTs with the 'go-bitcoin-multisig psbt combine' subcommand.
+// Takes flagPsbts (comma separated list of PSBTs in base64 or hex, all for the same transaction) as argument.
+func generatePsbtCombine(flagPsbts string) *btcutils.Psbt {
+	psbtStrings, err := csv.NewReader(strings.NewReader(flagPsbts)).Read()
+	if err != nil {
+		log.Fatal(err)
+	}
+	psbt := decodePsbt(psbtStrings[0])
+	for _, psbtString := range psbtStrings[1:] {
+		err := psbt.Combine(decodePsbt(psbtString))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	return psbt
+}
+
+// generatePsbtFinalize is the high-level logic for building final scriptSigs with the 'go-bitcoin-multisig psbt finalize' subcommand.
+// Takes flagPsbt (PSBT in base64 or hex with enough signatures for every input) as argument.
+func generatePsbtFinalize(flagPsbt string) *btcutils.Psbt {
+	psbt := decodePsbt(flagPsbt)
+	err := psbt.FinalizeMultisig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return psbt
+}
+
+// generatePsbtExtract is the high-level logic for getting the broadcastable transaction with the 'go-bitcoin-multisig psbt extract' subcommand.
+// Takes flagPsbt (finalized PSBT in base64 or hex) as argument.
+func generatePsbtExtract(flagPsbt string) string {
+	psbt := decodePsbt(flagPsbt)
+	transaction, err := psbt.Extract()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return hex.EncodeToString(transaction.Serialize())
+}
+
+// estimateSignedTransaction returns the transaction of psbt as it will be once every P2SH multisig input is finalized, with
+// placeholder signatures of the largest size cosigners can make, so fees can be worked out before anyone signs.
+func estimateSignedTransaction(psbt *btcutils.Psbt) []byte {
+	transaction := psbt.UnsignedTx.Copy()
+	for i, input := range psbt.Inputs {
+		if input.FinalScriptSig != nil || input.FinalScriptWitness != nil {
+			transaction.Inputs[i].ScriptSig = input.FinalScriptSig
+			transaction.Inputs[i].Witness = input.FinalScriptWitness
+			continue
+		}
+		if input.RedeemScript == nil {
+			continue
+		}
+		m, _, _, err := btcutils.ParseMOfNRedeemScript(input.RedeemScript)
+		if err != nil {
+			log.Fatal(err)
+		}
+		//Low-S DER signatures are at most 71 bytes, plus the hash type byte
+		signatures := make([][]byte, m)
+		for j := range signatures {
+			signatures[j] = make([]byte, 72)
+		}
+		transaction.Inputs[i].ScriptSig = btcutils.NewP2SHMultisigScriptSig(signatures, input.RedeemScript)
+	}
+	return transaction.Serialize()
+}
+
+// decodePsbt decodes a PSBT given either in base64 or as hex of the binary format.
+func decodePsbt(encodedPsbt string) *btcutils.Psbt {
+	encodedPsbt = strings.TrimSpace(encodedPsbt)
+	var psbt *btcutils.Psbt
+	var err error
+	if rawPsbt, hexErr := hex.DecodeString(encodedPsbt); hexErr == nil {
+		psbt, err = btcutils.ParsePsbt(rawPsbt)
+	} else {
+		psbt, err = btcutils.ParsePsbtBase64(encodedPsbt)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	return psbt
+}
+
+// containsPublicKey returns true if publicKey is one of publicKeys.
+func containsPublicKey(publicKeys [][]byte, publicKey []byte) bool {
+	for _, candidate := range publicKeys {
+		if bytes.Equal(candidate, publicKey) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package multisig
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
+	"testing"
+)
+
+func TestGeneratePsbt(t *testing.T) {
+	//2-of-3 multisig spend, signed by each cosigner separately. Must match the transaction 'spend' builds with both keys at once.
+	testPrivateKeys := []string{"5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3", "5JjHVMwJdjPEPQhq34WMUhzLcEd4SD7HgZktEh8WHstWcCLRceV"}
+	testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
+	testRedeemScript := "524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae"
+	testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+	testInputIndex := 0
+	testAmount := 55600
+	testFinalTransactionHex := "01000000013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b00200000000fd5e0100483045022100af4831eb0cee1b9642ff8691acb53c2fd8e28bbd6c0389af69c132b342405c8502200627e67cbb0bee502421be4d2b8e370d24eb17ff7c3d77d604a5c07ee5934a74014830450221009bfee48a5ae99a8fc0f78c631b0b2beb6d96337fc9d1e95c333a08bdcecb3647022040a30b3528b136821077d0bb45c324d7eedcd0ac61af7019ba1286e9277b7c54014cc9524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353aeffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000"
+
+	unsignedPsbt := generatePsbtCreate(testDestination, testRedeemScript, &btcutils.UTXO{TxHash: testInputTx, Index: uint32(testInputIndex)}, testAmount, nil, btcutils.SIGHASH_ALL, testTransactionOptions, btcutils.MainNet).Base64()
+	//Cosigners sign in reverse order, one passing hex and the other base64, to check neither order nor encoding matters
+	secondSignedPsbt := generatePsbtSign(unsignedPsbt, testPrivateKeys[1], btcutils.SIGHASH_ALL, btcutils.MainNet).Base64()
+	firstSignedPsbt := generatePsbtSign(unsignedPsbt, testPrivateKeys[0], btcutils.SIGHASH_ALL, btcutils.MainNet).Serialize()
+	combinedPsbt := generatePsbtCombine(secondSignedPsbt + "," + hex.EncodeToString(firstSignedPsbt)).Base64()
+	finalizedPsbt := generatePsbtFinalize(combinedPsbt).Base64()
+	finalTransactionHex := generatePsbtExtract(finalizedPsbt)
+	if testFinalTransactionHex != finalTransactionHex {
+		testutils.CompareError(t, "Extracted PSBT transaction different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
+	}
+}
+
+func TestGeneratePsbtSignSigHash(t *testing.T) {
+	testPrivateKey := "5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3"
+	testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
+	testRedeemScript := "524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae"
+	testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+	testAmount := 55600
+
+	//A cosigner confirming the PSBT's SIGHASH_NONE signs with it
+	unsignedPsbt := generatePsbtCreate(testDestination, testRedeemScript, &btcutils.UTXO{TxHash: testInputTx}, testAmount, nil, btcutils.SIGHASH_NONE, testTransactionOptions, btcutils.MainNet).Base64()
+	signedPsbt := generatePsbtSign(unsignedPsbt, testPrivateKey, btcutils.SIGHASH_NONE, btcutils.MainNet)
+	signature := signedPsbt.Inputs[0].PartialSigs[0].Signature
+	if signature[len(signature)-1] != byte(btcutils.SIGHASH_NONE) {
+		testutils.CompareError(t, "PSBT signature hash type different from expected hash type.", btcutils.SIGHASH_NONE, signature[len(signature)-1])
+	}
+
+	testCases := []struct {
+		psbtHashType uint32
+		hashType     uint32
+		valid        bool
+	}{
+		{0, btcutils.SIGHASH_ALL, true}, //PSBTs without a hash type ask for SIGHASH_ALL
+		{btcutils.SIGHASH_ALL, btcutils.SIGHASH_ALL, true},
+		{btcutils.SIGHASH_NONE, btcutils.SIGHASH_ALL, false}, //Not confirmed by the cosigner
+		{btcutils.SIGHASH_NONE, btcutils.SIGHASH_NONE, true},
+		{btcutils.SIGHASH_SINGLE | btcutils.SIGHASH_ANYONECANPAY, btcutils.SIGHASH_SINGLE, false},
+		{btcutils.SIGHASH_SINGLE | btcutils.SIGHASH_ANYONECANPAY, btcutils.SIGHASH_SINGLE | btcutils.SIGHASH_ANYONECANPAY, true},
+		{0, btcutils.SIGHASH_NONE, false}, //Cosigners cannot sign a SIGHASH_ALL PSBT with another type
+	}
+	for _, testCase := range testCases {
+		err := checkPsbtSigHash(0, testCase.psbtHashType, testCase.hashType)
+		if (err == nil) != testCase.valid {
+			t.Errorf("checkPsbtSigHash with PSBT hash type %#x and hash type %#x returned error %v, expected valid %v.", testCase.psbtHashType, testCase.hashType, err, testCase.valid)
+		}
+	}
+}
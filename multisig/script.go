@@ -0,0 +1,65 @@
+// script.go - Converting scripts between hex and human-readable ASM.
+package multisig
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
+
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// OutputScriptDecode formats and prints relevant outputs to the user.
+func OutputScriptDecode(flagScript string) {
+	asm := generateScriptDecode(flagScript)
+
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Script ASM:
+%v
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+		asm,
+	)
+}
+
+// OutputScriptEncode formats and prints relevant outputs to the user.
+func OutputScriptEncode(flagAsm string) {
+	scriptHex := generateScriptEncode(flagAsm)
+
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+Script hex:
+%v
+-----------------------------------------------------------------------------------------------------------------------------------
+`,
+		scriptHex,
+	)
+}
+
+// generateScriptDecode is the high-level logic for disassembling a script with the 'go-bitcoin-multisig script decode' subcommand.
+// Takes flagScript (hex representation of a script, eg. a redeem script as output by address) as argument.
+func generateScriptDecode(flagScript string) string {
+	script, err := hex.DecodeString(strings.TrimSpace(flagScript))
+	if err != nil {
+		log.Fatal(err)
+	}
+	asm, err := btcutils.DisassembleScript(script)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return asm
+}
+
+// generateScriptEncode is the high-level logic for assembling a script with the 'go-bitcoin-multisig script encode' subcommand.
+// Takes flagAsm (script in ASM, opcode names and hex data pushes separated by spaces) as argument.
+func generateScriptEncode(flagAsm string) string {
+	script, err := btcutils.AssembleScript(flagAsm)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return hex.EncodeToString(script)
+}
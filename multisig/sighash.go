@@ -0,0 +1,42 @@
+// sighash.go - Choosing which parts of a transaction signatures commit to.
+package multisig
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
+
+	"fmt"
+	"log"
+)
+
+// parseSigHashType returns the signature hash type named by flagSigHash (ALL, NONE or SINGLE, optionally followed by |ANYONECANPAY).
+func parseSigHashType(flagSigHash string) uint32 {
+	hashType, err := btcutils.ParseSigHashType(flagSigHash)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return hashType
+}
+
+// outputSigHashWarning prints a warning when signatures with hashType leave parts of the transaction open to change by others.
+func outputSigHashWarning(hashType uint32) {
+	if hashType == btcutils.SIGHASH_ALL {
+		return
+	}
+	fmt.Printf(`
+-----------------------------------------------------------------------------------------------------------------------------------
+WARNING: 
+Signatures are SIGHASH_%v, not SIGHASH_ALL. Anyone seeing this transaction can change it without invalidating them:
+`,
+		btcutils.SigHashTypeName(hashType),
+	)
+	switch hashType &^ btcutils.SIGHASH_ANYONECANPAY {
+	case btcutils.SIGHASH_NONE:
+		fmt.Println("- No outputs are signed for, so the funds can be sent anywhere.")
+	case btcutils.SIGHASH_SINGLE:
+		fmt.Println("- Only the output with the same index as the input is signed for, so other outputs can be added, changed or removed.")
+	}
+	if hashType&btcutils.SIGHASH_ANYONECANPAY != 0 {
+		fmt.Println("- Only this input is signed for, so other inputs can be added.")
+	}
+	fmt.Println("-----------------------------------------------------------------------------------------------------------------------------------")
+}
@@ -1,12 +1,10 @@
-// spend.go - Spending P2SH multisig funds to a Bitcoin address.
+// spend.go - Spending P2SH, P2WSH and nested P2SH-P2WSH multisig funds to a Bitcoin address of any type.
 package multisig
 
 import (
-	"github.com/prettymuchbryce/hellobitcoin/base58check"
 	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
 
 	"bytes"
-	"encoding/binary"
 	"encoding/csv"
 	"encoding/hex"
 	"fmt"
@@ -15,26 +13,37 @@ import (
 )
 
 //OutputSpend formats and prints relevant outputs to the user.
-func OutputSpend(flagPrivateKeys string, flagDestination string, flagRedeemScript string, flagInputTx string, flagAmount int) {
-	finalTransactionHex := generateSpend(flagPrivateKeys, flagDestination, flagRedeemScript, flagInputTx, flagAmount)
-	//Output final transaction
+func OutputSpend(flagPrivateKeys string, flagDestination string, flagRedeemScript string, flagInputTx string, flagInputIndex int, flagAmount int, flagType string, flagInputAmount int, flagInput string, flagPrevTx string, flagSort bool, flagRecovery bool, flagDelayed bool, flagSigHash string, options TransactionOptions, flagNetwork string) {
+	input := parseInput(flagInput, flagInputTx, flagInputIndex, flagInputAmount, flagPrevTx)
+	hashType := parseSigHashType(flagSigHash)
+	finalTransactionHex := generateSpend(flagPrivateKeys, flagDestination, flagRedeemScript, input, flagAmount, flagType, flagSort, flagRecovery, flagDelayed, hashType, options, parseNetwork(flagNetwork))
 	//Output our final transaction
 	fmt.Printf(`
 -----------------------------------------------------------------------------------------------------------------------------------
 Your raw spending transaction is:
 %v
-Broadcast this transaction to spend your multisig P2SH funds.
------------------------------------------------------------------------------------------------------------------------------------
+Broadcast this transaction to spend your multisig funds.
 `,
 		finalTransactionHex,
 	)
+	outputFee(finalTransactionHex, input.Amount)
+	outputLockTime(finalTransactionHex)
+	fmt.Println("-----------------------------------------------------------------------------------------------------------------------------------")
+	outputSigHashWarning(hashType)
 }
 
-// generateSpend is the high-level logic for spending from a P2SH multisig address with the 'go-bitcoin-multisig spend' subcommand.
+// generateSpend is the high-level logic for spending from a P2SH or P2WSH multisig address with the 'go-bitcoin-multisig spend' subcommand.
 // Takes flagPrivateKeys (comma separated list of M private keys), flagDestination (destination address of spent funds),
-// flagRedeemScript (redeemScript that matches P2SH script), flagInputTx (input transaction hash of P2SH input to spend)
-// and flagAmount (amount in Satoshis to send, with balance left over from input being used as transaction fee) as arguments.
-func generateSpend(flagPrivateKeys string, flagDestination string, flagRedeemScript string, flagInputTx string, flagAmount int) string {
+// flagRedeemScript (redeemScript that matches P2SH script, or witness script for P2WSH), input (multisig output to spend, with its amount
+// needed for p2wsh and p2sh-p2wsh and to work out change and fees, and its scriptPubKey checked against the redeem script when known),
+// flagAmount (amount in Satoshis to send), flagType (address type being spent, p2sh, p2wsh or p2sh-p2wsh), flagSort (true to check the redeem script is BIP67 sorted),
+// flagRecovery (true to sign with the recovery key of a recovery redeem script instead of M of its N keys), flagDelayed (true to
+// sign with the fewer keys the delayed branch of a delayed redeem script needs, once the input is old enough), hashType (signature
+// hash type, eg. SIGHASH_ALL), options (change address, fee, locktime, which defaults to the earliest the recovery key can spend with
+// flagRecovery, and replace-by-fee, which flagDelayed always signals) and network (network the private keys and addresses must belong
+// to) as arguments.
+// Without the input amount, the transaction is refused unless options.UnknownFee is set, when balance left over from input is used as transaction fee.
+func generateSpend(flagPrivateKeys string, flagDestination string, flagRedeemScript string, input *btcutils.UTXO, flagAmount int, flagType string, flagSort bool, flagRecovery bool, flagDelayed bool, hashType uint32, options TransactionOptions, network *btcutils.Network) string {
 	//First we create the raw transaction.
 	//In order to construct the raw transaction we need the input transaction hash,
 	//the destination address, the number of satoshis to send, and the scriptSig
@@ -57,83 +66,162 @@ func generateSpend(flagPrivateKeys string, flagDestination string, flagRedeemScr
 		if privateKeyString == "" {
 			log.Fatal("Provided private key cannot be empty.")
 		}
-		privateKeys[i] = base58check.Decode(privateKeyString) //Get private keys as slice of raw bytes
+		privateKeys[i], _, err = btcutils.ParseWIF(privateKeyString, network) //Get private keys as slice of raw bytes
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
-	//Create scriptPubKey with provided destination public key
-	publicKeyHash := base58check.Decode(flagDestination)
-	scriptPubKey, err := btcutils.NewP2PKHScriptPubKey(publicKeyHash)
-	if err != nil {
-		log.Fatal(err)
+	//Sign the multisig branch of the redeem script, the recovery branch no earlier than its locktime, or the delayed branch
+	//with the input's relative locktime
+	branch := spendBranch(redeemScript, flagRecovery, flagDelayed)
+	lockTime := branchLockTime(branch, parseLockTime(options.LockTime))
+	rbf := !options.NoRBF
+	if branch.sequence != 0 && !rbf {
+		log.Fatal("--no-rbf cannot be used with --delayed, since the input's relative locktime also signals replace-by-fee.")
 	}
-	//Create unsigned raw transaction
-	//scriptSig in unsigned transaction is serialized redeemScript of input P2SH transaction.
-	rawTransaction, err := btcutils.NewRawTransaction(flagInputTx, flagAmount, redeemScript, scriptPubKey)
-	if err != nil {
-		log.Fatal(err)
-	}
-	//After completing the raw transaction, we append
-	//SIGHASH_ALL in little-endian format to the end of the raw transaction.
-	hashCodeType, err := hex.DecodeString("01000000")
-	if err != nil {
-		log.Fatal(err)
+	//Signatures must be in redeem script order, whatever order private keys are given in
+	privateKeys = orderPrivateKeys(privateKeys, branch, flagSort)
+	//Create scriptPubKey matching the type of the destination address
+	scriptPubKey := destinationScriptPubKey(flagDestination, network)
+	//P2WSH inputs are signed with the BIP143 signature hash, with signatures in the witness instead of the scriptSig.
+	//The scriptSig is empty for native P2WSH, and only pushes the P2WSH witness program when nested in P2SH.
+	checkAddressType(flagType)
+	checkMultisigInput(input, flagType, redeemScript)
+	if flagType == addressTypeP2WSH || flagType == addressTypeP2SHP2WSH {
+		if input.Amount <= 0 {
+			log.Fatal("Amount of the SegWit input being spent must be provided, since SegWit signatures commit to it.")
+		}
+		var scriptSig []byte
+		if flagType == addressTypeP2SHP2WSH {
+			scriptSig, err = btcutils.NewP2SHP2WSHScriptSig(redeemScript)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		sign := func(outputs []*btcutils.TxOut) []byte {
+			transaction := newUnsignedTransaction(input, scriptSig, outputs, lockTime, branch.sequence, rbf)
+			finalTransaction, err := signWitnessMultisigTransaction(transaction, privateKeys, branch, redeemScript, input.Amount, hashType)
+			if err != nil {
+				log.Fatal(err)
+			}
+			return finalTransaction
+		}
+		finalTransaction := payWithFee(input.Amount, flagAmount, scriptPubKey, options, network, sign)
+		verifySignedTransaction(finalTransaction, multisigScriptPubKey(flagType, redeemScript), input.Amount)
+		return hex.EncodeToString(finalTransaction)
 	}
-	var rawTransactionBuffer bytes.Buffer
-	rawTransactionBuffer.Write(rawTransaction)
-	rawTransactionBuffer.Write(hashCodeType)
-	rawTransactionWithHashCodeType := rawTransactionBuffer.Bytes()
-	//Sign transaction
-	finalTransaction, err := signMultisigTransaction(rawTransactionWithHashCodeType, privateKeys, scriptPubKey, redeemScript, flagInputTx, flagAmount)
-	if err != nil {
-		log.Fatal(err)
+	sign := func(outputs []*btcutils.TxOut) []byte {
+		//Create unsigned raw transaction
+		//scriptSig in unsigned transaction is serialized redeemScript of input P2SH transaction.
+		transaction := newUnsignedTransaction(input, redeemScript, outputs, lockTime, branch.sequence, rbf)
+		//The signature hash commits to the parts of the transaction selected by hashType, with the redeemScript in place of the scriptSig
+		hash, err := btcutils.SignatureHash(transaction, 0, redeemScript, hashType)
+		if err != nil {
+			log.Fatal(err)
+		}
+		//Sign transaction
+		finalTransaction, err := signMultisigTransaction(hash, hashType, privateKeys, branch, redeemScript, transaction)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return finalTransaction
 	}
+	finalTransaction := payWithFee(input.Amount, flagAmount, scriptPubKey, options, network, sign)
+	verifySignedTransaction(finalTransaction, multisigScriptPubKey(flagType, redeemScript), input.Amount)
 	finalTransactionHex := hex.EncodeToString(finalTransaction)
 
 	return finalTransactionHex
 }
 
-// signMultisigTransaction signs a raw P2PKH transaction, given slice of private keys and the scriptPubKey, inputTx,
-// redeemScript and amount to construct the final transaction.
-func signMultisigTransaction(rawTransaction []byte, orderedPrivateKeys [][]byte, scriptPubKey []byte, redeemScript []byte, inputTx string, amount int) ([]byte, error) {
-	//Hash type SIGHASH_ALL
-	hashCodeType, err := hex.DecodeString("01")
-	if err != nil {
-		return nil, err
+// orderPrivateKeys matches each private key to the position of its public key in the redeem script branch being spent,
+// compressed or uncompressed, and returns the first m of them in redeem script order, since OP_CHECKMULTISIG needs signatures
+// in the same order as the public keys they belong to. Keys not in the branch, keys given twice and fewer than m keys are rejected.
+// With flagSort, the multisig public keys are also checked to be BIP67 sorted.
+func orderPrivateKeys(privateKeys [][]byte, branch scriptBranch, flagSort bool) [][]byte {
+	m, n, publicKeys := branch.m, len(branch.publicKeys), branch.publicKeys
+	if flagSort && branch.multisig {
+		sortedPublicKeys := btcutils.SortPublicKeys(publicKeys)
+		for i := range publicKeys {
+			if !bytes.Equal(publicKeys[i], sortedPublicKeys[i]) {
+				log.Fatal("Public keys in redeem script are not in BIP67 sorted order. Spend without --sort.")
+			}
+		}
+	}
+	//Find the position in the redeem script of each private key's public key
+	scriptPrivateKeys := make([][]byte, n)
+	for i, privateKey := range privateKeys {
+		scriptIndex := -1
+		for _, compressed := range []bool{true, false} {
+			publicKey, err := btcutils.NewPublicKey(privateKey, compressed)
+			if err != nil {
+				log.Fatal(err)
+			}
+			for j, scriptPublicKey := range publicKeys {
+				if bytes.Equal(publicKey, scriptPublicKey) {
+					scriptIndex = j
+				}
+			}
+		}
+		if scriptIndex == -1 {
+			log.Fatalf("Private key #%d does not match any public key in the redeem script.", i+1)
+		}
+		if scriptPrivateKeys[scriptIndex] != nil {
+			log.Fatalf("Private key #%d is given more than once, for public key #%d of the redeem script.", i+1, scriptIndex+1)
+		}
+		scriptPrivateKeys[scriptIndex] = privateKey
 	}
-	//Generate signatures for each provided key
+	//Sign with the first m keys in redeem script order, since more signatures than m make the scriptSig invalid
+	orderedPrivateKeys := make([][]byte, 0, m)
+	for _, privateKey := range scriptPrivateKeys {
+		if privateKey != nil && len(orderedPrivateKeys) < m {
+			orderedPrivateKeys = append(orderedPrivateKeys, privateKey)
+		}
+	}
+	if len(orderedPrivateKeys) < m {
+		log.Fatalf("%d-of-%d redeem script needs %d private keys to spend, but only %d were given.", m, n, m, len(orderedPrivateKeys))
+	}
+	return orderedPrivateKeys
+}
+
+// signMultisigTransaction signs a P2SH multisig transaction, given its signature hash for hashType, slice of private keys,
+// the branch of the redeemScript they sign, the redeemScript and the unsigned transaction whose first input receives the final scriptSig.
+func signMultisigTransaction(hash []byte, hashType uint32, orderedPrivateKeys [][]byte, branch scriptBranch, redeemScript []byte, transaction *btcutils.Transaction) ([]byte, error) {
+	//Generate signatures for each provided key, each followed by the hash type byte
 	signatures := make([][]byte, len(orderedPrivateKeys))
 	for i, privateKey := range orderedPrivateKeys {
-		signatures[i], err = btcutils.NewSignature(rawTransaction, privateKey)
+		signature, err := btcutils.NewSignatureForHash(hash, privateKey)
 		if err != nil {
 			return nil, err
 		}
-	}
-	//redeemScript length. To allow redeemScript > 255 bytes, we use OP_PUSHDATA2 and use two bytes to specify length
-	var redeemScriptLengthBytes []byte
-	var requiredOP_PUSHDATA int
-	if len(redeemScript) < 255 {
-		requiredOP_PUSHDATA = btcutils.OP_PUSHDATA1 //OP_PUSHDATA1 specifies next *one byte* will be length to be pushed to stack
-		redeemScriptLengthBytes = []byte{byte(len(redeemScript))}
-	} else {
-		requiredOP_PUSHDATA = btcutils.OP_PUSHDATA2 //OP_PUSHDATA2 specifies next *two bytes* will be length to be pushed to stack
-		redeemScriptLengthBytes = make([]byte, 2)
-		binary.LittleEndian.PutUint16(redeemScriptLengthBytes, uint16(len(redeemScript)))
+		signatures[i] = append(signature, byte(hashType))
 	}
 	//Create scriptSig
-	var buffer bytes.Buffer
-	buffer.WriteByte(byte(btcutils.OP_0)) //OP_0 for Multisig off-by-one error
-	for _, signature := range signatures {
-		buffer.WriteByte(byte(len(signature) + 1)) //PUSH each signature. Add one for hash type byte
-		buffer.Write(signature)                    // Signature bytes
-		buffer.WriteByte(hashCodeType[0])          //hash type
-	}
-	buffer.WriteByte(byte(requiredOP_PUSHDATA)) //OP_PUSHDATA1 or OP_PUSHDATA2 depending on size of redeemScript
-	buffer.Write(redeemScriptLengthBytes)       //PUSH redeemScript
-	buffer.Write(redeemScript)                  //redeemScript
-	scriptSig := buffer.Bytes()
+	scriptSig := btcutils.NewP2SHScriptSig(branch.stack(signatures), redeemScript)
 	//Finally create transaction with actual scriptSig
-	signedRawTransaction, err := btcutils.NewRawTransaction(inputTx, amount, scriptSig, scriptPubKey)
+	signedTransaction := transaction.Copy()
+	signedTransaction.Inputs[0].ScriptSig = scriptSig
+	return signedTransaction.Serialize(), nil
+}
+
+// signWitnessMultisigTransaction signs the first input of an unsigned transaction spending P2WSH (or P2SH-P2WSH) multisig funds, given slice
+// of private keys, the branch of the witnessScript they sign, the witnessScript, the amount of the output being spent and the signature hash type,
+// and returns the serialized signed transaction.
+func signWitnessMultisigTransaction(transaction *btcutils.Transaction, orderedPrivateKeys [][]byte, branch scriptBranch, witnessScript []byte, inputAmount int64, hashType uint32) ([]byte, error) {
+	hash, err := btcutils.WitnessSignatureHash(transaction, 0, witnessScript, inputAmount, hashType)
 	if err != nil {
 		return nil, err
 	}
-	return signedRawTransaction, nil
+	//Generate signatures for each provided key, each followed by the hash type byte
+	signatures := make([][]byte, len(orderedPrivateKeys))
+	for i, privateKey := range orderedPrivateKeys {
+		signature, err := btcutils.NewSignatureForHash(hash, privateKey)
+		if err != nil {
+			return nil, err
+		}
+		signatures[i] = append(signature, byte(hashType))
+	}
+	//Finally create transaction with witness in place of scriptSig
+	signedTransaction := transaction.Copy()
+	signedTransaction.Inputs[0].Witness = btcutils.NewP2WSHWitness(branch.stack(signatures), witnessScript)
+	return signedTransaction.Serialize(), nil
 }
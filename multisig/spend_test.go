@@ -3,22 +3,24 @@ package multisig
 import (
 	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
 	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"encoding/hex"
 	"reflect"
 	"testing"
 )
 
 func TestGenerateSpend(t *testing.T) {
-	btcutils.SetFixedNonce = true //SetFixedNonce set to true to get repeatable signatures with a fixed nonce for testing.
 	{
 		//5-of-7 spending multisig test
 		testPrivateKeys := "5HrL5AUs1WHYPxUmb7YwCYD448PixCH3epsf7meQg1tshQv8dbM,5JQLb8Hw69xZ9ybCAqUvDqdjyybSpcRFJCo921hZQgTX9eoBjgY,5K3AZzU3PbPQ2XmKSrnCuCvKVNebeG3VzVEjzMiszwpXT7y2qX1,5JcF9u4mxWVMHRHLZdQqDFuvv7izUkeTsmNiYdvEYyu5HfM2ju2,5K7DaqVHmZCv5jvUq8Ga9L9NoiiL4LUvpgUw4HwnvnFghgFBqLD"
 		testDestination := "1DJrhysUSzjNhP1GYJkgQkkEtCTgnnEWXi"
 		testRedeemScript := "554104c22e4293d1d462eef905e592ad4aff332aa52c3415b824cd85cf594258d92c836fe797187bc2459261e0597c4ef351c5d0c26f7a60165221e221a38e448ad08c4104bb28684dfe23852a7c276827dd448c955007e7ccbfacbf536e13f1097b30430ebec5af0bc001e50d3f0e796d52ba43e3c07337bfed2a842659d51632f2b21d2841048f8551173f8e7414ff0e144899b3f70accd957e6913f5cf877bd576f6c16f0aa67fb9b96e0df10562b4f7ba4060acd22f142329ff83f1d96e27f4e4394adeda24104aa81def7dda6a4f40be2f3287ee3423f255b07965104a7888df075217c9ee5b3e9e2e70115d43bfecbff8062f8289f5cab3d0ebd96c9f55c85f6147ff3a5e9494104493aa5f89ec34184a235b2c9f608eade1634636f94f64b59419875e15cb86a6d8c708a9d5eda3304cb983b2325a57af881ed75f28179f5f263d7758039b68d894104dc284f749208d7fec57937bc5e72187b064df7d29b7aa82cae273e9a1c91beae9c510e0fd632a3db272c67db04061ea761d1ed91fdb8ab07e354047c64ce405d41042fc7796f54dd482db20f1bcce584f930ae74d5f27fc8336e2701bd0243d681281810c57e079947ebdfdfc8860ed34b0ba32db82a85249adc7c64ab547d48af6457ae"
 		testInputTx := "c2e036e044445c3d699976b5ec8ef3419c228e3b150a48706ac49cad5b7669da"
+		testInputIndex := 0
 		testAmount := 145600
-		testFinalTransactionHex := "0100000001da69765bad9cc46a70480a153b8e229c41f38eecb57699693d5c4444e036e0c200000000fd3d030047304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e2022016de9b7ae8eaba28b761c09b5f5d58732aeb98bb0121e4f8411cb471824b13780147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202204f43b84c9ef4371ee5382e44002824485e1e2f6919eedbaf26e406f46318fbbd0147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202206876e87463a637f8168eed56da177f78c9a01e0439c46c937d86af182efd9e670147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e2022010b0ea71218abe8d5be9a586ae4c87b32215ed7eb28508c6dcde6c2c796c11620147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e2022070be464546c146a92dad100ead8f7bae32af8650ee763105e0cb5182b5063471014dd101554104c22e4293d1d462eef905e592ad4aff332aa52c3415b824cd85cf594258d92c836fe797187bc2459261e0597c4ef351c5d0c26f7a60165221e221a38e448ad08c4104bb28684dfe23852a7c276827dd448c955007e7ccbfacbf536e13f1097b30430ebec5af0bc001e50d3f0e796d52ba43e3c07337bfed2a842659d51632f2b21d2841048f8551173f8e7414ff0e144899b3f70accd957e6913f5cf877bd576f6c16f0aa67fb9b96e0df10562b4f7ba4060acd22f142329ff83f1d96e27f4e4394adeda24104aa81def7dda6a4f40be2f3287ee3423f255b07965104a7888df075217c9ee5b3e9e2e70115d43bfecbff8062f8289f5cab3d0ebd96c9f55c85f6147ff3a5e9494104493aa5f89ec34184a235b2c9f608eade1634636f94f64b59419875e15cb86a6d8c708a9d5eda3304cb983b2325a57af881ed75f28179f5f263d7758039b68d894104dc284f749208d7fec57937bc5e72187b064df7d29b7aa82cae273e9a1c91beae9c510e0fd632a3db272c67db04061ea761d1ed91fdb8ab07e354047c64ce405d41042fc7796f54dd482db20f1bcce584f930ae74d5f27fc8336e2701bd0243d681281810c57e079947ebdfdfc8860ed34b0ba32db82a85249adc7c64ab547d48af6457aeffffffff01c0380200000000001976a914870212de342646df8eb8874964f78ae2929f063e88ac00000000"
+		testFinalTransactionHex := "0100000001da69765bad9cc46a70480a153b8e229c41f38eecb57699693d5c4444e036e0c200000000fd4003004730440220444c3f5926d2942799fa3ccc03ac539be4af88e4180138181d247bf5e9c15fef022044d3f1a69e755ca45c8f3d592a603b47e3336716fe3eeeb8492d17c7fd7c6c3a0147304402205b61381a7dffb08084459b7eac64aabb03f44b998b3e232b2045ed8ba52e6f7202202fd27f3143ef335406a9472ed07d09f7554b30146a66499c6ab814f770fff0fb01483045022100cdda24d8bd8eb3515d4e130ca42df09e1cbf8c56c108c4557a67563c2d57160f02206569a950c3718b6f221354385184a143b154e7e57b5c75cc18cd323ab9de894001483045022100da7d42eb8b441e3868e7ff664381eb1d812f635b4fa580c4291a9a4eb647130d02201e99159e0ce585e652f8bef8b1c85a557b4557f7cda09c71c763d550b8f71afa01483045022100cab3ba0d10e91e1539be5e70e16901980bfe0cccd5fbe7a9cb731a977799eb0002201ee0200e952c2a6c05469805bc0b1603c972530b66152b8323819618385229e9014dd101554104c22e4293d1d462eef905e592ad4aff332aa52c3415b824cd85cf594258d92c836fe797187bc2459261e0597c4ef351c5d0c26f7a60165221e221a38e448ad08c4104bb28684dfe23852a7c276827dd448c955007e7ccbfacbf536e13f1097b30430ebec5af0bc001e50d3f0e796d52ba43e3c07337bfed2a842659d51632f2b21d2841048f8551173f8e7414ff0e144899b3f70accd957e6913f5cf877bd576f6c16f0aa67fb9b96e0df10562b4f7ba4060acd22f142329ff83f1d96e27f4e4394adeda24104aa81def7dda6a4f40be2f3287ee3423f255b07965104a7888df075217c9ee5b3e9e2e70115d43bfecbff8062f8289f5cab3d0ebd96c9f55c85f6147ff3a5e9494104493aa5f89ec34184a235b2c9f608eade1634636f94f64b59419875e15cb86a6d8c708a9d5eda3304cb983b2325a57af881ed75f28179f5f263d7758039b68d894104dc284f749208d7fec57937bc5e72187b064df7d29b7aa82cae273e9a1c91beae9c510e0fd632a3db272c67db04061ea761d1ed91fdb8ab07e354047c64ce405d41042fc7796f54dd482db20f1bcce584f930ae74d5f27fc8336e2701bd0243d681281810c57e079947ebdfdfc8860ed34b0ba32db82a85249adc7c64ab547d48af6457aeffffffff01c0380200000000001976a914870212de342646df8eb8874964f78ae2929f063e88ac00000000"
 
-		finalTransactionHex := generateSpend(testPrivateKeys, testDestination, testRedeemScript, testInputTx, testAmount)
+		finalTransactionHex := generateSpend(testPrivateKeys, testDestination, testRedeemScript, &btcutils.UTXO{TxHash: testInputTx, Index: uint32(testInputIndex)}, testAmount, addressTypeP2SH, false, false, false, btcutils.SIGHASH_ALL, testTransactionOptions, btcutils.MainNet)
 		if testFinalTransactionHex != finalTransactionHex {
 			testutils.CompareError(t, "Generated spend transaction different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
 		}
@@ -29,10 +31,11 @@ func TestGenerateSpend(t *testing.T) {
 		testDestination := "1EK4KToKVHdz787e26JCQuSTtnPAvJZRC5"
 		testRedeemScript := "57410446f1c8de232a065da428bf76e44b41f59a46620dec0aedfc9b5ab651e91f2051d610fddc78b8eba38a634bfe9a74bb015a88c52b9b844c74997035e08a695ce94104704e19d4fc234a42d707d41053c87011f990b564949532d72cab009e136bd60d7d0602f925fce79da77c0dfef4a49c6f44bd0540faef548e37557d74b36da1244104b75a8cb10fd3f1785addbafdb41b409ecd6ffd50d5ad71d8a3cdc5503bcb35d3d13cdf23f6d0eb6ab88446276e2ba5b92d8786da7e5c0fb63aafb62f87443d284104033a82ccb1291bbc27cf541c6c487c213f25db85c620ecb9cbb76ca461ef13db5a80b90c3ae7d2a5e47623cdf520a2586cac7e41f779103a71a1fe177189781e41045e3b4030be5fd9c4c40e7076bd49f022118d90ae9182de61f3a1adb2ff511c97e8a6a82a9292b01878a18c08b7cd658ebdf80e6ed3f26783b25ba1a52fa9e52d4104c93ceb8f4482e131addc58d3efa0b4967bb7c574de15786d55379cc4a43a61571518abe0f05ebf188bcce9580aa70b3f5b1024ca579819c8810ff79967de3f234104a66f63d2941f0befcfba4b73495a7b99fc7ed28cb41e7934e1de82d852628766dc96ee1e196387a68e7fd8898862c2260f1f2557ac2147af07900695f15abd3f57ae"
 		testInputTx := "8462ab27b115d66ea767cc50cb4f1b0070c0200d93d4a6984c374ad6459188f7"
+		testInputIndex := 0
 		testAmount := 75600
-		testFinalTransactionHex := "0100000001f7889145d64a374c98a6d4930d20c070001b4fcb50cc67a76ed615b127ab628400000000fdcd030047304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e20220792733272f3be0f852c4603d132327ba851c32dbdc98d4087521ace999111d590147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e2022056a02e4af79e085d9d577045b26774374c879374f3933dd2106e7e5cb64e8f080147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e2022016c85973985bd4afa0f5df71f8213512c8268c6db9f3267ce7bc8d3af75d25280147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202207d61422f4f32a06d93e9d78ad628bf33058a2a7763ce6ba93a09803ff372b8d20147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202201b64ecacd19fb31d446e446838edbd2af9da307fadf76b48ce6008cd21d0d8680147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e2022059cf7b566d5e7af104f1a257499b47a89db5a5bff482b2399734baaa605c490c0147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202200949969d89e6b890f342f8a9b5382f414324317a25c411ecb07a87a6b3c27c25014dd10157410446f1c8de232a065da428bf76e44b41f59a46620dec0aedfc9b5ab651e91f2051d610fddc78b8eba38a634bfe9a74bb015a88c52b9b844c74997035e08a695ce94104704e19d4fc234a42d707d41053c87011f990b564949532d72cab009e136bd60d7d0602f925fce79da77c0dfef4a49c6f44bd0540faef548e37557d74b36da1244104b75a8cb10fd3f1785addbafdb41b409ecd6ffd50d5ad71d8a3cdc5503bcb35d3d13cdf23f6d0eb6ab88446276e2ba5b92d8786da7e5c0fb63aafb62f87443d284104033a82ccb1291bbc27cf541c6c487c213f25db85c620ecb9cbb76ca461ef13db5a80b90c3ae7d2a5e47623cdf520a2586cac7e41f779103a71a1fe177189781e41045e3b4030be5fd9c4c40e7076bd49f022118d90ae9182de61f3a1adb2ff511c97e8a6a82a9292b01878a18c08b7cd658ebdf80e6ed3f26783b25ba1a52fa9e52d4104c93ceb8f4482e131addc58d3efa0b4967bb7c574de15786d55379cc4a43a61571518abe0f05ebf188bcce9580aa70b3f5b1024ca579819c8810ff79967de3f234104a66f63d2941f0befcfba4b73495a7b99fc7ed28cb41e7934e1de82d852628766dc96ee1e196387a68e7fd8898862c2260f1f2557ac2147af07900695f15abd3f57aeffffffff0150270100000000001976a9149203e47a16f799ded03532e3e452606fdc52007e88ac00000000"
+		testFinalTransactionHex := "0100000001f7889145d64a374c98a6d4930d20c070001b4fcb50cc67a76ed615b127ab628400000000fdd20300483045022100adf8b5493cc2758c4dc7fc25263efbf4e1803734fbbc906298b8fc0909da211802204aa3cf5cdfce75190f4a3998be2b055b303e16e0c0580fb2c7e0fbb69ccd46de01483045022100e0d72aa288d0dfc62cb901fdc7d452fbaee7ca2fb61b40ec7687fcbec37f62ec02203a82efd16c2d900317b2a5fa1568b89db00496622f292e8c0bad1a0a93cd16240147304402201325836f97262e6aadd70e116cdb7e048e0ae2fdd1da4b671e71ff71a58f78140220579dbfaafa899d9e9e87120f023ded1eb9aa9272c3d961731a67ecf32e1f333a01483045022100a282fce0fcde0522bcbcd35328582679b2e160cefa899c29f5523ad9f01277c802202acfa1afc8d8b01ae94b565901acfa179d57ca429a20071fe96418f9f78857e801483045022100829fcb4c530b0ece63f4354c750658be3cb825f047578a0505cb37c265cc0b8802200150d50c8dded79f9e47479238a4e5cbd5b803a353e5fde8ded524ec77be9b7801483045022100f3663c0d0cef0ac46b98c3d14392d9b9007a1c2f47a754fc44db6c8292bad25402201645b4181c5e1ee4aeb89dc54b10d12979632394e55381aea4d0f987122509b10147304402205d6ff8dcc4380d36a166c278b0b20ad8c8fcdd288a40f7e8d57c386be74db402022004c3e114b3ef5df45ce3873d468facd6337c382b5b759e9e219564c5bc351ad6014dd10157410446f1c8de232a065da428bf76e44b41f59a46620dec0aedfc9b5ab651e91f2051d610fddc78b8eba38a634bfe9a74bb015a88c52b9b844c74997035e08a695ce94104704e19d4fc234a42d707d41053c87011f990b564949532d72cab009e136bd60d7d0602f925fce79da77c0dfef4a49c6f44bd0540faef548e37557d74b36da1244104b75a8cb10fd3f1785addbafdb41b409ecd6ffd50d5ad71d8a3cdc5503bcb35d3d13cdf23f6d0eb6ab88446276e2ba5b92d8786da7e5c0fb63aafb62f87443d284104033a82ccb1291bbc27cf541c6c487c213f25db85c620ecb9cbb76ca461ef13db5a80b90c3ae7d2a5e47623cdf520a2586cac7e41f779103a71a1fe177189781e41045e3b4030be5fd9c4c40e7076bd49f022118d90ae9182de61f3a1adb2ff511c97e8a6a82a9292b01878a18c08b7cd658ebdf80e6ed3f26783b25ba1a52fa9e52d4104c93ceb8f4482e131addc58d3efa0b4967bb7c574de15786d55379cc4a43a61571518abe0f05ebf188bcce9580aa70b3f5b1024ca579819c8810ff79967de3f234104a66f63d2941f0befcfba4b73495a7b99fc7ed28cb41e7934e1de82d852628766dc96ee1e196387a68e7fd8898862c2260f1f2557ac2147af07900695f15abd3f57aeffffffff0150270100000000001976a9149203e47a16f799ded03532e3e452606fdc52007e88ac00000000"
 
-		finalTransactionHex := generateSpend(testPrivateKeys, testDestination, testRedeemScript, testInputTx, testAmount)
+		finalTransactionHex := generateSpend(testPrivateKeys, testDestination, testRedeemScript, &btcutils.UTXO{TxHash: testInputTx, Index: uint32(testInputIndex)}, testAmount, addressTypeP2SH, false, false, false, btcutils.SIGHASH_ALL, testTransactionOptions, btcutils.MainNet)
 		if testFinalTransactionHex != finalTransactionHex {
 			testutils.CompareError(t, "Generated spend transaction different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
 		}
@@ -43,18 +46,122 @@ func TestGenerateSpend(t *testing.T) {
 		testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
 		testRedeemScript := "524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae"
 		testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+		testInputIndex := 0
 		testAmount := 55600
-		testFinalTransactionHex := "01000000013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b00200000000fd5c010047304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e20220106d4068c7b29336dc39b96234e1b55fdbd79287eeb147d9405b189d4368b0c60147304402206d6caac248af96f6afa7f904f550253a0f3ef3f5aa2fe6838a95b216691468e202204b14745bcc78dbac7e57c5cd64fb5d351a00632293dd01d5e567b402a51ba831014cc9524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353aeffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000"
+		testFinalTransactionHex := "01000000013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b00200000000fd5e0100483045022100af4831eb0cee1b9642ff8691acb53c2fd8e28bbd6c0389af69c132b342405c8502200627e67cbb0bee502421be4d2b8e370d24eb17ff7c3d77d604a5c07ee5934a74014830450221009bfee48a5ae99a8fc0f78c631b0b2beb6d96337fc9d1e95c333a08bdcecb3647022040a30b3528b136821077d0bb45c324d7eedcd0ac61af7019ba1286e9277b7c54014cc9524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353aeffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000"
 
-		finalTransactionHex := generateSpend(testPrivateKeys, testDestination, testRedeemScript, testInputTx, testAmount)
+		finalTransactionHex := generateSpend(testPrivateKeys, testDestination, testRedeemScript, &btcutils.UTXO{TxHash: testInputTx, Index: uint32(testInputIndex)}, testAmount, addressTypeP2SH, false, false, false, btcutils.SIGHASH_ALL, testTransactionOptions, btcutils.MainNet)
 		if testFinalTransactionHex != finalTransactionHex {
 			testutils.CompareError(t, "Generated spend transaction different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
 		}
 	}
 }
 
+func TestGenerateSpendP2WSH(t *testing.T) {
+	//2-of-3 P2WSH spending multisig test, signatures committing to the 65600 satoshi input amount
+	testPrivateKeys := "5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3,5JjHVMwJdjPEPQhq34WMUhzLcEd4SD7HgZktEh8WHstWcCLRceV"
+	testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
+	testWitnessScript := "524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae"
+	testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+	testInputIndex := 0
+	testAmount := 55600
+	testInputAmount := 65600
+	testFinalTransactionHex := "010000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000ffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac0400483045022100cf0f422b00367b062740dcff95af09f41f015dccb6694ecb69dd0934fd4a3e0302204c3f3bc108ee873929a94d92dd94775e0aa8ce334b79e2e1e4394593c5e0bdcd01483045022100dd7caa994f6ac7eb91673ff74c31c340d6150c245031bf93196c8be5e8644a210220700165544e788844c28bba733c97db614030d2d76e3db947b02a8533968d3bc501c9524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae00000000"
+
+	finalTransactionHex := generateSpend(testPrivateKeys, testDestination, testWitnessScript, &btcutils.UTXO{TxHash: testInputTx, Index: uint32(testInputIndex), Amount: int64(testInputAmount)}, testAmount, addressTypeP2WSH, false, false, false, btcutils.SIGHASH_ALL, testTransactionOptions, btcutils.MainNet)
+	if testFinalTransactionHex != finalTransactionHex {
+		testutils.CompareError(t, "Generated P2WSH spend transaction different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
+	}
+}
+
+func TestGenerateSpendSigHash(t *testing.T) {
+	//2-of-3 spends from TestGenerateSpendP2WSH and the last TestGenerateSpend test, signed with other signature hash types.
+	//Expected transactions verified with btcsuite's txscript.
+	testPrivateKeys := "5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3,5JjHVMwJdjPEPQhq34WMUhzLcEd4SD7HgZktEh8WHstWcCLRceV"
+	testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
+	testRedeemScript := "524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae"
+	testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+	testAmount := 55600
+	testInputAmount := 65600
+	{
+		testFinalTransactionHex := "010000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000ffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac0400483045022100fd4e39cfc69b1897d48247ffaf5cf619b8076fbd026beb95d5634706a890c8b5022067d0d589a96faf2ef9093d2a32c491b3a2ea8c8ce4e363f24f12710ab93f9e9c8347304402207af617dc668afa20ada572346824eaf23d7678d2f9130c0f986a0c83df6a22e202201ddaed197f5977365a92c57a27812d6652e84c734dac0244c81b568be258cd3f83c9524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae00000000"
+
+		finalTransactionHex := generateSpend(testPrivateKeys, testDestination, testRedeemScript, &btcutils.UTXO{TxHash: testInputTx, Index: 0, Amount: int64(testInputAmount)}, testAmount, addressTypeP2WSH, false, false, false, btcutils.SIGHASH_SINGLE|btcutils.SIGHASH_ANYONECANPAY, testTransactionOptions, btcutils.MainNet)
+		if testFinalTransactionHex != finalTransactionHex {
+			testutils.CompareError(t, "Generated SINGLE|ANYONECANPAY P2WSH spend transaction different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
+		}
+	}
+	{
+		testFinalTransactionHex := "01000000013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b00200000000fd5e0100483045022100a4d1eb0bfb62cd5c27bbbbd78b6a80a1a32a4bfe73d91962b47bf08af8a0691c02205429377c3df1e7d20af2b1c2ec99b93611b58d2fe4ff2ed7375d6028bdd4101802483045022100f8c34a204c49c622fc7b638b3a2c43a4508b8249ebc6ea20c9d3e756cb21a4ca02203feabacaac7a08471d88d39446b1a8ac901aca8a4eec3ccee66c8803b29f9c7c024cc9524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353aeffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000"
+
+		finalTransactionHex := generateSpend(testPrivateKeys, testDestination, testRedeemScript, &btcutils.UTXO{TxHash: testInputTx, Index: 0}, testAmount, addressTypeP2SH, false, false, false, btcutils.SIGHASH_NONE, testTransactionOptions, btcutils.MainNet)
+		if testFinalTransactionHex != finalTransactionHex {
+			testutils.CompareError(t, "Generated NONE P2SH spend transaction different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
+		}
+	}
+}
+
+func TestGenerateSpendP2WSHCompressed(t *testing.T) {
+	//2-of-3 P2WSH spending multisig test with compressed public keys and compressed WIF private keys of the P2WSH test keys
+	testPrivateKeys := "L1qH5hF9PBBTEx8fyeSDiFp2ti7eSCsj3itBFTNV7a2w2ZkJWBXK,L1FdnBQgq8585udD26UZPHv5YX8AAGSkddah9S88HVcMfumhRQkt"
+	testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
+	testWitnessScript := "522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae"
+	testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+	testInputIndex := 0
+	testAmount := 55600
+	testInputAmount := 65600
+	testFinalTransactionHex := "010000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000ffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac0400473044022051e94657dd7654c881aa16d6f0e8b16801e5471ba46da7cc3df54b625f884270022041078fff8d287ad21d5a98018d471795658c67c2af548d0d4de6f6911beaf99101473044022033e50672858b02187fc4361ea0f4f23efeb1c0ea080722eae57dcded87bd9cea02207eb6fb5965fca629bc75efbffaa6dde804a0ec31870ddc3ef974cbff3aaca7740169522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae00000000"
+
+	finalTransactionHex := generateSpend(testPrivateKeys, testDestination, testWitnessScript, &btcutils.UTXO{TxHash: testInputTx, Index: uint32(testInputIndex), Amount: int64(testInputAmount)}, testAmount, addressTypeP2WSH, false, false, false, btcutils.SIGHASH_ALL, testTransactionOptions, btcutils.MainNet)
+	if testFinalTransactionHex != finalTransactionHex {
+		testutils.CompareError(t, "Generated P2WSH spend transaction different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
+	}
+}
+
+func TestGenerateSpendToAnyAddress(t *testing.T) {
+	//2-of-3 P2WSH compressed spending multisig test, sending to destinations of other types, including another multisig address
+	testPrivateKeys := "L1qH5hF9PBBTEx8fyeSDiFp2ti7eSCsj3itBFTNV7a2w2ZkJWBXK,L1FdnBQgq8585udD26UZPHv5YX8AAGSkddah9S88HVcMfumhRQkt"
+	testWitnessScript := "522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae"
+	testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+	testInputIndex := 0
+	testAmount := 55600
+	testInputAmount := 65600
+	testSpends := []struct {
+		destination         string
+		finalTransactionHex string
+	}{
+		{"347N1Thc213QqfYCz3PZkjoJpNv5b14kBd", "010000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000ffffffff0130d900000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e87040047304402200a7836a012c491cf810ed2d44c516694d30900ab477c5df7c73efd52345329bb022028ff1e6254394cff73456e844deef97af8b77fc39190ef8fba91aa9e586cf413014830450221009e7868c0eaad342dc4adfb78c1257b52bdffad654b9f68b0c066fedb8b6acf4e02204ef7f089be7826752aea12c4fe11f66cf196420ac19b6eabe5cd2ac376aead900169522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae00000000"},                                                   //P2SH
+		{"bc1quwq6xpy9ret6e7velxsnfddmj8f5plfn9z09fsaqsz44704l24kswgzmak", "010000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000ffffffff0130d9000000000000220020e381a304851e57acf999f9a134b5bb91d340fd33289e54c3a080ab5f3ebf556d04004730440220372ae1b0b3ff0ba145fe6c67b5dc3cff538ce89df4fc8a83c136945395204304022001cba5ebde80c011ce7c1de502bc4a4a19a25cbcbf19d473d22dfb0970f6cbe201483045022100b161b81c15312dedbbee09b267eed1ce2e32f22ba1cc183238e402ce94e8502c022027b45c6e9a78853e15b936a84af20cbd2ac1abc50e2707a2c88e8f15a55666200169522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae00000000"}, //P2WSH
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", "010000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000ffffffff0130d9000000000000160014751e76e8199196d454941c45d1b3a323f1433bd604004730440220651953ba65b47c8f236cc5d3f33ffb277cc5bf249dbc9297c412205e73223f4802207fc84073c90babdf83918994af4f3b8493fd93f6c2e0ad139a8a92a656e8bb8801473044022028a2fdebcd4bd8685a01f99afb886b46bb5b09c7002b85940d046c7fb0938b9f02204a368330f510a8126128b760627a6432d4f8ef17b6a8d00d656d46eb0ec1f2660169522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae00000000"},                                               //P2WPKH
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", "010000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000ffffffff0130d900000000000022512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798040047304402205d6b7de8dfcc654e2a4a8d76e4a58c7963997fddbaee606d7768cbd4d8750a9e02200d97fdc67704921e8ef76ec5e5d75cb9a068e31e4909049ec276d809c9394df201483045022100a0ed0cc8e64613bb33f6e79d624d6da3a6a86d17cf512a0c1ee1ae9aef6bc0f102203dabd0d34d27168d8b10b491f7ae04772ea6d2cb3565a98d90c59863cd39459f0169522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae00000000"}, //P2TR
+	}
+
+	for _, testSpend := range testSpends {
+		finalTransactionHex := generateSpend(testPrivateKeys, testSpend.destination, testWitnessScript, &btcutils.UTXO{TxHash: testInputTx, Index: uint32(testInputIndex), Amount: int64(testInputAmount)}, testAmount, addressTypeP2WSH, false, false, false, btcutils.SIGHASH_ALL, testTransactionOptions, btcutils.MainNet)
+		if testSpend.finalTransactionHex != finalTransactionHex {
+			testutils.CompareError(t, "Generated spend transaction to "+testSpend.destination+" different from expected transaction.", testSpend.finalTransactionHex, finalTransactionHex)
+		}
+	}
+}
+
+func TestGenerateSpendP2SHP2WSH(t *testing.T) {
+	//2-of-3 P2SH-P2WSH spending multisig test. Witness matches the P2WSH test, plus a scriptSig pushing the witness program.
+	testPrivateKeys := "5JruagvxNLXTnkksyLMfgFgf3CagJ3Ekxu5oGxpTm5mPfTAPez3,5JjHVMwJdjPEPQhq34WMUhzLcEd4SD7HgZktEh8WHstWcCLRceV"
+	testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
+	testWitnessScript := "524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae"
+	testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+	testInputIndex := 0
+	testAmount := 55600
+	testInputAmount := 65600
+	testFinalTransactionHex := "010000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000023220020e381a304851e57acf999f9a134b5bb91d340fd33289e54c3a080ab5f3ebf556dffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac0400483045022100cf0f422b00367b062740dcff95af09f41f015dccb6694ecb69dd0934fd4a3e0302204c3f3bc108ee873929a94d92dd94775e0aa8ce334b79e2e1e4394593c5e0bdcd01483045022100dd7caa994f6ac7eb91673ff74c31c340d6150c245031bf93196c8be5e8644a210220700165544e788844c28bba733c97db614030d2d76e3db947b02a8533968d3bc501c9524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae00000000"
+
+	finalTransactionHex := generateSpend(testPrivateKeys, testDestination, testWitnessScript, &btcutils.UTXO{TxHash: testInputTx, Index: uint32(testInputIndex), Amount: int64(testInputAmount)}, testAmount, addressTypeP2SHP2WSH, false, false, false, btcutils.SIGHASH_ALL, testTransactionOptions, btcutils.MainNet)
+	if testFinalTransactionHex != finalTransactionHex {
+		testutils.CompareError(t, "Generated P2SH-P2WSH spend transaction different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
+	}
+}
+
 func TestSignMultisigTransaction(t *testing.T) {
-	btcutils.SetFixedNonce = true //SetFixedNonce set to true to get repeatable signatures with a fixed nonce for testing.
 	{
 		testRawTransanction := []byte{1, 0, 0, 0, 1, 61, 205, 125, 135, 144, 76, 156, 183, 244, 183, 159, 54, 181, 160, 63, 150, 226, 231, 41, 40, 76, 9, 133, 98, 56, 213, 53, 62, 17, 130, 176, 2, 0, 0, 0, 0, 201, 82, 65, 4, 168, 130, 212, 20, 228, 120, 3, 156, 213, 181, 42, 146, 255, 177, 61, 213, 230, 189, 69, 21, 73, 116, 57, 223, 253, 105, 26, 15, 18, 175, 149, 117, 250, 52, 155, 86, 148, 237, 49, 85, 177, 54, 240, 158, 99, 151, 90, 23, 0, 201, 244, 212, 223, 132, 147, 35, 218, 192, 108, 243, 189, 100, 88, 205, 65, 4, 108, 227, 29, 185, 189, 213, 67, 231, 47, 227, 3, 154, 31, 28, 4, 125, 171, 135, 3, 124, 54, 166, 105, 255, 144, 226, 141, 161, 132, 143, 100, 13, 230, 140, 47, 233, 19, 211, 99, 165, 17, 84, 160, 198, 45, 122, 222, 161, 184, 34, 208, 80, 53, 7, 116, 24, 38, 123, 26, 19, 121, 121, 1, 135, 65, 4, 17, 255, 211, 108, 112, 119, 101, 56, 208, 121, 251, 174, 17, 125, 195, 142, 255, 175, 179, 51, 4, 175, 131, 206, 72, 148, 88, 151, 71, 174, 225, 239, 153, 47, 99, 40, 5, 103, 245, 47, 91, 168, 112, 103, 139, 74, 180, 255, 108, 142, 166, 0, 189, 33, 120, 112, 168, 180, 241, 240, 159, 58, 142, 131, 83, 174, 255, 255, 255, 255, 1, 48, 217, 0, 0, 0, 0, 0, 0, 25, 118, 169, 20, 86, 144, 118, 186, 57, 252, 79, 246, 162, 41, 29, 158, 169, 25, 109, 140, 8, 249, 199, 171, 136, 172, 0, 0, 0, 0, 1, 0, 0, 0}
 		testOrderedPrivateKeys := [][]byte{
@@ -64,10 +171,18 @@ func TestSignMultisigTransaction(t *testing.T) {
 		testScriptPubKey := []byte{118, 169, 20, 86, 144, 118, 186, 57, 252, 79, 246, 162, 41, 29, 158, 169, 25, 109, 140, 8, 249, 199, 171, 136, 172}
 		testRedeemScript := []byte{82, 65, 4, 168, 130, 212, 20, 228, 120, 3, 156, 213, 181, 42, 146, 255, 177, 61, 213, 230, 189, 69, 21, 73, 116, 57, 223, 253, 105, 26, 15, 18, 175, 149, 117, 250, 52, 155, 86, 148, 237, 49, 85, 177, 54, 240, 158, 99, 151, 90, 23, 0, 201, 244, 212, 223, 132, 147, 35, 218, 192, 108, 243, 189, 100, 88, 205, 65, 4, 108, 227, 29, 185, 189, 213, 67, 231, 47, 227, 3, 154, 31, 28, 4, 125, 171, 135, 3, 124, 54, 166, 105, 255, 144, 226, 141, 161, 132, 143, 100, 13, 230, 140, 47, 233, 19, 211, 99, 165, 17, 84, 160, 198, 45, 122, 222, 161, 184, 34, 208, 80, 53, 7, 116, 24, 38, 123, 26, 19, 121, 121, 1, 135, 65, 4, 17, 255, 211, 108, 112, 119, 101, 56, 208, 121, 251, 174, 17, 125, 195, 142, 255, 175, 179, 51, 4, 175, 131, 206, 72, 148, 88, 151, 71, 174, 225, 239, 153, 47, 99, 40, 5, 103, 245, 47, 91, 168, 112, 103, 139, 74, 180, 255, 108, 142, 166, 0, 189, 33, 120, 112, 168, 180, 241, 240, 159, 58, 142, 131, 83, 17}
 		testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+		testInputIndex := 0
 		testAmount := 55600
-		testSignedTx := []byte{1, 0, 0, 0, 1, 61, 205, 125, 135, 144, 76, 156, 183, 244, 183, 159, 54, 181, 160, 63, 150, 226, 231, 41, 40, 76, 9, 133, 98, 56, 213, 53, 62, 17, 130, 176, 2, 0, 0, 0, 0, 253, 92, 1, 0, 71, 48, 68, 2, 32, 109, 108, 170, 194, 72, 175, 150, 246, 175, 167, 249, 4, 245, 80, 37, 58, 15, 62, 243, 245, 170, 47, 230, 131, 138, 149, 178, 22, 105, 20, 104, 226, 2, 32, 16, 109, 64, 104, 199, 178, 147, 54, 220, 57, 185, 98, 52, 225, 181, 95, 219, 215, 146, 135, 238, 177, 71, 217, 64, 91, 24, 157, 67, 104, 176, 198, 1, 71, 48, 68, 2, 32, 109, 108, 170, 194, 72, 175, 150, 246, 175, 167, 249, 4, 245, 80, 37, 58, 15, 62, 243, 245, 170, 47, 230, 131, 138, 149, 178, 22, 105, 20, 104, 226, 2, 32, 75, 20, 116, 91, 204, 120, 219, 172, 126, 87, 197, 205, 100, 251, 93, 53, 26, 0, 99, 34, 147, 221, 1, 213, 229, 103, 180, 2, 165, 27, 168, 49, 1, 76, 201, 82, 65, 4, 168, 130, 212, 20, 228, 120, 3, 156, 213, 181, 42, 146, 255, 177, 61, 213, 230, 189, 69, 21, 73, 116, 57, 223, 253, 105, 26, 15, 18, 175, 149, 117, 250, 52, 155, 86, 148, 237, 49, 85, 177, 54, 240, 158, 99, 151, 90, 23, 0, 201, 244, 212, 223, 132, 147, 35, 218, 192, 108, 243, 189, 100, 88, 205, 65, 4, 108, 227, 29, 185, 189, 213, 67, 231, 47, 227, 3, 154, 31, 28, 4, 125, 171, 135, 3, 124, 54, 166, 105, 255, 144, 226, 141, 161, 132, 143, 100, 13, 230, 140, 47, 233, 19, 211, 99, 165, 17, 84, 160, 198, 45, 122, 222, 161, 184, 34, 208, 80, 53, 7, 116, 24, 38, 123, 26, 19, 121, 121, 1, 135, 65, 4, 17, 255, 211, 108, 112, 119, 101, 56, 208, 121, 251, 174, 17, 125, 195, 142, 255, 175, 179, 51, 4, 175, 131, 206, 72, 148, 88, 151, 71, 174, 225, 239, 153, 47, 99, 40, 5, 103, 245, 47, 91, 168, 112, 103, 139, 74, 180, 255, 108, 142, 166, 0, 189, 33, 120, 112, 168, 180, 241, 240, 159, 58, 142, 131, 83, 17, 255, 255, 255, 255, 1, 48, 217, 0, 0, 0, 0, 0, 0, 25, 118, 169, 20, 86, 144, 118, 186, 57, 252, 79, 246, 162, 41, 29, 158, 169, 25, 109, 140, 8, 249, 199, 171, 136, 172, 0, 0, 0, 0}
+		testSignedTx := []byte{1, 0, 0, 0, 1, 61, 205, 125, 135, 144, 76, 156, 183, 244, 183, 159, 54, 181, 160, 63, 150, 226, 231, 41, 40, 76, 9, 133, 98, 56, 213, 53, 62, 17, 130, 176, 2, 0, 0, 0, 0, 253, 94, 1, 0, 72, 48, 69, 2, 33, 0, 175, 72, 49, 235, 12, 238, 27, 150, 66, 255, 134, 145, 172, 181, 60, 47, 216, 226, 139, 189, 108, 3, 137, 175, 105, 193, 50, 179, 66, 64, 92, 133, 2, 32, 6, 39, 230, 124, 187, 11, 238, 80, 36, 33, 190, 77, 43, 142, 55, 13, 36, 235, 23, 255, 124, 61, 119, 214, 4, 165, 192, 126, 229, 147, 74, 116, 1, 72, 48, 69, 2, 33, 0, 155, 254, 228, 138, 90, 233, 154, 143, 192, 247, 140, 99, 27, 11, 43, 235, 109, 150, 51, 127, 201, 209, 233, 92, 51, 58, 8, 189, 206, 203, 54, 71, 2, 32, 64, 163, 11, 53, 40, 177, 54, 130, 16, 119, 208, 187, 69, 195, 36, 215, 238, 220, 208, 172, 97, 175, 112, 25, 186, 18, 134, 233, 39, 123, 124, 84, 1, 76, 201, 82, 65, 4, 168, 130, 212, 20, 228, 120, 3, 156, 213, 181, 42, 146, 255, 177, 61, 213, 230, 189, 69, 21, 73, 116, 57, 223, 253, 105, 26, 15, 18, 175, 149, 117, 250, 52, 155, 86, 148, 237, 49, 85, 177, 54, 240, 158, 99, 151, 90, 23, 0, 201, 244, 212, 223, 132, 147, 35, 218, 192, 108, 243, 189, 100, 88, 205, 65, 4, 108, 227, 29, 185, 189, 213, 67, 231, 47, 227, 3, 154, 31, 28, 4, 125, 171, 135, 3, 124, 54, 166, 105, 255, 144, 226, 141, 161, 132, 143, 100, 13, 230, 140, 47, 233, 19, 211, 99, 165, 17, 84, 160, 198, 45, 122, 222, 161, 184, 34, 208, 80, 53, 7, 116, 24, 38, 123, 26, 19, 121, 121, 1, 135, 65, 4, 17, 255, 211, 108, 112, 119, 101, 56, 208, 121, 251, 174, 17, 125, 195, 142, 255, 175, 179, 51, 4, 175, 131, 206, 72, 148, 88, 151, 71, 174, 225, 239, 153, 47, 99, 40, 5, 103, 245, 47, 91, 168, 112, 103, 139, 74, 180, 255, 108, 142, 166, 0, 189, 33, 120, 112, 168, 180, 241, 240, 159, 58, 142, 131, 83, 17, 255, 255, 255, 255, 1, 48, 217, 0, 0, 0, 0, 0, 0, 25, 118, 169, 20, 86, 144, 118, 186, 57, 252, 79, 246, 162, 41, 29, 158, 169, 25, 109, 140, 8, 249, 199, 171, 136, 172, 0, 0, 0, 0}
 
-		signedTx, err := signMultisigTransaction(testRawTransanction, testOrderedPrivateKeys, testScriptPubKey, testRedeemScript, testInputTx, testAmount)
+		testTxIn, err := btcutils.NewTxIn(testInputTx, uint32(testInputIndex), nil)
+		if err != nil {
+			t.Error(err)
+		}
+		testTransaction := btcutils.NewTransaction()
+		testTransaction.AddInput(testTxIn)
+		testTransaction.AddOutput(btcutils.NewTxOut(int64(testAmount), testScriptPubKey))
+		signedTx, err := signMultisigTransaction(btcutils.DoubleSha256(testRawTransanction), btcutils.SIGHASH_ALL, testOrderedPrivateKeys, scriptBranch{m: 2, multisig: true}, testRedeemScript, testTransaction)
 		if err != nil {
 			t.Error(err)
 		}
@@ -76,3 +191,137 @@ func TestSignMultisigTransaction(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateSpendSorted(t *testing.T) {
+	//2-of-3 BIP67 sorted P2WSH spending multisig test with the compressed test keys. Private keys are given in the opposite order to their
+	//public keys in the sorted witness script, so must be reordered to give the same transaction as when given in script order.
+	testPrivateKeys := "L1qH5hF9PBBTEx8fyeSDiFp2ti7eSCsj3itBFTNV7a2w2ZkJWBXK,L1FdnBQgq8585udD26UZPHv5YX8AAGSkddah9S88HVcMfumhRQkt"
+	testOrderedPrivateKeys := "L1FdnBQgq8585udD26UZPHv5YX8AAGSkddah9S88HVcMfumhRQkt,L1qH5hF9PBBTEx8fyeSDiFp2ti7eSCsj3itBFTNV7a2w2ZkJWBXK"
+	testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
+	testWitnessScript := "52210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef21036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d2103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957553ae"
+	testInputTx := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d"
+	testInputIndex := 0
+	testAmount := 55600
+	testInputAmount := 65600
+	testFinalTransactionHex := "010000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000ffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac0400483045022100c1038e41fc114c53009ff64b7f882c31720dd400993c836653c5bed175d69cfa02203041e6f7af443abd3de672b49f250a571a3511ebf972db3e8411c3962e5476230147304402206d5ce1954603ffb6bfae62020405f076eeffd10874271578cd175057d0cb499002203b36284ce7c7fee3ffa3cd70c902fa601e796e501bbeccccdd5596a747fff494016952210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef21036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d2103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957553ae00000000"
+
+	finalTransactionHex := generateSpend(testPrivateKeys, testDestination, testWitnessScript, &btcutils.UTXO{TxHash: testInputTx, Index: uint32(testInputIndex), Amount: int64(testInputAmount)}, testAmount, addressTypeP2WSH, true, false, false, btcutils.SIGHASH_ALL, testTransactionOptions, btcutils.MainNet)
+	if testFinalTransactionHex != finalTransactionHex {
+		testutils.CompareError(t, "Generated sorted P2WSH spend transaction different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
+	}
+	orderedFinalTransactionHex := generateSpend(testOrderedPrivateKeys, testDestination, testWitnessScript, &btcutils.UTXO{TxHash: testInputTx, Index: uint32(testInputIndex), Amount: int64(testInputAmount)}, testAmount, addressTypeP2WSH, false, false, false, btcutils.SIGHASH_ALL, testTransactionOptions, btcutils.MainNet)
+	if orderedFinalTransactionHex != finalTransactionHex {
+		testutils.CompareError(t, "Generated sorted P2WSH spend transaction different from transaction with private keys in script order.", orderedFinalTransactionHex, finalTransactionHex)
+	}
+}
+
+func TestGenerateSpendKeyOrder(t *testing.T) {
+	//2-of-3 P2WSH compressed spend from TestGenerateSpendP2WSHCompressed, with private keys given out of redeem script order
+	//and the second key as uncompressed WIF
+	testWitnessScript := "522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae"
+	testOrderedPrivateKeys := "L1qH5hF9PBBTEx8fyeSDiFp2ti7eSCsj3itBFTNV7a2w2ZkJWBXK,L1FdnBQgq8585udD26UZPHv5YX8AAGSkddah9S88HVcMfumhRQkt"
+	testPrivateKeys := "5JjHVMwJdjPEPQhq34WMUhzLcEd4SD7HgZktEh8WHstWcCLRceV,L1qH5hF9PBBTEx8fyeSDiFp2ti7eSCsj3itBFTNV7a2w2ZkJWBXK"
+	testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
+	testInput := &btcutils.UTXO{TxHash: "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d", Index: 0, Amount: 65600}
+
+	orderedFinalTransactionHex := generateSpend(testOrderedPrivateKeys, testDestination, testWitnessScript, testInput, 55600, addressTypeP2WSH, false, false, false, btcutils.SIGHASH_ALL, testTransactionOptions, btcutils.MainNet)
+	finalTransactionHex := generateSpend(testPrivateKeys, testDestination, testWitnessScript, testInput, 55600, addressTypeP2WSH, false, false, false, btcutils.SIGHASH_ALL, testTransactionOptions, btcutils.MainNet)
+	if orderedFinalTransactionHex != finalTransactionHex {
+		testutils.CompareError(t, "Generated spend transaction different from transaction with private keys in script order.", orderedFinalTransactionHex, finalTransactionHex)
+	}
+
+	//2-of-7 P2SH spend with the public keys of the 7-of-7 test. Given all 7 private keys in reverse order, only the first 2 in
+	//redeem script order sign.
+	testPrivateKeys = "5JrzNyakdSz2SWFeU7RxDCDNL2FtKQjCZXHm1NovR5k1hGfTXFU,5JFWG8sweJzBrejMpWBHpe4xcmbryNcGbzy3ueFEMM8SppBGxb4,5JyTXnoFxjfvMC7cgZLhkrjX2UVpeye9H4qJQjr5LrTpfpY1uon,5J9LHYJQoaip8Aufh2V6oLGwfMcQs88yYY4RBqHSiSiTqK8qHL5,5K23G9Tf7HpcP1gnNuXRUBREj81KGqF2L5dU1HeHX9qtii1kpq5,5JaNjcNtnmQRSqvRQLMMjRvxYVpKXVs6ME9hZxH8VeGPTG9VVoH,5HzzPqdbfmGLFZVYYYpr9Z1uU2D4Xxigik8A6U3zFTdDLtYof4b"
+	testOrderedPrivateKeys = "5HzzPqdbfmGLFZVYYYpr9Z1uU2D4Xxigik8A6U3zFTdDLtYof4b,5JaNjcNtnmQRSqvRQLMMjRvxYVpKXVs6ME9hZxH8VeGPTG9VVoH"
+	testRedeemScript, _ := hex.DecodeString("57410446f1c8de232a065da428bf76e44b41f59a46620dec0aedfc9b5ab651e91f2051d610fddc78b8eba38a634bfe9a74bb015a88c52b9b844c74997035e08a695ce94104704e19d4fc234a42d707d41053c87011f990b564949532d72cab009e136bd60d7d0602f925fce79da77c0dfef4a49c6f44bd0540faef548e37557d74b36da1244104b75a8cb10fd3f1785addbafdb41b409ecd6ffd50d5ad71d8a3cdc5503bcb35d3d13cdf23f6d0eb6ab88446276e2ba5b92d8786da7e5c0fb63aafb62f87443d284104033a82ccb1291bbc27cf541c6c487c213f25db85c620ecb9cbb76ca461ef13db5a80b90c3ae7d2a5e47623cdf520a2586cac7e41f779103a71a1fe177189781e41045e3b4030be5fd9c4c40e7076bd49f022118d90ae9182de61f3a1adb2ff511c97e8a6a82a9292b01878a18c08b7cd658ebdf80e6ed3f26783b25ba1a52fa9e52d4104c93ceb8f4482e131addc58d3efa0b4967bb7c574de15786d55379cc4a43a61571518abe0f05ebf188bcce9580aa70b3f5b1024ca579819c8810ff79967de3f234104a66f63d2941f0befcfba4b73495a7b99fc7ed28cb41e7934e1de82d852628766dc96ee1e196387a68e7fd8898862c2260f1f2557ac2147af07900695f15abd3f57ae")
+	_, _, publicKeys, err := btcutils.ParseMOfNRedeemScript(testRedeemScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redeemScript, err := btcutils.NewMOfNRedeemScript(2, 7, publicKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testInput = &btcutils.UTXO{TxHash: "8462ab27b115d66ea767cc50cb4f1b0070c0200d93d4a6984c374ad6459188f7", Index: 0}
+
+	orderedFinalTransactionHex = generateSpend(testOrderedPrivateKeys, "1EK4KToKVHdz787e26JCQuSTtnPAvJZRC5", hex.EncodeToString(redeemScript), testInput, 75600, addressTypeP2SH, false, false, false, btcutils.SIGHASH_ALL, testTransactionOptions, btcutils.MainNet)
+	finalTransactionHex = generateSpend(testPrivateKeys, "1EK4KToKVHdz787e26JCQuSTtnPAvJZRC5", hex.EncodeToString(redeemScript), testInput, 75600, addressTypeP2SH, false, false, false, btcutils.SIGHASH_ALL, testTransactionOptions, btcutils.MainNet)
+	if orderedFinalTransactionHex != finalTransactionHex {
+		testutils.CompareError(t, "Generated spend transaction different from transaction with the first M private keys in script order.", orderedFinalTransactionHex, finalTransactionHex)
+	}
+}
+
+func TestGenerateSpendRecovery(t *testing.T) {
+	//2-of-3 compressed key recovery witness script from TestGenerateAddressRecovery, spendable by the recovery key alone from block
+	//height 700000. Spent with 2 of the 3 keys at any time, and with the recovery key, whose transactions are locked until at least
+	//block height 700000. Expected transactions verified with btcsuite's txscript.
+	testWitnessScript := "63522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae670360ae0ab175210331393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bac68"
+	testPrivateKeys := "L1FdnBQgq8585udD26UZPHv5YX8AAGSkddah9S88HVcMfumhRQkt,L1qH5hF9PBBTEx8fyeSDiFp2ti7eSCsj3itBFTNV7a2w2ZkJWBXK"
+	testRecoveryPrivateKey := "5JJyqG4bb15zqi7fTA4b227aUxQhBo1Ux6qX69ngeXYLr7fk2hs"
+	testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
+	testInput := &btcutils.UTXO{TxHash: "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d", Index: 0, Amount: 65600}
+	testSpends := []struct {
+		description             string
+		privateKeys             string
+		addressType             string
+		recovery                bool
+		lockTime                int
+		testFinalTransactionHex string
+	}{
+		{"2-of-3 P2WSH", testPrivateKeys, addressTypeP2WSH, false, 0, "010000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000ffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac050047304402207ee38bad1ec7a0645940894820fa8b1dcfd7d27f3fd71918278f7e5c7f3ca25202200c080057274e8687053dce5b22164af034640588c65d07a826511cdb1a11965b014830450221009e1cb92826c116a97a1cc168f9a793be36fd837b6f0eb92ee3e58d5f61d4b6270220470072b1195bea22d065b1edbe09f9a95958300e449592c31425e659ead49cd40101019563522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae670360ae0ab175210331393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bac6800000000"},
+		{"2-of-3 P2SH", testPrivateKeys, addressTypeP2SH, false, 0, "01000000013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b00200000000fd2a0100483045022100b503fc80d841913f30ab704f99ffe8bac440029562d8cfbf479cd003836537c902202890634841f47842418ff7c3d8f928918f13288fdb0f375ffe1302a6756750eb0147304402207bbe773ee0b72a933fdb1b16ce38c67861600301a9695fd64875a64971115d740220120a82856144b073cc8177162d1744ef3f20425c14130650f52baf39f7593a3101514c9563522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae670360ae0ab175210331393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bac68ffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000"},
+		//Locktime defaults to the recovery locktime
+		{"recovery key P2WSH", testRecoveryPrivateKey, addressTypeP2WSH, true, 0, "010000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000feffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac03483045022100d6b05131926ad26a1235a2cc22a9bc32aa103c83474826952161a9b2792f4e520220541c034dd52d9a44f245cc681ebc2fe85a0cd44f93e02d3292c0cb55bda04be201009563522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae670360ae0ab175210331393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bac6860ae0a00"},
+		{"recovery key P2SH with a later locktime", testRecoveryPrivateKey, addressTypeP2SH, true, 700100, "01000000013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b00200000000e047304402202d4cb1f6645d76b67391006f6bd757fd366606497ed84524567c7905331245c4022079e912b190f57ab9579c9b34853fcb7d0acde2edad75c0f6f2f8fec7302dbbd401004c9563522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae670360ae0ab175210331393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bac68feffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88acc4ae0a00"},
+	}
+	for _, testSpend := range testSpends {
+		finalTransactionHex := generateSpend(testSpend.privateKeys, testDestination, testWitnessScript, testInput, 55600, testSpend.addressType, false, testSpend.recovery, false, btcutils.SIGHASH_ALL, TransactionOptions{MaxFee: 100000, LockTime: testSpend.lockTime, NoRBF: true}, btcutils.MainNet)
+		if testSpend.testFinalTransactionHex != finalTransactionHex {
+			testutils.CompareError(t, "Generated "+testSpend.description+" spend transaction different from expected transaction.", testSpend.testFinalTransactionHex, finalTransactionHex)
+		}
+	}
+}
+
+func TestGenerateSpendDelayed(t *testing.T) {
+	//2-of-3 now, or 1-of-3 after 4320 blocks, delayed witness script from TestGenerateAddressDelayed. Spent with 2 of the 3 keys
+	//at any time, and with 1 key in a version 2 transaction whose input sequence number is the 4320 block relative locktime.
+	//Expected transactions verified with btcsuite's txscript.
+	testWitnessScript := "63522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae6702e010b275512103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae68"
+	testPrivateKeys := "L1FdnBQgq8585udD26UZPHv5YX8AAGSkddah9S88HVcMfumhRQkt,L1qH5hF9PBBTEx8fyeSDiFp2ti7eSCsj3itBFTNV7a2w2ZkJWBXK"
+	testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
+	testInput := &btcutils.UTXO{TxHash: "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d", Index: 0, Amount: 65600}
+	testSpends := []struct {
+		description             string
+		privateKeys             string
+		addressType             string
+		delayed                 bool
+		testFinalTransactionHex string
+	}{
+		{"2-of-3 P2WSH", testPrivateKeys, addressTypeP2WSH, false, "010000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000ffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac0500483045022100fb2856428d6a76ddfa55184f063fa5a2e86734807723dd543c049a7d0a3c0c030220771784c84d89b8a3229128342dd59cfed84693af8c5105c641f3f3a93d678dd401473044022033b9f6958d5b52c992ced57779f5c49ce286fb2fcdac1e6d40e140e47bb9ad590220622b159c9b84ed56b5c8d52cf23d2726ee3e825f61d073c346beaa83740a93f3010101da63522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae6702e010b275512103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae6800000000"},
+		{"delayed 1-of-3 P2WSH", "L1qH5hF9PBBTEx8fyeSDiFp2ti7eSCsj3itBFTNV7a2w2ZkJWBXK", addressTypeP2WSH, true, "020000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000e01000000130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac0400483045022100c1ac5ad29fb4230b769c54d5a263e59bb640cb9ccc2c715c9fb25d9f81e1e4ad02202385e31fc3f260fbd72324143fd28c5a0a85de6a6c23f4e9f37165fe1971fd3f0100da63522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae6702e010b275512103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae6800000000"},
+		//Given 2 keys, only the first in redeem script order signs the delayed branch
+		{"delayed 1-of-3 P2SH-P2WSH", testPrivateKeys, addressTypeP2SHP2WSH, true, "020000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b002000000002322002094c979c35a725b9838d95f7d13ac6e80b39188542fb6a2754154c4c1a9b2a922e01000000130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac0400483045022100c1ac5ad29fb4230b769c54d5a263e59bb640cb9ccc2c715c9fb25d9f81e1e4ad02202385e31fc3f260fbd72324143fd28c5a0a85de6a6c23f4e9f37165fe1971fd3f0100da63522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae6702e010b275512103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae6800000000"},
+		{"delayed 1-of-3 P2SH", "L1FdnBQgq8585udD26UZPHv5YX8AAGSkddah9S88HVcMfumhRQkt", addressTypeP2SH, true, "02000000013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b00200000000fd270100483045022100d49a9557a78ec9caaf613f6988ef694eb9d9e73dacee9545d62091ccb00018d802205ef8c538763d7ced0efb302e4dae1eb9e51ff958311396439cfff8976cd4de9001004cda63522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae6702e010b275512103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae68e01000000130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000"},
+	}
+	for _, testSpend := range testSpends {
+		//Delayed spends signal replace-by-fee through their relative locktime whatever rbf is, so it is only set for them
+		finalTransactionHex := generateSpend(testSpend.privateKeys, testDestination, testWitnessScript, testInput, 55600, testSpend.addressType, false, false, testSpend.delayed, btcutils.SIGHASH_ALL, TransactionOptions{MaxFee: 100000, NoRBF: !testSpend.delayed}, btcutils.MainNet)
+		if testSpend.testFinalTransactionHex != finalTransactionHex {
+			testutils.CompareError(t, "Generated "+testSpend.description+" spend transaction different from expected transaction.", testSpend.testFinalTransactionHex, finalTransactionHex)
+		}
+	}
+}
+
+func TestGenerateSpendReplaceable(t *testing.T) {
+	//TestGenerateSpendP2WSHCompressed signalling replace-by-fee, with input sequence number 0xfffffffd.
+	//Expected transaction verified with btcsuite's txscript.
+	testWitnessScript := "522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae"
+	testInput := &btcutils.UTXO{TxHash: "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d", Index: 0, Amount: 65600}
+	testFinalTransactionHex := "010000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000fdffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac04004830450221008b19e0b06a3472bdfe3a83f3b8ed454b925fc451b0904aca1768f9f43c4209170220684ec5d50e244c927c00811afea7510f5057f21332dff63c257d0fd86c3d00fd014730440220474c1c910e3d6efb651c43181ba1618387ce782ed215dcdf86d6a31956a5c8a0022030eb952c66acb7c8ddefed70d770164e6e6a0e038df8444fd02d6cd22c6bbb410169522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae00000000"
+
+	finalTransactionHex := generateSpend("L1FdnBQgq8585udD26UZPHv5YX8AAGSkddah9S88HVcMfumhRQkt,L1qH5hF9PBBTEx8fyeSDiFp2ti7eSCsj3itBFTNV7a2w2ZkJWBXK", "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx", testWitnessScript, testInput, 55600, addressTypeP2WSH, false, false, false, btcutils.SIGHASH_ALL, TransactionOptions{MaxFee: 100000}, btcutils.MainNet)
+	if testFinalTransactionHex != finalTransactionHex {
+		testutils.CompareError(t, "Generated replaceable spend transaction different from expected transaction.", testFinalTransactionHex, finalTransactionHex)
+	}
+}
@@ -0,0 +1,176 @@
+// timelock.go - Transaction locktimes, and spending redeem scripts with more than one branch, such as recovery and delayed scripts.
+package multisig
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
+
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// scriptBranch is one way of satisfying a redeem script. M of its public keys sign, checked by OP_CHECKMULTISIG if multisig
+// is set, and selector follows the signatures, choosing the branch taken at each OP_IF of the script. Transactions spending
+// the branch must have a locktime of at least lockTime, and an input sequence number with a relative locktime of at least sequence.
+type scriptBranch struct {
+	m          int
+	publicKeys [][]byte
+	multisig   bool
+	selector   [][]byte
+	lockTime   uint32
+	sequence   uint32
+}
+
+// scriptBranches returns every way of satisfying redeemScript. A M-of-N multisig script has just the one, and a recovery script
+// (see btcutils.NewRecoveryRedeemScript) or delayed script (see btcutils.NewDelayedRedeemScript) two: its M-of-N multisig branch
+// followed by its recovery key or delayed multisig branch.
+func scriptBranches(redeemScript []byte) []scriptBranch {
+	if len(redeemScript) == 0 || redeemScript[0] != btcutils.OP_IF {
+		m, _, publicKeys, err := btcutils.ParseMOfNRedeemScript(redeemScript)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return []scriptBranch{{m: m, publicKeys: publicKeys, multisig: true}}
+	}
+	//OP_IF takes the multisig branch for a true selector, and OP_ELSE the recovery or delayed branch for an empty one
+	if m, _, publicKeys, recoveryPublicKey, lockTime, err := btcutils.ParseRecoveryRedeemScript(redeemScript); err == nil {
+		return []scriptBranch{
+			{m: m, publicKeys: publicKeys, multisig: true, selector: [][]byte{{1}}},
+			{m: 1, publicKeys: [][]byte{recoveryPublicKey}, selector: [][]byte{{}}, lockTime: lockTime},
+		}
+	}
+	m, _, publicKeys, delayedM, sequence, err := btcutils.ParseDelayedRedeemScript(redeemScript)
+	if err != nil {
+		log.Fatal("Redeem script starting with OP_IF is neither a recovery script, as made with 'address --recovery-key', nor a delayed script, as made with 'address --delayed-m'.")
+	}
+	return []scriptBranch{
+		{m: m, publicKeys: publicKeys, multisig: true, selector: [][]byte{{1}}},
+		{m: delayedM, publicKeys: publicKeys, multisig: true, selector: [][]byte{{}}, sequence: sequence},
+	}
+}
+
+// spendBranch returns the branch of redeemScript to sign: the M-of-N multisig branch, the recovery key branch of a
+// recovery script with flagRecovery, or the delayed multisig branch of a delayed script with flagDelayed.
+func spendBranch(redeemScript []byte, flagRecovery bool, flagDelayed bool) scriptBranch {
+	branches := scriptBranches(redeemScript)
+	switch {
+	case flagRecovery && flagDelayed:
+		log.Fatal("Give at most one of --recovery and --delayed.")
+	case flagRecovery:
+		if len(branches) < 2 || branches[1].lockTime == 0 {
+			log.Fatal("Redeem script has no recovery key. Spend without --recovery, or give the redeem script made with 'address --recovery-key'.")
+		}
+		return branches[1]
+	case flagDelayed:
+		if len(branches) < 2 || branches[1].sequence == 0 {
+			log.Fatal("Redeem script has no delayed branch. Spend without --delayed, or give the redeem script made with 'address --delayed-m'.")
+		}
+		return branches[1]
+	}
+	return branches[0]
+}
+
+// stack returns the items satisfying branch given signatures from its keys, bottom first, to go before the redeem script.
+func (branch scriptBranch) stack(signatures [][]byte) [][]byte {
+	var items [][]byte
+	if branch.multisig {
+		items = append(items, []byte{}) //Empty item for Multisig off-by-one error
+	}
+	items = append(items, signatures...)
+	return append(items, branch.selector...)
+}
+
+// parseLockTime checks flagLockTime is a transaction locktime: 0 for none, or a block height or Unix timestamp (see btcutils.LockTimeThreshold).
+func parseLockTime(flagLockTime int) uint32 {
+	if flagLockTime < 0 || int64(flagLockTime) > math.MaxUint32 {
+		log.Fatalf("--locktime must be a block height below %d or a Unix timestamp, up to %d.", btcutils.LockTimeThreshold, uint32(math.MaxUint32))
+	}
+	return uint32(flagLockTime)
+}
+
+// branchLockTime returns the locktime of a transaction spending branch: lockTime if given, otherwise the earliest locktime
+// branch can be spent with. A lockTime of a different kind (block height or timestamp) or earlier than the branch allows is rejected.
+func branchLockTime(branch scriptBranch, lockTime uint32) uint32 {
+	if branch.lockTime == 0 {
+		return lockTime
+	}
+	if lockTime == 0 {
+		return branch.lockTime
+	}
+	if (lockTime < btcutils.LockTimeThreshold) != (branch.lockTime < btcutils.LockTimeThreshold) {
+		log.Fatalf("--locktime %d and the redeem script's locktime %d must both be block heights or both Unix timestamps.", lockTime, branch.lockTime)
+	}
+	if lockTime < branch.lockTime {
+		log.Fatalf("--locktime %d is before the redeem script's locktime %d, so the recovery key cannot spend yet.", lockTime, branch.lockTime)
+	}
+	return lockTime
+}
+
+// newUnsignedTransaction creates the transaction spending input to outputs, with scriptSig in place until it is signed.
+// A non-zero lockTime is only enforced if an input has a sequence number below the final 0xffffffff, so the input is given one.
+// A non-zero sequence is a BIP68 relative locktime for the input, which is only enforced in version 2 transactions.
+// With rbf, the input sequence number signals the transaction can be replaced by one paying a higher fee (BIP125), which
+// relative locktimes always do.
+func newUnsignedTransaction(input *btcutils.UTXO, scriptSig []byte, outputs []*btcutils.TxOut, lockTime uint32, sequence uint32, rbf bool) *btcutils.Transaction {
+	txIn, err := btcutils.NewTxIn(input.TxHash, input.Index, scriptSig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch {
+	case rbf:
+		txIn.Sequence = btcutils.MaxRBFSequenceNum
+	case lockTime != 0:
+		txIn.Sequence = btcutils.MaxTxInSequenceNum - 1
+	}
+	transaction := btcutils.NewTransaction()
+	if sequence != 0 {
+		txIn.Sequence = sequence
+		transaction.Version = 2
+	}
+	transaction.LockTime = lockTime
+	transaction.AddInput(txIn)
+	for _, output := range outputs {
+		transaction.AddOutput(output)
+	}
+	return transaction
+}
+
+// formatLockTime describes lockTime as the block height or UTC time it refers to.
+func formatLockTime(lockTime uint32) string {
+	if lockTime < btcutils.LockTimeThreshold {
+		return fmt.Sprintf("block height %d", lockTime)
+	}
+	return time.Unix(int64(lockTime), 0).UTC().Format("2006-01-02 15:04:05 UTC")
+}
+
+// formatSequence describes the BIP68 relative locktime of sequence as the number of blocks or the time it refers to.
+func formatSequence(sequence uint32) string {
+	if sequence&btcutils.SequenceLockTimeIsSeconds == 0 {
+		return fmt.Sprintf("%d blocks", sequence&btcutils.SequenceLockTimeMask)
+	}
+	return (time.Duration(sequence&btcutils.SequenceLockTimeMask<<btcutils.SequenceLockTimeGranularity) * time.Second).String()
+}
+
+// outputLockTime prints when a signed transaction can first be mined, if it has a locktime or its input a relative locktime.
+func outputLockTime(finalTransactionHex string) {
+	finalTransaction, err := hex.DecodeString(finalTransactionHex)
+	if err != nil {
+		log.Fatal(err)
+	}
+	transaction, err := btcutils.ParseTransaction(finalTransaction)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if transaction.Version >= 2 && transaction.Inputs[0].Sequence&btcutils.SequenceLockTimeDisabled == 0 {
+		fmt.Printf("Transaction can only be mined once the output it spends has been in the blockchain for %v, and nodes will not relay it before then.\n", formatSequence(transaction.Inputs[0].Sequence))
+	}
+	switch {
+	case transaction.LockTime == 0:
+	case transaction.LockTime < btcutils.LockTimeThreshold:
+		fmt.Printf("Transaction is locked until %v: it can only be mined in later blocks, and nodes will not relay it before then.\n", formatLockTime(transaction.LockTime))
+	default:
+		fmt.Printf("Transaction is locked until %v: it can only be mined once the median time of the last 11 blocks is later, and nodes will not relay it before then.\n", formatLockTime(transaction.LockTime))
+	}
+}
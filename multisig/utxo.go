@@ -0,0 +1,131 @@
+// utxo.go - Working out the output being spent, and what it is worth, from the input flags of fund, spend and psbt create.
+package multisig
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
+
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strings"
+)
+
+// parseInput returns the output being spent, given flagInput (UTXO descriptor TXID:VOUT[:AMOUNT[:SCRIPTPUBKEY]]) or
+// flagInputTx and flagInputIndex (input transaction hash and output index), flagInputAmount (amount in Satoshis, 0 if unknown)
+// and flagPrevTx (raw hex of the input transaction, to read the amount and scriptPubKey from).
+// Amounts and scriptPubKeys given more than one way must agree.
+func parseInput(flagInput string, flagInputTx string, flagInputIndex int, flagInputAmount int, flagPrevTx string) *btcutils.UTXO {
+	if flagInputIndex < 0 || flagInputAmount < 0 {
+		log.Fatal("--input-index and --input-amount cannot be negative.")
+	}
+	var input *btcutils.UTXO
+	var err error
+	switch {
+	case flagInput != "" && flagInputTx != "":
+		log.Fatal("Give the input as either --input or --input-tx, not both.")
+	case flagInput != "":
+		input, err = btcutils.ParseUTXO(flagInput)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case flagInputTx != "":
+		input = &btcutils.UTXO{TxHash: strings.ToLower(strings.TrimSpace(flagInputTx)), Index: uint32(flagInputIndex)}
+	}
+	prevTx := parsePrevTx(flagPrevTx)
+	if input == nil {
+		if prevTx == nil {
+			log.Fatal("Input transaction must be given with --input, --input-tx or --prev-tx.")
+		}
+		//The previous transaction alone identifies the input, along with --input-index
+		input = &btcutils.UTXO{TxHash: prevTx.TxHash(), Index: uint32(flagInputIndex)}
+	}
+	if flagInputAmount > 0 {
+		err = input.Merge(&btcutils.UTXO{TxHash: input.TxHash, Index: input.Index, Amount: int64(flagInputAmount)})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if prevTx != nil {
+		if prevTx.TxHash() != input.TxHash {
+			log.Fatalf("--prev-tx is transaction %s, not the input transaction %s.", prevTx.TxHash(), input.TxHash)
+		}
+		prevOutput, err := prevTx.UTXO(input.Index)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = input.Merge(prevOutput)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	return input
+}
+
+// parsePrevTx decodes flagPrevTx (raw hex of a previous transaction), returning nil if it is empty.
+func parsePrevTx(flagPrevTx string) *btcutils.Transaction {
+	flagPrevTx = strings.TrimSpace(flagPrevTx)
+	if flagPrevTx == "" {
+		return nil
+	}
+	rawTransaction, err := hex.DecodeString(flagPrevTx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	prevTx, err := btcutils.ParseTransaction(rawTransaction)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return prevTx
+}
+
+// checkInputScriptPubKey checks the output being spent is locked by expectedScriptPubKey, described by description, when
+// its scriptPubKey is known. Signing an output with the wrong script gives a transaction the network rejects.
+func checkInputScriptPubKey(input *btcutils.UTXO, expectedScriptPubKey []byte, description string) {
+	if input.ScriptPubKey != nil && !bytes.Equal(input.ScriptPubKey, expectedScriptPubKey) {
+		log.Fatalf("Input %s has scriptPubKey %x, but %s has scriptPubKey %x.", input, input.ScriptPubKey, description, expectedScriptPubKey)
+	}
+}
+
+// checkMultisigInput checks the output being spent is a multisig output of type flagType (p2sh, p2wsh or p2sh-p2wsh) for
+// redeemScript, when its scriptPubKey is known, suggesting the right --type if it is a different type for the same script.
+func checkMultisigInput(input *btcutils.UTXO, flagType string, redeemScript []byte) {
+	if input.ScriptPubKey == nil {
+		return
+	}
+	for _, addressType := range []string{addressTypeP2SH, addressTypeP2WSH, addressTypeP2SHP2WSH} {
+		if addressType != flagType && bytes.Equal(input.ScriptPubKey, multisigScriptPubKey(addressType, redeemScript)) {
+			log.Fatalf("Input %s is a %s output of this redeem script, not %s. Spend it with --type=%s.", input, addressType, flagType, addressType)
+		}
+	}
+	checkInputScriptPubKey(input, multisigScriptPubKey(flagType, redeemScript), "a "+flagType+" output of this redeem script")
+}
+
+// multisigScriptPubKey creates the scriptPubKey of a multisig address of type flagType (p2sh, p2wsh or p2sh-p2wsh) for redeemScript,
+// the witness script for p2wsh and p2sh-p2wsh.
+func multisigScriptPubKey(flagType string, redeemScript []byte) []byte {
+	var scriptPubKey []byte
+	var err error
+	switch flagType {
+	case addressTypeP2SH:
+		var redeemScriptHash []byte
+		redeemScriptHash, err = btcutils.Hash160(redeemScript)
+		if err == nil {
+			scriptPubKey, err = btcutils.NewP2SHScriptPubKey(redeemScriptHash)
+		}
+	case addressTypeP2WSH:
+		witnessScriptHash := sha256.Sum256(redeemScript)
+		scriptPubKey, err = btcutils.NewP2WSHScriptPubKey(witnessScriptHash[:])
+	case addressTypeP2SHP2WSH:
+		//The P2SH redeem script is the P2WSH scriptPubKey
+		var witnessProgramHash []byte
+		witnessProgramHash, err = btcutils.Hash160(multisigScriptPubKey(addressTypeP2WSH, redeemScript))
+		if err == nil {
+			scriptPubKey, err = btcutils.NewP2SHScriptPubKey(witnessProgramHash)
+		}
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	return scriptPubKey
+}
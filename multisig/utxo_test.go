@@ -0,0 +1,73 @@
+package multisig
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
+	"github.com/soroushjp/go-bitcoin-multisig/testutils"
+
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// Funding transaction paying 65600 satoshi to the 2-of-3 P2SH multisig address 347N1Thc213QqfYCz3PZkjoJpNv5b14kBd, as generated by TestGenerateFund
+const testPrevTxHex = "0100000001acc6fb9ec2c3884d3a12a89e7078c83853d9b7912281cefb14bac00a2737d33a000000008b483045022100fb244ac83b257f4233920077819dfa5203a11cd330c58a37c984699bc8048e9102200caca5b3772022a5cb5ce8e31f644da4e27e2c4f121cfd9b5291e3bccf7017d701410431393af9984375830971ab5d3094c6a7d02db3568b2b06212a7090094549701bbb9e84d9477451acc42638963635899ce91bacb451a1bb6da73ddfbcf596bddfffffffff01400001000000000017a9141a8b0026343166625c7475f01e48b5ede8c0252e8700000000"
+
+func TestParseInput(t *testing.T) {
+	testTxHash := "57a3bf6123f1f9ad095f0b285236cd0f9e312225b868cf7e1c8c8d7be4cba08e"
+	testScriptPubKeyHex := "a9141a8b0026343166625c7475f01e48b5ede8c0252e87"
+	testCases := []struct {
+		input        *btcutils.UTXO
+		amount       int64
+		scriptPubKey string
+	}{
+		//Amount and scriptPubKey read from the previous transaction
+		{parseInput("", "", 0, 0, testPrevTxHex), 65600, testScriptPubKeyHex},
+		{parseInput("", testTxHash, 0, 65600, testPrevTxHex), 65600, testScriptPubKeyHex},
+		{parseInput(testTxHash+":0:65600:"+testScriptPubKeyHex, "", 0, 0, testPrevTxHex), 65600, testScriptPubKeyHex},
+		//UTXO descriptors
+		{parseInput(testTxHash+":0", "", 0, 65600, ""), 65600, ""},
+		{parseInput(testTxHash+":0:65600:"+testScriptPubKeyHex, "", 0, 0, ""), 65600, testScriptPubKeyHex},
+		//Input transaction hash and index, as before
+		{parseInput("", "57A3BF6123F1F9AD095F0B285236CD0F9E312225B868CF7E1C8C8D7BE4CBA08E", 0, 0, ""), 0, ""},
+	}
+	for _, testCase := range testCases {
+		if testCase.input.String() != testTxHash+":0" || testCase.input.Amount != testCase.amount || hex.EncodeToString(testCase.input.ScriptPubKey) != testCase.scriptPubKey {
+			testutils.CompareError(t, "Parsed input different from expected input.", testCase, testCase.input)
+		}
+	}
+}
+
+func TestMultisigScriptPubKey(t *testing.T) {
+	//2-of-3 multisig witness script with compressed public keys, and its addresses of each type
+	testWitnessScript, _ := hex.DecodeString("522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae")
+	testAddresses := map[string]string{
+		addressTypeP2SH:      "3MsXykid1v9i3FWP4Fbj8vVLKrjPbBQCbP",
+		addressTypeP2WSH:     "bc1qznadz5sy773nv3pr2n2jmymcyzkrn64tttz5640nz6988zmemzfs3dn6jt",
+		addressTypeP2SHP2WSH: "3F7ULEo5xPC4B9rkN3T8tWL8ZfTCvycAx4",
+	}
+	for addressType, address := range testAddresses {
+		testScriptPubKey := destinationScriptPubKey(address, btcutils.MainNet)
+		scriptPubKey := multisigScriptPubKey(addressType, testWitnessScript)
+		if !bytes.Equal(testScriptPubKey, scriptPubKey) {
+			testutils.CompareError(t, "Generated "+addressType+" multisig scriptPubKey different from expected scriptPubKey.", hex.EncodeToString(testScriptPubKey), hex.EncodeToString(scriptPubKey))
+		}
+	}
+}
+
+func TestGeneratePsbtCreatePrevTx(t *testing.T) {
+	//Same spend as TestGeneratePsbt, of the output created by the funding transaction in testPrevTxHex
+	testDestination := "18tiB1yNTzJMCg6bQS1Eh29dvJngq8QTfx"
+	testRedeemScript := "524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae"
+	testAmount := 55600
+
+	input := parseInput("", "", 0, 0, testPrevTxHex)
+	psbt := generatePsbtCreate(testDestination, testRedeemScript, input, testAmount, parsePrevTx(testPrevTxHex), btcutils.SIGHASH_ALL, testTransactionOptions, btcutils.MainNet)
+	prevTx := psbt.Inputs[0].NonWitnessUtxo
+	if prevTx == nil || prevTx.TxHash() != input.TxHash {
+		t.Fatal("PSBT created with --prev-tx does not include the previous transaction.")
+	}
+	txIn := psbt.UnsignedTx.Inputs[0]
+	if hex.EncodeToString(btcutils.ReverseBytes(txIn.PreviousTxHash)) != input.TxHash || txIn.PreviousOutputIndex != input.Index {
+		testutils.CompareError(t, "PSBT input different from expected input.", input, txIn)
+	}
+}
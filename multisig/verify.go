@@ -0,0 +1,133 @@
+// verify.go - Verifying signed transactions by running each input's scripts against the output it spends.
+package multisig
+
+import (
+	"github.com/soroushjp/go-bitcoin-multisig/btcutils"
+
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// OutputVerify formats and prints relevant outputs to the user.
+func OutputVerify(flagTransaction string, flagPrevTxs string, flagUtxos string, flagConsensus bool) {
+	prevOutputs, results := generateVerify(flagTransaction, flagPrevTxs, flagUtxos, flagConsensus)
+
+	fmt.Println("-----------------------------------------------------------------------------------------------------------------------------------")
+	valid := true
+	for i, result := range results {
+		if result != nil {
+			valid = false
+			fmt.Printf("Input #%d spending %v: INVALID. %v\n", i, prevOutputs[i], result)
+		} else {
+			fmt.Printf("Input #%d spending %v: valid.\n", i, prevOutputs[i])
+		}
+	}
+	fmt.Println("-----------------------------------------------------------------------------------------------------------------------------------")
+	if !valid {
+		log.Fatal("Transaction is not valid. Do not broadcast it.")
+	}
+	if flagConsensus {
+		fmt.Println("Transaction is valid by consensus rules. Nodes may still refuse to relay it if it is not standard.")
+	} else {
+		fmt.Println("Transaction is valid and standard. Broadcast it when ready.")
+	}
+}
+
+// generateVerify is the high-level logic for verifying a signed transaction with the 'go-bitcoin-multisig verify' subcommand.
+// Takes flagTransaction (hex representation of the signed transaction), flagPrevTxs (comma separated list of raw hex previous
+// transactions) and flagUtxos (comma separated list of UTXO descriptors TXID:VOUT:AMOUNT:SCRIPTPUBKEY), which between them
+// give the output spent by each input, and flagConsensus (true to check only consensus rules, not standardness) as arguments.
+// Returns the output spent by each input, and nil or the reason each input is invalid.
+func generateVerify(flagTransaction string, flagPrevTxs string, flagUtxos string, flagConsensus bool) ([]*btcutils.UTXO, []error) {
+	rawTransaction, err := hex.DecodeString(strings.TrimSpace(flagTransaction))
+	if err != nil {
+		log.Fatal(err)
+	}
+	transaction, err := btcutils.ParseTransaction(rawTransaction)
+	if err != nil {
+		log.Fatal(err)
+	}
+	//Collect every output given, by TXID:VOUT
+	knownOutputs := make(map[string]*btcutils.UTXO)
+	for _, prevTxHex := range splitList(flagPrevTxs) {
+		prevTx := parsePrevTx(prevTxHex)
+		for i := range prevTx.Outputs {
+			prevOutput, err := prevTx.UTXO(uint32(i))
+			if err != nil {
+				log.Fatal(err)
+			}
+			knownOutputs[prevOutput.String()] = prevOutput
+		}
+	}
+	for _, descriptor := range splitList(flagUtxos) {
+		utxo, err := btcutils.ParseUTXO(descriptor)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if utxo.Amount == 0 || utxo.ScriptPubKey == nil {
+			log.Fatalf("UTXO %s needs its amount and scriptPubKey, as TXID:VOUT:AMOUNT:SCRIPTPUBKEY.", utxo)
+		}
+		if knownOutput, ok := knownOutputs[utxo.String()]; ok {
+			err = knownOutput.Merge(utxo)
+			if err != nil {
+				log.Fatal(err)
+			}
+			continue
+		}
+		knownOutputs[utxo.String()] = utxo
+	}
+
+	flags := btcutils.StandardScriptFlags
+	if flagConsensus {
+		flags = btcutils.ConsensusScriptFlags
+	}
+	prevOutputs := make([]*btcutils.UTXO, len(transaction.Inputs))
+	results := make([]error, len(transaction.Inputs))
+	for i, txIn := range transaction.Inputs {
+		outPoint := fmt.Sprintf("%x:%d", btcutils.ReverseBytes(txIn.PreviousTxHash), txIn.PreviousOutputIndex)
+		prevOutput, ok := knownOutputs[outPoint]
+		if !ok {
+			log.Fatalf("Output %s spent by input #%d is unknown. Give its previous transaction with --prev-txs, or the output with --utxos.", outPoint, i)
+		}
+		prevOutputs[i] = prevOutput
+		results[i] = btcutils.VerifyInput(transaction, i, prevOutput.ScriptPubKey, prevOutput.Amount, flags)
+	}
+
+	return prevOutputs, results
+}
+
+// verifySignedTransaction checks the first input of a transaction just signed by fund or spend satisfies scriptPubKey, the script
+// of the output it spends, worth amount Satoshis. Only consensus rules are checked, since spending non-standard addresses (eg. SegWit
+// with uncompressed public keys) is still allowed.
+func verifySignedTransaction(finalTransaction []byte, scriptPubKey []byte, amount int64) {
+	transaction, err := btcutils.ParseTransaction(finalTransaction)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = btcutils.VerifyInput(transaction, 0, scriptPubKey, amount, btcutils.ConsensusScriptFlags)
+	if err != nil {
+		log.Fatalf("Signed transaction is not valid, so it has not been output. %v", err)
+	}
+}
+
+// splitList splits a comma separated flag value into its trimmed, non-empty items.
+func splitList(flagList string) []string {
+	var items []string
+	if strings.TrimSpace(flagList) == "" {
+		return items
+	}
+	records, err := csv.NewReader(strings.NewReader(flagList)).Read()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, item := range records {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,45 @@
+package multisig
+
+import (
+	"testing"
+)
+
+func TestGenerateVerify(t *testing.T) {
+	//Spends of output 0 of transaction 02b08211... from TestGenerateSpend, TestGenerateSpendP2WSH and TestGenerateSpendP2WSHCompressed
+	testInput := "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d:0:65600:"
+	testP2SHTransactionHex := "01000000013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b00200000000fd5e0100483045022100af4831eb0cee1b9642ff8691acb53c2fd8e28bbd6c0389af69c132b342405c8502200627e67cbb0bee502421be4d2b8e370d24eb17ff7c3d77d604a5c07ee5934a74014830450221009bfee48a5ae99a8fc0f78c631b0b2beb6d96337fc9d1e95c333a08bdcecb3647022040a30b3528b136821077d0bb45c324d7eedcd0ac61af7019ba1286e9277b7c54014cc9524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353aeffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac00000000"
+	testP2WSHUncompressedTransactionHex := "010000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000ffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac0400483045022100cf0f422b00367b062740dcff95af09f41f015dccb6694ecb69dd0934fd4a3e0302204c3f3bc108ee873929a94d92dd94775e0aa8ce334b79e2e1e4394593c5e0bdcd01483045022100dd7caa994f6ac7eb91673ff74c31c340d6150c245031bf93196c8be5e8644a210220700165544e788844c28bba733c97db614030d2d76e3db947b02a8533968d3bc501c9524104a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af9575fa349b5694ed3155b136f09e63975a1700c9f4d4df849323dac06cf3bd6458cd41046ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640de68c2fe913d363a51154a0c62d7adea1b822d05035077418267b1a1379790187410411ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef992f63280567f52f5ba870678b4ab4ff6c8ea600bd217870a8b4f1f09f3a8e8353ae00000000"
+	testP2WSHTransactionHex := "010000000001013dcd7d87904c9cb7f4b79f36b5a03f96e2e729284c09856238d5353e1182b0020000000000ffffffff0130d90000000000001976a914569076ba39fc4ff6a2291d9ea9196d8c08f9c7ab88ac0400473044022051e94657dd7654c881aa16d6f0e8b16801e5471ba46da7cc3df54b625f884270022041078fff8d287ad21d5a98018d471795658c67c2af548d0d4de6f6911beaf99101473044022033e50672858b02187fc4361ea0f4f23efeb1c0ea080722eae57dcded87bd9cea02207eb6fb5965fca629bc75efbffaa6dde804a0ec31870ddc3ef974cbff3aaca7740169522103a882d414e478039cd5b52a92ffb13dd5e6bd4515497439dffd691a0f12af957521036ce31db9bdd543e72fe3039a1f1c047dab87037c36a669ff90e28da1848f640d210311ffd36c70776538d079fbae117dc38effafb33304af83ce4894589747aee1ef53ae00000000"
+	testP2SHScriptPubKeyHex := "a9141a8b0026343166625c7475f01e48b5ede8c0252e87"
+	testP2WSHUncompressedScriptPubKeyHex := "0020e381a304851e57acf999f9a134b5bb91d340fd33289e54c3a080ab5f3ebf556d"
+	testP2WSHScriptPubKeyHex := "002014fad15204f7a336442354d52d937820ac39eaab5ac54d55f3168a738b79d893"
+
+	testCases := []struct {
+		transaction string
+		utxos       string
+		consensus   bool
+		valid       bool
+	}{
+		{testP2SHTransactionHex, testInput + testP2SHScriptPubKeyHex, false, true},
+		{testP2WSHTransactionHex, testInput + testP2WSHScriptPubKeyHex, false, true},
+		//Uncompressed public keys in a witness script are valid, but not standard
+		{testP2WSHUncompressedTransactionHex, testInput + testP2WSHUncompressedScriptPubKeyHex, true, true},
+		{testP2WSHUncompressedTransactionHex, testInput + testP2WSHUncompressedScriptPubKeyHex, false, false},
+		//Witness signatures commit to the amount spent
+		{testP2WSHTransactionHex, "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d:0:65601:" + testP2WSHScriptPubKeyHex, true, false},
+		//Wrong scriptPubKey
+		{testP2SHTransactionHex, testInput + testP2WSHScriptPubKeyHex, true, false},
+	}
+	for _, testCase := range testCases {
+		prevOutputs, results := generateVerify(testCase.transaction, "", testCase.utxos, testCase.consensus)
+		if len(results) != 1 || prevOutputs[0].String() != "02b082113e35d5386285094c2829e7e2963fa0b5369fb7f4b79c4c90877dcd3d:0" {
+			t.Fatal("Verify results do not match the transaction inputs:", prevOutputs)
+		}
+		if testCase.valid && results[0] != nil {
+			t.Error("Verify rejecting valid transaction:", results[0], testCase.utxos)
+		}
+		if !testCase.valid && results[0] == nil {
+			t.Error("Verify accepting invalid transaction:", testCase.utxos, testCase.consensus)
+		}
+	}
+}